@@ -0,0 +1,40 @@
+// Command migrate-money converts prices and amounts stored as plain numbers
+// into the Money format. Run it once against MONGO_URI after upgrading:
+//
+//	go run ./cmd/migrate-money -currency USD
+package main
+
+import (
+	"context"
+	"flag"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	currency := flag.String("currency", models.DefaultCurrency, "currency the existing amounts are in")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found. Proceeding with environment variables.")
+	}
+	if _, err := models.ParseMoney("0", *currency); err != nil {
+		log.Fatalf("Invalid currency: %v", err)
+	}
+
+	client := utils.ConnectDB()
+	defer client.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	n, err := store.NewMongo(client).MigrateMoney(ctx, *currency)
+	if err != nil {
+		log.Fatalf("Migration stopped after %d documents: %v", n, err)
+	}
+	log.Printf("Converted %d documents to %s money amounts", n, *currency)
+}
@@ -0,0 +1,33 @@
+// Command open-stock-ledger records the stock products already hold as
+// opening movements of the inventory ledger. Run it once against MONGO_URI
+// after upgrading, before reconciling any product:
+//
+//	go run ./cmd/open-stock-ledger
+package main
+
+import (
+	"context"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found. Proceeding with environment variables.")
+	}
+
+	client := utils.ConnectDB()
+	defer client.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	n, err := store.NewMongo(client).OpenStockLedger(ctx)
+	if err != nil {
+		log.Fatalf("Migration stopped after %d products: %v", n, err)
+	}
+	log.Printf("Opened the stock ledger of %d products", n)
+}
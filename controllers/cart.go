@@ -3,90 +3,190 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go-ecommerce/middleware"
 	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/tax"
 	"go-ecommerce/utils"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // CartController handles cart-related requests
 type CartController struct {
-	Collection *mongo.Collection
+	Carts        store.CartStore
+	Users        store.UserStore
+	Products     store.ProductStore
+	Promotions   store.PromotionStore
+	Shipping     store.ShippingStore
+	Tax          tax.Calculator
+	EmailService *utils.EmailService
+	// ReminderAfter is how long a cart sits untouched before, and between,
+	// abandoned-cart reminders; ReminderLimit caps how many are sent
+	ReminderAfter time.Duration
+	ReminderLimit int
 }
 
 // NewCartController creates a new CartController
-func NewCartController(client *mongo.Client) *CartController {
-	collection := client.Database("ecommerce").Collection("carts")
+func NewCartController(s store.Store, taxes tax.Calculator, emailService *utils.EmailService) *CartController {
 	return &CartController{
-		Collection: collection,
+		Carts:         s,
+		Users:         s,
+		Products:      s,
+		Promotions:    s,
+		Shipping:      s,
+		Tax:           taxes,
+		EmailService:  emailService,
+		ReminderAfter: 24 * time.Hour,
+		ReminderLimit: 2,
 	}
 }
 
-// AddToCart adds a product to the user's cart
-func (cc *CartController) AddToCart(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+// cartResponse is the cart priced at current prices, with its coupon applied
+// and tax worked out for the user's address
+type cartResponse struct {
+	models.Cart
+	Lines       []models.OrderItem       `json:"lines"`
+	Warnings    []models.CartWarning     `json:"warnings"` // Items whose price or availability changed since they were added
+	Saved       []models.SavedLine       `json:"saved"`    // The items saved for later, at current prices and stock
+	Subtotal    models.Money             `json:"subtotal"`
+	Discount    models.Money             `json:"discount"`
+	Tax         models.Money             `json:"tax"`
+	TaxIncluded bool                     `json:"tax_included,omitempty"` // Tax is part of the prices, not added to them
+	Total       models.Money             `json:"total"`
+	Promotion   *models.AppliedPromotion `json:"promotion,omitempty"`
+	CouponError string                   `json:"coupon_error,omitempty"` // Why the cart's coupon does not apply right now
+
+	couponErr error
+}
+
+// priceCart prices the cart's items, applies its coupon and taxes them for
+// delivery to address. Items whose product or variant no longer exists are
+// left out with a warning; checkout rejects them.
+func (cc *CartController) priceCart(ctx context.Context, cart *models.Cart, address models.Address) (*cartResponse, error) {
+	response := &cartResponse{
+		Cart:     *cart,
+		Lines:    []models.OrderItem{},
+		Warnings: []models.CartWarning{},
+		Saved:    []models.SavedLine{},
+		Subtotal: models.NewMoney(0, models.DefaultCurrency),
+		Discount: models.NewMoney(0, models.DefaultCurrency),
+		Tax:      models.NewMoney(0, models.DefaultCurrency),
+		Total:    models.NewMoney(0, models.DefaultCurrency),
+	}
+	for _, item := range cart.Items {
+		product, err := cc.Products.FindProductByID(ctx, item.ProductID)
+		if errors.Is(err, store.ErrNotFound) {
+			response.Warnings = append(response.Warnings, models.CartWarning{
+				ProductID: item.ProductID,
+				SKU:       item.SKU,
+				Code:      models.CartItemUnavailable,
+				Message:   "This product is no longer available",
+			})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		response.Warnings = append(response.Warnings, product.CartWarnings(item)...)
+		if _, ok := product.Variant(item.SKU); product.HasVariants() && !ok {
+			continue
+		}
+		response.Lines = append(response.Lines, models.NewOrderItem(*product, item.SKU, item.Quantity))
+	}
+	for _, item := range cart.SavedForLater {
+		line, err := savedLine(ctx, cc.Products, item.ProductID, item.SKU, item.Quantity, item.UnitPrice)
+		if err != nil {
+			return nil, err
+		}
+		response.Saved = append(response.Saved, line)
 	}
 
+	if cart.CouponCode != "" {
+		applied, err := applyCoupon(ctx, cc.Promotions, cart.CouponCode, cart.UserID, response.Lines)
+		if couponRejected(err) {
+			response.couponErr = err
+			response.CouponError = err.Error()
+		} else if err != nil {
+			return nil, err
+		}
+		response.Promotion = applied
+	}
+	if err := cc.Tax.Calculate(ctx, address, response.Lines); err != nil {
+		return nil, err
+	}
+
+	for _, line := range response.Lines {
+		response.Subtotal = response.Subtotal.Add(line.Subtotal())
+		response.Discount = response.Discount.Add(line.Discount)
+		response.Tax = response.Tax.Add(line.Tax)
+		response.TaxIncluded = response.TaxIncluded || line.TaxIncluded
+		response.Total = response.Total.Add(line.LineTotal)
+	}
+	return response, nil
+}
+
+// AddToCart adds a product to the user's cart, or to a guest cart for an
+// anonymous visitor. The first item a guest adds starts their cart and
+// returns its token in the X-Cart-Token header.
+func (cc *CartController) AddToCart(w http.ResponseWriter, r *http.Request) {
 	var item models.CartItem
 	err := json.NewDecoder(r.Body).Decode(&item)
 	if err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	if item.Quantity <= 0 {
+		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+		return
+	}
 
-	// Find user ID from email
-	userCollection := cc.Collection.Database().Collection("users")
-	var user models.User
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	err = userCollection.FindOne(ctx, bson.M{"email": claims.Email}).Decode(&user)
+	user, err := cc.shopper(ctx, r)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
+	product, err := cc.Products.FindProductByID(ctx, item.ProductID)
+	if err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
 
 	// Check if cart exists
-	var cart models.Cart
-	err = cc.Collection.FindOne(ctx, bson.M{"user_id": user.ID}).Decode(&cart)
+	cart, err := cc.findCart(ctx, r, user)
 	if err != nil {
 		// Create new cart
-		cart = models.Cart{
-			UserID: user.ID,
-			Items:  []models.CartItem{item},
+		cart = &models.Cart{}
+		if user != nil {
+			cart.UserID = user.ID
 		}
-		_, err := cc.Collection.InsertOne(ctx, cart)
-		if err != nil {
-			http.Error(w, "Error creating cart", http.StatusInternalServerError)
-			return
-		}
-		json.NewEncoder(w).Encode("Item added to cart")
-		return
 	}
 
-	// Update existing cart
-	updated := false
-	for i, existingItem := range cart.Items {
-		if existingItem.ProductID == item.ProductID {
-			cart.Items[i].Quantity += item.Quantity
-			updated = true
-			break
-		}
+	// Add to the line for the product if there is one, checking stock for
+	// the quantity the cart will hold
+	quantity := item.Quantity
+	if existing, ok := cart.Item(item.ProductID, item.SKU); ok {
+		quantity += existing.Quantity
 	}
-
-	if !updated {
-		cart.Items = append(cart.Items, item)
+	if !checkCartQuantity(w, *product, item.SKU, quantity) {
+		return
+	}
+	price := product.PriceFor(item.SKU)
+	if existing, ok := cart.Item(item.ProductID, item.SKU); ok {
+		existing.Quantity = quantity
+		existing.UnitPrice = &price
+	} else {
+		cart.Items = append(cart.Items, models.CartItem{ProductID: item.ProductID, SKU: item.SKU, Quantity: quantity, UnitPrice: &price})
 	}
 
-	_, err = cc.Collection.UpdateOne(ctx, bson.M{"_id": cart.ID}, bson.M{"$set": bson.M{"items": cart.Items}})
+	err = cc.saveCart(ctx, w, cart)
 	if err != nil {
 		http.Error(w, "Error updating cart", http.StatusInternalServerError)
 		return
@@ -95,35 +195,130 @@ func (cc *CartController) AddToCart(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode("Item added to cart")
 }
 
-// RemoveFromCart removes a product from the user's cart
-func (cc *CartController) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+// UpdateCartItem sets the quantity of a product, or of the variant in
+// {"sku"}, already in the cart to {"quantity"}; 0 removes it. The price the
+// customer sees is refreshed, and the repriced cart is returned.
+func (cc *CartController) UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := primitive.ObjectIDFromHex(mux.Vars(r)["product_id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		SKU      string `json:"sku"`
+		Quantity *int   `json:"quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if body.Quantity == nil || *body.Quantity < 0 {
+		http.Error(w, "Quantity must be zero or more", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.shopper(ctx, r)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	cart, err := cc.findCart(ctx, r, user)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
+	line, ok := cart.Item(productID, body.SKU)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		http.Error(w, "Item not in cart", http.StatusNotFound)
+		return
+	}
+
+	if *body.Quantity == 0 {
+		items := []models.CartItem{}
+		for _, item := range cart.Items {
+			if item.ProductID != productID || item.SKU != body.SKU {
+				items = append(items, item)
+			}
+		}
+		cart.Items = items
+	} else {
+		product, err := cc.Products.FindProductByID(ctx, productID)
+		if err != nil {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		if !checkCartQuantity(w, *product, body.SKU, *body.Quantity) {
+			return
+		}
+		price := product.PriceFor(body.SKU)
+		line.Quantity = *body.Quantity
+		line.UnitPrice = &price
+	}
+
+	err = cc.saveCart(ctx, w, cart)
+	if err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
+	cc.writeCart(ctx, w, cart, user)
+}
+
+// writeCart responds with the cart priced for the user, or for a guest
+// when user is nil
+func (cc *CartController) writeCart(ctx context.Context, w http.ResponseWriter, cart *models.Cart, user *models.User) {
+	var address models.Address
+	if user != nil {
+		address = user.Address
+	}
+	response, err := cc.priceCart(ctx, cart, address)
+	if err != nil {
+		http.Error(w, "Error pricing cart", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// checkCartQuantity writes the error and returns false unless the cart may hold
+// quantity units of the product or its variant
+func checkCartQuantity(w http.ResponseWriter, product models.Product, sku string, quantity int) bool {
+	err := product.CheckCartQuantity(sku, quantity)
+	if errors.Is(err, models.ErrNotEnoughStock) {
+		http.Error(w, fmt.Sprintf("Only %d of %s in stock", product.AvailableFor(sku), product.Name), http.StatusConflict)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Invalid cart item: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// RemoveFromCart removes a product, or one variant of it, from the user's
+// or guest's cart
+func (cc *CartController) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	productID, err := primitive.ObjectIDFromHex(params["product_id"])
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
 	}
+	// Optional; without it every variant of the product is removed
+	sku := r.URL.Query().Get("sku")
 
-	// Find user ID from email
-	userCollection := cc.Collection.Database().Collection("users")
-	var user models.User
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	err = userCollection.FindOne(ctx, bson.M{"email": claims.Email}).Decode(&user)
+	user, err := cc.shopper(ctx, r)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
 
 	// Find cart
-	var cart models.Cart
-	err = cc.Collection.FindOne(ctx, bson.M{"user_id": user.ID}).Decode(&cart)
+	cart, err := cc.findCart(ctx, r, user)
 	if err != nil {
 		http.Error(w, "Cart not found", http.StatusNotFound)
 		return
@@ -132,12 +327,13 @@ func (cc *CartController) RemoveFromCart(w http.ResponseWriter, r *http.Request)
 	// Remove the item
 	updatedItems := []models.CartItem{}
 	for _, item := range cart.Items {
-		if item.ProductID != productID {
+		if item.ProductID != productID || (sku != "" && item.SKU != sku) {
 			updatedItems = append(updatedItems, item)
 		}
 	}
 
-	_, err = cc.Collection.UpdateOne(ctx, bson.M{"_id": cart.ID}, bson.M{"$set": bson.M{"items": updatedItems}})
+	cart.Items = updatedItems
+	err = cc.saveCart(ctx, w, cart)
 	if err != nil {
 		http.Error(w, "Error updating cart", http.StatusInternalServerError)
 		return
@@ -146,33 +342,162 @@ func (cc *CartController) RemoveFromCart(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode("Item removed from cart")
 }
 
-// GetCart retrieves the user's cart
+// GetCart retrieves the user's or guest's cart. Guests have no address yet,
+// so their cart is priced without tax.
 func (cc *CartController) GetCart(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.shopper(ctx, r)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// Find cart
+	cart, err := cc.findCart(ctx, r, user)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
+	cc.writeCart(ctx, w, cart, user)
+}
+
+// GetShippingOptions quotes every shipping method that delivers the user's
+// cart to their address, or to the ?state= and ?zipcode= given instead
+func (cc *CartController) GetShippingOptions(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Find user ID from email
-	userCollection := cc.Collection.Database().Collection("users")
-	var user models.User
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	err := userCollection.FindOne(ctx, bson.M{"email": claims.Email}).Decode(&user)
+	user, err := cc.Users.FindUserByEmail(ctx, claims.Email)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
+	cart, err := cc.Carts.FindCartByUserID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
 
-	// Find cart
-	var cart models.Cart
-	err = cc.Collection.FindOne(ctx, bson.M{"user_id": user.ID}).Decode(&cart)
+	address := user.Address
+	if state := r.URL.Query().Get("state"); state != "" {
+		address = models.Address{State: state, ZipCode: r.URL.Query().Get("zipcode")}
+	}
+	priced, err := cc.priceCart(ctx, cart, address)
+	if err != nil {
+		http.Error(w, "Error pricing cart", http.StatusInternalServerError)
+		return
+	}
+	free := priced.Promotion != nil && priced.Promotion.FreeShipping
+	quotes, err := quoteShipping(ctx, cc.Shipping, address, priced.Lines, free)
+	if err != nil {
+		http.Error(w, "Error quoting shipping", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"address": address,
+		"options": quotes,
+	})
+}
+
+// ApplyCoupon applies a coupon code to the user's cart, replacing any
+// earlier one, and returns the repriced cart
+func (cc *CartController) ApplyCoupon(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	code := models.NormalizeCouponCode(body.Code)
+	if code == "" {
+		http.Error(w, "Coupon code is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	cart, err := cc.Carts.FindCartByUserID(ctx, user.ID)
 	if err != nil {
 		http.Error(w, "Cart not found", http.StatusNotFound)
 		return
 	}
+	if len(cart.Items) == 0 {
+		http.Error(w, "Cart is empty", http.StatusBadRequest)
+		return
+	}
+
+	cart.CouponCode = code
+	response, err := cc.priceCart(ctx, cart, user.Address)
+	if err != nil {
+		http.Error(w, "Error pricing cart", http.StatusInternalServerError)
+		return
+	}
+	if errors.Is(response.couponErr, errCouponNotFound) {
+		http.Error(w, "Coupon not found", http.StatusNotFound)
+		return
+	}
+	if response.couponErr != nil {
+		http.Error(w, "Coupon cannot be applied: "+response.CouponError, http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = cc.saveCart(ctx, w, cart)
+	if err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(cart)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RemoveCoupon takes the coupon off the user's cart
+func (cc *CartController) RemoveCoupon(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	cart, err := cc.Carts.FindCartByUserID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
+
+	cart.CouponCode = ""
+	err = cc.saveCart(ctx, w, cart)
+	if err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Coupon removed from cart")
 }
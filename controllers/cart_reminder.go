@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SendCartReminders emails the owners of carts left untouched for
+// ReminderAfter, up to ReminderLimit times per cart, and returns how many
+// emails went out. Carts stop being reminded once they are ordered, which
+// clears them, or their owner unsubscribes.
+func (cc *CartController) SendCartReminders(ctx context.Context, now time.Time) (int, error) {
+	carts, err := cc.Carts.ListAbandonedCarts(ctx, now.Add(-cc.ReminderAfter), cc.ReminderLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, cart := range carts {
+		user, err := cc.Users.FindUserByID(ctx, cart.UserID)
+		if err != nil {
+			log.Printf("Failed to find the owner of cart %s: %v", cart.ID.Hex(), err)
+			continue
+		}
+		if user.CartRemindersOff {
+			continue
+		}
+		priced, err := cc.priceCart(ctx, &cart, user.Address)
+		if err != nil {
+			log.Printf("Failed to price cart %s: %v", cart.ID.Hex(), err)
+			continue
+		}
+		if len(priced.Lines) == 0 {
+			continue // Nothing left in it can be bought
+		}
+		token, err := utils.GenerateUnsubscribeToken(user.Email)
+		if err != nil {
+			return sent, err
+		}
+
+		// Count the reminder before sending it so a concurrent run cannot send it too
+		err = cc.Carts.MarkCartReminded(ctx, cart.ID, cart.RemindersSent, now)
+		if errors.Is(err, store.ErrConflict) || errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return sent, err
+		}
+		if err := cc.EmailService.SendCartReminderEmail(user.Email, priced.Lines, token); err != nil {
+			log.Printf("Failed to send email to %s: %v", user.Email, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// WatchCarts sends due cart reminders every interval until ctx is done. It
+// is meant to run in its own goroutine.
+func (cc *CartController) WatchCarts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			runCtx, cancel := context.WithTimeout(ctx, time.Minute)
+			if _, err := cc.SendCartReminders(runCtx, now); err != nil {
+				log.Printf("Failed to send cart reminders: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// UnsubscribeFromCartReminders turns off abandoned-cart emails for the
+// customer named by the ?token= from the link in one of them
+func (cc *CartController) UnsubscribeFromCartReminders(w http.ResponseWriter, r *http.Request) {
+	email, err := utils.ParseUnsubscribeToken(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.Users.FindUserByEmail(ctx, email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err := cc.Users.SetCartRemindersOff(ctx, user.ID, true); err != nil {
+		http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("You will no longer receive cart reminders")
+}
+
+// GetCartRecoveryStats reports how many reminded carts were ordered, since
+// the optional ?since= date (YYYY-MM-DD) or ever (Admin only)
+func (cc *CartController) GetCartRecoveryStats(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		since, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid since date, want YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stats, err := cc.Carts.CartRecoveryStats(ctx, since)
+	if err != nil {
+		http.Error(w, "Failed to compute cart recovery", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
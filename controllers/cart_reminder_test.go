@@ -0,0 +1,87 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var unsubscribeToken = regexp.MustCompile(`unsubscribe\?token=([\w.-]+)`)
+
+const cartReminder = "You left something in your cart"
+
+func TestCartReminders(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 2}, nil)
+
+	now := time.Now()
+	remind := func(at time.Duration, want int) {
+		t.Helper()
+		sent, err := s.carts.SendCartReminders(context.Background(), now.Add(at))
+		if err != nil || sent != want {
+			t.Errorf("SendCartReminders after %v = %d, %v; want %d", at, sent, err, want)
+		}
+	}
+	remind(time.Hour, 0) // Not idle long enough
+	remind(25*time.Hour, 1)
+	remind(26*time.Hour, 0) // Already reminded
+	remind(50*time.Hour, 1)
+	remind(100*time.Hour, 0) // Limit reached
+
+	if subjects := s.mail.to(customerEmail); len(subjects) != 2 || subjects[0] != cartReminder {
+		t.Fatalf("emails = %v, want two cart reminders", subjects)
+	}
+	if body := s.mail.body(customerEmail, cartReminder); !regexp.MustCompile(`2 x Mug`).MatchString(body) {
+		t.Errorf("reminder = %q, want it to list the mugs", body)
+	}
+}
+
+func TestUnsubscribeFromCartReminders(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	if _, err := s.carts.SendCartReminders(context.Background(), time.Now().Add(25*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	match := unsubscribeToken.FindStringSubmatch(s.mail.body(customerEmail, cartReminder))
+	if match == nil {
+		t.Fatal("no unsubscribe link in the reminder")
+	}
+	s.expect(t, http.StatusBadRequest, "GET", "/cart/reminders/unsubscribe?token=forged", "", nil, nil)
+	s.expect(t, http.StatusOK, "GET", "/cart/reminders/unsubscribe?token="+match[1], "", nil, nil)
+	if !s.customerUser(t).CartRemindersOff {
+		t.Error("customer is still subscribed to cart reminders")
+	}
+
+	sent, err := s.carts.SendCartReminders(context.Background(), time.Now().Add(50*time.Hour))
+	if err != nil || sent != 0 {
+		t.Errorf("SendCartReminders after unsubscribing = %d, %v; want none", sent, err)
+	}
+}
+
+func TestCartRecoveryReport(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	if _, err := s.carts.SendCartReminders(context.Background(), time.Now().Add(25*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	s.placeOrder(t, mug, payments.TokenSuccess)
+
+	s.expect(t, http.StatusForbidden, "GET", "/admin/carts/recovery", s.customer, nil, nil)
+	s.expect(t, http.StatusBadRequest, "GET", "/admin/carts/recovery?since=yesterday", s.admin, nil, nil)
+	var stats models.CartRecoveryStats
+	s.expect(t, http.StatusOK, "GET", "/admin/carts/recovery", s.admin, nil, &stats)
+	if stats.RemindedCarts != 1 || stats.RemindersSent != 1 || stats.RecoveredCarts != 1 || stats.RecoveryRate != 1 {
+		t.Errorf("stats = %+v, want the one reminded cart recovered", stats)
+	}
+	if stats.RecoveredRevenue.IsZero() {
+		t.Error("recovered revenue is zero")
+	}
+}
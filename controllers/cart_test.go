@@ -0,0 +1,109 @@
+package controllers_test
+
+import (
+	"go-ecommerce/models"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pricedCart is the part of the GET /cart response the cart tests look at
+type pricedCart struct {
+	Lines    []models.OrderItem   `json:"lines"`
+	Subtotal models.Money         `json:"subtotal"`
+	Warnings []models.CartWarning `json:"warnings"`
+}
+
+func TestAddToCartValidation(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 3})
+	add := func(status int, item map[string]interface{}) {
+		t.Helper()
+		s.expect(t, status, "POST", "/cart", s.customer, item, nil)
+	}
+
+	add(http.StatusBadRequest, map[string]interface{}{"product_id": mug.ID, "quantity": 0})
+	add(http.StatusBadRequest, map[string]interface{}{"product_id": mug.ID, "quantity": -2})
+	add(http.StatusNotFound, map[string]interface{}{"product_id": primitive.NewObjectID(), "quantity": 1})
+	add(http.StatusBadRequest, map[string]interface{}{"product_id": mug.ID, "sku": "MUG-XL", "quantity": 1})
+	add(http.StatusConflict, map[string]interface{}{"product_id": mug.ID, "quantity": 4})
+
+	// The stock check counts what the cart already holds
+	add(http.StatusOK, map[string]interface{}{"product_id": mug.ID, "quantity": 2})
+	add(http.StatusConflict, map[string]interface{}{"product_id": mug.ID, "quantity": 2})
+	add(http.StatusOK, map[string]interface{}{"product_id": mug.ID, "quantity": 1})
+
+	var cart pricedCart
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if len(cart.Lines) != 1 || cart.Lines[0].Quantity != 3 {
+		t.Errorf("lines = %+v, want three mugs", cart.Lines)
+	}
+}
+
+func TestUpdateCartItem(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5, "image_url": "https://img.test/mug.png"})
+	plate := s.createProduct(t, map[string]interface{}{"name": "Plate", "price": 4, "stock": 5})
+	path := "/cart/items/" + mug.ID.Hex()
+
+	s.expect(t, http.StatusNotFound, "PATCH", path, s.customer, map[string]int{"quantity": 2}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": plate.ID, "quantity": 1}, nil)
+
+	s.expect(t, http.StatusBadRequest, "PATCH", path, s.customer, map[string]int{"quantity": -1}, nil)
+	s.expect(t, http.StatusBadRequest, "PATCH", path, s.customer, map[string]interface{}{}, nil)
+	s.expect(t, http.StatusConflict, "PATCH", path, s.customer, map[string]int{"quantity": 6}, nil)
+
+	// The quantity is set, not added to
+	var cart pricedCart
+	s.expect(t, http.StatusOK, "PATCH", path, s.customer, map[string]int{"quantity": 4}, &cart)
+	if len(cart.Lines) != 2 || cart.Lines[0].Quantity != 4 || cart.Lines[0].ImageURL != "https://img.test/mug.png" {
+		t.Fatalf("lines = %+v, want four mugs with their image", cart.Lines)
+	}
+	if cart.Subtotal != models.NewMoney(4400, models.DefaultCurrency) {
+		t.Errorf("subtotal = %v, want 44.00", cart.Subtotal)
+	}
+
+	// Zero removes the line
+	s.expect(t, http.StatusOK, "PATCH", "/cart/items/"+plate.ID.Hex(), s.customer, map[string]int{"quantity": 0}, &cart)
+	if len(cart.Lines) != 1 || cart.Lines[0].ProductID != mug.ID {
+		t.Errorf("lines = %+v, want only the mugs", cart.Lines)
+	}
+}
+
+func TestCartWarnsOfChanges(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	plate := s.createProduct(t, map[string]interface{}{"name": "Plate", "price": 4, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 3}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": plate.ID, "quantity": 1}, nil)
+
+	var cart pricedCart
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if len(cart.Warnings) != 0 {
+		t.Errorf("warnings = %+v, want none", cart.Warnings)
+	}
+
+	// The mug gets dearer and scarcer; the plate is withdrawn
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Mug", "price": 12, "stock": 2}, nil)
+	s.expect(t, http.StatusOK, "DELETE", "/products/"+plate.ID.Hex(), s.admin, nil, nil)
+
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	codes := map[models.CartWarningCode]primitive.ObjectID{}
+	for _, w := range cart.Warnings {
+		codes[w.Code] = w.ProductID
+	}
+	if len(cart.Warnings) != 3 || codes[models.CartItemLowStock] != mug.ID || codes[models.CartItemPriceChanged] != mug.ID || codes[models.CartItemUnavailable] != plate.ID {
+		t.Errorf("warnings = %+v, want the mug's stock and price and the plate's removal", cart.Warnings)
+	}
+	if len(cart.Lines) != 1 || cart.Lines[0].UnitPrice != models.NewMoney(1200, models.DefaultCurrency) {
+		t.Errorf("lines = %+v, want the mug at its new price", cart.Lines)
+	}
+
+	// Setting the quantity acknowledges the new price
+	s.expect(t, http.StatusOK, "PATCH", "/cart/items/"+mug.ID.Hex(), s.customer, map[string]int{"quantity": 2}, &cart)
+	if len(cart.Warnings) != 1 || cart.Warnings[0].Code != models.CartItemUnavailable {
+		t.Errorf("warnings = %+v, want only the plate's", cart.Warnings)
+	}
+}
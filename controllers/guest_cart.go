@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CartTokenHeader carries the signed token of a visitor's guest cart: the
+// server sends it when it starts the cart, and the visitor sends it back
+// with every cart request and when logging in or registering
+const CartTokenHeader = "X-Cart-Token"
+
+// guestCartID returns the ID of the guest cart named by the request's cart
+// token. Missing, expired and forged tokens all count as no cart.
+func guestCartID(r *http.Request) (primitive.ObjectID, bool) {
+	token := r.Header.Get(CartTokenHeader)
+	if token == "" {
+		return primitive.NilObjectID, false
+	}
+	cartID, err := utils.ParseCartToken(token)
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(cartID)
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// shopper returns the signed-in user a cart request is for, or nil when it
+// comes from an anonymous visitor
+func (cc *CartController) shopper(ctx context.Context, r *http.Request) (*models.User, error) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		return nil, nil
+	}
+	return cc.Users.FindUserByEmail(ctx, claims.Email)
+}
+
+// findCart returns the user's cart or, for a guest, the cart named by the
+// request's cart token
+func (cc *CartController) findCart(ctx context.Context, r *http.Request, user *models.User) (*models.Cart, error) {
+	if user != nil {
+		return cc.Carts.FindCartByUserID(ctx, user.ID)
+	}
+	id, ok := guestCartID(r)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return cc.Carts.FindGuestCart(ctx, id)
+}
+
+// saveCart records a change by the customer to a user's cart or a guest
+// cart. A guest cart saved for the first time gets its token in the
+// response's CartTokenHeader.
+func (cc *CartController) saveCart(ctx context.Context, w http.ResponseWriter, cart *models.Cart) error {
+	cart.Touch(time.Now())
+	if !cart.UserID.IsZero() {
+		return cc.Carts.SaveCart(ctx, cart)
+	}
+	started := cart.ID.IsZero()
+	if err := cc.Carts.SaveGuestCart(ctx, cart); err != nil {
+		return err
+	}
+	if started {
+		token, err := utils.GenerateCartToken(cart.ID.Hex())
+		if err != nil {
+			return err
+		}
+		w.Header().Set(CartTokenHeader, token)
+	}
+	return nil
+}
+
+// mergeGuestCart moves the guest cart named by the request's cart token, if
+// any, into the user's cart and deletes it so the token cannot be used again
+func mergeGuestCart(ctx context.Context, carts store.CartStore, r *http.Request, userID primitive.ObjectID) error {
+	id, ok := guestCartID(r)
+	if !ok {
+		return nil
+	}
+	guest, err := carts.FindGuestCart(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cart, err := carts.FindCartByUserID(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		cart = &models.Cart{UserID: userID}
+	} else if err != nil {
+		return err
+	}
+	cart.Merge(*guest)
+	cart.Touch(time.Now())
+	if err := carts.SaveCart(ctx, cart); err != nil {
+		return err
+	}
+	return carts.DeleteGuestCart(ctx, id)
+}
@@ -0,0 +1,131 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-ecommerce/controllers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// asGuest sends a request without a login, carrying cartToken when set
+func (s *shop) asGuest(t *testing.T, method, path, cartToken string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if cartToken != "" {
+		req.Header.Set(controllers.CartTokenHeader, cartToken)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGuestCart(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	plate := s.createProduct(t, map[string]interface{}{"name": "Plate", "price": 4, "stock": 5})
+
+	if rec := s.asGuest(t, "GET", "/cart", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /cart without a cart = %d, want 404", rec.Code)
+	}
+
+	// The first item starts a guest cart and hands out its token
+	rec := s.asGuest(t, "POST", "/cart", "", map[string]interface{}{"product_id": mug.ID, "quantity": 2})
+	token := rec.Header().Get(controllers.CartTokenHeader)
+	if rec.Code != http.StatusOK || token == "" {
+		t.Fatalf("POST /cart = %d with token %q, want 200 and a cart token", rec.Code, token)
+	}
+	rec = s.asGuest(t, "POST", "/cart", token, map[string]interface{}{"product_id": plate.ID, "quantity": 1})
+	if rec.Code != http.StatusOK || rec.Header().Get(controllers.CartTokenHeader) != "" {
+		t.Errorf("second POST /cart = %d, want 200 without a new token", rec.Code)
+	}
+	rec = s.asGuest(t, "DELETE", "/cart/items/"+plate.ID.Hex(), token, nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("DELETE /cart/items = %d, want 200", rec.Code)
+	}
+
+	var cart struct {
+		Lines []struct {
+			Quantity int `json:"quantity"`
+		} `json:"lines"`
+	}
+	rec = s.asGuest(t, "GET", "/cart", token, nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &cart); err != nil || len(cart.Lines) != 1 || cart.Lines[0].Quantity != 2 {
+		t.Fatalf("GET /cart = %d %s, want two mugs", rec.Code, rec.Body.String())
+	}
+
+	// A forged token names no cart, and coupons and checkout need a login
+	if rec := s.asGuest(t, "GET", "/cart", token+"x", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /cart with a forged token = %d, want 404", rec.Code)
+	}
+	if rec := s.asGuest(t, "POST", "/cart/coupon", token, map[string]string{"code": "SAVE10"}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST /cart/coupon as a guest = %d, want 401", rec.Code)
+	}
+}
+
+func TestGuestCartMergesOnLogin(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	plate := s.createProduct(t, map[string]interface{}{"name": "Plate", "price": 4, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 3}, nil)
+
+	rec := s.asGuest(t, "POST", "/cart", "", map[string]interface{}{"product_id": mug.ID, "quantity": 1})
+	token := rec.Header().Get(controllers.CartTokenHeader)
+	s.asGuest(t, "POST", "/cart", token, map[string]interface{}{"product_id": plate.ID, "quantity": 2})
+
+	rec = s.asGuest(t, "POST", "/login", token, map[string]string{"email": customerEmail, "password": testPassword})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /login = %d %s", rec.Code, rec.Body.String())
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &session)
+
+	// The mug keeps the larger quantity and the plate joins it
+	var cart struct {
+		Items []struct {
+			ProductID string `json:"product_id"`
+			Quantity  int    `json:"quantity"`
+		} `json:"items"`
+	}
+	s.expect(t, http.StatusOK, "GET", "/cart", session.Token, nil, &cart)
+	if len(cart.Items) != 2 || cart.Items[0].Quantity != 3 || cart.Items[1].ProductID != plate.ID.Hex() || cart.Items[1].Quantity != 2 {
+		t.Errorf("cart items = %+v, want 3 mugs and 2 plates", cart.Items)
+	}
+
+	// The guest cart is gone once merged
+	if rec := s.asGuest(t, "GET", "/cart", token, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /cart with a merged token = %d, want 404", rec.Code)
+	}
+}
+
+func TestGuestCartMergesOnRegister(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	rec := s.asGuest(t, "POST", "/cart", "", map[string]interface{}{"product_id": mug.ID, "quantity": 2})
+	token := rec.Header().Get(controllers.CartTokenHeader)
+
+	user := map[string]string{"name": "Newcomer", "email": "new@example.com", "password": testPassword}
+	if rec := s.asGuest(t, "POST", "/register", token, user); rec.Code != http.StatusCreated {
+		t.Fatalf("POST /register = %d %s", rec.Code, rec.Body.String())
+	}
+	ctx := context.Background()
+	created, err := s.db.FindUserByEmail(ctx, "new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cart, err := s.db.FindCartByUserID(ctx, created.ID)
+	if err != nil || len(cart.Items) != 1 || cart.Items[0].Quantity != 2 {
+		t.Errorf("new user's cart = %+v, %v; want the guest's two mugs", cart, err)
+	}
+}
@@ -0,0 +1,247 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-ecommerce/controllers"
+	"go-ecommerce/delivery"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"go-ecommerce/routes"
+	"go-ecommerce/search"
+	"go-ecommerce/store"
+	"go-ecommerce/tax"
+	"go-ecommerce/utils"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	adminEmail    = "admin@example.com"
+	customerEmail = "customer@example.com"
+	testPassword  = "password"
+)
+
+// shop is the whole API wired to an in-memory store, a mock payment
+// provider and a fake mail server
+type shop struct {
+	db       *store.Memory
+	router   *mux.Router
+	payments *payments.Mock
+	orders   *controllers.OrderController
+	products *controllers.ProductController
+	carts    *controllers.CartController
+	mail     *mailbox
+	admin    string // Bearer tokens
+	customer string
+}
+
+// mailbox is a fake Postmark API that keeps what it is sent
+type mailbox struct {
+	mu   sync.Mutex
+	sent []map[string]interface{}
+}
+
+func (m *mailbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var email map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&email)
+	m.mu.Lock()
+	m.sent = append(m.sent, email)
+	m.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ErrorCode": 0, "Message": "OK"}`))
+}
+
+// to returns the subjects of the emails sent to address
+func (m *mailbox) to(address string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subjects []string
+	for _, email := range m.sent {
+		if email["To"] == address {
+			subjects = append(subjects, email["Subject"].(string))
+		}
+	}
+	return subjects
+}
+
+// body returns the HTML of the last email sent to address with subject
+func (m *mailbox) body(address, subject string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i]["To"] == address && m.sent[i]["Subject"] == subject {
+			return m.sent[i]["HtmlBody"].(string)
+		}
+	}
+	return ""
+}
+
+func newShop(t *testing.T) *shop {
+	t.Helper()
+	mail := &mailbox{}
+	server := httptest.NewServer(mail)
+	t.Cleanup(server.Close)
+	t.Setenv("POSTMARK_API_TOKEN", "test")
+	t.Setenv("POSTMARK_API_URL", server.URL)
+	emails := utils.NewEmailService()
+
+	db := store.NewMemory()
+	middleware.Sessions = db
+	taxes := tax.NewTable(db, false)
+	provider := payments.NewMock("http://shop.test", []byte("whsec_test"))
+	// With no cutoff every order dispatches on the day it is placed
+	calendar := delivery.NewCalendar(db, 24*time.Hour, time.UTC)
+	orders := controllers.NewOrderController(db, emails, provider, taxes, calendar)
+	products := controllers.NewProductController(db, search.NewMemory(), emails)
+	carts := controllers.NewCartController(db, taxes, emails)
+
+	router := mux.NewRouter()
+	routes.RegisterRoutes(router,
+		controllers.NewUserController(db, emails),
+		products,
+		carts,
+		orders,
+		controllers.NewPromotionController(db),
+		controllers.NewTaxController(db),
+		controllers.NewShippingController(db),
+		controllers.NewHolidayController(db),
+		controllers.NewWishlistController(db),
+	)
+	router.HandleFunc("/payments/mock/3ds/{id}", provider.ChallengeHandler).Methods("GET", "POST")
+
+	s := &shop{db: db, router: router, payments: provider, orders: orders, products: products, carts: carts, mail: mail}
+	s.addUser(t, adminEmail, "admin")
+	s.addUser(t, customerEmail, "user")
+	s.admin = s.login(t, adminEmail)
+	s.customer = s.login(t, customerEmail)
+
+	free := models.NewMoney(0, models.DefaultCurrency)
+	zone := models.ShippingZone{
+		Name:    "California",
+		Regions: []models.ShippingRegion{{State: "CA"}},
+		Methods: []models.ShippingMethod{{Code: "standard", Name: "Standard", Kind: "standard", Rate: "flat", FlatFee: &free, MinDays: 3, MaxDays: 5}},
+	}
+	if err := db.CreateShippingZone(context.Background(), &zone); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (s *shop) addUser(t *testing.T, email, role string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{
+		Name:       role,
+		Email:      email,
+		Password:   string(hash),
+		Role:       role,
+		IsVerified: true,
+		Address:    models.Address{Street: "1 Main St", City: "Los Angeles", State: "CA", ZipCode: "90012"},
+	}
+	if err := s.db.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (s *shop) login(t *testing.T, email string) string {
+	t.Helper()
+	var session struct {
+		Token string `json:"token"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/login", "", map[string]string{"email": email, "password": testPassword}, &session)
+	return session.Token
+}
+
+// do sends a request with body encoded as JSON, authenticated by token when set
+func (s *shop) do(t *testing.T, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect sends a request, fails the test unless it gets status, and decodes
+// the response into out when set
+func (s *shop) expect(t *testing.T, status int, method, path, token string, body, out interface{}) {
+	t.Helper()
+	rec := s.do(t, method, path, token, body)
+	if rec.Code != status {
+		t.Fatalf("%s %s = %d %s, want %d", method, path, rec.Code, rec.Body.String(), status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", method, path, rec.Body.String(), err)
+		}
+	}
+}
+
+// createProduct adds a product through the API
+func (s *shop) createProduct(t *testing.T, product map[string]interface{}) models.Product {
+	t.Helper()
+	var created models.Product
+	s.expect(t, http.StatusCreated, "POST", "/products", s.admin, product, &created)
+	return created
+}
+
+// placeOrder has the customer check out one of product with a card token
+// and returns the order's payment
+func (s *shop) placeOrder(t *testing.T, product models.Product, token string) models.Payment {
+	t.Helper()
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": product.ID, "quantity": 1}, nil)
+	var result struct {
+		OrderID primitive.ObjectID `json:"order_id"`
+	}
+	rec := s.do(t, "POST", "/order", s.customer, map[string]interface{}{"shipping_method": "standard", "payment_method": "card", "payment_token": token})
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.OrderID.IsZero() {
+		t.Fatalf("POST /order = %d %s", rec.Code, rec.Body.String())
+	}
+	payment, err := s.db.FindPaymentByOrderID(context.Background(), result.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *payment
+}
+
+func (s *shop) customerUser(t *testing.T) models.User {
+	t.Helper()
+	user, err := s.db.FindUserByEmail(context.Background(), customerEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *user
+}
+
+// eventually retries check until it passes, for work done in the background
+func eventually(t *testing.T, check func() bool) bool {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if check() {
+			return true
+		}
+	}
+	return false
+}
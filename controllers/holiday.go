@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HolidayController handles holiday calendar requests
+type HolidayController struct {
+	Holidays store.HolidayStore
+}
+
+// NewHolidayController creates a new HolidayController
+func NewHolidayController(s store.Store) *HolidayController {
+	return &HolidayController{
+		Holidays: s,
+	}
+}
+
+// ListHolidays lists holidays, optionally only those observed in ?state= (Admin only)
+func (hc *HolidayController) ListHolidays(w http.ResponseWriter, r *http.Request) {
+	state := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("state")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	holidays, err := hc.Holidays.ListHolidays(ctx, state)
+	if err != nil {
+		http.Error(w, "Failed to retrieve holidays", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holidays)
+}
+
+// CreateHoliday adds a holiday (Admin only)
+func (hc *HolidayController) CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	var holiday models.Holiday
+	if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	holiday.ID = primitive.NilObjectID
+	holiday.Normalize()
+	if err := holiday.Validate(); err != nil {
+		http.Error(w, "Invalid holiday: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	holiday.CreatedAt = time.Now()
+	holiday.UpdatedAt = holiday.CreatedAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := hc.Holidays.CreateHoliday(ctx, &holiday); err != nil {
+		http.Error(w, "Error creating holiday", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(holiday)
+}
+
+// UpdateHoliday replaces a holiday. Orders already placed keep the delivery
+// window they were given. (Admin only)
+func (hc *HolidayController) UpdateHoliday(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid holiday ID", http.StatusBadRequest)
+		return
+	}
+	var holiday models.Holiday
+	if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	holiday.Normalize()
+	if err := holiday.Validate(); err != nil {
+		http.Error(w, "Invalid holiday: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	existing, err := hc.Holidays.FindHolidayByID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Holiday not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve holiday", http.StatusInternalServerError)
+		return
+	}
+	holiday.ID = existing.ID
+	holiday.CreatedAt = existing.CreatedAt
+	holiday.UpdatedAt = time.Now()
+
+	if err := hc.Holidays.UpdateHoliday(ctx, &holiday); err != nil {
+		http.Error(w, "Error updating holiday", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holiday)
+}
+
+// DeleteHoliday removes a holiday (Admin only)
+func (hc *HolidayController) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid holiday ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = hc.Holidays.DeleteHoliday(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Holiday not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting holiday", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Holiday deleted")
+}
@@ -0,0 +1,47 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHolidayCalendar(t *testing.T) {
+	s := newShop(t)
+	s.expect(t, http.StatusBadRequest, "POST", "/admin/holidays", s.admin, map[string]string{"name": "Christmas", "date": "25/12/2026"}, nil)
+	s.expect(t, http.StatusForbidden, "GET", "/admin/holidays", s.customer, nil, nil)
+	var christmas models.Holiday
+	s.expect(t, http.StatusCreated, "POST", "/admin/holidays", s.admin, map[string]string{"name": "Christmas", "date": "2026-12-25"}, &christmas)
+	s.expect(t, http.StatusOK, "PUT", "/admin/holidays/"+christmas.ID.Hex(), s.admin, map[string]string{"name": "Christmas Eve", "date": "2026-12-24"}, nil)
+	s.expect(t, http.StatusOK, "DELETE", "/admin/holidays/"+christmas.ID.Hex(), s.admin, nil, nil)
+	s.expect(t, http.StatusNotFound, "DELETE", "/admin/holidays/"+christmas.ID.Hex(), s.admin, nil, nil)
+
+	// A California holiday on the first day the order could arrive pushes the window back
+	ctx := context.Background()
+	california := models.Address{State: "CA"}
+	standard := models.ShippingQuote{MinDays: 3, MaxDays: 5}
+	before, err := s.orders.Delivery.Estimate(ctx, time.Now(), california, standard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holiday := map[string]string{"name": "Shop closed", "date": before.Earliest.Format(models.DateLayout), "state": "ca"}
+	s.expect(t, http.StatusCreated, "POST", "/admin/holidays", s.admin, holiday, nil)
+	var holidays []models.Holiday
+	s.expect(t, http.StatusOK, "GET", "/admin/holidays?state=NY", s.admin, nil, &holidays)
+	if len(holidays) != 0 {
+		t.Errorf("NY holidays = %+v, want none", holidays)
+	}
+
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	var result struct {
+		DeliveryDate models.DeliveryWindow `json:"delivery_date"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, &result)
+	if !result.DeliveryDate.Earliest.After(before.Earliest) || !result.DeliveryDate.Latest.After(before.Latest) {
+		t.Errorf("delivery date = %v, want it after %v", result.DeliveryDate, before)
+	}
+}
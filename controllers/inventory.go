@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListStockMovements returns a product's inventory ledger, oldest first (Admin only)
+func (pc *ProductController) ListStockMovements(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	movements, err := pc.Inventory.ListStockMovements(ctx, id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve stock movements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
+
+// RecordStockMovement changes a product's stock by hand: a customer return,
+// a restock from a supplier or an adjustment after a count (Admin only).
+// Expects {"sku", "delta", "reason", "reference", "note"}.
+func (pc *ProductController) RecordStockMovement(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var movement models.StockMovement
+	if err := json.NewDecoder(r.Body).Decode(&movement); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	movement.Normalize()
+	if err := movement.Validate(); err != nil {
+		http.Error(w, "Invalid stock movement: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	movement.ID = primitive.NilObjectID
+	movement.ProductID = id
+	movement.Actor = claims.Email
+	movement.CreatedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = pc.Inventory.MoveStock(ctx, &movement)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Product or variant not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrInsufficientStock) {
+		http.Error(w, "Not enough unreserved stock for this movement", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to record stock movement", http.StatusInternalServerError)
+		return
+	}
+	if movement.Delta > 0 {
+		if product, err := pc.Products.FindProductByID(ctx, id); err == nil {
+			notifyBackInStock(pc.Alerts, pc.EmailService, *product)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// ReconcileStock rebuilds a product's stock figures from its ledger and
+// reports those that had drifted (Admin only)
+func (pc *ProductController) ReconcileStock(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	counts, err := pc.Inventory.ReconcileStock(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reconcile stock", http.StatusInternalServerError)
+		return
+	}
+
+	corrected := []models.StockCount{}
+	for _, c := range counts {
+		if c.Recorded != c.Ledger {
+			corrected = append(corrected, c)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"product_id": id,
+		"counts":     counts,
+		"corrected":  corrected,
+	})
+}
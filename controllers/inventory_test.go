@@ -0,0 +1,57 @@
+package controllers_test
+
+import (
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"testing"
+)
+
+func TestStockMovements(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	path := "/products/" + mug.ID.Hex() + "/stock-movements"
+
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Mug", "price": 10, "stock": 3}, nil)
+	var restock models.StockMovement
+	s.expect(t, http.StatusCreated, "POST", path, s.admin, map[string]interface{}{"delta": 10, "reason": "Restock", "reference": "INV-42"}, &restock)
+	if restock.Balance != 13 || restock.Actor != adminEmail {
+		t.Errorf("restock = %+v, want a balance of 13 by the admin", restock)
+	}
+	s.expect(t, http.StatusBadRequest, "POST", path, s.admin, map[string]interface{}{"delta": -1, "reason": "restock"}, nil)
+	s.expect(t, http.StatusBadRequest, "POST", path, s.admin, map[string]interface{}{"delta": 1, "reason": "sale"}, nil)
+	s.expect(t, http.StatusBadRequest, "POST", path, s.admin, map[string]interface{}{"delta": -14, "reason": "adjustment"}, nil)
+	s.expect(t, http.StatusNotFound, "POST", path, s.admin, map[string]interface{}{"sku": "MUG-XL", "delta": 1, "reason": "return"}, nil)
+	s.expect(t, http.StatusForbidden, "GET", path, s.customer, nil, nil)
+
+	s.placeOrder(t, mug, payments.TokenSuccess)
+
+	var movements []models.StockMovement
+	s.expect(t, http.StatusOK, "GET", path, s.admin, nil, &movements)
+	reasons := []models.StockReason{}
+	for _, m := range movements {
+		reasons = append(reasons, m.Reason)
+	}
+	want := []models.StockReason{models.StockOpening, models.StockAdjustment, models.StockRestock, models.StockSale}
+	if len(reasons) != len(want) {
+		t.Fatalf("reasons = %v, want %v", reasons, want)
+	}
+	for i := range want {
+		if reasons[i] != want[i] {
+			t.Errorf("reasons = %v, want %v", reasons, want)
+			break
+		}
+	}
+	if sale := movements[3]; sale.Delta != -1 || sale.Balance != 12 || sale.Reference == "" {
+		t.Errorf("sale = %+v, want one unit taken by the order", sale)
+	}
+
+	var report struct {
+		Counts    []models.StockCount `json:"counts"`
+		Corrected []models.StockCount `json:"corrected"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/products/"+mug.ID.Hex()+"/stock/reconcile", s.admin, nil, &report)
+	if len(report.Counts) != 1 || report.Counts[0].Ledger != 12 || len(report.Corrected) != 0 {
+		t.Errorf("reconciliation = %+v, want 12 in stock with nothing to correct", report)
+	}
+}
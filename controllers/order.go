@@ -4,45 +4,66 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go-ecommerce/delivery"
 	"go-ecommerce/middleware"
 	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"go-ecommerce/store"
+	"go-ecommerce/tax"
 	"go-ecommerce/utils"
 	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // OrderController handles order-related requests
 type OrderController struct {
-	OrderCollection   *mongo.Collection
-	CartCollection    *mongo.Collection
-	ProductCollection *mongo.Collection
-	UserCollection    *mongo.Collection
-	EmailService      *utils.EmailService
+	Orders       store.OrderStore
+	Carts        store.CartStore
+	Products     store.ProductStore
+	Users        store.UserStore
+	Payments     store.PaymentStore
+	Webhooks     store.WebhookStore
+	Promotions   store.PromotionStore
+	Shipping     store.ShippingStore
+	Alerts       store.StockAlertStore
+	Provider     payments.Provider
+	Tax          tax.Calculator
+	Delivery     delivery.Estimator
+	EmailService *utils.EmailService
+
+	// ReservationTTL is how long checkout holds stock for an unpaid order,
+	// by payment method
+	ReservationTTL map[string]time.Duration
 }
 
 // NewOrderController creates a new OrderController
-func NewOrderController(client *mongo.Client, emailService *utils.EmailService) *OrderController {
-	orderCollection := client.Database("ecommerce").Collection("orders")
-	cartCollection := client.Database("ecommerce").Collection("carts")
-	productCollection := client.Database("ecommerce").Collection("products")
-	userCollection := client.Database("ecommerce").Collection("users")
+func NewOrderController(s store.Store, emailService *utils.EmailService, provider payments.Provider, taxes tax.Calculator, estimator delivery.Estimator) *OrderController {
 	return &OrderController{
-		OrderCollection:   orderCollection,
-		CartCollection:    cartCollection,
-		ProductCollection: productCollection,
-		UserCollection:    userCollection,
-		EmailService:      emailService,
+		Orders:       s,
+		Carts:        s,
+		Products:     s,
+		Users:        s,
+		Payments:     s,
+		Webhooks:     s,
+		Promotions:   s,
+		Shipping:     s,
+		Alerts:       s,
+		Provider:     provider,
+		Tax:          taxes,
+		Delivery:     estimator,
+		EmailService: emailService,
+		ReservationTTL: map[string]time.Duration{
+			"card":   30 * time.Minute,
+			"crypto": 24 * time.Hour, // Proof of a transfer takes longer to arrive
+		},
 	}
 }
 
@@ -56,18 +77,16 @@ func (oc *OrderController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find the user in the database
-	var user models.User
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	err := oc.UserCollection.FindOne(ctx, bson.M{"email": claims.Email}).Decode(&user)
+	user, err := oc.Users.FindUserByEmail(ctx, claims.Email)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
 
 	// Find the user's cart
-	var cart models.Cart
-	err = oc.CartCollection.FindOne(ctx, bson.M{"user_id": user.ID}).Decode(&cart)
+	cart, err := oc.Carts.FindCartByUserID(ctx, user.ID)
 	if err != nil {
 		http.Error(w, "Cart not found", http.StatusNotFound)
 		return
@@ -79,9 +98,16 @@ func (oc *OrderController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse payment method from request
-	// Expecting JSON body with "payment_method": "card" or "crypto"
+	// Expecting JSON body with "payment_method": "card" or "crypto". Card
+	// payments also carry the gateway's "payment_token" for the card.
+	// "shipping_method" is the code of one of the cart's shipping options, and
+	// an optional "address" ships the order somewhere other than the profile
+	// address.
 	var paymentRequest struct {
-		PaymentMethod string `json:"payment_method"`
+		PaymentMethod  string          `json:"payment_method"`
+		PaymentToken   string          `json:"payment_token"`
+		ShippingMethod string          `json:"shipping_method"`
+		Address        *models.Address `json:"address"`
 	}
 	err = json.NewDecoder(r.Body).Decode(&paymentRequest)
 	if err != nil {
@@ -94,151 +120,192 @@ func (oc *OrderController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid payment method", http.StatusBadRequest)
 		return
 	}
+	if paymentMethod == "card" && strings.TrimSpace(paymentRequest.PaymentToken) == "" {
+		http.Error(w, "Payment token is required for card payments", http.StatusBadRequest)
+		return
+	}
 
-	// Calculate total amount and check stock
-	totalAmount := 0.0
+	// Price each line as it stands now and check unreserved stock
+	items := make([]models.OrderItem, 0, len(cart.Items))
 	for _, item := range cart.Items {
-		var product models.Product
-		err := oc.ProductCollection.FindOne(ctx, bson.M{"_id": item.ProductID}).Decode(&product)
+		product, err := oc.Products.FindProductByID(ctx, item.ProductID)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Product with ID %s not found", item.ProductID.Hex()), http.StatusNotFound)
 			return
 		}
-		if product.Stock < item.Quantity {
+		if product.HasVariants() {
+			if _, ok := product.Variant(item.SKU); !ok {
+				http.Error(w, fmt.Sprintf("Choose a valid variant for product: %s", product.Name), http.StatusBadRequest)
+				return
+			}
+		} else if item.SKU != "" {
+			http.Error(w, fmt.Sprintf("Product %s has no variants", product.Name), http.StatusBadRequest)
+			return
+		}
+		if product.AvailableFor(item.SKU) < item.Quantity {
 			http.Error(w, fmt.Sprintf("Insufficient stock for product: %s", product.Name), http.StatusBadRequest)
 			return
 		}
-		totalAmount += product.Price * float64(item.Quantity)
+		items = append(items, models.NewOrderItem(*product, item.SKU, item.Quantity))
 	}
 
-	// Deduct stock for each product
-	for _, item := range cart.Items {
-		_, err := oc.ProductCollection.UpdateOne(ctx, bson.M{"_id": item.ProductID}, bson.M{
-			"$inc": bson.M{"stock": -item.Quantity},
-		})
+	// Apply the cart's coupon. One that no longer applies stops checkout so
+	// the customer is never charged a total they were not shown.
+	var promotion *models.AppliedPromotion
+	if cart.CouponCode != "" {
+		promotion, err = applyCoupon(ctx, oc.Promotions, cart.CouponCode, user.ID, items)
+		if couponRejected(err) {
+			http.Error(w, "Coupon cannot be applied: "+err.Error()+"; remove it from the cart to continue", http.StatusConflict)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Failed to update product stock", http.StatusInternalServerError)
+			http.Error(w, "Failed to apply coupon", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// Set delivery date to 7 working days from now
-	deliveryDate := time.Now().AddDate(0, 0, 10) // Approximation: 7 working days ~10 calendar days
-
-	// Create the order
-	order := models.Order{
-		UserID:        user.ID,
-		Items:         cart.Items,
-		TotalAmount:   totalAmount,
-		DeliveryDate:  deliveryDate.Format("2006-01-02"),
-		PaymentMethod: paymentMethod,
+	address := user.Address
+	if paymentRequest.Address != nil {
+		address = *paymentRequest.Address
+	}
+	if err := oc.Tax.Calculate(ctx, address, items); err != nil {
+		log.Printf("Failed to calculate tax for user %s: %v", user.ID.Hex(), err)
+		http.Error(w, "Failed to calculate tax", http.StatusInternalServerError)
+		return
 	}
 
-	// Insert the order into the database
-	orderResult, err := oc.OrderCollection.InsertOne(ctx, order)
+	free := promotion != nil && promotion.FreeShipping
+	quotes, err := quoteShipping(ctx, oc.Shipping, address, items, free)
 	if err != nil {
-		http.Error(w, "Failed to create order", http.StatusInternalServerError)
+		http.Error(w, "Failed to quote shipping", http.StatusInternalServerError)
 		return
 	}
-
-	// Handle crypto payment proof upload
-	if paymentMethod == "crypto" {
-		// Create a unique directory for the user if it doesn't exist
-		uploadPath := filepath.Join("uploads", "payments", user.ID.Hex())
-		err := os.MkdirAll(uploadPath, os.ModePerm)
-		if err != nil {
-			http.Error(w, "Failed to create upload directory", http.StatusInternalServerError)
-			return
-		}
-
-		// Parse multipart form with a max memory of 10MB
-		err = r.ParseMultipartForm(10 << 20)
-		if err != nil {
-			http.Error(w, "Failed to parse multipart form", http.StatusBadRequest)
-			return
+	if len(quotes) == 0 {
+		http.Error(w, "No shipping method delivers to this address", http.StatusBadRequest)
+		return
+	}
+	var shipping *models.ShippingQuote
+	codes := make([]string, len(quotes))
+	for i := range quotes {
+		codes[i] = quotes[i].Method
+		if quotes[i].Method == strings.ToLower(strings.TrimSpace(paymentRequest.ShippingMethod)) {
+			shipping = &quotes[i]
 		}
+	}
+	if shipping == nil {
+		http.Error(w, "Choose a shipping method: "+strings.Join(codes, ", "), http.StatusBadRequest)
+		return
+	}
 
-		// Retrieve the file from form data
-		file, handler, err := r.FormFile("crypto_proof")
-		if err != nil {
-			http.Error(w, "Failed to retrieve file", http.StatusBadRequest)
-			return
-		}
-		defer file.Close()
+	totalAmount := shipping.Total
+	for _, line := range items {
+		totalAmount = totalAmount.Add(line.LineTotal)
+	}
 
-		// Create a unique filename
-		filename := fmt.Sprintf("%s_%s", orderResult.InsertedID.(primitive.ObjectID).Hex(), handler.Filename)
-		filePath := filepath.Join(uploadPath, filename)
+	// Estimate delivery in business days from when the order is placed
+	now := time.Now()
+	deliveryDate, err := oc.Delivery.Estimate(ctx, now, address, *shipping)
+	if err != nil {
+		log.Printf("Failed to estimate delivery for user %s: %v", user.ID.Hex(), err)
+		http.Error(w, "Failed to estimate delivery", http.StatusInternalServerError)
+		return
+	}
 
-		// Create the file on the server
-		dst, err := os.Create(filePath)
-		if err != nil {
-			http.Error(w, "Failed to create file on server", http.StatusInternalServerError)
-			return
-		}
-		defer dst.Close()
+	// Create the order
+	order := models.Order{
+		UserID:       user.ID,
+		Items:        items,
+		TotalAmount:  totalAmount,
+		Promotion:    promotion,
+		Address:      address,
+		Shipping:     shipping,
+		DeliveryDate: deliveryDate,
+		Reservation: &models.StockReservation{
+			Status:    models.ReservationActive,
+			ExpiresAt: now.Add(oc.ReservationTTL[paymentMethod]),
+		},
+		PaymentMethod: paymentMethod,
+		PaymentStatus: models.PaymentPending,
+		Status:        models.OrderPending,
+		History: []models.StatusChange{
+			{To: models.OrderPending, Actor: user.Email, At: now},
+		},
+		CreatedAt:     now,
+		CartReminders: cart.RemindersSent,
+	}
 
-		// Copy the uploaded file to the server
-		_, err = io.Copy(dst, file)
-		if err != nil {
-			http.Error(w, "Failed to save file", http.StatusInternalServerError)
-			return
-		}
+	// Reserve stock, insert the order and clear the cart as one unit. The
+	// stock check above is advisory; this is where overselling is actually
+	// prevented. The reservation is committed when the order is paid and
+	// released if it is cancelled or expires first.
+	err = oc.Orders.PlaceOrder(ctx, &order)
+	if errors.Is(err, store.ErrInsufficientStock) {
+		http.Error(w, "Insufficient stock to complete the order", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, store.ErrInvalidQuantity) {
+		http.Error(w, "Invalid item quantity in cart", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, store.ErrLimitReached) || errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Coupon is no longer available; remove it from the cart to continue", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create order", http.StatusInternalServerError)
+		return
+	}
 
-		// Update the order with the crypto proof path
-		_, err = oc.OrderCollection.UpdateOne(ctx, bson.M{"_id": orderResult.InsertedID}, bson.M{
-			"$set": bson.M{"crypto_proof": filePath},
-		})
+	// Charge the card now that the stock is held. A decline or pending
+	// challenge leaves the order pending so the customer can confirm again.
+	if paymentMethod == "card" {
+		payment, err := oc.startCardPayment(ctx, &order, paymentRequest.PaymentToken)
 		if err != nil {
-			http.Error(w, "Failed to update order with crypto proof", http.StatusInternalServerError)
+			log.Printf("Failed to process card payment for order %s: %v", order.ID.Hex(), err)
+			http.Error(w, fmt.Sprintf("Order %s was created but the payment could not be processed; please retry the payment", order.ID.Hex()), http.StatusBadGateway)
 			return
 		}
-
-		// Send notification email to user
-		go func(email string) {
-			subject := "Crypto Payment Received - E-commerce Platform"
-			content := fmt.Sprintf("Dear %s,\n\nWe have received your cryptocurrency payment. Please upload the proof of payment to complete your order. Your order will be processed once the payment is verified.\n\nThank you for shopping with us!\n", user.Name)
-			err := oc.EmailService.SendEmail(email, subject, content)
-			if err != nil {
-				log.Printf("Failed to send email to %s: %v", email, err)
-			}
-		}(user.Email)
-	} else if paymentMethod == "card" {
-		// For card payments, integrate with a payment gateway here
-		// For simplicity, we'll assume the payment is successful
-		_, err := oc.OrderCollection.UpdateOne(ctx, bson.M{"_id": orderResult.InsertedID}, bson.M{
-			"$set": bson.M{"payment_status": "completed"},
-		})
-		if err != nil {
-			http.Error(w, "Failed to update payment status", http.StatusInternalServerError)
-			return
+		if payment.Status == models.PaymentCompleted {
+			oc.sendOrderConfirmation(user.Email, &order)
 		}
-
-		// Send confirmation email to user
-		go func(email string) {
-			subject := "Order Confirmation - E-commerce Platform"
-			content := fmt.Sprintf("Dear %s,\n\nThank you for your purchase! Your order has been placed successfully and will be delivered by %s.\n\nTotal Amount: $%.2f\nPayment Method: %s\n\nThank you for shopping with us!\n", user.Name, deliveryDate.Format("2006-01-02"), totalAmount, paymentMethod)
-			err := oc.EmailService.SendEmail(email, subject, content)
-			if err != nil {
-				log.Printf("Failed to send email to %s: %v", email, err)
-			}
-		}(user.Email)
+		writePaymentResult(w, &order, payment)
+		return
 	}
 
-	// Clear the user's cart
-	_, err = oc.CartCollection.DeleteOne(ctx, bson.M{"user_id": user.ID})
+	err = oc.Payments.CreatePayment(ctx, &models.Payment{
+		OrderID:       order.ID,
+		PaymentMethod: paymentMethod,
+		Amount:        totalAmount,
+		Status:        models.PaymentPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
 	if err != nil {
-		http.Error(w, "Failed to clear cart", http.StatusInternalServerError)
+		http.Error(w, "Failed to record payment", http.StatusInternalServerError)
 		return
 	}
 
+	// Crypto orders wait for the customer to upload proof of the transfer
+	go func(email string) {
+		subject := "Order Placed - Awaiting Crypto Payment"
+		content := fmt.Sprintf("Dear %s,\n\nYour order (ID: %s) has been placed. Please upload the proof of your cryptocurrency payment by %s to complete it; after that your items are released. Your order will be processed once the payment is verified.\n\nThank you for shopping with us!\n", user.Name, order.ID.Hex(), order.Reservation.ExpiresAt.Format(time.RFC1123))
+		err := oc.EmailService.SendEmail(email, subject, content)
+		if err != nil {
+			log.Printf("Failed to send email to %s: %v", email, err)
+		}
+	}(user.Email)
+
 	// Respond with the created order details
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"order_id":      orderResult.InsertedID,
-		"total_amount":  totalAmount,
-		"delivery_date": deliveryDate.Format("2006-01-02"),
-		"message":       "Order created successfully. It will take 7 working days to arrive at your provided address.",
+		"order_id":       order.ID,
+		"total_amount":   totalAmount,
+		"delivery_date":  deliveryDate,
+		"shipping":       shipping,
+		"payment_status": models.PaymentPending,
+		"reserved_until": order.Reservation.ExpiresAt,
+		"proof_upload":   "/orders/" + order.ID.Hex() + "/payment-proof",
+		"message":        "Order created successfully. Upload proof of your crypto payment to complete it.",
 	})
 }
 
@@ -252,41 +319,136 @@ func (oc *OrderController) GetOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find the user in the database
-	var user models.User
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	err := oc.UserCollection.FindOne(ctx, bson.M{"email": claims.Email}).Decode(&user)
+	user, err := oc.Users.FindUserByEmail(ctx, claims.Email)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
 
 	// Find all orders for the user
-	cursor, err := oc.OrderCollection.Find(ctx, bson.M{"user_id": user.ID})
+	orders, err := oc.Orders.ListOrdersByUser(ctx, user.ID)
 	if err != nil {
 		http.Error(w, "Failed to retrieve orders", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
 
-	var orders []models.Order
-	for cursor.Next(ctx) {
-		var order models.Order
-		err := cursor.Decode(&order)
-		if err != nil {
-			http.Error(w, "Error decoding order", http.StatusInternalServerError)
-			return
-		}
-		orders = append(orders, order)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+// GetInvoice returns the invoice of one of the authenticated user's orders
+func (oc *OrderController) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
-	if err := cursor.Err(); err != nil {
-		http.Error(w, "Cursor error", http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := oc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+	if order.UserID != user.ID {
+		http.Error(w, "Order not found", http.StatusNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+	json.NewEncoder(w).Encode(models.InvoiceFor(*order))
+}
+
+// CancelOrder lets the owner cancel an order that has not shipped yet. Stock
+// is restored and a completed payment is flagged for refund.
+func (oc *OrderController) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if reason == "" {
+		reason = "Cancelled by customer"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := oc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+	// Other users' orders are reported as missing rather than forbidden
+	if order.UserID != user.ID {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	if !order.Status.Cancellable() {
+		http.Error(w, "Order can no longer be cancelled", http.StatusConflict)
+		return
+	}
+
+	change := models.StatusChange{
+		From:  order.Status,
+		To:    models.OrderCancelled,
+		Actor: user.Email,
+		Note:  reason,
+		At:    time.Now(),
+	}
+	err = oc.Orders.CancelOrder(ctx, order.ID, order.Status, change, reason)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Order status changed concurrently; reload and retry", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to cancel order", http.StatusInternalServerError)
+		return
+	}
+	oc.notifyRestocked(ctx, *order)
+
+	refundPending := order.PaymentStatus == models.PaymentCompleted
+	if refundPending {
+		order.PaymentStatus = models.PaymentRefundPending
+		// A failed refund stays refund_pending for an admin to retry
+		if err := oc.refundCardPayment(ctx, order); err != nil {
+			log.Printf("Failed to refund payment for order %s: %v", order.ID.Hex(), err)
+		}
+	}
+	go func(email string, order models.Order) {
+		err := oc.EmailService.SendOrderCancellationEmail(email, order, reason, refundPending)
+		if err != nil {
+			log.Printf("Failed to send email to %s: %v", email, err)
+		}
+	}(user.Email, *order)
+
+	message := "Order cancelled."
+	if refundPending {
+		message += " Your payment will be refunded."
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
 }
 
 // UpdateOrderPaymentStatus allows admin to update payment status
@@ -308,7 +470,7 @@ func (oc *OrderController) UpdateOrderPaymentStatus(w http.ResponseWriter, r *ht
 
 	// Parse the request body for new payment status
 	var paymentUpdate struct {
-		PaymentStatus string `json:"payment_status"` // "completed", "failed"
+		PaymentStatus models.PaymentStatus `json:"payment_status"` // "completed", "failed"
 	}
 	err = json.NewDecoder(r.Body).Decode(&paymentUpdate)
 	if err != nil {
@@ -316,7 +478,7 @@ func (oc *OrderController) UpdateOrderPaymentStatus(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if paymentUpdate.PaymentStatus != "completed" && paymentUpdate.PaymentStatus != "failed" {
+	if paymentUpdate.PaymentStatus != models.PaymentCompleted && paymentUpdate.PaymentStatus != models.PaymentFailed {
 		http.Error(w, "Invalid payment status", http.StatusBadRequest)
 		return
 	}
@@ -324,32 +486,32 @@ func (oc *OrderController) UpdateOrderPaymentStatus(w http.ResponseWriter, r *ht
 	// Update the payment status in the order
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	update := bson.M{
-		"$set": bson.M{
-			"payment_status": paymentUpdate.PaymentStatus,
-		},
+	err = oc.Orders.UpdateOrderPaymentStatus(ctx, orderID, paymentUpdate.PaymentStatus)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
 	}
-	result, err := oc.OrderCollection.UpdateOne(ctx, bson.M{"_id": orderID}, update)
 	if err != nil {
 		http.Error(w, "Failed to update payment status", http.StatusInternalServerError)
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		http.Error(w, "Order not found", http.StatusNotFound)
-		return
-	}
-
 	// Optionally, send an email notification to the user about the payment status update
-	var order models.Order
-	err = oc.OrderCollection.FindOne(ctx, bson.M{"_id": orderID}).Decode(&order)
+	order, err := oc.Orders.FindOrderByID(ctx, orderID)
 	if err != nil {
 		http.Error(w, "Failed to retrieve updated order", http.StatusInternalServerError)
 		return
 	}
 
-	var user models.User
-	err = oc.UserCollection.FindOne(ctx, bson.M{"_id": order.UserID}).Decode(&user)
+	// A completed payment moves a pending order forward
+	if paymentUpdate.PaymentStatus == models.PaymentCompleted && order.Status.CanTransitionTo(models.OrderPaid) {
+		err = oc.changeOrderStatus(ctx, order, models.OrderPaid, claims.Email, "Payment marked completed")
+		if err != nil {
+			log.Printf("Failed to mark order %s paid: %v", orderID.Hex(), err)
+		}
+	}
+
+	user, err := oc.Users.FindUserByID(ctx, order.UserID)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errIllegalTransition is returned when the order lifecycle forbids a status change
+var errIllegalTransition = errors.New("illegal order status transition")
+
+// changeOrderStatus moves an order to a new status, records who did it in the
+// order history and emails the customer. order is updated in place.
+func (oc *OrderController) changeOrderStatus(ctx context.Context, order *models.Order, to models.OrderStatus, actor, note string) error {
+	if !order.Status.CanTransitionTo(to) {
+		return errIllegalTransition
+	}
+
+	change := models.StatusChange{
+		From:  order.Status,
+		To:    to,
+		Actor: actor,
+		Note:  note,
+		At:    time.Now(),
+	}
+	// Card payments are refunded before the order is marked refunded
+	if to == models.OrderRefunded {
+		if err := oc.refundCardPayment(ctx, order); err != nil {
+			return err
+		}
+	}
+
+	// Paying and cancelling go through PayOrder and CancelOrder so the
+	// order's stock is committed, released or restored with it
+	var err error
+	switch to {
+	case models.OrderPaid:
+		err = oc.Orders.PayOrder(ctx, order.ID, order.Status, change)
+	case models.OrderCancelled:
+		err = oc.Orders.CancelOrder(ctx, order.ID, order.Status, change, note)
+	default:
+		err = oc.Orders.TransitionOrderStatus(ctx, order.ID, order.Status, change)
+	}
+	if err != nil {
+		return err
+	}
+	if to == models.OrderCancelled {
+		oc.notifyRestocked(ctx, *order)
+		order.CancelReason = note
+		if order.PaymentStatus == models.PaymentCompleted {
+			order.PaymentStatus = models.PaymentRefundPending
+			// A failed refund stays refund_pending; moving the order to
+			// refunded retries it
+			if err := oc.refundCardPayment(ctx, order); err != nil {
+				log.Printf("Failed to refund payment for order %s: %v", order.ID.Hex(), err)
+			}
+		}
+	}
+	order.Status = to
+	order.History = append(order.History, change)
+
+	go func(order models.Order) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		user, err := oc.Users.FindUserByID(ctx, order.UserID)
+		if err != nil {
+			log.Printf("Failed to find user for order %s: %v", order.ID.Hex(), err)
+			return
+		}
+		if err := oc.EmailService.SendOrderStatusEmail(user.Email, order, change); err != nil {
+			log.Printf("Failed to send email to %s: %v", user.Email, err)
+		}
+	}(*order)
+	return nil
+}
+
+// orderFromRequest loads the order named by the {id} route variable, writing
+// an error response and returning nil if it cannot
+func (oc *OrderController) orderFromRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.Order {
+	orderID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return nil
+	}
+	order, err := oc.Orders.FindOrderByID(ctx, orderID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
+		return nil
+	}
+	return order
+}
+
+// ListAllOrders lists every order, newest first, optionally filtered by ?status= (Admin only)
+func (oc *OrderController) ListAllOrders(w http.ResponseWriter, r *http.Request) {
+	status := models.OrderStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.Valid() {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	orders, err := oc.Orders.ListOrders(ctx, status)
+	if err != nil {
+		http.Error(w, "Failed to retrieve orders", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+// GetOrderByID retrieves any order, including its status history (Admin only)
+func (oc *OrderController) GetOrderByID(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetOrderInvoice returns the invoice of any order (Admin only)
+func (oc *OrderController) GetOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.InvoiceFor(*order))
+}
+
+// GetOrderHistory retrieves the status history of an order (Admin only)
+func (oc *OrderController) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+
+	history := order.History
+	if history == nil {
+		history = []models.StatusChange{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// UpdateOrderStatus moves an order to a new lifecycle status (Admin only)
+func (oc *OrderController) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Status models.OrderStatus `json:"status"`
+		Note   string             `json:"note"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !body.Status.Valid() {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+
+	from := order.Status
+	err = oc.changeOrderStatus(ctx, order, body.Status, claims.Email, body.Note)
+	if errors.Is(err, errIllegalTransition) {
+		http.Error(w, "Cannot move order from "+string(from)+" to "+string(body.Status), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Order status changed concurrently; reload and retry", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errRefundFailed) {
+		log.Printf("Failed to refund order %s: %v", order.ID.Hex(), err)
+		http.Error(w, "Payment provider did not refund the payment", http.StatusBadGateway)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update order status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
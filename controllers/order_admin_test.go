@@ -0,0 +1,93 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkout has the customer buy one of a new product by card and returns the order ID
+func (s *shop) checkout(t *testing.T) primitive.ObjectID {
+	t.Helper()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	var result struct {
+		OrderID primitive.ObjectID `json:"order_id"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, &result)
+	return result.OrderID
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	s := newShop(t)
+	orderID := s.checkout(t)
+	path := "/admin/orders/" + orderID.Hex()
+
+	steps := []struct {
+		status models.OrderStatus
+		want   int
+	}{
+		{models.OrderShipped, http.StatusConflict}, // Must be processed first
+		{models.OrderProcessing, http.StatusOK},
+		{models.OrderShipped, http.StatusOK},
+		{models.OrderCancelled, http.StatusConflict}, // Too late once shipped
+		{"lost", http.StatusBadRequest},
+		{models.OrderDelivered, http.StatusOK},
+	}
+	for _, step := range steps {
+		rec := s.do(t, "POST", path+"/status", s.admin, map[string]string{"status": string(step.status), "note": "step"})
+		if rec.Code != step.want {
+			t.Errorf("move to %s = %d %s, want %d", step.status, rec.Code, rec.Body.String(), step.want)
+		}
+	}
+
+	var history []models.StatusChange
+	s.expect(t, http.StatusOK, "GET", path+"/history", s.admin, nil, &history)
+	var got []models.OrderStatus
+	for _, change := range history {
+		got = append(got, change.To)
+	}
+	want := []models.OrderStatus{models.OrderPending, models.OrderPaid, models.OrderProcessing, models.OrderShipped, models.OrderDelivered}
+	if len(got) != len(want) {
+		t.Fatalf("history = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("history = %v, want %v", got, want)
+		}
+	}
+	if last := history[len(history)-1]; last.Actor != adminEmail || last.From != models.OrderShipped {
+		t.Errorf("last change = %+v, want the admin moving it from shipped", last)
+	}
+	if !eventually(t, func() bool { return len(s.mail.to(customerEmail)) >= 4 }) {
+		t.Errorf("emails to the customer = %v, want one per status change", s.mail.to(customerEmail))
+	}
+
+	var orders []models.Order
+	s.expect(t, http.StatusOK, "GET", "/admin/orders?status=delivered", s.admin, nil, &orders)
+	if len(orders) != 1 || orders[0].ID != orderID {
+		t.Errorf("delivered orders = %+v", orders)
+	}
+	s.expect(t, http.StatusBadRequest, "GET", "/admin/orders?status=lost", s.admin, nil, nil)
+	s.expect(t, http.StatusForbidden, "GET", "/admin/orders", s.customer, nil, nil)
+}
+
+func TestUpdateOrderPaymentStatus(t *testing.T) {
+	s := newShop(t)
+	order := models.Order{UserID: s.customerUser(t).ID, Status: models.OrderPending, PaymentMethod: "crypto", PaymentStatus: models.PaymentPending}
+	if err := s.db.CreateOrder(context.Background(), &order); err != nil {
+		t.Fatal(err)
+	}
+	path := "/admin/orders/" + order.ID.Hex()
+
+	s.expect(t, http.StatusBadRequest, "PUT", path+"/payment-status", s.admin, map[string]string{"payment_status": "refunded"}, nil)
+	s.expect(t, http.StatusOK, "PUT", path+"/payment-status", s.admin, map[string]string{"payment_status": "completed"}, nil)
+	s.expect(t, http.StatusOK, "GET", path, s.admin, nil, &order)
+	if order.Status != models.OrderPaid || order.PaymentStatus != models.PaymentCompleted {
+		t.Errorf("order = %+v, want it paid", order)
+	}
+}
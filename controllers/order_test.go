@@ -0,0 +1,151 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateOrderPaidByCard(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 12.5, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 2}, nil)
+
+	var result struct {
+		OrderID     primitive.ObjectID `json:"order_id"`
+		TotalAmount models.Money       `json:"total_amount"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, &result)
+	if result.TotalAmount != models.NewMoney(2500, models.DefaultCurrency) {
+		t.Errorf("total = %v, want 25", result.TotalAmount)
+	}
+
+	order, err := s.db.FindOrderByID(ctx, result.OrderID)
+	if err != nil || order.PaymentStatus != "completed" || len(order.Items) != 1 {
+		t.Fatalf("order = %+v, %v; want it paid", order, err)
+	}
+	if product, err := s.db.FindProductByID(ctx, mug.ID); err != nil || product.Stock != 3 {
+		t.Errorf("stock = %+v, %v; want 3", product, err)
+	}
+	s.expect(t, http.StatusNotFound, "GET", "/cart", s.customer, nil, nil)
+	if !eventually(t, func() bool { return len(s.mail.to(customerEmail)) == 1 }) {
+		t.Errorf("emails to the customer = %v, want the confirmation", s.mail.to(customerEmail))
+	}
+}
+
+func TestCreateOrderInsufficientStock(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 12.5, "stock": 2})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 2}, nil)
+	// One breaks after it was added to the cart
+	breakage := map[string]interface{}{"delta": -1, "reason": "adjustment", "note": "Broken"}
+	s.expect(t, http.StatusCreated, "POST", "/products/"+mug.ID.Hex()+"/stock-movements", s.admin, breakage, nil)
+
+	s.expect(t, http.StatusBadRequest, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, nil)
+	if product, err := s.db.FindProductByID(ctx, mug.ID); err != nil || product.Stock != 1 {
+		t.Errorf("stock = %+v, %v; want it untouched", product, err)
+	}
+	if orders, err := s.db.ListOrdersByUser(ctx, s.customerUser(t).ID); err != nil || len(orders) != 0 {
+		t.Errorf("orders = %+v, %v; want none", orders, err)
+	}
+}
+
+func TestCreateOrderWithVariants(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	shirt := s.createProduct(t, map[string]interface{}{
+		"name":    "Shirt",
+		"price":   20,
+		"options": []map[string]interface{}{{"name": "size", "values": []string{"S", "M"}}},
+		"variants": []map[string]interface{}{
+			{"sku": "SHIRT-S", "options": map[string]string{"size": "S"}, "stock": 2},
+			{"sku": "SHIRT-M", "options": map[string]string{"size": "M"}, "stock": 2, "price": 25},
+		},
+	})
+
+	// A product sold by variant needs a SKU
+	s.expect(t, http.StatusBadRequest, "POST", "/cart", s.customer, map[string]interface{}{"product_id": shirt.ID, "quantity": 1}, nil)
+
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": shirt.ID, "sku": "SHIRT-S", "quantity": 1}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": shirt.ID, "sku": "SHIRT-M", "quantity": 2}, nil)
+	var result struct {
+		TotalAmount models.Money `json:"total_amount"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, &result)
+	if result.TotalAmount != models.NewMoney(7000, models.DefaultCurrency) {
+		t.Errorf("total = %v, want 70 with the medium's price override", result.TotalAmount)
+	}
+	if product, err := s.db.FindProductByID(ctx, shirt.ID); err != nil || product.StockFor("SHIRT-S") != 1 || product.StockFor("SHIRT-M") != 0 {
+		t.Errorf("product = %+v, %v; want 1 small and no medium left", product, err)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	orderID := s.checkout(t)
+	path := "/orders/" + orderID.Hex() + "/cancel"
+
+	// Another user's order looks missing
+	s.expect(t, http.StatusNotFound, "POST", path, s.admin, nil, nil)
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	s.expect(t, http.StatusOK, "POST", path, s.customer, map[string]string{"reason": "Found it cheaper"}, &result)
+	if result.Message != "Order cancelled. Your payment will be refunded." {
+		t.Errorf("message = %q", result.Message)
+	}
+	order, err := s.db.FindOrderByID(ctx, orderID)
+	if err != nil || order.Status != models.OrderCancelled || order.CancelReason != "Found it cheaper" {
+		t.Fatalf("order = %+v, %v; want it cancelled", order, err)
+	}
+	if product, err := s.db.FindProductByID(ctx, order.Items[0].ProductID); err != nil || product.Stock != 5 {
+		t.Errorf("stock = %+v, %v; want it restored to 5", product, err)
+	}
+	s.expect(t, http.StatusConflict, "POST", path, s.customer, nil, nil)
+}
+
+func TestCancelShippedOrder(t *testing.T) {
+	s := newShop(t)
+	orderID := s.checkout(t)
+	for _, status := range []string{"processing", "shipped"} {
+		s.expect(t, http.StatusOK, "POST", "/admin/orders/"+orderID.Hex()+"/status", s.admin, map[string]string{"status": status}, nil)
+	}
+	s.expect(t, http.StatusConflict, "POST", "/orders/"+orderID.Hex()+"/cancel", s.customer, nil, nil)
+}
+
+func TestInvoiceKeepsPurchasePrices(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 12.5, "stock": 5})
+	payment := s.placeOrder(t, mug, payments.TokenSuccess)
+	path := "/orders/" + payment.OrderID.Hex() + "/invoice"
+
+	// Later catalog changes do not reach the order
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Big mug", "price": 20, "stock": 4}, nil)
+	s.expect(t, http.StatusOK, "DELETE", "/products/"+mug.ID.Hex(), s.admin, nil, nil)
+
+	var invoice models.Invoice
+	s.expect(t, http.StatusOK, "GET", path, s.customer, nil, &invoice)
+	usd := func(cents int64) models.Money { return models.NewMoney(cents, models.DefaultCurrency) }
+	if len(invoice.Lines) != 1 || invoice.Lines[0].Name != "Mug" || invoice.Lines[0].UnitPrice != usd(1250) {
+		t.Errorf("invoice lines = %+v, want the mug as bought", invoice.Lines)
+	}
+	if invoice.Subtotal != usd(1250) || invoice.Total != usd(1250) || invoice.Number != "INV-"+payment.OrderID.Hex() {
+		t.Errorf("invoice = %+v", invoice)
+	}
+	s.expect(t, http.StatusOK, "GET", "/admin/orders/"+payment.OrderID.Hex()+"/invoice", s.admin, nil, nil)
+	// The customer route only serves the caller's own orders
+	s.expect(t, http.StatusNotFound, "GET", path, s.admin, nil, nil)
+
+	if !eventually(t, func() bool { return strings.Contains(s.mail.body(customerEmail, "Order Confirmation"), "<td>Mug</td>") }) {
+		t.Errorf("confirmation email = %q, want the invoice lines", s.mail.body(customerEmail, "Order Confirmation"))
+	}
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// passwordResetTTL is how long an emailed reset link stays valid
+	passwordResetTTL = time.Hour
+	// minPasswordLength is the shortest password accepted on reset
+	minPasswordLength = 8
+)
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the email is registered so accounts cannot be enumerated.
+func (uc *UserController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email string `json:"email"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body.Email == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	// Do the lookup and send off the request path so timing reveals nothing either
+	go uc.sendPasswordReset(body.Email)
+
+	json.NewEncoder(w).Encode("If that email is registered, a password reset link has been sent.")
+}
+
+// sendPasswordReset creates a reset token for the user, if any, and emails it
+func (uc *UserController) sendPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := uc.Users.FindUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Password reset lookup failed: %v", err)
+		}
+		return
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("Failed to generate password reset token: %v", err)
+		return
+	}
+	now := time.Now()
+	err = uc.Resets.CreatePasswordReset(ctx, &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetTTL),
+	})
+	if err != nil {
+		log.Printf("Failed to store password reset token: %v", err)
+		return
+	}
+
+	err = uc.EmailService.SendPasswordResetEmail(user.Email, token, passwordResetTTL)
+	if err != nil {
+		log.Printf("Failed to send email to %s: %v", user.Email, err)
+	}
+}
+
+// ResetPassword sets a new password using an emailed reset token and logs
+// the user out of every existing session
+func (uc *UserController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body.Token == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if len(body.Password) < minPasswordLength {
+		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reset, err := uc.Resets.FindPasswordResetByHash(ctx, utils.HashToken(body.Token))
+	if err != nil || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+
+	// Claim the token before changing anything so it cannot be used twice
+	err = uc.Resets.ConsumePasswordReset(ctx, reset.ID)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error resetting password", http.StatusInternalServerError)
+		return
+	}
+
+	err = uc.Users.UpdateUserPassword(ctx, reset.UserID, string(hashedPassword))
+	if err != nil {
+		http.Error(w, "Error resetting password", http.StatusInternalServerError)
+		return
+	}
+
+	err = uc.Sessions.RevokeUserSessions(ctx, reset.UserID)
+	if err != nil {
+		http.Error(w, "Password updated but existing sessions could not be revoked", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Password has been reset. Please log in with your new password.")
+}
@@ -0,0 +1,57 @@
+package controllers_test
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+var resetToken = regexp.MustCompile(`token=([\w-]+)`)
+
+func TestPasswordReset(t *testing.T) {
+	s := newShop(t)
+	other := s.loginTokens(t, customerEmail)
+
+	s.expect(t, http.StatusOK, "POST", "/password/forgot", "", map[string]string{"email": customerEmail}, nil)
+	var link []string
+	if !eventually(t, func() bool {
+		link = resetToken.FindStringSubmatch(s.mail.body(customerEmail, "Reset Your Password"))
+		return link != nil
+	}) {
+		t.Fatalf("emails to the customer = %v, want a reset link", s.mail.to(customerEmail))
+	}
+	token := link[1]
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		want     int
+	}{
+		{"short password", token, "short", http.StatusBadRequest},
+		{"unknown token", "not-a-token", "new-password", http.StatusBadRequest},
+		{"reset", token, "new-password", http.StatusOK},
+		// Tokens are single-use
+		{"reused token", token, "another-password", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := s.do(t, "POST", "/password/reset", "", map[string]string{"token": tt.token, "password": tt.password})
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d %s, want %d", tt.name, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+
+	// Every existing session is revoked
+	s.expect(t, http.StatusUnauthorized, "GET", "/profile", s.customer, nil, nil)
+	s.expect(t, http.StatusUnauthorized, "POST", "/token/refresh", "", map[string]string{"refresh_token": other.RefreshToken}, nil)
+	s.expect(t, http.StatusUnauthorized, "POST", "/login", "", map[string]string{"email": customerEmail, "password": testPassword}, nil)
+	s.expect(t, http.StatusOK, "POST", "/login", "", map[string]string{"email": customerEmail, "password": "new-password"}, nil)
+	s.expect(t, http.StatusOK, "GET", "/profile", s.admin, nil, nil)
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	s := newShop(t)
+	// The response does not reveal whether the account exists
+	s.expect(t, http.StatusOK, "POST", "/password/forgot", "", map[string]string{"email": "nobody@example.com"}, nil)
+	s.expect(t, http.StatusBadRequest, "POST", "/password/forgot", "", map[string]string{}, nil)
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errRefundFailed is returned when the payment provider does not refund a payment
+var errRefundFailed = errors.New("payment refund failed")
+
+// paymentStatusFor maps a gateway intent status onto a payment status
+func paymentStatusFor(status payments.IntentStatus) models.PaymentStatus {
+	switch status {
+	case payments.IntentRequiresAction:
+		return models.PaymentRequiresAction
+	case payments.IntentRequiresCapture:
+		return models.PaymentAuthorized
+	case payments.IntentSucceeded:
+		return models.PaymentCompleted
+	case payments.IntentFailed:
+		return models.PaymentFailed
+	case payments.IntentRefunded:
+		return models.PaymentRefunded
+	default:
+		return models.PaymentPending
+	}
+}
+
+// startCardPayment opens a gateway intent and a payment record for a placed
+// order, then attempts the charge with the customer's card token
+func (oc *OrderController) startCardPayment(ctx context.Context, order *models.Order, token string) (*models.Payment, error) {
+	intent, err := oc.Provider.CreateIntent(ctx, order.TotalAmount.Amount, order.TotalAmount.Currency, order.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	payment := &models.Payment{
+		OrderID:       order.ID,
+		PaymentMethod: "card",
+		Amount:        order.TotalAmount,
+		Status:        models.PaymentPending,
+		Provider:      oc.Provider.Name(),
+		ProviderRef:   intent.ID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := oc.Payments.CreatePayment(ctx, payment); err != nil {
+		return nil, err
+	}
+	return payment, oc.confirmCardPayment(ctx, order, payment, token)
+}
+
+// confirmCardPayment confirms the payment's intent, captures it once the
+// issuer has authorized it and records the outcome
+func (oc *OrderController) confirmCardPayment(ctx context.Context, order *models.Order, payment *models.Payment, token string) error {
+	intent, err := oc.Provider.ConfirmIntent(ctx, payment.ProviderRef, token)
+	if err != nil {
+		return err
+	}
+	if intent.Status == payments.IntentRequiresCapture {
+		intent, err = oc.Provider.Capture(ctx, intent.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return oc.applyIntent(ctx, order, payment, intent)
+}
+
+// applyIntent copies a gateway intent's state onto the payment and its order,
+// and moves the order to paid once the money has been captured. Money
+// captured for an order that cannot be paid, because it was cancelled or no
+// longer has the stock it held, is refunded. Both are updated in place.
+func (oc *OrderController) applyIntent(ctx context.Context, order *models.Order, payment *models.Payment, intent *payments.Intent) error {
+	payment.Status = paymentStatusFor(intent.Status)
+	payment.NextActionURL = intent.NextActionURL
+	payment.FailureReason = intent.FailureReason
+	payment.UpdatedAt = time.Now()
+	if err := oc.Payments.UpdatePayment(ctx, payment); err != nil {
+		return err
+	}
+	if err := oc.Orders.UpdateOrderPaymentStatus(ctx, order.ID, payment.Status); err != nil {
+		return err
+	}
+	order.PaymentStatus = payment.Status
+
+	if payment.Status != models.PaymentCompleted {
+		return nil
+	}
+	err := oc.markOrderPaid(ctx, order, "system", "Card payment completed")
+	if errors.Is(err, store.ErrConflict) {
+		// Its status changed since it was loaded: paid by a concurrent
+		// request, or cancelled, e.g. by the reservation sweeper
+		fresh, ferr := oc.Orders.FindOrderByID(ctx, order.ID)
+		if ferr != nil {
+			return ferr
+		}
+		*order = *fresh
+		err = nil
+	}
+	if err != nil && !errors.Is(err, store.ErrInsufficientStock) {
+		return err
+	}
+	if err == nil && order.Status != models.OrderCancelled {
+		return nil
+	}
+	return oc.refundUnpaidOrder(ctx, order, payment)
+}
+
+// refundUnpaidOrder gives back money captured for an order that could not be
+// paid. A refund the provider refuses leaves the payment and the order
+// refund_pending for an admin to retry. Both are updated in place.
+func (oc *OrderController) refundUnpaidOrder(ctx context.Context, order *models.Order, payment *models.Payment) error {
+	refundErr := oc.refundCardPayment(ctx, order)
+	if refundErr != nil {
+		log.Printf("Failed to refund payment for order %s: %v", order.ID.Hex(), refundErr)
+	}
+	// The refund, or a cancellation that got there first, saved its own copy
+	fresh, err := oc.Payments.FindPaymentByID(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+	*payment = *fresh
+	if refundErr != nil && payment.Status == models.PaymentCompleted {
+		payment.Status = models.PaymentRefundPending
+		payment.UpdatedAt = time.Now()
+		if err := oc.Payments.UpdatePayment(ctx, payment); err != nil {
+			return err
+		}
+	}
+	if err := oc.Orders.UpdateOrderPaymentStatus(ctx, order.ID, payment.Status); err != nil {
+		return err
+	}
+	order.PaymentStatus = payment.Status
+	return nil
+}
+
+// markOrderPaid moves a pending order to paid without the status email; the
+// caller tells the customer. Orders past pending are left as they are.
+func (oc *OrderController) markOrderPaid(ctx context.Context, order *models.Order, actor, note string) error {
+	if !order.Status.CanTransitionTo(models.OrderPaid) {
+		return nil
+	}
+	change := models.StatusChange{
+		From:  order.Status,
+		To:    models.OrderPaid,
+		Actor: actor,
+		Note:  note,
+		At:    time.Now(),
+	}
+	if err := oc.Orders.PayOrder(ctx, order.ID, order.Status, change); err != nil {
+		return err
+	}
+	order.Status = models.OrderPaid
+	order.History = append(order.History, change)
+	return nil
+}
+
+// refundCardPayment refunds an order's captured card payment through the
+// provider. Crypto payments and unpaid orders are left alone; those are
+// refunded by hand.
+func (oc *OrderController) refundCardPayment(ctx context.Context, order *models.Order) error {
+	payment, err := oc.Payments.FindPaymentByOrderID(ctx, order.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if payment.Provider == "" || (payment.Status != models.PaymentCompleted && payment.Status != models.PaymentRefundPending) {
+		return nil
+	}
+
+	intent, err := oc.Provider.Refund(ctx, payment.ProviderRef)
+	if err != nil {
+		return errors.Join(errRefundFailed, err)
+	}
+	return oc.applyIntent(ctx, order, payment, intent)
+}
+
+// ConfirmPayment retries or completes the card payment of the user's order,
+// e.g. after a 3-D Secure challenge or with a new card after a decline
+func (oc *OrderController) ConfirmPayment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		PaymentToken string `json:"payment_token"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := oc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+	if order.UserID != user.ID {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	if order.Status != models.OrderPending {
+		http.Error(w, "Order is not awaiting payment", http.StatusConflict)
+		return
+	}
+
+	payment, err := oc.Payments.FindPaymentByOrderID(ctx, order.ID)
+	if err != nil || payment.Provider == "" {
+		http.Error(w, "Order has no card payment", http.StatusNotFound)
+		return
+	}
+	switch payment.Status {
+	case models.PaymentPending, models.PaymentFailed:
+		if strings.TrimSpace(body.PaymentToken) == "" {
+			http.Error(w, "Payment token is required", http.StatusBadRequest)
+			return
+		}
+	case models.PaymentRequiresAction:
+	default:
+		http.Error(w, "Payment cannot be confirmed in status "+string(payment.Status), http.StatusConflict)
+		return
+	}
+
+	err = oc.confirmCardPayment(ctx, order, payment, body.PaymentToken)
+	if err != nil {
+		log.Printf("Failed to confirm payment for order %s: %v", order.ID.Hex(), err)
+		http.Error(w, "Failed to process payment; please retry", http.StatusBadGateway)
+		return
+	}
+
+	if payment.Status == models.PaymentCompleted {
+		oc.sendOrderConfirmation(user.Email, order)
+	}
+	writePaymentResult(w, order, payment)
+}
+
+// sendOrderConfirmation emails the customer that their order is paid and on its way
+func (oc *OrderController) sendOrderConfirmation(email string, order *models.Order) {
+	go func(order models.Order) {
+		err := oc.EmailService.SendOrderConfirmationEmail(email, order)
+		if err != nil {
+			log.Printf("Failed to send email to %s: %v", email, err)
+		}
+	}(*order)
+}
+
+// writePaymentResult responds with an order's payment outcome. A declined
+// payment is reported as 402 so clients can prompt for another card.
+func writePaymentResult(w http.ResponseWriter, order *models.Order, payment *models.Payment) {
+	response := map[string]interface{}{
+		"order_id":       order.ID,
+		"total_amount":   order.TotalAmount,
+		"delivery_date":  order.DeliveryDate,
+		"shipping":       order.Shipping,
+		"payment_status": payment.Status,
+	}
+	status := http.StatusOK
+	switch payment.Status {
+	case models.PaymentCompleted:
+		response["message"] = "Payment completed. Expected delivery: " + order.DeliveryDate.String() + "."
+	case models.PaymentRequiresAction:
+		response["next_action_url"] = payment.NextActionURL
+		response["reserved_until"] = reservedUntil(order)
+		response["message"] = "Complete card authentication, then confirm the payment."
+	case models.PaymentFailed:
+		response["failure_reason"] = payment.FailureReason
+		response["reserved_until"] = reservedUntil(order)
+		response["message"] = "Payment was declined. Confirm the payment with another card or cancel the order."
+		status = http.StatusPaymentRequired
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxProofBytes caps the size of an uploaded payment proof
+const maxProofBytes = 5 << 20
+
+// proofTypes are the accepted proof content types and the extension each is stored with
+var proofTypes = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// ProofUploadDir is where payment proof files are stored
+var ProofUploadDir = filepath.Join("uploads", "payments")
+
+// UploadPaymentProof accepts a multipart "proof" file (PNG, JPEG, WebP or PDF,
+// up to 5MB) for a crypto order awaiting payment and queues it for review
+func (oc *OrderController) UploadPaymentProof(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxProofBytes+64<<10)
+	if err := r.ParseMultipartForm(maxProofBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Proof file must be 5MB or smaller", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to parse multipart form", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("proof")
+	if err != nil {
+		http.Error(w, "Missing proof file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	if header.Size > maxProofBytes {
+		http.Error(w, "Proof file must be 5MB or smaller", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Trust the file's content rather than the type the client declares
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		http.Error(w, "Failed to read proof file", http.StatusBadRequest)
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := proofTypes[contentType]
+	if !ok {
+		http.Error(w, "Proof must be a PNG, JPEG, WebP or PDF file", http.StatusUnsupportedMediaType)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Failed to read proof file", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := oc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	order := oc.orderFromRequest(ctx, w, r)
+	if order == nil {
+		return
+	}
+	if order.UserID != user.ID {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	if order.PaymentMethod != "crypto" {
+		http.Error(w, "Order is not paid by crypto", http.StatusBadRequest)
+		return
+	}
+	if order.Status != models.OrderPending {
+		http.Error(w, "Order is not awaiting payment", http.StatusConflict)
+		return
+	}
+	payment, err := oc.Payments.FindPaymentByOrderID(ctx, order.ID)
+	if err != nil {
+		http.Error(w, "Payment not found", http.StatusNotFound)
+		return
+	}
+	// A rejected proof may be replaced; one under review may not
+	if payment.Status != models.PaymentPending && payment.Status != models.PaymentFailed {
+		http.Error(w, "Payment proof cannot be uploaded in status "+string(payment.Status), http.StatusConflict)
+		return
+	}
+
+	// The client's filename is never used on disk
+	path, err := saveProof(order.ID, ext, file)
+	if err != nil {
+		log.Printf("Failed to save payment proof for order %s: %v", order.ID.Hex(), err)
+		http.Error(w, "Failed to save proof file", http.StatusInternalServerError)
+		return
+	}
+
+	previous := payment.ProofPath
+	payment.Status = models.PaymentAwaitingReview
+	payment.ProofPath = path
+	payment.ProofURL = "/admin/payments/" + payment.ID.Hex() + "/proof"
+	payment.ProofType = contentType
+	payment.FailureReason = ""
+	payment.UpdatedAt = time.Now()
+	if err := oc.Payments.UpdatePayment(ctx, payment); err != nil {
+		os.Remove(path)
+		http.Error(w, "Failed to record payment proof", http.StatusInternalServerError)
+		return
+	}
+	if err := oc.Orders.UpdateOrderPaymentStatus(ctx, order.ID, payment.Status); err != nil {
+		log.Printf("Failed to update payment status of order %s: %v", order.ID.Hex(), err)
+	}
+	if previous != "" {
+		os.Remove(previous)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"order_id":       order.ID,
+		"payment_status": payment.Status,
+		"message":        "Payment proof received. Your order will be processed once the payment is verified.",
+	})
+}
+
+// saveProof writes an uploaded proof under a random name and returns its path
+func saveProof(orderID primitive.ObjectID, ext string, src io.Reader) (string, error) {
+	dir := filepath.Join(ProofUploadDir, orderID.Hex())
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, hex.EncodeToString(b)+ext)
+
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// paymentFromRequest loads the payment named by the {id} route variable,
+// writing an error response and returning nil if it cannot
+func (oc *OrderController) paymentFromRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.Payment {
+	paymentID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+		return nil
+	}
+	payment, err := oc.Payments.FindPaymentByID(ctx, paymentID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Payment not found", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve payment", http.StatusInternalServerError)
+		return nil
+	}
+	return payment
+}
+
+// ListPendingProofs lists crypto payments awaiting review, oldest first (Admin only)
+func (oc *OrderController) ListPendingProofs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	payments, err := oc.Payments.ListPaymentsByStatus(ctx, models.PaymentAwaitingReview)
+	if err != nil {
+		http.Error(w, "Failed to retrieve payments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payments)
+}
+
+// GetPaymentProof serves the uploaded proof file of a payment (Admin only)
+func (oc *OrderController) GetPaymentProof(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	payment := oc.paymentFromRequest(ctx, w, r)
+	if payment == nil {
+		return
+	}
+	if payment.ProofPath == "" {
+		http.Error(w, "Payment has no proof", http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(payment.ProofPath)
+	if err != nil {
+		http.Error(w, "Proof file not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Failed to read proof file", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", payment.ProofType)
+	w.Header().Set("Content-Disposition", "inline")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, filepath.Base(payment.ProofPath), info.ModTime(), file)
+}
+
+// ReviewPaymentProof approves or rejects a crypto payment proof with an
+// optional note. Approval marks the payment completed and the order paid;
+// rejection lets the customer upload a new proof. A proof for an order that
+// is no longer pending cannot be approved. (Admin only)
+func (oc *OrderController) ReviewPaymentProof(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Decision string `json:"decision"` // "approve" or "reject"
+		Note     string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body.Note = strings.TrimSpace(body.Note)
+	approved := body.Decision == "approve"
+	if !approved && body.Decision != "reject" {
+		http.Error(w, "Decision must be approve or reject", http.StatusBadRequest)
+		return
+	}
+	if !approved && body.Note == "" {
+		http.Error(w, "A note is required when rejecting a proof", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	payment := oc.paymentFromRequest(ctx, w, r)
+	if payment == nil {
+		return
+	}
+	if payment.Status != models.PaymentAwaitingReview {
+		http.Error(w, "Payment is not awaiting review", http.StatusConflict)
+		return
+	}
+	order, err := oc.Orders.FindOrderByID(ctx, payment.OrderID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve order", http.StatusInternalServerError)
+		return
+	}
+
+	// Only a pending order can still be paid for; one cancelled while its
+	// proof waited must not be recorded as paid. The order is marked paid
+	// before the payment so that a cancellation racing the review is caught.
+	if approved {
+		if order.Status != models.OrderPending {
+			http.Error(w, "Order is no longer pending", http.StatusConflict)
+			return
+		}
+		note := "Crypto payment proof approved"
+		if body.Note != "" {
+			note += ": " + body.Note
+		}
+		err := oc.markOrderPaid(ctx, order, claims.Email, note)
+		if errors.Is(err, store.ErrConflict) {
+			http.Error(w, "Order is no longer pending", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to update order", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	payment.Status = models.PaymentFailed
+	payment.FailureReason = "proof_rejected"
+	if approved {
+		payment.Status = models.PaymentCompleted
+		payment.FailureReason = ""
+	}
+	payment.ReviewNote = body.Note
+	payment.ReviewedBy = claims.Email
+	now := time.Now()
+	payment.ReviewedAt = &now
+	payment.UpdatedAt = now
+	if err := oc.Payments.UpdatePayment(ctx, payment); err != nil {
+		http.Error(w, "Failed to update payment", http.StatusInternalServerError)
+		return
+	}
+	if err := oc.Orders.UpdateOrderPaymentStatus(ctx, order.ID, payment.Status); err != nil {
+		http.Error(w, "Failed to update order", http.StatusInternalServerError)
+		return
+	}
+	order.PaymentStatus = payment.Status
+
+	go func(order models.Order) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		user, err := oc.Users.FindUserByID(ctx, order.UserID)
+		if err != nil {
+			log.Printf("Failed to find user for order %s: %v", order.ID.Hex(), err)
+			return
+		}
+		if err := oc.EmailService.SendPaymentReviewEmail(user.Email, order, approved, body.Note); err != nil {
+			log.Printf("Failed to send email to %s: %v", user.Email, err)
+		}
+	}(*order)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
@@ -0,0 +1,184 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"go-ecommerce/controllers"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pngProof is enough of a PNG for content sniffing
+var pngProof = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// useProofDir stores uploaded proofs in a temporary directory for the test
+func useProofDir(t *testing.T) {
+	t.Helper()
+	dir := controllers.ProofUploadDir
+	controllers.ProofUploadDir = t.TempDir()
+	t.Cleanup(func() { controllers.ProofUploadDir = dir })
+}
+
+// cryptoOrder has the customer check out one of a new product by crypto
+func (s *shop) cryptoOrder(t *testing.T) primitive.ObjectID {
+	t.Helper()
+	product := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": product.ID, "quantity": 1}, nil)
+	var result struct {
+		OrderID primitive.ObjectID `json:"order_id"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "crypto"}, &result)
+	return result.OrderID
+}
+
+// uploadProof posts content as the "proof" file of the order's payment
+func (s *shop) uploadProof(t *testing.T, orderID primitive.ObjectID, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	if content != nil {
+		part, err := form.CreateFormFile("proof", "../../etc/proof.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	form.Close()
+	req := httptest.NewRequest("POST", "/orders/"+orderID.Hex()+"/payment-proof", &buf)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+s.customer)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUploadPaymentProof(t *testing.T) {
+	useProofDir(t)
+	s := newShop(t)
+	orderID := s.cryptoOrder(t)
+	card := s.placeOrder(t, s.createProduct(t, map[string]interface{}{"name": "Pen", "price": 2, "stock": 5}), payments.TokenSuccess)
+
+	tests := []struct {
+		name    string
+		orderID primitive.ObjectID
+		content []byte
+		want    int
+	}{
+		{"missing file", orderID, nil, http.StatusBadRequest},
+		{"not an image or PDF", orderID, []byte("just some text"), http.StatusUnsupportedMediaType},
+		{"card order", card.OrderID, pngProof, http.StatusBadRequest},
+		{"unknown order", primitive.NewObjectID(), pngProof, http.StatusNotFound},
+		{"proof", orderID, pngProof, http.StatusOK},
+		{"proof already under review", orderID, pngProof, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.uploadProof(t, tt.orderID, tt.content); rec.Code != tt.want {
+				t.Errorf("upload = %d %s, want %d", rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+
+	payment, err := s.db.FindPaymentByOrderID(context.Background(), orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != models.PaymentAwaitingReview || payment.ProofType != "image/png" {
+		t.Errorf("payment = %+v, want a PNG proof awaiting review", payment)
+	}
+	order, err := s.db.FindOrderByID(context.Background(), orderID)
+	if err != nil || order.PaymentStatus != models.PaymentAwaitingReview {
+		t.Errorf("order = %+v, %v; want its payment awaiting review", order, err)
+	}
+}
+
+func TestReviewPaymentProof(t *testing.T) {
+	useProofDir(t)
+	s := newShop(t)
+	orderID := s.cryptoOrder(t)
+	if rec := s.uploadProof(t, orderID, pngProof); rec.Code != http.StatusOK {
+		t.Fatalf("upload = %d %s", rec.Code, rec.Body.String())
+	}
+
+	var queue []models.Payment
+	s.expect(t, http.StatusOK, "GET", "/admin/payments/proofs", s.admin, nil, &queue)
+	if len(queue) != 1 || queue[0].OrderID != orderID {
+		t.Fatalf("review queue = %+v, want the order's payment", queue)
+	}
+	path := "/admin/payments/" + queue[0].ID.Hex()
+	s.expect(t, http.StatusForbidden, "GET", "/admin/payments/proofs", s.customer, nil, nil)
+
+	rec := s.do(t, "GET", path+"/proof", s.admin, nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || !bytes.Equal(rec.Body.Bytes(), pngProof) {
+		t.Errorf("GET proof = %d %q, want the uploaded PNG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	s.expect(t, http.StatusBadRequest, "POST", path+"/review", s.admin, map[string]string{"decision": "maybe"}, nil)
+	s.expect(t, http.StatusBadRequest, "POST", path+"/review", s.admin, map[string]string{"decision": "reject"}, nil)
+
+	var payment models.Payment
+	s.expect(t, http.StatusOK, "POST", path+"/review", s.admin, map[string]string{"decision": "reject", "note": "Amount does not match"}, &payment)
+	if payment.Status != models.PaymentFailed || payment.ReviewedBy != adminEmail {
+		t.Errorf("rejected payment = %+v", payment)
+	}
+	s.expect(t, http.StatusConflict, "POST", path+"/review", s.admin, map[string]string{"decision": "approve"}, nil)
+	if !eventually(t, func() bool { return slices.Contains(s.mail.to(customerEmail), "Payment Proof Rejected") }) {
+		t.Errorf("emails = %v, want the rejection", s.mail.to(customerEmail))
+	}
+
+	// A rejected proof can be replaced and approved
+	if rec := s.uploadProof(t, orderID, pngProof); rec.Code != http.StatusOK {
+		t.Fatalf("second upload = %d %s", rec.Code, rec.Body.String())
+	}
+	s.expect(t, http.StatusOK, "POST", path+"/review", s.admin, map[string]string{"decision": "approve"}, &payment)
+	if payment.Status != models.PaymentCompleted {
+		t.Errorf("approved payment = %+v", payment)
+	}
+	var order models.Order
+	s.expect(t, http.StatusOK, "GET", "/admin/orders/"+orderID.Hex(), s.admin, nil, &order)
+	if order.Status != models.OrderPaid || order.PaymentStatus != models.PaymentCompleted {
+		t.Errorf("order = %+v, want it paid", order)
+	}
+	if !eventually(t, func() bool { return slices.Contains(s.mail.to(customerEmail), "Payment Verified") }) {
+		t.Errorf("emails = %v, want the approval", s.mail.to(customerEmail))
+	}
+	s.expect(t, http.StatusOK, "GET", "/admin/payments/proofs", s.admin, nil, &queue)
+	if len(queue) != 0 {
+		t.Errorf("review queue = %+v, want it empty", queue)
+	}
+
+	if rec := s.uploadProof(t, orderID, pngProof); rec.Code != http.StatusConflict {
+		t.Errorf("upload to a paid order = %d %s, want 409", rec.Code, rec.Body.String())
+	}
+}
+
+func TestApproveProofForCancelledOrder(t *testing.T) {
+	useProofDir(t)
+	s := newShop(t)
+	orderID := s.cryptoOrder(t)
+	if rec := s.uploadProof(t, orderID, pngProof); rec.Code != http.StatusOK {
+		t.Fatalf("upload = %d %s", rec.Code, rec.Body.String())
+	}
+	s.expect(t, http.StatusOK, "POST", "/orders/"+orderID.Hex()+"/cancel", s.customer, nil, nil)
+
+	payment, err := s.db.FindPaymentByOrderID(context.Background(), orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.expect(t, http.StatusConflict, "POST", "/admin/payments/"+payment.ID.Hex()+"/review", s.admin, map[string]string{"decision": "approve"}, nil)
+
+	order, err := s.db.FindOrderByID(context.Background(), orderID)
+	if err != nil || order.Status != models.OrderCancelled {
+		t.Errorf("order = %+v, %v; want it still cancelled", order, err)
+	}
+	if payment, err := s.db.FindPaymentByOrderID(context.Background(), orderID); err != nil || payment.Status != models.PaymentAwaitingReview {
+		t.Errorf("payment = %+v, %v; want it still awaiting review", payment, err)
+	}
+}
@@ -0,0 +1,126 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"go-ecommerce/store"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCardPaymentDeclinedThenRetried(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	var declined struct {
+		FailureReason string `json:"failure_reason"`
+	}
+	s.expect(t, http.StatusPaymentRequired, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenDecline}, &declined)
+	if declined.FailureReason != "card_declined" {
+		t.Errorf("failure reason = %q", declined.FailureReason)
+	}
+	orders, err := s.db.ListOrdersByUser(ctx, s.customerUser(t).ID)
+	if err != nil || len(orders) != 1 || orders[0].Status != models.OrderPending {
+		t.Fatalf("orders = %+v, %v; want one pending order", orders, err)
+	}
+	confirm := "/orders/" + orders[0].ID.Hex() + "/payment/confirm"
+
+	s.expect(t, http.StatusBadRequest, "POST", confirm, s.customer, nil, nil)
+	s.expect(t, http.StatusOK, "POST", confirm, s.customer, map[string]string{"payment_token": payments.TokenSuccess}, nil)
+	order, err := s.db.FindOrderByID(ctx, orders[0].ID)
+	if err != nil || order.Status != models.OrderPaid || order.PaymentStatus != models.PaymentCompleted {
+		t.Errorf("order = %+v, %v; want it paid", order, err)
+	}
+	s.expect(t, http.StatusConflict, "POST", confirm, s.customer, map[string]string{"payment_token": payments.TokenSuccess}, nil)
+}
+
+func TestCardPaymentThreeDS(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+
+	payment := s.placeOrder(t, mug, payments.TokenThreeDS)
+	if payment.Status != models.PaymentRequiresAction || payment.NextActionURL == "" {
+		t.Fatalf("payment = %+v, want it waiting on a challenge", payment)
+	}
+	challenge, err := url.Parse(payment.NextActionURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.expect(t, http.StatusOK, "POST", challenge.Path, "", nil, nil)
+	s.expect(t, http.StatusOK, "POST", "/orders/"+payment.OrderID.Hex()+"/payment/confirm", s.customer, nil, nil)
+
+	order, err := s.db.FindOrderByID(ctx, payment.OrderID)
+	if err != nil || order.Status != models.OrderPaid {
+		t.Errorf("order = %+v, %v; want it paid", order, err)
+	}
+	if !eventually(t, func() bool { return len(s.mail.to(customerEmail)) == 1 }) {
+		t.Errorf("emails to the customer = %v, want the confirmation", s.mail.to(customerEmail))
+	}
+}
+
+func TestRefunds(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+
+	// Cancelling a paid order refunds the card straight away
+	cancelled := s.placeOrder(t, mug, payments.TokenSuccess)
+	s.expect(t, http.StatusOK, "POST", "/orders/"+cancelled.OrderID.Hex()+"/cancel", s.customer, nil, nil)
+	if payment, err := s.db.FindPaymentByID(ctx, cancelled.ID); err != nil || payment.Status != models.PaymentRefunded {
+		t.Errorf("cancelled order's payment = %+v, %v; want it refunded", payment, err)
+	}
+
+	delivered := s.placeOrder(t, mug, payments.TokenSuccess)
+	path := "/admin/orders/" + delivered.OrderID.Hex() + "/status"
+	for _, status := range []string{"processing", "shipped", "delivered", "refunded"} {
+		s.expect(t, http.StatusOK, "POST", path, s.admin, map[string]string{"status": status}, nil)
+	}
+	if payment, err := s.db.FindPaymentByID(ctx, delivered.ID); err != nil || payment.Status != models.PaymentRefunded {
+		t.Errorf("returned order's payment = %+v, %v; want it refunded", payment, err)
+	}
+}
+
+// sweptOrders cancels an order just before it is marked paid, as the
+// reservation sweeper can while a card payment is being captured
+type sweptOrders struct {
+	store.OrderStore
+}
+
+func (o sweptOrders) PayOrder(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange) error {
+	swept := models.StatusChange{From: from, To: models.OrderCancelled, Actor: "system", At: time.Now()}
+	if err := o.CancelOrder(ctx, id, from, swept, "Reservation expired"); err != nil {
+		return err
+	}
+	return o.OrderStore.PayOrder(ctx, id, from, change)
+}
+
+func TestCardPaymentForCancelledOrder(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.orders.Orders = sweptOrders{s.db}
+
+	captured := s.placeOrder(t, mug, payments.TokenSuccess)
+	if payment, err := s.db.FindPaymentByID(ctx, captured.ID); err != nil || payment.Status != models.PaymentRefunded {
+		t.Errorf("payment = %+v, %v; want the captured money refunded", payment, err)
+	}
+	order, err := s.db.FindOrderByID(ctx, captured.OrderID)
+	if err != nil || order.Status != models.OrderCancelled || order.PaymentStatus != models.PaymentRefunded {
+		t.Errorf("order = %+v, %v; want it cancelled and refunded", order, err)
+	}
+	if product, err := s.db.FindProductByID(ctx, mug.ID); err != nil || product.Stock != 5 || product.AvailableFor("") != 5 {
+		t.Errorf("mug = %+v, %v; want nothing sold or held", product, err)
+	}
+	if eventually(t, func() bool { return slices.Contains(s.mail.to(customerEmail), "Order Confirmation") }) {
+		t.Errorf("emails to the customer = %v, want no confirmation", s.mail.to(customerEmail))
+	}
+}
@@ -3,31 +3,54 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ecommerce/middleware"
 	"go-ecommerce/models"
+	"go-ecommerce/search"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // ProductController handles product-related requests
 type ProductController struct {
-	Collection *mongo.Collection
+	Products     store.ProductStore
+	Inventory    store.InventoryStore
+	Alerts       store.StockAlertStore
+	Users        store.UserStore
+	Search       search.Index
+	EmailService *utils.EmailService
 }
 
 // NewProductController creates a new ProductController
-func NewProductController(client *mongo.Client) *ProductController {
-	collection := client.Database("ecommerce").Collection("products")
+func NewProductController(s store.Store, index search.Index, emailService *utils.EmailService) *ProductController {
 	return &ProductController{
-		Collection: collection,
+		Products:     s,
+		Inventory:    s,
+		Alerts:       s,
+		Users:        s,
+		Search:       index,
+		EmailService: emailService,
 	}
 }
 
 // CreateProduct handles adding a new product (Admin only)
 func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var product models.Product
 	// Decode the request body into product
 	err := json.NewDecoder(r.Body).Decode(&product)
@@ -35,47 +58,186 @@ func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	if err := product.ValidateVariants(); err != nil {
+		http.Error(w, "Invalid variants: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// An omitted price is free rather than currency-less
+	if product.Price.IsZero() && product.Price.Currency == "" {
+		product.Price.Currency = models.DefaultCurrency
+	}
+	if err := product.ValidatePrices(models.DefaultCurrency); err != nil {
+		http.Error(w, "Invalid price: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if product.Stock < 0 {
+		http.Error(w, "Stock must not be negative", http.StatusBadRequest)
+		return
+	}
+	if product.ReorderThreshold < 0 {
+		http.Error(w, "Reorder threshold must not be negative", http.StatusBadRequest)
+		return
+	}
+	// Nothing is reserved until checkout holds some of it
+	product.KeepReserved(models.Product{})
 
 	// Insert the product into the database
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	result, err := pc.Collection.InsertOne(ctx, product)
+	err = pc.Products.CreateProduct(ctx, &product, claims.Email)
 	if err != nil {
 		http.Error(w, "Error creating product", http.StatusInternalServerError)
 		return
 	}
+	if err := pc.Search.Upsert(ctx, product); err != nil {
+		log.Printf("Failed to index product %s: %v", product.ID.Hex(), err)
+	}
 
+	product.FillAvailable()
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(product)
 }
 
-// GetProducts retrieves all products
+const (
+	defaultProductPageSize = 20
+	maxProductPageSize     = 100
+)
+
+// productListResponse is the envelope returned by GetProducts
+type productListResponse struct {
+	Products   []models.Product `json:"products"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Total      int64            `json:"total"`
+}
+
+// GetProducts retrieves a page of products. Supported query parameters:
+// category, min_price, max_price, in_stock=true, sort (newest, price_asc,
+// price_desc, name_asc, name_desc), limit and cursor.
 func (pc *ProductController) GetProducts(w http.ResponseWriter, r *http.Request) {
-	var products []models.Product
+	query, err := parseProductQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Find all products
-	cursor, err := pc.Collection.Find(ctx, bson.M{})
+	page, err := pc.Products.ListProducts(ctx, query)
 	if err != nil {
 		http.Error(w, "Error fetching products", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var product models.Product
-		cursor.Decode(&product)
-		products = append(products, product)
+	for i := range page.Products {
+		page.Products[i].FillAvailable()
+	}
+	response := productListResponse{
+		Products: page.Products,
+		Total:    page.Total,
+	}
+	if page.Next != nil {
+		response.NextCursor = page.Next.Encode()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseProductQuery builds a product listing query from URL parameters
+func parseProductQuery(values url.Values) (store.ProductQuery, error) {
+	query := store.ProductQuery{
+		ProductFilter: store.ProductFilter{Category: values.Get("category")},
+		Sort:          store.SortNewest,
+		Limit:         defaultProductPageSize,
+	}
+
+	for name, bound := range map[string]**int64{
+		"min_price": &query.MinPrice,
+		"max_price": &query.MaxPrice,
+	} {
+		if raw := values.Get(name); raw != "" {
+			price, err := models.ParseMoney(raw, models.DefaultCurrency)
+			if err != nil || price.IsNegative() {
+				return query, fmt.Errorf("Invalid %s", name)
+			}
+			*bound = &price.Amount
+		}
+	}
+
+	if raw := values.Get("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, errors.New("Invalid in_stock")
+		}
+		query.InStockOnly = inStock
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		query.Sort = store.ProductSort(raw)
+		if !query.Sort.Valid() {
+			return query, errors.New("Invalid sort")
+		}
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return query, errors.New("Invalid limit")
+		}
+		query.Limit = min(limit, maxProductPageSize)
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := store.DecodeProductCursor(raw, query.Sort)
+		if err != nil {
+			return query, errors.New("Invalid cursor")
+		}
+		query.After = cursor
+	}
+
+	return query, nil
+}
+
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 50
+)
+
+// SearchProducts runs a full-text search over product names, categories and
+// descriptions. Query parameters: q (required) and limit.
+func (pc *ProductController) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Search query missing", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchResults
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxSearchResults)
 	}
 
-	if err := cursor.Err(); err != nil {
-		http.Error(w, "Error reading products", http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hits, err := pc.Search.Search(ctx, query, limit)
+	if err != nil {
+		http.Error(w, "Error searching products", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   query,
+		"results": hits,
+	})
 }
 
 // GetProductByID retrieves a single product by ID
@@ -87,22 +249,28 @@ func (pc *ProductController) GetProductByID(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var product models.Product
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err = pc.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+	product, err := pc.Products.FindProductByID(ctx, id)
 	if err != nil {
 		http.Error(w, "Product not found", http.StatusNotFound)
 		return
 	}
 
+	// Show what can still be bought: on-hand stock less active reservations
+	product.FillAvailable()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
 
 // UpdateProduct handles updating a product (Admin only)
 func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	params := mux.Vars(r)
 	id, err := primitive.ObjectIDFromHex(params["id"])
 	if err != nil {
@@ -116,21 +284,59 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
-
-	update := bson.M{
-		"$set": product,
+	if err := product.ValidateVariants(); err != nil {
+		http.Error(w, "Invalid variants: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// An omitted price is free rather than currency-less
+	if product.Price.IsZero() && product.Price.Currency == "" {
+		product.Price.Currency = models.DefaultCurrency
+	}
+	if err := product.ValidatePrices(models.DefaultCurrency); err != nil {
+		http.Error(w, "Invalid price: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if product.Stock < 0 {
+		http.Error(w, "Stock must not be negative", http.StatusBadRequest)
+		return
+	}
+	if product.ReorderThreshold < 0 {
+		http.Error(w, "Reorder threshold must not be negative", http.StatusBadRequest)
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := pc.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	// Stock set here is recorded in the ledger as an adjustment
+	err = pc.Products.UpdateProduct(ctx, id, product, claims.Email)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrInsufficientStock) {
+		http.Error(w, "Stock cannot be set below what unpaid orders hold", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error updating product", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(result)
+	// Reread it for the reserved counts the store kept, and tell anyone
+	// waiting for it if it is back in stock
+	if updated, err := pc.Products.FindProductByID(ctx, id); err == nil {
+		product = *updated
+		notifyBackInStock(pc.Alerts, pc.EmailService, product)
+	}
+	product.ID = id
+	if err := pc.Search.Upsert(ctx, product); err != nil {
+		log.Printf("Failed to index product %s: %v", id.Hex(), err)
+	}
+	product.FillAvailable()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
 }
 
 // DeleteProduct handles deleting a product (Admin only)
@@ -145,11 +351,18 @@ func (pc *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Reques
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := pc.Collection.DeleteOne(ctx, bson.M{"_id": id})
+	err = pc.Products.DeleteProduct(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error deleting product", http.StatusInternalServerError)
 		return
 	}
+	if err := pc.Search.Remove(ctx, id); err != nil {
+		log.Printf("Failed to remove product %s from search index: %v", id.Hex(), err)
+	}
 
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode("Product deleted")
 }
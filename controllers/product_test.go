@@ -0,0 +1,118 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"go-ecommerce/models"
+	"net/http"
+	"testing"
+)
+
+func TestProductCRUD(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 8.5, "stock": 3})
+
+	update := map[string]interface{}{"name": "Big mug", "price": 9.5, "stock": 3}
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, update, nil)
+	s.expect(t, http.StatusBadRequest, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Big mug", "price": 9.5, "stock": -1}, nil)
+	s.expect(t, http.StatusBadRequest, "POST", "/products", s.admin, map[string]interface{}{"name": "Lamp", "price": 20, "stock": -1}, nil)
+	var got models.Product
+	s.expect(t, http.StatusOK, "GET", "/products/"+mug.ID.Hex(), s.customer, nil, &got)
+	if got.Name != "Big mug" || got.Price != models.NewMoney(950, models.DefaultCurrency) {
+		t.Errorf("product = %+v, want the update", got)
+	}
+
+	// Only admins manage the catalog
+	s.expect(t, http.StatusForbidden, "POST", "/products", s.customer, map[string]interface{}{"name": "Lamp"}, nil)
+	s.expect(t, http.StatusForbidden, "DELETE", "/products/"+mug.ID.Hex(), s.customer, nil, nil)
+
+	s.expect(t, http.StatusOK, "DELETE", "/products/"+mug.ID.Hex(), s.admin, nil, nil)
+	s.expect(t, http.StatusNotFound, "GET", "/products/"+mug.ID.Hex(), s.customer, nil, nil)
+}
+
+func TestProductPrices(t *testing.T) {
+	s := newShop(t)
+	tests := []struct {
+		name  string
+		price interface{}
+		want  int
+	}{
+		{"number", 19.99, http.StatusCreated},
+		{"decimal string", "19.99", http.StatusCreated},
+		{"amount and currency", map[string]string{"amount": "19.99", "currency": "USD"}, http.StatusCreated},
+		{"negative", -1, http.StatusBadRequest},
+		{"another currency", map[string]string{"amount": "19.99", "currency": "EUR"}, http.StatusBadRequest},
+		{"unknown currency", map[string]string{"amount": "19.99", "currency": "XYZ"}, http.StatusBadRequest},
+		{"not a number", "cheap", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(t, "POST", "/products", s.admin, map[string]interface{}{"name": "Mug", "price": tt.price, "stock": 1})
+			if rec.Code != tt.want {
+				t.Fatalf("POST /products = %d %s, want %d", rec.Code, rec.Body.String(), tt.want)
+			}
+			if rec.Code == http.StatusCreated {
+				var created models.Product
+				json.Unmarshal(rec.Body.Bytes(), &created)
+				if created.Price != models.NewMoney(1999, models.DefaultCurrency) {
+					t.Errorf("price = %v, want 19.99 USD", created.Price)
+				}
+			}
+		})
+	}
+}
+
+func TestGetProductsPages(t *testing.T) {
+	s := newShop(t)
+	for _, name := range []string{"Mug", "Lamp", "Rug"} {
+		s.createProduct(t, map[string]interface{}{"name": name, "price": 10, "stock": 1})
+	}
+
+	type listing struct {
+		Products   []models.Product `json:"products"`
+		NextCursor string           `json:"next_cursor"`
+		Total      int64            `json:"total"`
+	}
+	var page listing
+	s.expect(t, http.StatusOK, "GET", "/products?sort=name_asc&limit=2", "", nil, &page)
+	if len(page.Products) != 2 || page.Products[0].Name != "Lamp" || page.Total != 3 || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	cursor := page.NextCursor
+	page = listing{}
+	s.expect(t, http.StatusOK, "GET", "/products?sort=name_asc&limit=2&cursor="+cursor, "", nil, &page)
+	if len(page.Products) != 1 || page.Products[0].Name != "Rug" || page.NextCursor != "" {
+		t.Errorf("last page = %+v", page)
+	}
+
+	for _, query := range []string{"min_price=-1", "max_price=abc", "in_stock=maybe", "sort=random", "limit=0", "cursor=abc"} {
+		s.expect(t, http.StatusBadRequest, "GET", "/products?"+query, "", nil, nil)
+	}
+}
+
+func TestSearchProducts(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Ceramic mug", "price": 8.5, "stock": 3})
+	lamp := s.createProduct(t, map[string]interface{}{"name": "Desk lamp", "price": 30, "stock": 1})
+
+	var result struct {
+		Results []struct {
+			Product models.Product `json:"product"`
+		} `json:"results"`
+	}
+	s.expect(t, http.StatusOK, "GET", "/products/search?q=lmap", "", nil, &result)
+	if len(result.Results) != 1 || result.Results[0].Product.ID != lamp.ID {
+		t.Errorf("results = %+v, want the lamp", result.Results)
+	}
+
+	// The index follows catalog changes
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Ceramic cup", "price": 8.5, "stock": 3}, nil)
+	s.expect(t, http.StatusOK, "DELETE", "/products/"+lamp.ID.Hex(), s.admin, nil, nil)
+	result.Results = nil
+	s.expect(t, http.StatusOK, "GET", "/products/search?q=cup+lamp", "", nil, &result)
+	if len(result.Results) != 1 || result.Results[0].Product.ID != mug.ID {
+		t.Errorf("results = %+v, want the renamed mug", result.Results)
+	}
+
+	s.expect(t, http.StatusBadRequest, "GET", "/products/search?q=+", "", nil, nil)
+	s.expect(t, http.StatusBadRequest, "GET", "/products/search?q=mug&limit=0", "", nil, nil)
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errCouponNotFound is returned for a coupon code that no promotion uses
+var errCouponNotFound = errors.New("coupon not found")
+
+// couponErrors are the reasons a coupon is turned down that are shown to the customer
+var couponErrors = []error{
+	errCouponNotFound,
+	models.ErrPromotionDisabled,
+	models.ErrPromotionNotStarted,
+	models.ErrPromotionExpired,
+	models.ErrPromotionUsedUp,
+	models.ErrPromotionUserLimit,
+	models.ErrPromotionMinSpend,
+	models.ErrPromotionNotApplicable,
+}
+
+// couponRejected reports whether err says why a coupon does not apply, as
+// opposed to a failure to look it up
+func couponRejected(err error) bool {
+	for _, reason := range couponErrors {
+		if errors.Is(err, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCoupon discounts lines with the promotion behind a coupon code once
+// it has checked that the user may still redeem it
+func applyCoupon(ctx context.Context, promotions store.PromotionStore, code string, userID primitive.ObjectID, lines []models.OrderItem) (*models.AppliedPromotion, error) {
+	promotion, err := promotions.FindPromotionByCode(ctx, models.NormalizeCouponCode(code))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, errCouponNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	used := 0
+	if promotion.PerUserLimit > 0 {
+		used, err = promotions.CountPromotionRedemptions(ctx, promotion.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := promotion.Available(time.Now(), used); err != nil {
+		return nil, err
+	}
+	return promotion.Apply(lines)
+}
+
+// PromotionController handles promotion management requests
+type PromotionController struct {
+	Promotions store.PromotionStore
+}
+
+// NewPromotionController creates a new PromotionController
+func NewPromotionController(s store.Store) *PromotionController {
+	return &PromotionController{
+		Promotions: s,
+	}
+}
+
+// promotionFromRequest loads the promotion named by the {id} route variable,
+// writing an error response and returning nil if it cannot
+func (pc *PromotionController) promotionFromRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.Promotion {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return nil
+	}
+	promotion, err := pc.Promotions.FindPromotionByID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve promotion", http.StatusInternalServerError)
+		return nil
+	}
+	return promotion
+}
+
+// ListPromotions lists every promotion, newest first (Admin only)
+func (pc *PromotionController) ListPromotions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	promotions, err := pc.Promotions.ListPromotions(ctx)
+	if err != nil {
+		http.Error(w, "Failed to retrieve promotions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotions)
+}
+
+// GetPromotion returns a single promotion with its usage count (Admin only)
+func (pc *PromotionController) GetPromotion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	promotion := pc.promotionFromRequest(ctx, w, r)
+	if promotion == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// CreatePromotion adds a promotion (Admin only)
+func (pc *PromotionController) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var promotion models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	promotion.ID = primitive.NilObjectID
+	promotion.Code = models.NormalizeCouponCode(promotion.Code)
+	promotion.UsedCount = 0
+	if err := promotion.Validate(models.DefaultCurrency); err != nil {
+		http.Error(w, "Invalid promotion: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	promotion.CreatedAt = time.Now()
+	promotion.UpdatedAt = promotion.CreatedAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := pc.Promotions.CreatePromotion(ctx, &promotion)
+	if errors.Is(err, store.ErrDuplicate) {
+		http.Error(w, "A promotion with this code already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error creating promotion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// UpdatePromotion replaces a promotion's settings. Its usage count is kept,
+// and orders already placed keep the discount they were given. (Admin only)
+func (pc *PromotionController) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	var update models.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	update.Code = models.NormalizeCouponCode(update.Code)
+	if err := update.Validate(models.DefaultCurrency); err != nil {
+		http.Error(w, "Invalid promotion: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	promotion := pc.promotionFromRequest(ctx, w, r)
+	if promotion == nil {
+		return
+	}
+	update.ID = promotion.ID
+	update.UsedCount = promotion.UsedCount
+	update.CreatedAt = promotion.CreatedAt
+	update.UpdatedAt = time.Now()
+
+	err := pc.Promotions.UpdatePromotion(ctx, &update)
+	if errors.Is(err, store.ErrDuplicate) {
+		http.Error(w, "A promotion with this code already exists", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error updating promotion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(update)
+}
+
+// DeletePromotion removes a promotion. Carts holding its code lose the
+// discount; placed orders keep it. (Admin only)
+func (pc *PromotionController) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = pc.Promotions.DeletePromotion(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting promotion", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Promotion deleted")
+}
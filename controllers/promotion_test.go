@@ -0,0 +1,127 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createPromotion adds a promotion through the admin API
+func (s *shop) createPromotion(t *testing.T, promotion map[string]interface{}) models.Promotion {
+	t.Helper()
+	var created models.Promotion
+	s.expect(t, http.StatusCreated, "POST", "/admin/promotions", s.admin, promotion, &created)
+	return created
+}
+
+func TestCouponCheckout(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	promotion := s.createPromotion(t, map[string]interface{}{"code": " save20 ", "type": "percentage", "percent_off": 20, "per_user_limit": 1})
+	if promotion.Code != "SAVE20" {
+		t.Errorf("code = %q, want it upper case and trimmed", promotion.Code)
+	}
+
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 2}, nil)
+	var cart struct {
+		Subtotal  models.Money             `json:"subtotal"`
+		Discount  models.Money             `json:"discount"`
+		Total     models.Money             `json:"total"`
+		Promotion *models.AppliedPromotion `json:"promotion"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/cart/coupon", s.customer, map[string]string{"code": "save20"}, &cart)
+	usd := func(cents int64) models.Money { return models.NewMoney(cents, models.DefaultCurrency) }
+	if cart.Subtotal != usd(2000) || cart.Discount != usd(400) || cart.Total != usd(1600) || cart.Promotion == nil {
+		t.Errorf("cart = %+v, want 20%% off 20.00", cart)
+	}
+
+	var result struct {
+		OrderID     primitive.ObjectID `json:"order_id"`
+		TotalAmount models.Money       `json:"total_amount"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, &result)
+	if result.TotalAmount != usd(1600) {
+		t.Errorf("total = %v, want 16.00", result.TotalAmount)
+	}
+	var invoice models.Invoice
+	s.expect(t, http.StatusOK, "GET", "/orders/"+result.OrderID.Hex()+"/invoice", s.customer, nil, &invoice)
+	if invoice.Discount != usd(400) || invoice.Total != usd(1600) {
+		t.Errorf("invoice = %+v, want the discount frozen on it", invoice)
+	}
+
+	// Editing the promotion keeps its usage and leaves the order alone
+	update := map[string]interface{}{"code": "SAVE20", "type": "percentage", "percent_off": 50, "per_user_limit": 1}
+	s.expect(t, http.StatusOK, "PUT", "/admin/promotions/"+promotion.ID.Hex(), s.admin, update, nil)
+	s.expect(t, http.StatusOK, "GET", "/admin/promotions/"+promotion.ID.Hex(), s.admin, nil, &promotion)
+	if promotion.UsedCount != 1 || promotion.PercentOff != 50 {
+		t.Errorf("promotion = %+v, want 50%% off with one use", promotion)
+	}
+	s.expect(t, http.StatusOK, "GET", "/orders/"+result.OrderID.Hex()+"/invoice", s.customer, nil, &invoice)
+	if invoice.Total != usd(1600) {
+		t.Errorf("invoice total = %v after the promotion changed, want 16.00", invoice.Total)
+	}
+
+	// The customer has used their one redemption
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	s.expect(t, http.StatusUnprocessableEntity, "POST", "/cart/coupon", s.customer, map[string]string{"code": "SAVE20"}, nil)
+}
+
+func TestApplyCoupon(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	s.createPromotion(t, map[string]interface{}{"code": "EXPIRED", "type": "percentage", "percent_off": 10, "ends_at": past})
+	s.createPromotion(t, map[string]interface{}{"code": "SOON", "type": "percentage", "percent_off": 10, "starts_at": future})
+	s.createPromotion(t, map[string]interface{}{"code": "BIGSPEND", "type": "percentage", "percent_off": 10, "min_spend": 100})
+	s.createPromotion(t, map[string]interface{}{"code": "OFF", "type": "percentage", "percent_off": 10, "disabled": true})
+	s.createPromotion(t, map[string]interface{}{"code": "GONE", "type": "percentage", "percent_off": 10, "usage_limit": 1})
+	s.expect(t, http.StatusConflict, "POST", "/admin/promotions", s.admin, map[string]interface{}{"code": "gone", "type": "free_shipping"}, nil)
+	s.expect(t, http.StatusBadRequest, "POST", "/admin/promotions", s.admin, map[string]interface{}{"code": "BAD", "type": "percentage"}, nil)
+	s.expect(t, http.StatusForbidden, "POST", "/admin/promotions", s.customer, map[string]interface{}{"code": "MINE", "type": "free_shipping"}, nil)
+
+	// There is no cart to apply a coupon to yet
+	s.expect(t, http.StatusNotFound, "POST", "/cart/coupon", s.customer, map[string]string{"code": "GONE"}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	tests := []struct {
+		code string
+		want int
+	}{
+		{"", http.StatusBadRequest},
+		{"NOPE", http.StatusNotFound},
+		{"EXPIRED", http.StatusUnprocessableEntity},
+		{"SOON", http.StatusUnprocessableEntity},
+		{"BIGSPEND", http.StatusUnprocessableEntity},
+		{"OFF", http.StatusUnprocessableEntity},
+		{"GONE", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			s.expect(t, tt.want, "POST", "/cart/coupon", s.customer, map[string]string{"code": tt.code}, nil)
+		})
+	}
+
+	// Someone else uses up the last redemption before the customer checks out
+	ctx := context.Background()
+	promotion, err := s.db.FindPromotionByCode(ctx, "GONE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := models.Order{
+		UserID:    primitive.NewObjectID(),
+		Items:     []models.OrderItem{{ProductID: mug.ID, Quantity: 1}},
+		Promotion: &models.AppliedPromotion{PromotionID: promotion.ID, Code: promotion.Code},
+	}
+	if err := s.db.PlaceOrder(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+	s.expect(t, http.StatusConflict, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, nil)
+
+	s.expect(t, http.StatusOK, "DELETE", "/cart/coupon", s.customer, nil, nil)
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess}, nil)
+}
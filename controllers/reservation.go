@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"log"
+	"time"
+)
+
+// reservedUntil returns when an unpaid order's stock is released, or nil
+// when it holds none
+func reservedUntil(order *models.Order) *time.Time {
+	if order.Reservation == nil || order.Reservation.Status != models.ReservationActive {
+		return nil
+	}
+	return &order.Reservation.ExpiresAt
+}
+
+// ReleaseExpiredReservations cancels the pending orders whose stock
+// reservation expired before now, returning their stock to sale. Orders whose
+// payment has been captured or is awaiting review are left for the payment
+// to settle. It returns how many orders were cancelled.
+func (oc *OrderController) ReleaseExpiredReservations(ctx context.Context, now time.Time) (int, error) {
+	orders, err := oc.Orders.ListExpiredReservations(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	released := 0
+	for i := range orders {
+		order := &orders[i]
+		switch order.PaymentStatus {
+		case models.PaymentAwaitingReview, models.PaymentAuthorized, models.PaymentCompleted:
+			continue
+		}
+		err := oc.changeOrderStatus(ctx, order, models.OrderCancelled, "system", "Payment not received before the stock reservation expired")
+		// Paid or cancelled since it was listed
+		if errors.Is(err, store.ErrConflict) || errors.Is(err, errIllegalTransition) {
+			continue
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
+
+// SweepReservations releases expired reservations every interval until ctx
+// is done. It is meant to run in its own goroutine.
+func (oc *OrderController) SweepReservations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sweepCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			n, err := oc.ReleaseExpiredReservations(sweepCtx, now)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to release expired reservations: %v", err)
+			}
+			if n > 0 {
+				log.Printf("Released the stock of %d unpaid orders", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStockReservations(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	available := func(want int) {
+		t.Helper()
+		var product models.Product
+		s.expect(t, http.StatusOK, "GET", "/products/"+mug.ID.Hex(), "", nil, &product)
+		if product.Available != want || product.Stock != 5 {
+			t.Errorf("product = %+v, want 5 on hand with %d available", product, want)
+		}
+	}
+
+	// A declined card keeps the stock held so the customer can retry
+	declined := s.placeOrder(t, mug, payments.TokenDecline)
+	available(4)
+
+	// Crypto orders are held while the transfer is proven
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 3}, nil)
+	var result struct {
+		ReservedUntil time.Time `json:"reserved_until"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "crypto"}, &result)
+	if until := time.Until(result.ReservedUntil); until < 23*time.Hour || until > 24*time.Hour {
+		t.Errorf("reserved until %v, want a day from now", result.ReservedUntil)
+	}
+	available(1)
+	// Stock cannot be edited below what the orders hold
+	s.expect(t, http.StatusConflict, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Mug", "price": 10, "stock": 3}, nil)
+	available(1)
+
+	// The card reservation lapses first
+	n, err := s.orders.ReleaseExpiredReservations(ctx, time.Now().Add(time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("ReleaseExpiredReservations = %d, %v; want the card order", n, err)
+	}
+	available(2)
+	order, err := s.db.FindOrderByID(ctx, declined.OrderID)
+	if err != nil || order.Status != models.OrderCancelled || order.Reservation.Status != models.ReservationReleased {
+		t.Errorf("order = %+v, %v; want it cancelled with its stock released", order, err)
+	}
+
+	n, err = s.orders.ReleaseExpiredReservations(ctx, time.Now().Add(25*time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("ReleaseExpiredReservations = %d, %v; want the crypto order", n, err)
+	}
+	available(5)
+}
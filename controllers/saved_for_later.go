@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"go-ecommerce/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// cartItemFromRequest reads the {product_id} in the path and the optional
+// {"sku"} in the body naming a cart line, writing the error and returning
+// false if they are malformed
+func cartItemFromRequest(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, string, bool) {
+	productID, err := primitive.ObjectIDFromHex(mux.Vars(r)["product_id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return primitive.NilObjectID, "", false
+	}
+	var body struct {
+		SKU string `json:"sku"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return primitive.NilObjectID, "", false
+		}
+	}
+	return productID, strings.TrimSpace(body.SKU), true
+}
+
+// SaveForLater moves a product, or the variant in {"sku"}, out of the cart
+// into its saved-for-later section and returns the repriced cart
+func (cc *CartController) SaveForLater(w http.ResponseWriter, r *http.Request) {
+	productID, sku, ok := cartItemFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.shopper(ctx, r)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	cart, err := cc.findCart(ctx, r, user)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
+	if !cart.SaveForLater(productID, sku) {
+		http.Error(w, "Item not in cart", http.StatusNotFound)
+		return
+	}
+
+	if err := cc.saveCart(ctx, w, cart); err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
+	cc.writeCart(ctx, w, cart, user)
+}
+
+// MoveToCart moves a product, or the variant in {"sku"}, saved for later
+// back into the cart, as long as enough of it is in stock, and returns the
+// repriced cart
+func (cc *CartController) MoveToCart(w http.ResponseWriter, r *http.Request) {
+	productID, sku, ok := cartItemFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.shopper(ctx, r)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	cart, err := cc.findCart(ctx, r, user)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
+	saved, ok := cart.SavedItem(productID, sku)
+	if !ok {
+		http.Error(w, "Item not saved for later", http.StatusNotFound)
+		return
+	}
+	product, err := cc.Products.FindProductByID(ctx, productID)
+	if err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+
+	quantity := saved.Quantity
+	if existing, ok := cart.Item(productID, sku); ok {
+		quantity += existing.Quantity
+	}
+	if !checkCartQuantity(w, *product, sku, quantity) {
+		return
+	}
+	cart.RemoveSavedItem(productID, sku)
+	price := product.PriceFor(sku)
+	if existing, ok := cart.Item(productID, sku); ok {
+		existing.Quantity = quantity
+		existing.UnitPrice = &price
+	} else {
+		cart.Items = append(cart.Items, models.CartItem{ProductID: productID, SKU: sku, Quantity: quantity, UnitPrice: &price})
+	}
+
+	if err := cc.saveCart(ctx, w, cart); err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
+	cc.writeCart(ctx, w, cart, user)
+}
+
+// RemoveSavedItem deletes a product, or the variant in ?sku=, from the
+// cart's saved-for-later section
+func (cc *CartController) RemoveSavedItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := primitive.ObjectIDFromHex(mux.Vars(r)["product_id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	sku := r.URL.Query().Get("sku")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := cc.shopper(ctx, r)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	cart, err := cc.findCart(ctx, r, user)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := cart.RemoveSavedItem(productID, sku); !ok {
+		http.Error(w, "Item not saved for later", http.StatusNotFound)
+		return
+	}
+
+	if err := cc.saveCart(ctx, w, cart); err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Item removed from saved for later")
+}
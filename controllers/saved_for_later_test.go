@@ -0,0 +1,77 @@
+package controllers_test
+
+import (
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"testing"
+)
+
+// savedCart is the part of the GET /cart response the saved-for-later tests look at
+type savedCart struct {
+	Lines []models.OrderItem `json:"lines"`
+	Saved []models.SavedLine `json:"saved"`
+}
+
+func TestSaveForLater(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 3})
+	plate := s.createProduct(t, map[string]interface{}{"name": "Plate", "price": 4, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 2}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": plate.ID, "quantity": 1}, nil)
+
+	var cart savedCart
+	s.expect(t, http.StatusNotFound, "POST", "/cart/saved/"+mug.ID.Hex()+"/move-to-cart", s.customer, nil, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart/items/"+mug.ID.Hex()+"/save-for-later", s.customer, nil, &cart)
+	if len(cart.Lines) != 1 || cart.Lines[0].ProductID != plate.ID {
+		t.Errorf("lines = %+v, want only the plate left to buy", cart.Lines)
+	}
+	if len(cart.Saved) != 1 || cart.Saved[0].Quantity != 2 || !cart.Saved[0].InStock || cart.Saved[0].Available != 3 {
+		t.Fatalf("saved = %+v, want two mugs, three in stock", cart.Saved)
+	}
+	s.expect(t, http.StatusNotFound, "POST", "/cart/items/"+mug.ID.Hex()+"/save-for-later", s.customer, nil, nil)
+
+	// Saved items are shown at their current price
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Mug", "price": 8, "stock": 1}, nil)
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if saved := cart.Saved[0]; *saved.Price != models.NewMoney(800, models.DefaultCurrency) || !saved.PriceDropped {
+		t.Errorf("saved = %+v, want the mug's lower price flagged", saved)
+	}
+
+	// Moving back checks stock; checking out leaves saved items alone
+	s.expect(t, http.StatusConflict, "POST", "/cart/saved/"+mug.ID.Hex()+"/move-to-cart", s.customer, nil, nil)
+	s.placeOrder(t, plate, payments.TokenSuccess)
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if len(cart.Lines) != 0 || len(cart.Saved) != 1 {
+		t.Errorf("cart after checkout = %+v, want only the saved mugs", cart)
+	}
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Mug", "price": 8, "stock": 5}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart/saved/"+mug.ID.Hex()+"/move-to-cart", s.customer, nil, &cart)
+	if len(cart.Lines) != 1 || cart.Lines[0].Quantity != 2 || len(cart.Saved) != 0 {
+		t.Errorf("cart = %+v, want the two mugs back in it", cart)
+	}
+
+	s.expect(t, http.StatusOK, "POST", "/cart/items/"+mug.ID.Hex()+"/save-for-later", s.customer, nil, nil)
+	s.expect(t, http.StatusOK, "DELETE", "/cart/saved/"+mug.ID.Hex(), s.customer, nil, nil)
+	s.expect(t, http.StatusNotFound, "DELETE", "/cart/saved/"+mug.ID.Hex(), s.customer, nil, nil)
+}
+
+func TestGuestSaveForLater(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 3})
+	rec := s.asGuest(t, "POST", "/cart", "", map[string]interface{}{"product_id": mug.ID, "quantity": 1})
+	token := rec.Header().Get("X-Cart-Token")
+	if rec := s.asGuest(t, "POST", "/cart/items/"+mug.ID.Hex()+"/save-for-later", token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("save for later = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Signing in brings the saved items along
+	if rec := s.asGuest(t, "POST", "/login", token, map[string]string{"email": customerEmail, "password": testPassword}); rec.Code != http.StatusOK {
+		t.Fatalf("login = %d %s", rec.Code, rec.Body.String())
+	}
+	var cart savedCart
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if len(cart.Lines) != 0 || len(cart.Saved) != 1 || cart.Saved[0].ProductID != mug.ID {
+		t.Errorf("cart = %+v, want the guest's mug saved for later", cart)
+	}
+}
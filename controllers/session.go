@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// tokenResponse is returned by Login and RefreshToken
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// issueTokens creates an access token and a fresh refresh token within a session
+func (uc *UserController) issueTokens(ctx context.Context, user *models.User, sessionID primitive.ObjectID) (*tokenResponse, error) {
+	accessToken, err := utils.GenerateAccessToken(user.Email, user.Role, sessionID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = uc.Sessions.CreateRefreshToken(ctx, &models.RefreshToken{
+		SessionID: sessionID,
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		CreatedAt: now,
+		ExpiresAt: now.Add(utils.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// startSession opens a new session for the user and issues its first tokens
+func (uc *UserController) startSession(ctx context.Context, user *models.User) (*tokenResponse, error) {
+	session := models.Session{
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+	}
+	if err := uc.Sessions.CreateSession(ctx, &session); err != nil {
+		return nil, err
+	}
+	return uc.issueTokens(ctx, user, session.ID)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a rotated
+// refresh token. Presenting a token that was already used revokes its session.
+func (uc *UserController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil || body.RefreshToken == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	token, err := uc.Sessions.FindRefreshTokenByHash(ctx, utils.HashToken(body.RefreshToken))
+	if err != nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := uc.Sessions.FindSessionByID(ctx, token.SessionID)
+	if err != nil || session.RevokedAt != nil {
+		http.Error(w, "Session has been revoked", http.StatusUnauthorized)
+		return
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		http.Error(w, "Refresh token expired", http.StatusUnauthorized)
+		return
+	}
+
+	// A second use means the token was copied; kill the whole family
+	err = uc.Sessions.ConsumeRefreshToken(ctx, token.ID)
+	if errors.Is(err, store.ErrConflict) {
+		log.Printf("Refresh token reuse detected for session %s; revoking", session.ID.Hex())
+		if err := uc.Sessions.RevokeSession(ctx, session.ID); err != nil {
+			log.Printf("Failed to revoke session %s: %v", session.ID.Hex(), err)
+		}
+		http.Error(w, "Refresh token already used", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error refreshing token", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := uc.Users.FindUserByID(ctx, token.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := uc.issueTokens(ctx, user, session.ID)
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout revokes the session behind the current access token
+func (uc *UserController) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		http.Error(w, "Invalid session", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = uc.Sessions.RevokeSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Error logging out", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Logged out successfully")
+}
+
+// LogoutAll revokes every session of the current user, logging out all devices
+func (uc *UserController) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := uc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	err = uc.Sessions.RevokeUserSessions(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Error logging out", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Logged out of all devices")
+}
@@ -0,0 +1,52 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+type tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *shop) loginTokens(t *testing.T, email string) tokens {
+	t.Helper()
+	var issued tokens
+	s.expect(t, http.StatusOK, "POST", "/login", "", map[string]string{"email": email, "password": testPassword}, &issued)
+	return issued
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	s := newShop(t)
+	first := s.loginTokens(t, customerEmail)
+
+	var second tokens
+	s.expect(t, http.StatusOK, "POST", "/token/refresh", "", map[string]string{"refresh_token": first.RefreshToken}, &second)
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	s.expect(t, http.StatusOK, "GET", "/profile", second.Token, nil, nil)
+
+	// Replaying the used token revokes the whole session, rotated tokens included
+	s.expect(t, http.StatusUnauthorized, "POST", "/token/refresh", "", map[string]string{"refresh_token": first.RefreshToken}, nil)
+	s.expect(t, http.StatusUnauthorized, "GET", "/profile", second.Token, nil, nil)
+	s.expect(t, http.StatusUnauthorized, "POST", "/token/refresh", "", map[string]string{"refresh_token": second.RefreshToken}, nil)
+
+	// Other sessions are untouched
+	s.expect(t, http.StatusOK, "GET", "/profile", s.customer, nil, nil)
+}
+
+func TestLogout(t *testing.T) {
+	s := newShop(t)
+	other := s.loginTokens(t, customerEmail)
+
+	s.expect(t, http.StatusOK, "POST", "/logout", other.Token, nil, nil)
+	s.expect(t, http.StatusUnauthorized, "GET", "/profile", other.Token, nil, nil)
+	s.expect(t, http.StatusUnauthorized, "POST", "/token/refresh", "", map[string]string{"refresh_token": other.RefreshToken}, nil)
+	s.expect(t, http.StatusOK, "GET", "/profile", s.customer, nil, nil)
+
+	s.expect(t, http.StatusOK, "POST", "/logout/all", s.customer, nil, nil)
+	s.expect(t, http.StatusUnauthorized, "GET", "/profile", s.customer, nil, nil)
+	s.expect(t, http.StatusOK, "GET", "/profile", s.admin, nil, nil)
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// quoteShipping prices every method of the zone that serves address for the
+// given lines. Methods whose tiers do not cover the order are left out, and
+// free waives each fee.
+func quoteShipping(ctx context.Context, zones store.ShippingStore, address models.Address, lines []models.OrderItem, free bool) ([]models.ShippingQuote, error) {
+	all, err := zones.ListShippingZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var zone *models.ShippingZone
+	best := 0
+	for i := range all {
+		if n, ok := all[i].Covers(address); ok && n > best {
+			zone, best = &all[i], n
+		}
+	}
+
+	quotes := []models.ShippingQuote{}
+	if zone == nil {
+		return quotes, nil
+	}
+	for _, method := range zone.Methods {
+		if quote, ok := method.Quote(lines, free); ok {
+			quotes = append(quotes, quote)
+		}
+	}
+	return quotes, nil
+}
+
+// ShippingController handles shipping zone requests
+type ShippingController struct {
+	Zones store.ShippingStore
+}
+
+// NewShippingController creates a new ShippingController
+func NewShippingController(s store.Store) *ShippingController {
+	return &ShippingController{
+		Zones: s,
+	}
+}
+
+// ListShippingZones lists every shipping zone with its methods (Admin only)
+func (sc *ShippingController) ListShippingZones(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	zones, err := sc.Zones.ListShippingZones(ctx)
+	if err != nil {
+		http.Error(w, "Failed to retrieve shipping zones", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
+
+// CreateShippingZone adds a shipping zone (Admin only)
+func (sc *ShippingController) CreateShippingZone(w http.ResponseWriter, r *http.Request) {
+	var zone models.ShippingZone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	zone.ID = primitive.NilObjectID
+	zone.Normalize()
+	if err := zone.Validate(models.DefaultCurrency); err != nil {
+		http.Error(w, "Invalid shipping zone: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	zone.CreatedAt = time.Now()
+	zone.UpdatedAt = zone.CreatedAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sc.Zones.CreateShippingZone(ctx, &zone); err != nil {
+		http.Error(w, "Error creating shipping zone", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(zone)
+}
+
+// UpdateShippingZone replaces a shipping zone. Orders already placed keep
+// the method and cost they were quoted. (Admin only)
+func (sc *ShippingController) UpdateShippingZone(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+	var zone models.ShippingZone
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	zone.Normalize()
+	if err := zone.Validate(models.DefaultCurrency); err != nil {
+		http.Error(w, "Invalid shipping zone: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	existing, err := sc.Zones.FindShippingZoneByID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Shipping zone not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve shipping zone", http.StatusInternalServerError)
+		return
+	}
+	zone.ID = existing.ID
+	zone.CreatedAt = existing.CreatedAt
+	zone.UpdatedAt = time.Now()
+
+	if err := sc.Zones.UpdateShippingZone(ctx, &zone); err != nil {
+		http.Error(w, "Error updating shipping zone", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+// DeleteShippingZone removes a shipping zone (Admin only)
+func (sc *ShippingController) DeleteShippingZone(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = sc.Zones.DeleteShippingZone(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Shipping zone not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting shipping zone", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Shipping zone deleted")
+}
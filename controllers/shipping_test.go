@@ -0,0 +1,128 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type shippingOptions struct {
+	Address models.Address         `json:"address"`
+	Options []models.ShippingQuote `json:"options"`
+}
+
+func methodCodes(quotes []models.ShippingQuote) []string {
+	codes := []string{}
+	for _, quote := range quotes {
+		codes = append(codes, quote.Method)
+	}
+	return codes
+}
+
+func TestShippingOptions(t *testing.T) {
+	s := newShop(t)
+	// Los Angeles has its own zone on top of the shop's California one
+	losAngeles := map[string]interface{}{
+		"name":    "Los Angeles",
+		"regions": []map[string]string{{"state": "ca", "zip_prefix": "900"}},
+		"methods": []map[string]interface{}{
+			{"code": "Courier", "name": "Same-day courier", "kind": "express", "rate": "flat", "flat_fee": 15, "min_days": 0, "max_days": 1},
+			{"code": "ground", "name": "Ground", "kind": "standard", "rate": "weight", "min_days": 2, "max_days": 6,
+				"weight_tiers": []map[string]interface{}{{"max_grams": 1000, "fee": 4}, {"max_grams": 5000, "fee": 9}}},
+		},
+	}
+	s.expect(t, http.StatusCreated, "POST", "/admin/shipping-zones", s.admin, losAngeles, nil)
+	s.expect(t, http.StatusBadRequest, "POST", "/admin/shipping-zones", s.admin, map[string]interface{}{"name": "Empty"}, nil)
+	s.expect(t, http.StatusForbidden, "GET", "/admin/shipping-zones", s.customer, nil, nil)
+
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 20, "weight": 400})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 3}, nil)
+
+	usd := func(cents int64) models.Money { return models.NewMoney(cents, models.DefaultCurrency) }
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"profile address in Los Angeles", "", []string{"courier", "ground"}},
+		{"elsewhere in California", "?state=CA&zipcode=94105", []string{"standard"}},
+		{"zip code sharing a prefix digit", "?state=CA&zipcode=90112", []string{"standard"}},
+		{"no zone", "?state=TX&zipcode=73301", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options shippingOptions
+			s.expect(t, http.StatusOK, "GET", "/cart/shipping-options"+tt.query, s.customer, nil, &options)
+			if got := methodCodes(options.Options); !slices.Equal(got, tt.want) {
+				t.Errorf("methods = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 1.2kg lands in the second weight tier
+	var options shippingOptions
+	s.expect(t, http.StatusOK, "GET", "/cart/shipping-options", s.customer, nil, &options)
+	if len(options.Options) != 2 || options.Options[1].Cost != usd(900) {
+		t.Errorf("options = %+v, want ground at 9.00", options.Options)
+	}
+
+	checkout := map[string]string{"payment_method": "card", "payment_token": payments.TokenSuccess}
+	s.expect(t, http.StatusBadRequest, "POST", "/order", s.customer, checkout, nil)
+	checkout["shipping_method"] = "standard" // Not offered in Los Angeles
+	s.expect(t, http.StatusBadRequest, "POST", "/order", s.customer, checkout, nil)
+
+	checkout["shipping_method"] = "courier"
+	var result struct {
+		OrderID      primitive.ObjectID    `json:"order_id"`
+		TotalAmount  models.Money          `json:"total_amount"`
+		DeliveryDate models.DeliveryWindow `json:"delivery_date"`
+		Shipping     *models.ShippingQuote `json:"shipping"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, checkout, &result)
+	if result.TotalAmount != usd(4500) || result.Shipping == nil || result.Shipping.Name != "Same-day courier" {
+		t.Errorf("order = %+v, want 30.00 plus 15.00 courier", result)
+	}
+	want, err := s.orders.Delivery.Estimate(context.Background(), time.Now(), models.Address{State: "CA"}, *result.Shipping)
+	if err != nil || result.DeliveryDate != want {
+		t.Errorf("delivery date = %v, want %v", result.DeliveryDate, want)
+	}
+	var invoice models.Invoice
+	s.expect(t, http.StatusOK, "GET", "/orders/"+result.OrderID.Hex()+"/invoice", s.customer, nil, &invoice)
+	if invoice.Shipping != usd(1500) {
+		t.Errorf("invoice shipping = %v, want 15.00", invoice.Shipping)
+	}
+}
+
+func TestFreeShippingCoupon(t *testing.T) {
+	s := newShop(t)
+	zone := map[string]interface{}{
+		"name":    "Los Angeles",
+		"regions": []map[string]string{{"state": "CA", "zip_prefix": "900"}},
+		"methods": []map[string]interface{}{{"code": "courier", "name": "Courier", "kind": "express", "rate": "flat", "flat_fee": 15, "min_days": 0, "max_days": 1}},
+	}
+	s.expect(t, http.StatusCreated, "POST", "/admin/shipping-zones", s.admin, zone, nil)
+	s.createPromotion(t, map[string]interface{}{"code": "SHIPFREE", "type": "free_shipping"})
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	s.expect(t, http.StatusOK, "POST", "/cart/coupon", s.customer, map[string]string{"code": "SHIPFREE"}, nil)
+
+	var options shippingOptions
+	s.expect(t, http.StatusOK, "GET", "/cart/shipping-options", s.customer, nil, &options)
+	if len(options.Options) != 1 || options.Options[0].Discount != options.Options[0].Cost || !options.Options[0].Total.IsZero() {
+		t.Errorf("options = %+v, want the courier fee waived", options.Options)
+	}
+
+	var result struct {
+		TotalAmount models.Money `json:"total_amount"`
+	}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "courier", "payment_method": "card", "payment_token": payments.TokenSuccess}, &result)
+	if result.TotalAmount != models.NewMoney(1000, models.DefaultCurrency) {
+		t.Errorf("total = %v, want 10.00 with free shipping", result.TotalAmount)
+	}
+}
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SubscribeToStock asks for an email when a sold-out product comes back.
+// Products sold by variant take the variant's {"sku"} in the body.
+func (pc *ProductController) SubscribeToStock(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		SKU string `json:"sku"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	sku := strings.TrimSpace(body.SKU)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := pc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	product, err := pc.Products.FindProductByID(ctx, id)
+	if err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if product.HasVariants() {
+		if _, ok := product.Variant(sku); !ok {
+			http.Error(w, "Choose a valid variant", http.StatusBadRequest)
+			return
+		}
+	} else if sku != "" {
+		http.Error(w, "Product has no variants", http.StatusBadRequest)
+		return
+	}
+	if product.AvailableFor(sku) > 0 {
+		http.Error(w, "Product is in stock", http.StatusConflict)
+		return
+	}
+
+	subscription := models.StockSubscription{
+		ProductID: id,
+		SKU:       sku,
+		UserID:    user.ID,
+		Email:     user.Email,
+		CreatedAt: time.Now(),
+	}
+	err = pc.Alerts.CreateStockSubscription(ctx, &subscription)
+	if errors.Is(err, store.ErrDuplicate) {
+		http.Error(w, "You will already be notified when it is back in stock", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to subscribe", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// UnsubscribeFromStock cancels a back-in-stock subscription, for the
+// variant in ?sku= if the product has variants
+func (pc *ProductController) UnsubscribeFromStock(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	sku := strings.TrimSpace(r.URL.Query().Get("sku"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := pc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	err = pc.Alerts.DeleteStockSubscription(ctx, user.ID, id, sku)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "You will not be notified about this product"})
+}
+
+// notifyBackInStock emails the subscribers waiting for whichever of the
+// product's figures can be bought now. It runs in the background; each
+// subscription is claimed before its email goes out, so none is sent twice.
+func notifyBackInStock(alerts store.StockAlertStore, emailService *utils.EmailService, product models.Product) {
+	skus := []string{""}
+	if product.HasVariants() {
+		skus = skus[:0]
+		for _, v := range product.Variants {
+			skus = append(skus, v.SKU)
+		}
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, sku := range skus {
+			if product.AvailableFor(sku) <= 0 {
+				continue
+			}
+			subscriptions, err := alerts.ClaimStockSubscriptions(ctx, product.ID, sku, time.Now())
+			if err != nil {
+				log.Printf("Failed to claim stock subscriptions for product %s: %v", product.ID.Hex(), err)
+			}
+			for _, sub := range subscriptions {
+				if err := emailService.SendBackInStockEmail(sub.Email, product, sku); err != nil {
+					log.Printf("Failed to send email to %s: %v", sub.Email, err)
+				}
+			}
+		}
+	}()
+}
+
+// notifyRestocked emails the subscribers waiting for the products whose
+// stock a cancelled order gave back
+func (oc *OrderController) notifyRestocked(ctx context.Context, order models.Order) {
+	seen := make(map[primitive.ObjectID]bool, len(order.Items))
+	for _, item := range order.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		seen[item.ProductID] = true
+		// Products deleted since the order was placed have no one to tell
+		if product, err := oc.Products.FindProductByID(ctx, item.ProductID); err == nil {
+			notifyBackInStock(oc.Alerts, oc.EmailService, *product)
+		}
+	}
+}
+
+// SendLowStockDigest emails every admin the products at or below their
+// reorder threshold, if there are any, and returns how many were listed
+func (pc *ProductController) SendLowStockDigest(ctx context.Context) (int, error) {
+	products, err := pc.Alerts.ListLowStockProducts(ctx)
+	if err != nil || len(products) == 0 {
+		return 0, err
+	}
+	admins, err := pc.Users.ListUsersByRole(ctx, "admin")
+	if err != nil {
+		return 0, err
+	}
+	for _, admin := range admins {
+		if err := pc.EmailService.SendLowStockDigest(admin.Email, products); err != nil {
+			log.Printf("Failed to send email to %s: %v", admin.Email, err)
+		}
+	}
+	return len(products), nil
+}
+
+// WatchStock sends the low-stock digest every interval until ctx is done.
+// It is meant to run in its own goroutine.
+func (pc *ProductController) WatchStock(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			digestCtx, cancel := context.WithTimeout(ctx, time.Minute)
+			if _, err := pc.SendLowStockDigest(digestCtx); err != nil {
+				log.Printf("Failed to send the low-stock digest: %v", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package controllers_test
+
+import (
+	"context"
+	"go-ecommerce/payments"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackInStockSubscription(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 0})
+	path := "/products/" + mug.ID.Hex() + "/stock-subscription"
+
+	s.expect(t, http.StatusUnauthorized, "POST", path, "", nil, nil)
+	s.expect(t, http.StatusBadRequest, "POST", path, s.customer, map[string]string{"sku": "MUG-XL"}, nil)
+	s.expect(t, http.StatusCreated, "POST", path, s.customer, nil, nil)
+	s.expect(t, http.StatusConflict, "POST", path, s.customer, nil, nil)
+
+	// A restock notifies the subscriber once
+	restock := map[string]interface{}{"delta": 4, "reason": "restock"}
+	s.expect(t, http.StatusCreated, "POST", "/products/"+mug.ID.Hex()+"/stock-movements", s.admin, restock, nil)
+	if !eventually(t, func() bool { return len(s.mail.to(customerEmail)) == 1 }) {
+		t.Fatalf("emails to the customer = %v, want the back-in-stock notice", s.mail.to(customerEmail))
+	}
+	if subject := s.mail.to(customerEmail)[0]; subject != "Mug is back in stock" {
+		t.Errorf("subject = %q", subject)
+	}
+	s.expect(t, http.StatusCreated, "POST", "/products/"+mug.ID.Hex()+"/stock-movements", s.admin, restock, nil)
+	s.expect(t, http.StatusConflict, "POST", path, s.customer, nil, nil)
+	s.expect(t, http.StatusNotFound, "DELETE", path, s.customer, nil, nil)
+	if eventually(t, func() bool { return len(s.mail.to(customerEmail)) > 1 }) {
+		t.Errorf("emails to the customer = %v, want a single notice", s.mail.to(customerEmail))
+	}
+}
+
+func TestUnsubscribeFromStock(t *testing.T) {
+	s := newShop(t)
+	shirt := s.createProduct(t, map[string]interface{}{
+		"name":    "Shirt",
+		"price":   20,
+		"options": []map[string]interface{}{{"name": "size", "values": []string{"S", "M"}}},
+		"variants": []map[string]interface{}{
+			{"sku": "SHIRT-S", "options": map[string]string{"size": "S"}, "stock": 0},
+			{"sku": "SHIRT-M", "options": map[string]string{"size": "M"}, "stock": 2},
+		},
+	})
+	path := "/products/" + shirt.ID.Hex() + "/stock-subscription"
+
+	s.expect(t, http.StatusBadRequest, "POST", path, s.customer, nil, nil)
+	s.expect(t, http.StatusConflict, "POST", path, s.customer, map[string]string{"sku": "SHIRT-M"}, nil)
+	s.expect(t, http.StatusCreated, "POST", path, s.customer, map[string]string{"sku": "SHIRT-S"}, nil)
+	s.expect(t, http.StatusOK, "DELETE", path+"?sku=SHIRT-S", s.customer, nil, nil)
+
+	s.expect(t, http.StatusCreated, "POST", "/products/"+shirt.ID.Hex()+"/stock-movements", s.admin,
+		map[string]interface{}{"sku": "SHIRT-S", "delta": 3, "reason": "restock"}, nil)
+	if eventually(t, func() bool { return len(s.mail.to(customerEmail)) > 0 }) {
+		t.Errorf("emails to the customer = %v, want none after unsubscribing", s.mail.to(customerEmail))
+	}
+}
+
+func TestLowStockDigest(t *testing.T) {
+	s := newShop(t)
+	ctx := context.Background()
+	s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 2, "reorder_threshold": 3})
+	s.createProduct(t, map[string]interface{}{"name": "Plate", "price": 10, "stock": 8, "reorder_threshold": 3})
+	s.expect(t, http.StatusBadRequest, "POST", "/products", s.admin, map[string]interface{}{"name": "Bowl", "price": 10, "reorder_threshold": -1}, nil)
+
+	n, err := s.products.SendLowStockDigest(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("SendLowStockDigest = %d, %v; want the mug", n, err)
+	}
+	subject := "Low stock: 1 to reorder"
+	body := s.mail.body(adminEmail, subject)
+	if !strings.Contains(body, "Mug") || strings.Contains(body, "Plate") {
+		t.Errorf("digest = %q, want only the mug", body)
+	}
+	if got := s.mail.to(customerEmail); len(got) != 0 {
+		t.Errorf("emails to the customer = %v, want none", got)
+	}
+}
+
+func TestCancellationNotifiesBackInStock(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 1})
+	payment := s.placeOrder(t, mug, payments.TokenSuccess)
+
+	s.addUser(t, "waiting@example.com", "user")
+	waiting := s.login(t, "waiting@example.com")
+	s.expect(t, http.StatusCreated, "POST", "/products/"+mug.ID.Hex()+"/stock-subscription", waiting, nil, nil)
+
+	// Cancelling the order puts the last mug back on sale
+	s.expect(t, http.StatusOK, "POST", "/orders/"+payment.OrderID.Hex()+"/cancel", s.customer, nil, nil)
+	if !eventually(t, func() bool { return slices.Contains(s.mail.to("waiting@example.com"), "Mug is back in stock") }) {
+		t.Errorf("emails to the subscriber = %v, want the back-in-stock notice", s.mail.to("waiting@example.com"))
+	}
+}
+
+func TestExpiredReservationNotifiesBackInStock(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 1})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 1}, nil)
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]string{"shipping_method": "standard", "payment_method": "crypto"}, nil)
+
+	s.addUser(t, "waiting@example.com", "user")
+	waiting := s.login(t, "waiting@example.com")
+	s.expect(t, http.StatusCreated, "POST", "/products/"+mug.ID.Hex()+"/stock-subscription", waiting, nil, nil)
+
+	if n, err := s.orders.ReleaseExpiredReservations(context.Background(), time.Now().Add(48*time.Hour)); err != nil || n != 1 {
+		t.Fatalf("ReleaseExpiredReservations = %d, %v; want the crypto order", n, err)
+	}
+	if !eventually(t, func() bool { return slices.Contains(s.mail.to("waiting@example.com"), "Mug is back in stock") }) {
+		t.Errorf("emails to the subscriber = %v, want the back-in-stock notice", s.mail.to("waiting@example.com"))
+	}
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxController handles tax rate table requests
+type TaxController struct {
+	TaxRates store.TaxStore
+}
+
+// NewTaxController creates a new TaxController
+func NewTaxController(s store.Store) *TaxController {
+	return &TaxController{
+		TaxRates: s,
+	}
+}
+
+// ListTaxRates lists tax rates, optionally only those of ?state= (Admin only)
+func (tc *TaxController) ListTaxRates(w http.ResponseWriter, r *http.Request) {
+	state := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("state")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rates, err := tc.TaxRates.ListTaxRates(ctx, state)
+	if err != nil {
+		http.Error(w, "Failed to retrieve tax rates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// CreateTaxRate adds a tax rate (Admin only)
+func (tc *TaxController) CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	var rate models.TaxRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	rate.ID = primitive.NilObjectID
+	rate.Normalize()
+	if err := rate.Validate(); err != nil {
+		http.Error(w, "Invalid tax rate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	rate.CreatedAt = time.Now()
+	rate.UpdatedAt = rate.CreatedAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := tc.TaxRates.CreateTaxRate(ctx, &rate); err != nil {
+		http.Error(w, "Error creating tax rate", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rate)
+}
+
+// UpdateTaxRate replaces a tax rate. Orders already placed keep the tax they
+// were charged. (Admin only)
+func (tc *TaxController) UpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tax rate ID", http.StatusBadRequest)
+		return
+	}
+	var rate models.TaxRate
+	if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	rate.Normalize()
+	if err := rate.Validate(); err != nil {
+		http.Error(w, "Invalid tax rate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	existing, err := tc.TaxRates.FindTaxRateByID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Tax rate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve tax rate", http.StatusInternalServerError)
+		return
+	}
+	rate.ID = existing.ID
+	rate.CreatedAt = existing.CreatedAt
+	rate.UpdatedAt = time.Now()
+
+	if err := tc.TaxRates.UpdateTaxRate(ctx, &rate); err != nil {
+		http.Error(w, "Error updating tax rate", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rate)
+}
+
+// DeleteTaxRate removes a tax rate (Admin only)
+func (tc *TaxController) DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tax rate ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = tc.TaxRates.DeleteTaxRate(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Tax rate not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error deleting tax rate", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Tax rate deleted")
+}
@@ -0,0 +1,61 @@
+package controllers_test
+
+import (
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCheckoutTax(t *testing.T) {
+	s := newShop(t)
+	for _, rate := range []map[string]interface{}{
+		{"name": "CA State Tax", "state": "ca", "rate": 725},
+		{"name": "NY State Tax", "state": "NY", "rate": 400},
+	} {
+		s.expect(t, http.StatusCreated, "POST", "/admin/tax-rates", s.admin, rate, nil)
+	}
+	s.expect(t, http.StatusBadRequest, "POST", "/admin/tax-rates", s.admin, map[string]interface{}{"name": "Bad", "state": "CA", "rate": 10001}, nil)
+	s.expect(t, http.StatusForbidden, "GET", "/admin/tax-rates", s.customer, nil, nil)
+	var rates []models.TaxRate
+	s.expect(t, http.StatusOK, "GET", "/admin/tax-rates?state=ca", s.admin, nil, &rates)
+	if len(rates) != 1 || rates[0].State != "CA" {
+		t.Errorf("CA rates = %+v", rates)
+	}
+
+	usd := func(cents int64) models.Money { return models.NewMoney(cents, models.DefaultCurrency) }
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	s.expect(t, http.StatusOK, "POST", "/cart", s.customer, map[string]interface{}{"product_id": mug.ID, "quantity": 2}, nil)
+	var cart struct {
+		Tax   models.Money `json:"tax"`
+		Total models.Money `json:"total"`
+	}
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if cart.Tax != usd(145) || cart.Total != usd(2145) {
+		t.Errorf("cart = %+v, want 1.45 of CA tax on 20.00", cart)
+	}
+
+	// Shipping to New York taxes the order at New York's rate
+	newYorkZone := map[string]interface{}{
+		"name":    "New York",
+		"regions": []map[string]string{{"state": "NY"}},
+		"methods": []map[string]interface{}{{"code": "standard", "name": "Standard", "kind": "standard", "rate": "flat", "flat_fee": 0, "min_days": 2, "max_days": 4}},
+	}
+	s.expect(t, http.StatusCreated, "POST", "/admin/shipping-zones", s.admin, newYorkZone, nil)
+	var result struct {
+		OrderID     primitive.ObjectID `json:"order_id"`
+		TotalAmount models.Money       `json:"total_amount"`
+	}
+	newYork := models.Address{Street: "1 Broadway", City: "New York", State: "NY", ZipCode: "10004"}
+	s.expect(t, http.StatusOK, "POST", "/order", s.customer, map[string]interface{}{"shipping_method": "standard", "payment_method": "card", "payment_token": payments.TokenSuccess, "address": newYork}, &result)
+	if result.TotalAmount != usd(2080) {
+		t.Errorf("total = %v, want 20.80 with NY tax", result.TotalAmount)
+	}
+	var invoice models.Invoice
+	s.expect(t, http.StatusOK, "GET", "/orders/"+result.OrderID.Hex()+"/invoice", s.customer, nil, &invoice)
+	if invoice.Tax != usd(80) || len(invoice.Lines) != 1 || len(invoice.Lines[0].TaxBreakdown) != 1 || invoice.Lines[0].TaxBreakdown[0].Name != "NY State Tax" {
+		t.Errorf("invoice = %+v, want the NY tax broken down", invoice)
+	}
+}
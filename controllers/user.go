@@ -3,29 +3,35 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"go-ecommerce/middleware"
 	"go-ecommerce/models"
+	"go-ecommerce/store"
 	"go-ecommerce/utils"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // UserController handles user-related requests
 type UserController struct {
-	Collection   *mongo.Collection
+	Users        store.UserStore
+	Sessions     store.SessionStore
+	Resets       store.PasswordResetStore
+	Carts        store.CartStore
 	EmailService *utils.EmailService
 }
 
 // NewUserController creates a new UserController with EmailService
-func NewUserController(client *mongo.Client, emailService *utils.EmailService) *UserController {
-	collection := client.Database("ecommerce").Collection("users")
+func NewUserController(s store.Store, emailService *utils.EmailService) *UserController {
 	return &UserController{
-		Collection:   collection,
+		Users:        s,
+		Sessions:     s,
+		Resets:       s,
+		Carts:        s,
 		EmailService: emailService,
 	}
 }
@@ -43,13 +49,13 @@ func (uc *UserController) Register(w http.ResponseWriter, r *http.Request) {
 	// Check if user already exists
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	count, err := uc.Collection.CountDocuments(ctx, bson.M{"email": user.Email})
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	_, err = uc.Users.FindUserByEmail(ctx, user.Email)
+	if err == nil {
+		http.Error(w, "User already exists", http.StatusBadRequest)
 		return
 	}
-	if count > 0 {
-		http.Error(w, "User already exists", http.StatusBadRequest)
+	if !errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
@@ -72,12 +78,17 @@ func (uc *UserController) Register(w http.ResponseWriter, r *http.Request) {
 	user.VerificationToken = verificationToken
 
 	// Insert the user into the database
-	_, err = uc.Collection.InsertOne(ctx, user)
+	err = uc.Users.CreateUser(ctx, &user)
 	if err != nil {
 		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
 
+	// Keep what they put in their cart before registering
+	if err := mergeGuestCart(ctx, uc.Carts, r, user.ID); err != nil {
+		log.Printf("Failed to merge the guest cart of %s: %v", user.Email, err)
+	}
+
 	// Send verification email
 	err = uc.EmailService.SendVerificationEmail(user.Email, verificationToken)
 	if err != nil {
@@ -110,20 +121,14 @@ func (uc *UserController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	// Find the user with the verification token
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	var user models.User
-	err = uc.Collection.FindOne(ctx, bson.M{"verification_token": token}).Decode(&user)
+	user, err := uc.Users.FindUserByVerificationToken(ctx, token)
 	if err != nil {
 		http.Error(w, "User not found or already verified", http.StatusBadRequest)
 		return
 	}
 
 	// Update the user's verification status
-	_, err = uc.Collection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
-		"$set": bson.M{
-			"is_verified":        true,
-			"verification_token": "",
-		},
-	})
+	err = uc.Users.MarkUserVerified(ctx, user.ID)
 	if err != nil {
 		http.Error(w, "Error updating user verification status", http.StatusInternalServerError)
 		return
@@ -148,8 +153,7 @@ func (uc *UserController) Login(w http.ResponseWriter, r *http.Request) {
 	// Find the user in the database
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	var user models.User
-	err = uc.Collection.FindOne(ctx, bson.M{"email": creds.Email}).Decode(&user)
+	user, err := uc.Users.FindUserByEmail(ctx, creds.Email)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusUnauthorized)
 		return
@@ -168,16 +172,21 @@ func (uc *UserController) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.Email, user.Role)
+	// Keep what they put in their cart before logging in
+	if err := mergeGuestCart(ctx, uc.Carts, r, user.ID); err != nil {
+		log.Printf("Failed to merge the guest cart of %s: %v", user.Email, err)
+	}
+
+	// Start a session and issue its access and refresh tokens
+	tokens, err := uc.startSession(ctx, user)
 	if err != nil {
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	// Return the token
+	// Return the tokens
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	json.NewEncoder(w).Encode(tokens)
 }
 
 // GetProfile retrieves the authenticated user's profile
@@ -192,8 +201,7 @@ func (uc *UserController) GetProfile(w http.ResponseWriter, r *http.Request) {
 	// Find the user in the database
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	var user models.User
-	err := uc.Collection.FindOne(ctx, bson.M{"email": claims.Email}).Decode(&user)
+	user, err := uc.Users.FindUserByEmail(ctx, claims.Email)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"go-ecommerce/store"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxWebhookBytes caps the size of a webhook request body
+const maxWebhookBytes = 1 << 20
+
+// eventIntentStatus maps a webhook event type onto the intent status it reports
+var eventIntentStatus = map[payments.EventType]payments.IntentStatus{
+	payments.EventPaymentSucceeded: payments.IntentSucceeded,
+	payments.EventPaymentFailed:    payments.IntentFailed,
+	payments.EventPaymentRefunded:  payments.IntentRefunded,
+}
+
+// HandlePaymentWebhook receives signed events from the payment provider. Each
+// event is recorded once; redeliveries are acknowledged without being applied
+// again unless the first attempt failed.
+func (oc *OrderController) HandlePaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	event, err := oc.Provider.ParseWebhook(payload, r.Header)
+	if errors.Is(err, payments.ErrInvalidSignature) || errors.Is(err, payments.ErrStaleWebhook) {
+		http.Error(w, "Invalid webhook signature", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	record := &models.WebhookEvent{
+		ID:            oc.Provider.Name() + ":" + event.ID,
+		Provider:      oc.Provider.Name(),
+		EventID:       event.ID,
+		Type:          string(event.Type),
+		IntentID:      event.IntentID,
+		Amount:        models.NewMoney(event.Amount, event.Currency),
+		FailureReason: event.FailureReason,
+		OccurredAt:    time.Unix(event.Created, 0),
+		Status:        models.WebhookReceived,
+		ReceivedAt:    time.Now(),
+	}
+	err = oc.Webhooks.CreateWebhookEvent(ctx, record)
+	if errors.Is(err, store.ErrDuplicate) {
+		record, err = oc.Webhooks.FindWebhookEventByID(ctx, record.ID)
+		if err != nil {
+			http.Error(w, "Failed to load webhook event", http.StatusInternalServerError)
+			return
+		}
+		// Only a failed first attempt is worth another try
+		if record.Status != models.WebhookFailed {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"status": "duplicate"})
+			return
+		}
+	} else if err != nil {
+		http.Error(w, "Failed to record webhook event", http.StatusInternalServerError)
+		return
+	}
+
+	// A failure is answered with 500 so the provider redelivers the event
+	if err := oc.runWebhookEvent(ctx, record); err != nil {
+		http.Error(w, "Failed to process webhook event", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": string(record.Status)})
+}
+
+// runWebhookEvent applies a recorded event and stores the outcome. Failed
+// events are kept with their error for replay.
+func (oc *OrderController) runWebhookEvent(ctx context.Context, record *models.WebhookEvent) error {
+	status, err := oc.applyWebhookEvent(ctx, record)
+	record.Status = status
+	record.Error = ""
+	if err != nil {
+		record.Error = err.Error()
+		log.Printf("Failed to process webhook event %s: %v", record.ID, err)
+	}
+	record.Attempts++
+	now := time.Now()
+	record.ProcessedAt = &now
+	if uerr := oc.Webhooks.UpdateWebhookEvent(ctx, record); uerr != nil {
+		log.Printf("Failed to save webhook event %s: %v", record.ID, uerr)
+	}
+	return err
+}
+
+// applyWebhookEvent moves the event's payment and order forward. Events are
+// not guaranteed to arrive in order, so one that would take a payment back to
+// an earlier stage, or that is older than the last event applied at the same
+// stage, is ignored.
+func (oc *OrderController) applyWebhookEvent(ctx context.Context, record *models.WebhookEvent) (models.WebhookEventStatus, error) {
+	intentStatus, ok := eventIntentStatus[payments.EventType(record.Type)]
+	if !ok {
+		return models.WebhookIgnored, nil
+	}
+
+	// The event can beat our own write of the payment record; replaying it
+	// later picks the payment up
+	payment, err := oc.Payments.FindPaymentByProviderRef(ctx, record.Provider, record.IntentID)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.WebhookFailed, fmt.Errorf("no payment for intent %s", record.IntentID)
+	}
+	if err != nil {
+		return models.WebhookFailed, err
+	}
+
+	to := paymentStatusFor(intentStatus)
+	stale := to.Stage() < payment.Status.Stage() ||
+		(to.Stage() == payment.Status.Stage() && !record.OccurredAt.After(payment.LastEventAt))
+	if stale {
+		return models.WebhookIgnored, nil
+	}
+
+	order, err := oc.Orders.FindOrderByID(ctx, payment.OrderID)
+	if err != nil {
+		return models.WebhookFailed, err
+	}
+	intent := &payments.Intent{
+		ID:            record.IntentID,
+		Status:        intentStatus,
+		Amount:        record.Amount.Amount,
+		Currency:      record.Amount.Currency,
+		FailureReason: record.FailureReason,
+	}
+	payment.LastEventAt = record.OccurredAt
+	before := order.Status
+	if err := oc.applyIntent(ctx, order, payment, intent); err != nil {
+		return models.WebhookFailed, err
+	}
+	if before != models.OrderPaid && order.Status == models.OrderPaid {
+		if user, err := oc.Users.FindUserByID(ctx, order.UserID); err == nil {
+			oc.sendOrderConfirmation(user.Email, order)
+		}
+	}
+	return models.WebhookProcessed, nil
+}
+
+// ListWebhookEvents lists received payment webhook events, newest first,
+// optionally filtered by ?status= (Admin only)
+func (oc *OrderController) ListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	status := models.WebhookEventStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.WebhookReceived, models.WebhookProcessed, models.WebhookIgnored, models.WebhookFailed:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := oc.Webhooks.ListWebhookEvents(ctx, status)
+	if err != nil {
+		http.Error(w, "Failed to retrieve webhook events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// ReplayWebhookEvent processes a failed webhook event again (Admin only)
+func (oc *OrderController) ReplayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	record, err := oc.Webhooks.FindWebhookEventByID(ctx, mux.Vars(r)["id"])
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Webhook event not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve webhook event", http.StatusInternalServerError)
+		return
+	}
+	if record.Status != models.WebhookFailed {
+		http.Error(w, "Only failed webhook events can be replayed", http.StatusConflict)
+		return
+	}
+
+	if err := oc.runWebhookEvent(ctx, record); err != nil {
+		http.Error(w, "Replay failed: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
@@ -0,0 +1,136 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-ecommerce/models"
+	"go-ecommerce/payments"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sendWebhook delivers a payment event signed by the mock gateway
+func (s *shop) sendWebhook(t *testing.T, event payments.Event) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/webhooks/payments", bytes.NewReader(payload))
+	req.Header.Set(payments.SignatureHeader, s.payments.SignWebhook(payload))
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func succeeded(payment models.Payment) payments.Event {
+	return payments.Event{
+		ID:       "evt_" + payment.ProviderRef,
+		Type:     payments.EventPaymentSucceeded,
+		IntentID: payment.ProviderRef,
+		Amount:   payment.Amount.Amount,
+		Currency: payment.Amount.Currency,
+		Created:  time.Now().Unix(),
+	}
+}
+
+func (s *shop) orderStatus(t *testing.T, payment models.Payment) models.OrderStatus {
+	t.Helper()
+	order, err := s.db.FindOrderByID(context.Background(), payment.OrderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order.Status
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	payment := s.placeOrder(t, mug, payments.TokenThreeDS)
+
+	if rec := s.sendWebhook(t, succeeded(payment)); rec.Code != http.StatusOK {
+		t.Fatalf("webhook = %d %s", rec.Code, rec.Body.String())
+	}
+	if status := s.orderStatus(t, payment); status != models.OrderPaid {
+		t.Errorf("order status = %s, want paid", status)
+	}
+	if !eventually(t, func() bool { return len(s.mail.to(customerEmail)) == 1 }) {
+		t.Errorf("emails to the customer = %v, want the confirmation", s.mail.to(customerEmail))
+	}
+
+	// A redelivery is acknowledged without being applied again
+	rec := s.sendWebhook(t, succeeded(payment))
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte("duplicate")) {
+		t.Errorf("redelivery = %d %s, want a duplicate", rec.Code, rec.Body.String())
+	}
+
+	// A failure reported before the success arrived must not undo it
+	failed := succeeded(payment)
+	failed.ID, failed.Type, failed.Created = "evt_late", payments.EventPaymentFailed, time.Now().Add(-time.Minute).Unix()
+	rec = s.sendWebhook(t, failed)
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte("ignored")) {
+		t.Errorf("late failure = %d %s, want it ignored", rec.Code, rec.Body.String())
+	}
+	stored, err := s.db.FindPaymentByID(context.Background(), payment.ID)
+	if err != nil || stored.Status != models.PaymentCompleted {
+		t.Errorf("payment = %+v, %v; want it still completed", stored, err)
+	}
+}
+
+func TestPaymentWebhookSignature(t *testing.T) {
+	s := newShop(t)
+	payload := []byte(`{"id":"evt_1","type":"payment.succeeded","intent_id":"pi_1"}`)
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"unsigned", ""},
+		{"wrong secret", payments.Sign([]byte("whsec_other"), payload, time.Now())},
+		{"stale", payments.Sign([]byte("whsec_test"), payload, time.Now().Add(-time.Hour))},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/webhooks/payments", bytes.NewReader(payload))
+		req.Header.Set(payments.SignatureHeader, tt.header)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", tt.name, rec.Code)
+		}
+	}
+	if events, err := s.db.ListWebhookEvents(context.Background(), ""); err != nil || len(events) != 0 {
+		t.Errorf("recorded events = %+v, %v; want none", events, err)
+	}
+}
+
+func TestReplayWebhookEvent(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 5})
+	payment := s.placeOrder(t, mug, payments.TokenThreeDS)
+
+	// An event for an intent we do not know yet fails and is kept for replay
+	event := succeeded(payment)
+	event.IntentID = "pi_unknown"
+	if rec := s.sendWebhook(t, event); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("webhook = %d %s, want 500 so it is redelivered", rec.Code, rec.Body.String())
+	}
+	var failed []models.WebhookEvent
+	s.expect(t, http.StatusOK, "GET", "/admin/webhooks?status=failed", s.admin, nil, &failed)
+	if len(failed) != 1 || failed[0].Error == "" {
+		t.Fatalf("failed events = %+v", failed)
+	}
+	s.expect(t, http.StatusUnprocessableEntity, "POST", "/admin/webhooks/"+failed[0].ID+"/replay", s.admin, nil, nil)
+
+	// Only failed events can be replayed
+	good := succeeded(payment)
+	good.ID = event.ID + "_ok"
+	if rec := s.sendWebhook(t, good); rec.Code != http.StatusOK {
+		t.Fatalf("webhook = %d %s", rec.Code, rec.Body.String())
+	}
+	s.expect(t, http.StatusConflict, "POST", "/admin/webhooks/mock:"+good.ID+"/replay", s.admin, nil, nil)
+	s.expect(t, http.StatusNotFound, "POST", "/admin/webhooks/mock:evt_missing/replay", s.admin, nil, nil)
+	s.expect(t, http.StatusBadRequest, "GET", "/admin/webhooks?status=lost", s.admin, nil, nil)
+	s.expect(t, http.StatusForbidden, "GET", "/admin/webhooks", s.customer, nil, nil)
+}
@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ecommerce/middleware"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WishlistController handles wishlist requests
+type WishlistController struct {
+	Wishlists store.WishlistStore
+	Carts     store.CartStore
+	Users     store.UserStore
+	Products  store.ProductStore
+}
+
+// NewWishlistController creates a new WishlistController
+func NewWishlistController(s store.Store) *WishlistController {
+	return &WishlistController{
+		Wishlists: s,
+		Carts:     s,
+		Users:     s,
+		Products:  s,
+	}
+}
+
+// wishlistResponse is a wishlist with each item at its current price and
+// stock, and the link it can be viewed at while public
+type wishlistResponse struct {
+	models.Wishlist
+	Lines    []models.SavedLine `json:"lines"`
+	ShareURL string             `json:"share_url,omitempty"`
+}
+
+// sharedWishlistResponse is what anyone with a public wishlist's link sees
+type sharedWishlistResponse struct {
+	Name      string             `json:"name"`
+	Owner     string             `json:"owner"`
+	Lines     []models.SavedLine `json:"lines"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// savedLine shows a saved item at its product's current price and stock; an
+// item whose product was deleted is shown as unavailable
+func savedLine(ctx context.Context, products store.ProductStore, productID primitive.ObjectID, sku string, quantity int, savedPrice *models.Money) (models.SavedLine, error) {
+	product, err := products.FindProductByID(ctx, productID)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.NewSavedLine(nil, productID, sku, quantity, savedPrice), nil
+	}
+	if err != nil {
+		return models.SavedLine{}, err
+	}
+	return models.NewSavedLine(product, productID, sku, quantity, savedPrice), nil
+}
+
+// wishlistLines shows each of the wishlist's items as it stands now
+func (wc *WishlistController) wishlistLines(ctx context.Context, wishlist *models.Wishlist) ([]models.SavedLine, error) {
+	lines := []models.SavedLine{}
+	for _, item := range wishlist.Items {
+		line, err := savedLine(ctx, wc.Products, item.ProductID, item.SKU, 0, item.SavedPrice)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// writeWishlist responds with the wishlist as its owner sees it
+func (wc *WishlistController) writeWishlist(ctx context.Context, w http.ResponseWriter, status int, wishlist *models.Wishlist) {
+	lines, err := wc.wishlistLines(ctx, wishlist)
+	if err != nil {
+		http.Error(w, "Error pricing wishlist", http.StatusInternalServerError)
+		return
+	}
+	response := wishlistResponse{Wishlist: *wishlist, Lines: lines}
+	if wishlist.Public {
+		response.ShareURL = fmt.Sprintf("http://localhost:%s/wishlists/shared/%s", os.Getenv("PORT"), wishlist.ShareToken)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// setPublic shares the wishlist under a new link, or stops sharing it so
+// that the old link no longer works
+func setPublic(wishlist *models.Wishlist, public bool) error {
+	if public == wishlist.Public {
+		return nil
+	}
+	wishlist.Public = public
+	wishlist.ShareToken = ""
+	if public {
+		token, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			return err
+		}
+		wishlist.ShareToken = token
+	}
+	return nil
+}
+
+// userFromRequest returns the signed-in user, writing the error and returning
+// nil if there is none
+func (wc *WishlistController) userFromRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) *models.User {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*utils.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+	user, err := wc.Users.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil
+	}
+	return user
+}
+
+// wishlistFromRequest loads the user's wishlist named by the {id} in the
+// path, writing the error and returning nil if it is malformed or not theirs
+func (wc *WishlistController) wishlistFromRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, user *models.User) *models.Wishlist {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid wishlist ID", http.StatusBadRequest)
+		return nil
+	}
+	wishlist, err := wc.Wishlists.FindWishlistByID(ctx, id)
+	if err != nil || wishlist.UserID != user.ID {
+		http.Error(w, "Wishlist not found", http.StatusNotFound)
+		return nil
+	}
+	return wishlist
+}
+
+// updateWishlist saves a change to a wishlist, writing the error and
+// returning false if it fails
+func (wc *WishlistController) updateWishlist(ctx context.Context, w http.ResponseWriter, wishlist *models.Wishlist) bool {
+	wishlist.UpdatedAt = time.Now()
+	err := wc.Wishlists.UpdateWishlist(ctx, wishlist)
+	if errors.Is(err, store.ErrDuplicate) {
+		http.Error(w, "You already have a wishlist with that name", http.StatusConflict)
+		return false
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Wishlist not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Failed to update wishlist", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// ListWishlists lists the user's wishlists, oldest first
+func (wc *WishlistController) ListWishlists(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlists, err := wc.Wishlists.ListWishlistsByUser(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve wishlists", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wishlists)
+}
+
+// CreateWishlist starts a wishlist called {"name"}, shared by link when
+// {"public"} is true
+func (wc *WishlistController) CreateWishlist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	wishlist := models.Wishlist{Name: body.Name, Items: []models.WishlistItem{}}
+	wishlist.Normalize()
+	if err := wishlist.Validate(); err != nil {
+		http.Error(w, "Invalid wishlist: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := setPublic(&wishlist, body.Public); err != nil {
+		http.Error(w, "Failed to share wishlist", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist.UserID = user.ID
+	wishlist.CreatedAt = time.Now()
+	wishlist.UpdatedAt = wishlist.CreatedAt
+
+	err := wc.Wishlists.CreateWishlist(ctx, &wishlist)
+	if errors.Is(err, store.ErrDuplicate) {
+		http.Error(w, "You already have a wishlist with that name", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create wishlist", http.StatusInternalServerError)
+		return
+	}
+	wc.writeWishlist(ctx, w, http.StatusCreated, &wishlist)
+}
+
+// GetWishlist shows one of the user's wishlists with each item's current
+// price and stock, flagging those whose price dropped since they were added
+func (wc *WishlistController) GetWishlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist := wc.wishlistFromRequest(ctx, w, r, user)
+	if wishlist == nil {
+		return
+	}
+	wc.writeWishlist(ctx, w, http.StatusOK, wishlist)
+}
+
+// UpdateWishlist renames a wishlist to {"name"} and shares it by link or
+// stops sharing it as {"public"} says. Sharing again gives a new link.
+func (wc *WishlistController) UpdateWishlist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist := wc.wishlistFromRequest(ctx, w, r, user)
+	if wishlist == nil {
+		return
+	}
+	wishlist.Name = body.Name
+	wishlist.Normalize()
+	if err := wishlist.Validate(); err != nil {
+		http.Error(w, "Invalid wishlist: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := setPublic(wishlist, body.Public); err != nil {
+		http.Error(w, "Failed to share wishlist", http.StatusInternalServerError)
+		return
+	}
+	if !wc.updateWishlist(ctx, w, wishlist) {
+		return
+	}
+	wc.writeWishlist(ctx, w, http.StatusOK, wishlist)
+}
+
+// DeleteWishlist deletes one of the user's wishlists
+func (wc *WishlistController) DeleteWishlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist := wc.wishlistFromRequest(ctx, w, r, user)
+	if wishlist == nil {
+		return
+	}
+	if err := wc.Wishlists.DeleteWishlist(ctx, wishlist.ID); err != nil {
+		http.Error(w, "Failed to delete wishlist", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode("Wishlist deleted")
+}
+
+// AddWishlistItem adds {"product_id"}, or its variant {"sku"}, to a
+// wishlist at its current price. Products that are out of stock may be
+// added; adding an item already there changes nothing.
+func (wc *WishlistController) AddWishlistItem(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ProductID primitive.ObjectID `json:"product_id"`
+		SKU       string             `json:"sku"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	sku := strings.TrimSpace(body.SKU)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist := wc.wishlistFromRequest(ctx, w, r, user)
+	if wishlist == nil {
+		return
+	}
+	product, err := wc.Products.FindProductByID(ctx, body.ProductID)
+	if err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if err := product.CheckVariant(sku); err != nil {
+		http.Error(w, "Invalid wishlist item: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := wishlist.Item(product.ID, sku); !ok {
+		price := product.PriceFor(sku)
+		wishlist.Items = append(wishlist.Items, models.WishlistItem{ProductID: product.ID, SKU: sku, SavedPrice: &price, AddedAt: time.Now()})
+		if !wc.updateWishlist(ctx, w, wishlist) {
+			return
+		}
+	}
+	wc.writeWishlist(ctx, w, http.StatusOK, wishlist)
+}
+
+// RemoveWishlistItem takes a product, or the variant in ?sku=, off a wishlist
+func (wc *WishlistController) RemoveWishlistItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := primitive.ObjectIDFromHex(mux.Vars(r)["product_id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	sku := r.URL.Query().Get("sku")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist := wc.wishlistFromRequest(ctx, w, r, user)
+	if wishlist == nil {
+		return
+	}
+	if _, ok := wishlist.RemoveItem(productID, sku); !ok {
+		http.Error(w, "Item not on wishlist", http.StatusNotFound)
+		return
+	}
+	if !wc.updateWishlist(ctx, w, wishlist) {
+		return
+	}
+
+	json.NewEncoder(w).Encode("Item removed from wishlist")
+}
+
+// MoveWishlistItemToCart moves a product, or the variant in {"sku"}, from a
+// wishlist into the user's cart, {"quantity"} of it or one, as long as
+// enough is in stock. It returns the wishlist as it is left.
+func (wc *WishlistController) MoveWishlistItemToCart(w http.ResponseWriter, r *http.Request) {
+	productID, err := primitive.ObjectIDFromHex(mux.Vars(r)["product_id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	sku := strings.TrimSpace(body.SKU)
+	if body.Quantity < 0 {
+		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+		return
+	}
+	if body.Quantity == 0 {
+		body.Quantity = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist := wc.wishlistFromRequest(ctx, w, r, user)
+	if wishlist == nil {
+		return
+	}
+	if _, ok := wishlist.Item(productID, sku); !ok {
+		http.Error(w, "Item not on wishlist", http.StatusNotFound)
+		return
+	}
+	product, err := wc.Products.FindProductByID(ctx, productID)
+	if err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+
+	cart, err := wc.Carts.FindCartByUserID(ctx, user.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		cart = &models.Cart{UserID: user.ID}
+	} else if err != nil {
+		http.Error(w, "Error loading cart", http.StatusInternalServerError)
+		return
+	}
+	quantity := body.Quantity
+	if existing, ok := cart.Item(productID, sku); ok {
+		quantity += existing.Quantity
+	}
+	if !checkCartQuantity(w, *product, sku, quantity) {
+		return
+	}
+	price := product.PriceFor(sku)
+	if existing, ok := cart.Item(productID, sku); ok {
+		existing.Quantity = quantity
+		existing.UnitPrice = &price
+	} else {
+		cart.Items = append(cart.Items, models.CartItem{ProductID: productID, SKU: sku, Quantity: quantity, UnitPrice: &price})
+	}
+	cart.Touch(time.Now())
+	if err := wc.Carts.SaveCart(ctx, cart); err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
+
+	wishlist.RemoveItem(productID, sku)
+	if !wc.updateWishlist(ctx, w, wishlist) {
+		return
+	}
+	wc.writeWishlist(ctx, w, http.StatusOK, wishlist)
+}
+
+// MoveCartItemToWishlist moves a product, or the variant in {"sku"}, out of
+// the user's cart onto the wishlist {"wishlist_id"} and returns the wishlist
+func (wc *WishlistController) MoveCartItemToWishlist(w http.ResponseWriter, r *http.Request) {
+	productID, err := primitive.ObjectIDFromHex(mux.Vars(r)["product_id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		SKU        string             `json:"sku"`
+		WishlistID primitive.ObjectID `json:"wishlist_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	sku := strings.TrimSpace(body.SKU)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user := wc.userFromRequest(ctx, w, r)
+	if user == nil {
+		return
+	}
+	wishlist, err := wc.Wishlists.FindWishlistByID(ctx, body.WishlistID)
+	if err != nil || wishlist.UserID != user.ID {
+		http.Error(w, "Wishlist not found", http.StatusNotFound)
+		return
+	}
+	cart, err := wc.Carts.FindCartByUserID(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Cart not found", http.StatusNotFound)
+		return
+	}
+	if _, ok := cart.RemoveItem(productID, sku); !ok {
+		http.Error(w, "Item not in cart", http.StatusNotFound)
+		return
+	}
+
+	if _, ok := wishlist.Item(productID, sku); !ok {
+		item := models.WishlistItem{ProductID: productID, SKU: sku, AddedAt: time.Now()}
+		if product, err := wc.Products.FindProductByID(ctx, productID); err == nil {
+			price := product.PriceFor(sku)
+			item.SavedPrice = &price
+		}
+		wishlist.Items = append(wishlist.Items, item)
+		if !wc.updateWishlist(ctx, w, wishlist) {
+			return
+		}
+	}
+	cart.Touch(time.Now())
+	if err := wc.Carts.SaveCart(ctx, cart); err != nil {
+		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+		return
+	}
+	wc.writeWishlist(ctx, w, http.StatusOK, wishlist)
+}
+
+// GetSharedWishlist shows a public wishlist to anyone with its link
+func (wc *WishlistController) GetSharedWishlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	wishlist, err := wc.Wishlists.FindWishlistByShareToken(ctx, mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, "Wishlist not found", http.StatusNotFound)
+		return
+	}
+	lines, err := wc.wishlistLines(ctx, wishlist)
+	if err != nil {
+		http.Error(w, "Error pricing wishlist", http.StatusInternalServerError)
+		return
+	}
+	response := sharedWishlistResponse{Name: wishlist.Name, Lines: lines, UpdatedAt: wishlist.UpdatedAt}
+	if owner, err := wc.Users.FindUserByID(ctx, wishlist.UserID); err == nil {
+		response.Owner = owner.Name
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,155 @@
+package controllers_test
+
+import (
+	"go-ecommerce/models"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// wishlistView is the part of a wishlist response the wishlist tests look at
+type wishlistView struct {
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	Public   bool               `json:"public"`
+	Lines    []models.SavedLine `json:"lines"`
+	ShareURL string             `json:"share_url"`
+}
+
+func TestWishlists(t *testing.T) {
+	s := newShop(t)
+	s.expect(t, http.StatusUnauthorized, "GET", "/wishlists", "", nil, nil)
+	s.expect(t, http.StatusBadRequest, "POST", "/wishlists", s.customer, map[string]string{"name": " "}, nil)
+
+	var birthday, home wishlistView
+	s.expect(t, http.StatusCreated, "POST", "/wishlists", s.customer, map[string]string{"name": "Birthday"}, &birthday)
+	s.expect(t, http.StatusConflict, "POST", "/wishlists", s.customer, map[string]string{"name": "Birthday"}, nil)
+	s.expect(t, http.StatusCreated, "POST", "/wishlists", s.customer, map[string]string{"name": "Home"}, &home)
+	s.expect(t, http.StatusConflict, "PUT", "/wishlists/"+home.ID, s.customer, map[string]string{"name": "Birthday"}, nil)
+	s.expect(t, http.StatusOK, "PUT", "/wishlists/"+home.ID, s.customer, map[string]string{"name": "Kitchen"}, &home)
+	if home.Name != "Kitchen" || birthday.Public || birthday.ShareURL != "" {
+		t.Errorf("wishlists = %+v, %+v; want a private Birthday and Kitchen", birthday, home)
+	}
+
+	var lists []models.Wishlist
+	s.expect(t, http.StatusOK, "GET", "/wishlists", s.customer, nil, &lists)
+	if len(lists) != 2 || lists[0].Name != "Birthday" || lists[1].Name != "Kitchen" {
+		t.Errorf("lists = %+v, want Birthday and Kitchen", lists)
+	}
+
+	// Other users cannot see or change them
+	s.addUser(t, "other@example.com", "user")
+	other := s.login(t, "other@example.com")
+	s.expect(t, http.StatusNotFound, "GET", "/wishlists/"+birthday.ID, other, nil, nil)
+	s.expect(t, http.StatusNotFound, "DELETE", "/wishlists/"+birthday.ID, other, nil, nil)
+	s.expect(t, http.StatusOK, "GET", "/wishlists", other, nil, &lists)
+	if len(lists) != 0 {
+		t.Errorf("other user's lists = %+v, want none", lists)
+	}
+
+	s.expect(t, http.StatusOK, "DELETE", "/wishlists/"+home.ID, s.customer, nil, nil)
+	s.expect(t, http.StatusNotFound, "GET", "/wishlists/"+home.ID, s.customer, nil, nil)
+}
+
+func TestWishlistItems(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 0})
+	shirt := s.createProduct(t, map[string]interface{}{
+		"name": "Shirt", "price": 20,
+		"options":  []map[string]interface{}{{"name": "size", "values": []string{"S", "M"}}},
+		"variants": []map[string]interface{}{{"sku": "SHIRT-S", "options": map[string]string{"size": "S"}, "stock": 4}},
+	})
+	var list wishlistView
+	s.expect(t, http.StatusCreated, "POST", "/wishlists", s.customer, map[string]string{"name": "Birthday"}, &list)
+	items := "/wishlists/" + list.ID + "/items"
+
+	s.expect(t, http.StatusBadRequest, "POST", items, s.customer, map[string]interface{}{"product_id": shirt.ID}, nil)
+	s.expect(t, http.StatusBadRequest, "POST", items, s.customer, map[string]interface{}{"product_id": mug.ID, "sku": "MUG-XL"}, nil)
+	// Out of stock products can be wished for; adding one twice keeps one entry
+	s.expect(t, http.StatusOK, "POST", items, s.customer, map[string]interface{}{"product_id": mug.ID}, nil)
+	s.expect(t, http.StatusOK, "POST", items, s.customer, map[string]interface{}{"product_id": mug.ID}, nil)
+	s.expect(t, http.StatusOK, "POST", items, s.customer, map[string]interface{}{"product_id": shirt.ID, "sku": "SHIRT-S"}, &list)
+	if len(list.Lines) != 2 || list.Lines[0].InStock || !list.Lines[1].InStock || list.Lines[1].Options["size"] != "S" {
+		t.Fatalf("lines = %+v, want the sold-out mug and a small shirt in stock", list.Lines)
+	}
+
+	// A price drop is flagged; a deleted product stays on the list as unavailable
+	s.expect(t, http.StatusOK, "PUT", "/products/"+mug.ID.Hex(), s.admin, map[string]interface{}{"name": "Mug", "price": 7, "stock": 0}, nil)
+	s.expect(t, http.StatusOK, "DELETE", "/products/"+shirt.ID.Hex(), s.admin, nil, nil)
+	s.expect(t, http.StatusOK, "GET", "/wishlists/"+list.ID, s.customer, nil, &list)
+	if mugLine := list.Lines[0]; !mugLine.PriceDropped || *mugLine.Price != models.NewMoney(700, models.DefaultCurrency) || *mugLine.SavedPrice != models.NewMoney(1000, models.DefaultCurrency) {
+		t.Errorf("mug = %+v, want its drop from 10.00 to 7.00 flagged", mugLine)
+	}
+	if !list.Lines[1].Unavailable {
+		t.Errorf("shirt = %+v, want it unavailable", list.Lines[1])
+	}
+
+	s.expect(t, http.StatusOK, "DELETE", items+"/"+shirt.ID.Hex()+"?sku=SHIRT-S", s.customer, nil, nil)
+	s.expect(t, http.StatusNotFound, "DELETE", items+"/"+shirt.ID.Hex()+"?sku=SHIRT-S", s.customer, nil, nil)
+}
+
+func TestMoveBetweenWishlistAndCart(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 3})
+	var list wishlistView
+	s.expect(t, http.StatusCreated, "POST", "/wishlists", s.customer, map[string]string{"name": "Birthday"}, &list)
+	s.expect(t, http.StatusOK, "POST", "/wishlists/"+list.ID+"/items", s.customer, map[string]interface{}{"product_id": mug.ID}, nil)
+	toCart := "/wishlists/" + list.ID + "/items/" + mug.ID.Hex() + "/move-to-cart"
+
+	s.expect(t, http.StatusConflict, "POST", toCart, s.customer, map[string]int{"quantity": 4}, nil)
+	s.expect(t, http.StatusOK, "POST", toCart, s.customer, map[string]int{"quantity": 2}, &list)
+	if len(list.Lines) != 0 {
+		t.Errorf("lines = %+v, want the mug moved off the wishlist", list.Lines)
+	}
+	s.expect(t, http.StatusNotFound, "POST", toCart, s.customer, nil, nil)
+	var cart pricedCart
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if len(cart.Lines) != 1 || cart.Lines[0].Quantity != 2 {
+		t.Fatalf("cart lines = %+v, want two mugs", cart.Lines)
+	}
+
+	toWishlist := "/cart/items/" + mug.ID.Hex() + "/move-to-wishlist"
+	s.expect(t, http.StatusNotFound, "POST", toWishlist, s.customer, map[string]string{"wishlist_id": mug.ID.Hex()}, nil)
+	s.expect(t, http.StatusOK, "POST", toWishlist, s.customer, map[string]string{"wishlist_id": list.ID}, &list)
+	if len(list.Lines) != 1 || list.Lines[0].ProductID != mug.ID {
+		t.Errorf("lines = %+v, want the mug back on the wishlist", list.Lines)
+	}
+	s.expect(t, http.StatusOK, "GET", "/cart", s.customer, nil, &cart)
+	if len(cart.Lines) != 0 {
+		t.Errorf("cart lines = %+v, want none", cart.Lines)
+	}
+	s.expect(t, http.StatusNotFound, "POST", toWishlist, s.customer, map[string]string{"wishlist_id": list.ID}, nil)
+}
+
+func TestSharedWishlist(t *testing.T) {
+	s := newShop(t)
+	mug := s.createProduct(t, map[string]interface{}{"name": "Mug", "price": 10, "stock": 3})
+	var list wishlistView
+	s.expect(t, http.StatusCreated, "POST", "/wishlists", s.customer, map[string]interface{}{"name": "Birthday", "public": true}, &list)
+	s.expect(t, http.StatusOK, "POST", "/wishlists/"+list.ID+"/items", s.customer, map[string]interface{}{"product_id": mug.ID}, nil)
+	if !list.Public || !strings.Contains(list.ShareURL, "/wishlists/shared/") {
+		t.Fatalf("wishlist = %+v, want a share link", list)
+	}
+	link := list.ShareURL[strings.Index(list.ShareURL, "/wishlists/shared/"):]
+
+	// Anyone with the link sees it, without signing in
+	var shared struct {
+		Name   string             `json:"name"`
+		Owner  string             `json:"owner"`
+		Lines  []models.SavedLine `json:"lines"`
+		UserID string             `json:"user_id"`
+	}
+	s.expect(t, http.StatusOK, "GET", link, "", nil, &shared)
+	if shared.Name != "Birthday" || shared.Owner == "" || len(shared.Lines) != 1 || shared.UserID != "" {
+		t.Errorf("shared = %+v, want Birthday with the mug and its owner's name only", shared)
+	}
+	s.expect(t, http.StatusNotFound, "GET", "/wishlists/shared/forged", "", nil, nil)
+
+	// Making it private stops the link working; sharing again gives a new one
+	s.expect(t, http.StatusOK, "PUT", "/wishlists/"+list.ID, s.customer, map[string]interface{}{"name": "Birthday", "public": false}, &list)
+	s.expect(t, http.StatusNotFound, "GET", link, "", nil, nil)
+	s.expect(t, http.StatusOK, "PUT", "/wishlists/"+list.ID, s.customer, map[string]interface{}{"name": "Birthday", "public": true}, &list)
+	if strings.HasSuffix(list.ShareURL, link) {
+		t.Error("sharing again reused the old link")
+	}
+}
@@ -0,0 +1,76 @@
+package delivery
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"strings"
+	"time"
+)
+
+// HolidaySource lists the holidays observed in a state; store.HolidayStore satisfies it
+type HolidaySource interface {
+	ListHolidays(ctx context.Context, state string) ([]models.Holiday, error)
+}
+
+// Calendar estimates delivery in business days: Monday to Friday, less the
+// holidays observed at the destination. Orders are dispatched on the day
+// they are placed if that is a business day before Cutoff, and on the next
+// business day otherwise. A method's MinDays and MaxDays count business days
+// from dispatch.
+type Calendar struct {
+	Holidays HolidaySource
+	Cutoff   time.Duration  // Time of day, since midnight, after which orders wait for the next dispatch
+	Location *time.Location // Where the cutoff and the calendar days are reckoned
+}
+
+var _ Estimator = (*Calendar)(nil)
+
+// NewCalendar creates a Calendar reading holidays from source
+func NewCalendar(source HolidaySource, cutoff time.Duration, location *time.Location) *Calendar {
+	return &Calendar{Holidays: source, Cutoff: cutoff, Location: location}
+}
+
+// Estimate counts the method's business days from the order's dispatch day
+func (c *Calendar) Estimate(ctx context.Context, placed time.Time, address models.Address, method models.ShippingQuote) (models.DeliveryWindow, error) {
+	state := strings.ToUpper(strings.TrimSpace(address.State))
+	holidays, err := c.Holidays.ListHolidays(ctx, state)
+	if err != nil {
+		return models.DeliveryWindow{}, err
+	}
+	closed := make(map[string]bool, len(holidays))
+	for _, holiday := range holidays {
+		// Without a state only the holidays of every state are known to apply
+		if state != "" || holiday.State == "" {
+			closed[holiday.Date] = true
+		}
+	}
+	open := func(day time.Time) bool {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			return false
+		}
+		return !closed[day.Format(models.DateLayout)]
+	}
+
+	placed = placed.In(c.Location)
+	day := time.Date(placed.Year(), placed.Month(), placed.Day(), 0, 0, 0, 0, c.Location)
+	if placed.Sub(day) >= c.Cutoff {
+		day = day.AddDate(0, 0, 1)
+	}
+	for !open(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	// after returns the day n business days after from
+	after := func(from time.Time, n int) time.Time {
+		for n > 0 {
+			from = from.AddDate(0, 0, 1)
+			if open(from) {
+				n--
+			}
+		}
+		return from
+	}
+	earliest := after(day, method.MinDays)
+	latest := after(earliest, method.MaxDays-method.MinDays)
+	return models.NewDeliveryWindow(earliest, latest), nil
+}
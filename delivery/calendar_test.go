@@ -0,0 +1,59 @@
+package delivery
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+)
+
+// holidays is a HolidaySource over a fixed list
+type holidays []models.Holiday
+
+func (hs holidays) ListHolidays(ctx context.Context, state string) ([]models.Holiday, error) {
+	var observed []models.Holiday
+	for _, holiday := range hs {
+		if state == "" || holiday.State == "" || holiday.State == state {
+			observed = append(observed, holiday)
+		}
+	}
+	return observed, nil
+}
+
+func TestCalendarEstimate(t *testing.T) {
+	eastern := time.FixedZone("EST", -5*60*60)
+	calendar := NewCalendar(holidays{
+		{Name: "Thanksgiving", Date: "2026-11-26"},
+		{Name: "Day after Thanksgiving", Date: "2026-11-27", State: "CA"},
+	}, 14*time.Hour, eastern)
+
+	standard := models.ShippingQuote{MinDays: 1, MaxDays: 3}
+	sameDay := models.ShippingQuote{MinDays: 0, MaxDays: 0}
+	tests := []struct {
+		name     string
+		placed   time.Time
+		state    string
+		method   models.ShippingQuote
+		earliest string
+		latest   string
+	}{
+		{"before the cutoff", time.Date(2026, 10, 14, 9, 0, 0, 0, eastern), "NY", standard, "2026-10-15", "2026-10-19"},
+		{"after the cutoff on a Friday", time.Date(2026, 10, 16, 15, 0, 0, 0, eastern), "NY", standard, "2026-10-20", "2026-10-22"},
+		{"on a Saturday", time.Date(2026, 10, 17, 9, 0, 0, 0, eastern), "NY", standard, "2026-10-20", "2026-10-22"},
+		{"UTC time past the local cutoff", time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC), "NY", standard, "2026-10-20", "2026-10-22"},
+		{"same day", time.Date(2026, 10, 16, 9, 0, 0, 0, eastern), "NY", sameDay, "2026-10-16", "2026-10-16"},
+		{"over a holiday everywhere", time.Date(2026, 11, 25, 10, 0, 0, 0, eastern), "NY", standard, "2026-11-27", "2026-12-01"},
+		{"over a state holiday too", time.Date(2026, 11, 25, 10, 0, 0, 0, eastern), "ca", standard, "2026-11-30", "2026-12-02"},
+		{"without a state", time.Date(2026, 11, 25, 10, 0, 0, 0, eastern), "", standard, "2026-11-27", "2026-12-01"},
+	}
+	for _, tt := range tests {
+		window, err := calendar.Estimate(context.Background(), tt.placed, models.Address{State: tt.state}, tt.method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := window.Earliest.Format(models.DateLayout) + " " + window.Latest.Format(models.DateLayout)
+		if want := tt.earliest + " " + tt.latest; got != want {
+			t.Errorf("%s: Estimate() = %s, want %s", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+// Package delivery estimates when orders arrive. Estimator is the extension
+// point for a carrier's own estimates; Calendar is the built-in estimator
+// counting business days around weekends and an admin-managed holiday
+// calendar.
+package delivery
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"time"
+)
+
+// Estimator works out delivery windows
+type Estimator interface {
+	// Estimate returns the days an order placed at placed arrives in when
+	// shipped to address by the quoted method
+	Estimate(ctx context.Context, placed time.Time, address models.Address, method models.ShippingQuote) (models.DeliveryWindow, error)
+}
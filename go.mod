@@ -6,6 +6,7 @@ require (
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/gorilla/mux v1.8.1
 	github.com/joho/godotenv v1.5.1
+	github.com/keighl/postmark v0.0.0-20190821160221-28358b1a94e3
 	github.com/sendgrid/sendgrid-go v3.16.0+incompatible
 	go.mongodb.org/mongo-driver v1.17.1
 	golang.org/x/crypto v0.28.0
@@ -13,7 +14,6 @@ require (
 
 require (
 	github.com/golang/snappy v0.0.4 // indirect
-	github.com/keighl/postmark v0.0.0-20190821160221-28358b1a94e3 // indirect
 	github.com/klauspost/compress v1.17.10 // indirect
 	github.com/montanaflynn/stats v0.7.1 // indirect
 	github.com/sendgrid/rest v2.6.9+incompatible // indirect
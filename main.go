@@ -5,12 +5,19 @@ import (
 	"context"
 	"fmt"
 	"go-ecommerce/controllers"
+	"go-ecommerce/delivery"
 	"go-ecommerce/middleware"
+	"go-ecommerce/payments"
 	"go-ecommerce/routes"
+	"go-ecommerce/search"
+	"go-ecommerce/store"
+	"go-ecommerce/tax"
 	"go-ecommerce/utils"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -29,32 +36,135 @@ func main() {
 	// Initialize EmailService
 	emailService := utils.NewEmailService()
 
-	// Connect to MongoDB
-	client := utils.ConnectDB()
-	defer func() {
-		if err = client.Disconnect(context.TODO()); err != nil {
-			log.Fatal(err)
+	// Select the storage backend: MongoDB by default, or in-memory for local demos
+	var db store.Store
+	var index search.Index
+	if os.Getenv("STORE") == "memory" {
+		log.Println("Using in-memory store. Data will be lost on restart.")
+		db = store.NewMemory()
+		index = search.NewMemory()
+	} else {
+		// Connect to MongoDB
+		client := utils.ConnectDB()
+		defer func() {
+			if err = client.Disconnect(context.TODO()); err != nil {
+				log.Fatal(err)
+			}
+		}()
+		db = store.NewMongo(client)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		index, err = search.NewMongo(ctx, client)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to create product search index: %v", err)
 		}
-	}()
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8000"
+	}
+
+	// Select the payment provider. Only the local mock gateway ships today;
+	// real gateways implement payments.Provider. PAYMENT_PROVIDER must be set,
+	// so that a deploy which forgets it cannot take orders on the mock.
+	baseURL := os.Getenv("PUBLIC_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+	webhookSecret := []byte(os.Getenv("PAYMENT_WEBHOOK_SECRET"))
+	if len(webhookSecret) == 0 {
+		log.Println("PAYMENT_WEBHOOK_SECRET is not set. Payment webhooks will be rejected.")
+	}
+	var provider payments.Provider
+	var mockGateway *payments.Mock
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "":
+		log.Fatal("PAYMENT_PROVIDER is not set. Set it to mock to use the mock payment gateway.")
+	case "mock":
+		log.Println("Using the mock payment gateway. No real money will move.")
+		mockGateway = payments.NewMock(baseURL, webhookSecret)
+		provider = mockGateway
+	default:
+		log.Fatalf("Unknown payment provider %q", name)
+	}
+
+	// Tax comes from the admin-managed rate tables. PRICES_INCLUDE_TAX=true
+	// treats catalog prices as tax-inclusive, as is usual outside the US.
+	taxCalculator := tax.NewTable(db, os.Getenv("PRICES_INCLUDE_TAX") == "true")
+
+	// Delivery is estimated in business days, skipping weekends and the
+	// admin-managed holidays. Orders placed after ORDER_CUTOFF (HH:MM, 14:00
+	// by default) in SHOP_TIMEZONE dispatch on the next business day.
+	location, err := time.LoadLocation(os.Getenv("SHOP_TIMEZONE"))
+	if err != nil {
+		log.Fatalf("Invalid SHOP_TIMEZONE: %v", err)
+	}
+	cutoff := 14 * time.Hour
+	if value := os.Getenv("ORDER_CUTOFF"); value != "" {
+		at, err := time.Parse("15:04", value)
+		if err != nil {
+			log.Fatalf("Invalid ORDER_CUTOFF %q: want HH:MM", value)
+		}
+		cutoff = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+	deliveryCalendar := delivery.NewCalendar(db, cutoff, location)
+
+	// Let the auth middleware reject tokens from revoked sessions
+	middleware.Sessions = db
 
 	// Initialize controllers
-	userController := controllers.NewUserController(client, emailService)
-	productController := controllers.NewProductController(client)
-	cartController := controllers.NewCartController(client)
-	orderController := controllers.NewOrderController(client, emailService)
+	userController := controllers.NewUserController(db, emailService)
+	productController := controllers.NewProductController(db, index, emailService)
+	cartController := controllers.NewCartController(db, taxCalculator, emailService)
+	orderController := controllers.NewOrderController(db, emailService, provider, taxCalculator, deliveryCalendar)
+	promotionController := controllers.NewPromotionController(db)
+	taxController := controllers.NewTaxController(db)
+	shippingController := controllers.NewShippingController(db)
+	holidayController := controllers.NewHolidayController(db)
+	wishlistController := controllers.NewWishlistController(db)
+
+	// Return the stock of orders left unpaid past their reservation
+	go orderController.SweepReservations(context.Background(), time.Minute)
+
+	// Email admins the products at or below their reorder threshold every
+	// LOW_STOCK_DIGEST_INTERVAL (a duration such as 12h; daily by default)
+	digestInterval := 24 * time.Hour
+	if value := os.Getenv("LOW_STOCK_DIGEST_INTERVAL"); value != "" {
+		digestInterval, err = time.ParseDuration(value)
+		if err != nil || digestInterval <= 0 {
+			log.Fatalf("Invalid LOW_STOCK_DIGEST_INTERVAL %q: want a duration such as 24h", value)
+		}
+	}
+	go productController.WatchStock(context.Background(), digestInterval)
+
+	// Remind customers of carts left untouched for CART_REMINDER_AFTER (a
+	// duration, 24h by default), at most CART_REMINDER_LIMIT times (2)
+	if value := os.Getenv("CART_REMINDER_AFTER"); value != "" {
+		cartController.ReminderAfter, err = time.ParseDuration(value)
+		if err != nil || cartController.ReminderAfter <= 0 {
+			log.Fatalf("Invalid CART_REMINDER_AFTER %q: want a duration such as 24h", value)
+		}
+	}
+	if value := os.Getenv("CART_REMINDER_LIMIT"); value != "" {
+		cartController.ReminderLimit, err = strconv.Atoi(value)
+		if err != nil || cartController.ReminderLimit < 0 {
+			log.Fatalf("Invalid CART_REMINDER_LIMIT %q: want a whole number", value)
+		}
+	}
+	go cartController.WatchCarts(context.Background(), 15*time.Minute)
+
 	// Set up the router
 	router := mux.NewRouter()
 	// Register routes
-	routes.RegisterRoutes(router, userController, productController, cartController, orderController)
-
-	// Apply middleware for authentication (optional)
-	router.Use(middleware.AuthMiddleware)
+	routes.RegisterRoutes(router, userController, productController, cartController, orderController, promotionController, taxController, shippingController, holidayController, wishlistController)
+	if mockGateway != nil {
+		// Stands in for the card issuer's 3-D Secure page
+		router.HandleFunc("/payments/mock/3ds/{id}", mockGateway.ChallengeHandler).Methods("GET", "POST")
+	}
 
 	// Start the server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8000"
-	}
 	fmt.Printf("Server is running on port %s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, router))
 }
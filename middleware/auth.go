@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
+	"go-ecommerce/store"
 	"go-ecommerce/utils"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // Key type for context
@@ -14,6 +17,10 @@ type contextKey string
 
 const UserContextKey = contextKey("user")
 
+// Sessions is consulted to reject access tokens whose session has been revoked.
+// It is set once at startup; when nil, revocation is not checked.
+var Sessions store.SessionStore
+
 // AuthMiddleware verifies JWT tokens and attaches user information to the context
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -22,29 +29,54 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			http.Error(w, "Authorization header missing", http.StatusUnauthorized)
 			return
 		}
+		authenticate(next, w, r, authHeader)
+	})
+}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+// OptionalAuthMiddleware attaches user information to the context like
+// AuthMiddleware when the request carries a token, and lets anonymous
+// requests through. A token that is present but invalid is still rejected.
+func OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
+		authenticate(next, w, r, authHeader)
+	})
+}
 
-		tokenStr := parts[1]
-		claims := &utils.Claims{}
+// authenticate checks the bearer token in authHeader and serves the request
+// with its claims in the context
+func authenticate(next http.Handler, w http.ResponseWriter, r *http.Request, authHeader string) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+		return
+	}
 
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return utils.JwtKey, nil
-		})
+	tokenStr := parts[1]
+	claims := &utils.Claims{}
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		// Attach user information to the request context
-		ctx := context.WithValue(r.Context(), UserContextKey, claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return utils.JwtKey, nil
 	})
+
+	if err != nil || !token.Valid {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	// Only access tokens carry a session; this also rejects verification tokens
+	if !sessionActive(r.Context(), claims.SessionID) {
+		http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+		return
+	}
+
+	// Attach user information to the request context
+	ctx := context.WithValue(r.Context(), UserContextKey, claims)
+	next.ServeHTTP(w, r.WithContext(ctx))
 }
 
 // AdminMiddleware ensures that the user has admin privileges
@@ -58,3 +90,22 @@ func AdminMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// sessionActive reports whether the session behind an access token is still live
+func sessionActive(ctx context.Context, sessionID string) bool {
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false
+	}
+	if Sessions == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	session, err := Sessions.FindSessionByID(ctx, id)
+	if err != nil {
+		return false
+	}
+	return session.RevokedAt == nil
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"go-ecommerce/store"
+	"go-ecommerce/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	ctx := context.Background()
+	db := store.NewMemory()
+	Sessions = db
+	t.Cleanup(func() { Sessions = nil })
+
+	live := models.Session{UserID: primitive.NewObjectID()}
+	revoked := models.Session{UserID: live.UserID}
+	for _, session := range []*models.Session{&live, &revoked} {
+		if err := db.CreateSession(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.RevokeSession(ctx, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(sessionID string) string {
+		t.Helper()
+		signed, err := utils.GenerateAccessToken("user@example.com", "user", sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+	verification, err := utils.GenerateJWT("user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"live session", token(live.ID.Hex()), http.StatusOK},
+		{"revoked session", token(revoked.ID.Hex()), http.StatusUnauthorized},
+		{"unknown session", token(primitive.NewObjectID().Hex()), http.StatusUnauthorized},
+		// Verification tokens carry no session and must not authenticate
+		{"no session", "Bearer " + verification, http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"not bearer", "Basic abc", http.StatusUnauthorized},
+		{"bad signature", token(live.ID.Hex()) + "x", http.StatusUnauthorized},
+	}
+	handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(UserContextKey).(*utils.Claims); !ok {
+			t.Error("claims missing from the request context")
+		}
+	}))
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/profile", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	var claims *utils.Claims
+	handler := OptionalAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = r.Context().Value(UserContextKey).(*utils.Claims)
+	}))
+
+	// Anonymous requests go through without claims
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/cart", nil))
+	if rec.Code != http.StatusOK || claims != nil {
+		t.Errorf("anonymous: status = %d, claims = %+v; want 200 without claims", rec.Code, claims)
+	}
+
+	// A bad token is not mistaken for an anonymous visit
+	req := httptest.NewRequest("GET", "/cart", nil)
+	req.Header.Set("Authorization", "Bearer nonsense")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad token: status = %d, want 401", rec.Code)
+	}
+
+	// Valid tokens attach their claims
+	signed, err := utils.GenerateAccessToken("user@example.com", "user", primitive.NewObjectID().Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest("GET", "/cart", nil)
+	req.Header.Set("Authorization", "Bearer "+signed)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || claims == nil || claims.Email != "user@example.com" {
+		t.Errorf("signed in: status = %d, claims = %+v", rec.Code, claims)
+	}
+}
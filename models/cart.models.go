@@ -1,13 +1,25 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrNotEnoughStock is returned when a cart line asks for more units than
+// are available
+var ErrNotEnoughStock = errors.New("not enough stock")
+
 // CartItem represents an item in the cart
 type CartItem struct {
 	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	SKU       string             `bson:"sku,omitempty" json:"sku,omitempty"` // Required when the product has variants
 	Quantity  int                `bson:"quantity" json:"quantity"`
+	// UnitPrice is the price the customer saw when they last added or
+	// changed the item, so the cart can warn them if it has changed since
+	UnitPrice *Money `bson:"unit_price,omitempty" json:"unit_price,omitempty"`
 }
 
 // Cart represents a user's shopping cart
@@ -15,4 +27,211 @@ type Cart struct {
 	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
 	Items  []CartItem         `bson:"items" json:"items"`
+	// SavedForLater holds items the customer moved out of the cart to buy
+	// another time; they are not priced into the cart or ordered with it
+	SavedForLater []CartItem `bson:"saved_for_later,omitempty" json:"saved_for_later,omitempty"`
+	// CouponCode is the coupon the customer applied; it is checked again
+	// whenever the cart is priced
+	CouponCode string    `bson:"coupon_code,omitempty" json:"coupon_code,omitempty"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"` // Last change by the customer
+
+	// RemindersSent counts the abandoned-cart emails sent about the cart;
+	// LastRemindedAt is when the latest went out
+	RemindersSent  int        `bson:"reminders_sent" json:"reminders_sent,omitempty"`
+	LastRemindedAt *time.Time `bson:"last_reminded_at,omitempty" json:"last_reminded_at,omitempty"`
+}
+
+// CartRecoveryStats reports how abandoned-cart reminders are doing: how many
+// carts were sent reminders and how many of those went on to be ordered
+type CartRecoveryStats struct {
+	RemindedCarts    int     `json:"reminded_carts"`
+	RemindersSent    int     `json:"reminders_sent"`
+	RecoveredCarts   int     `json:"recovered_carts"` // Ordered after a reminder, not counting cancelled orders
+	RecoveryRate     float64 `json:"recovery_rate"`   // RecoveredCarts / RemindedCarts
+	RecoveredRevenue Money   `json:"recovered_revenue"`
+}
+
+// Rate works out RecoveryRate from the counts
+func (s *CartRecoveryStats) Rate() {
+	s.RecoveryRate = 0
+	if s.RemindedCarts > 0 {
+		s.RecoveryRate = float64(s.RecoveredCarts) / float64(s.RemindedCarts)
+	}
+}
+
+// Touch records a change by the customer at the given time
+func (c *Cart) Touch(at time.Time) {
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = at
+	}
+	c.UpdatedAt = at
+}
+
+// Abandoned reports whether a reminder is due: the cart holds items, has
+// sent fewer than limit reminders, and neither the customer nor an earlier
+// reminder has touched it since idleSince
+func (c Cart) Abandoned(idleSince time.Time, limit int) bool {
+	if len(c.Items) == 0 || c.RemindersSent >= limit || c.UpdatedAt.IsZero() || !c.UpdatedAt.Before(idleSince) {
+		return false
+	}
+	return c.LastRemindedAt == nil || c.LastRemindedAt.Before(idleSince)
+}
+
+// CartWarningCode says what changed about a cart item since it was added
+type CartWarningCode string
+
+const (
+	// CartItemUnavailable marks an item whose product or variant was removed
+	CartItemUnavailable CartWarningCode = "unavailable"
+	// CartItemOutOfStock marks an item none of which can be bought now
+	CartItemOutOfStock CartWarningCode = "out_of_stock"
+	// CartItemLowStock marks an item with fewer units available than the cart holds
+	CartItemLowStock CartWarningCode = "low_stock"
+	// CartItemPriceChanged marks an item whose price changed since it was added
+	CartItemPriceChanged CartWarningCode = "price_changed"
+)
+
+// CartWarning tells the customer about a cart item they should look at
+// before checking out
+type CartWarning struct {
+	ProductID primitive.ObjectID `json:"product_id"`
+	SKU       string             `json:"sku,omitempty"`
+	Code      CartWarningCode    `json:"code"`
+	Message   string             `json:"message"`
+}
+
+// Item returns the cart's line for a product, or one variant of it
+func (c *Cart) Item(productID primitive.ObjectID, sku string) (*CartItem, bool) {
+	for i := range c.Items {
+		if c.Items[i].ProductID == productID && c.Items[i].SKU == sku {
+			return &c.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// SavedItem returns the cart's saved-for-later line for a product, or one
+// variant of it
+func (c *Cart) SavedItem(productID primitive.ObjectID, sku string) (*CartItem, bool) {
+	for i := range c.SavedForLater {
+		if c.SavedForLater[i].ProductID == productID && c.SavedForLater[i].SKU == sku {
+			return &c.SavedForLater[i], true
+		}
+	}
+	return nil, false
+}
+
+// RemoveItem takes the line for a product, or one variant of it, out of
+// the cart and returns it
+func (c *Cart) RemoveItem(productID primitive.ObjectID, sku string) (CartItem, bool) {
+	return removeCartItem(&c.Items, productID, sku)
+}
+
+// RemoveSavedItem takes a line out of the cart's saved-for-later section
+// and returns it
+func (c *Cart) RemoveSavedItem(productID primitive.ObjectID, sku string) (CartItem, bool) {
+	return removeCartItem(&c.SavedForLater, productID, sku)
+}
+
+func removeCartItem(items *[]CartItem, productID primitive.ObjectID, sku string) (CartItem, bool) {
+	for i, item := range *items {
+		if item.ProductID == productID && item.SKU == sku {
+			*items = append((*items)[:i:i], (*items)[i+1:]...)
+			return item, true
+		}
+	}
+	return CartItem{}, false
+}
+
+// SaveForLater moves a line from the cart to its saved-for-later section,
+// adding to the quantity of a line already saved there
+func (c *Cart) SaveForLater(productID primitive.ObjectID, sku string) bool {
+	item, ok := c.RemoveItem(productID, sku)
+	if !ok {
+		return false
+	}
+	if saved, ok := c.SavedItem(productID, sku); ok {
+		saved.Quantity += item.Quantity
+		saved.UnitPrice = item.UnitPrice
+		return true
+	}
+	c.SavedForLater = append(c.SavedForLater, item)
+	return true
+}
+
+// Merge moves a guest cart's items, and those it saved for later, into c
+// when the guest signs in. An item already in c keeps the larger of the two
+// quantities rather than their sum, so something picked again before
+// signing in is not doubled; the rest are added after c's own items.
+func (c *Cart) Merge(guest Cart) {
+	for _, item := range guest.Items {
+		if existing, ok := c.Item(item.ProductID, item.SKU); ok {
+			existing.Quantity = max(existing.Quantity, item.Quantity)
+			continue
+		}
+		c.Items = append(c.Items, item)
+	}
+	for _, item := range guest.SavedForLater {
+		if existing, ok := c.SavedItem(item.ProductID, item.SKU); ok {
+			existing.Quantity = max(existing.Quantity, item.Quantity)
+			continue
+		}
+		c.SavedForLater = append(c.SavedForLater, item)
+	}
+}
+
+// CheckCartQuantity checks that a cart may hold quantity units of the
+// product, or of its variant with the given SKU: the quantity is positive, a
+// variant is chosen exactly when the product has variants, and that many
+// units are available. A shortage is reported as ErrNotEnoughStock.
+func (p Product) CheckCartQuantity(sku string, quantity int) error {
+	if quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+	if err := p.CheckVariant(sku); err != nil {
+		return err
+	}
+	if available := p.AvailableFor(sku); quantity > available {
+		return fmt.Errorf("%w: only %d available", ErrNotEnoughStock, available)
+	}
+	return nil
+}
+
+// CheckVariant checks that a variant is chosen, by its SKU, exactly when
+// the product has variants
+func (p Product) CheckVariant(sku string) error {
+	if p.HasVariants() {
+		if _, ok := p.Variant(sku); !ok {
+			return errors.New("choose a valid variant")
+		}
+	} else if sku != "" {
+		return errors.New("product has no variants")
+	}
+	return nil
+}
+
+// CartWarnings returns what the customer should know about a cart item of
+// the product before checking out: that its variant is gone, that too few
+// units are left, or that its price changed since it was added
+func (p Product) CartWarnings(item CartItem) []CartWarning {
+	warn := func(code CartWarningCode, message string) CartWarning {
+		return CartWarning{ProductID: item.ProductID, SKU: item.SKU, Code: code, Message: message}
+	}
+	if _, ok := p.Variant(item.SKU); p.HasVariants() && !ok {
+		return []CartWarning{warn(CartItemUnavailable, p.Name+" is no longer sold in this variant")}
+	}
+
+	var warnings []CartWarning
+	switch available := p.AvailableFor(item.SKU); {
+	case available == 0:
+		warnings = append(warnings, warn(CartItemOutOfStock, p.Name+" is out of stock"))
+	case available < item.Quantity:
+		warnings = append(warnings, warn(CartItemLowStock, fmt.Sprintf("Only %d of %s left in stock", available, p.Name)))
+	}
+	if price := p.PriceFor(item.SKU); item.UnitPrice != nil && *item.UnitPrice != price {
+		warnings = append(warnings, warn(CartItemPriceChanged,
+			fmt.Sprintf("The price of %s changed from %s to %s", p.Name, item.UnitPrice, price)))
+	}
+	return warnings
 }
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMergeCart(t *testing.T) {
+	mug, shirt := primitive.NewObjectID(), primitive.NewObjectID()
+	cart := Cart{
+		Items: []CartItem{{ProductID: mug, Quantity: 1}, {ProductID: shirt, SKU: "SHIRT-S", Quantity: 3}},
+	}
+	guest := Cart{
+		Items: []CartItem{
+			{ProductID: mug, Quantity: 2},
+			{ProductID: shirt, SKU: "SHIRT-S", Quantity: 1},
+			{ProductID: shirt, SKU: "SHIRT-M", Quantity: 1},
+		},
+	}
+	cart.SavedForLater = []CartItem{{ProductID: mug, Quantity: 1}}
+	guest.SavedForLater = []CartItem{{ProductID: mug, Quantity: 4}, {ProductID: shirt, SKU: "SHIRT-M", Quantity: 1}}
+	cart.Merge(guest)
+
+	want := []CartItem{
+		{ProductID: mug, Quantity: 2},
+		{ProductID: shirt, SKU: "SHIRT-S", Quantity: 3},
+		{ProductID: shirt, SKU: "SHIRT-M", Quantity: 1},
+	}
+	if len(cart.Items) != len(want) {
+		t.Fatalf("items = %+v, want %+v", cart.Items, want)
+	}
+	for i := range want {
+		if cart.Items[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, cart.Items[i], want[i])
+		}
+	}
+	if len(cart.SavedForLater) != 2 || cart.SavedForLater[0].Quantity != 4 || cart.SavedForLater[1].SKU != "SHIRT-M" {
+		t.Errorf("saved = %+v, want four mugs and the guest's shirt", cart.SavedForLater)
+	}
+}
+
+func TestSaveForLater(t *testing.T) {
+	mug, plate := primitive.NewObjectID(), primitive.NewObjectID()
+	cart := Cart{
+		Items:         []CartItem{{ProductID: mug, Quantity: 2}, {ProductID: plate, Quantity: 1}},
+		SavedForLater: []CartItem{{ProductID: mug, Quantity: 1}},
+	}
+	if cart.SaveForLater(primitive.NewObjectID(), "") {
+		t.Error("SaveForLater of an item not in the cart succeeded")
+	}
+	if !cart.SaveForLater(mug, "") || !cart.SaveForLater(plate, "") {
+		t.Fatal("SaveForLater failed")
+	}
+	if len(cart.Items) != 0 {
+		t.Errorf("items = %+v, want none left", cart.Items)
+	}
+	if saved, ok := cart.SavedItem(mug, ""); !ok || saved.Quantity != 3 || len(cart.SavedForLater) != 2 {
+		t.Errorf("saved = %+v, want the mugs added to those already saved and the plate", cart.SavedForLater)
+	}
+
+	if item, ok := cart.RemoveSavedItem(plate, ""); !ok || item.Quantity != 1 {
+		t.Errorf("RemoveSavedItem = %+v, %v; want the plate", item, ok)
+	}
+	if _, ok := cart.RemoveSavedItem(plate, ""); ok {
+		t.Error("RemoveSavedItem removed the plate twice")
+	}
+}
+
+func TestCheckCartQuantity(t *testing.T) {
+	p := shirt()
+	p.Variants[1].Reserved = 1
+	tests := []struct {
+		sku      string
+		quantity int
+		ok       bool
+	}{
+		{"SHIRT-M", 1, true},
+		{"SHIRT-M", 2, false}, // One of the two is reserved
+		{"SHIRT-S", 1, false},
+		{"SHIRT-M", 0, false},
+		{"", 1, false},
+		{"SHIRT-XL", 1, false},
+	}
+	for _, tt := range tests {
+		if err := p.CheckCartQuantity(tt.sku, tt.quantity); (err == nil) != tt.ok {
+			t.Errorf("CheckCartQuantity(%q, %d) = %v, want ok %v", tt.sku, tt.quantity, err, tt.ok)
+		}
+	}
+	if err := p.CheckCartQuantity("SHIRT-S", 1); !errors.Is(err, ErrNotEnoughStock) {
+		t.Errorf("sold-out variant error = %v, want ErrNotEnoughStock", err)
+	}
+}
+
+func TestCartWarnings(t *testing.T) {
+	p := shirt()
+	old := NewMoney(2000, DefaultCurrency)
+	codes := func(item CartItem) []CartWarningCode {
+		var codes []CartWarningCode
+		for _, w := range p.CartWarnings(item) {
+			codes = append(codes, w.Code)
+		}
+		return codes
+	}
+
+	if got := codes(CartItem{SKU: "SHIRT-M", Quantity: 3, UnitPrice: &old}); len(got) != 2 || got[0] != CartItemLowStock || got[1] != CartItemPriceChanged {
+		t.Errorf("warnings for SHIRT-M = %v, want low stock and a price change", got)
+	}
+	if got := codes(CartItem{SKU: "SHIRT-S", Quantity: 1, UnitPrice: &old}); len(got) != 1 || got[0] != CartItemOutOfStock {
+		t.Errorf("warnings for SHIRT-S = %v, want out of stock", got)
+	}
+	if got := codes(CartItem{SKU: "SHIRT-XL", Quantity: 1}); len(got) != 1 || got[0] != CartItemUnavailable {
+		t.Errorf("warnings for SHIRT-XL = %v, want unavailable", got)
+	}
+	// Items added before prices were kept get no price warning
+	if got := codes(CartItem{SKU: "SHIRT-M", Quantity: 1}); len(got) != 0 {
+		t.Errorf("warnings for an untracked price = %v, want none", got)
+	}
+}
+
+func TestCartAbandoned(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	cart := Cart{Items: []CartItem{{ProductID: primitive.NewObjectID(), Quantity: 1}}}
+	cart.Touch(start)
+	cart.Touch(start.Add(time.Hour))
+	if !cart.CreatedAt.Equal(start) || !cart.UpdatedAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("timestamps = %v, %v; want created at the first touch and updated at the last", cart.CreatedAt, cart.UpdatedAt)
+	}
+
+	// A day without changes makes a reminder due, a day after the last one
+	day := 24 * time.Hour
+	due := func(now time.Time, limit int) bool { return cart.Abandoned(now.Add(-day), limit) }
+	if due(start.Add(2*time.Hour), 2) {
+		t.Error("Abandoned right after an update")
+	}
+	if !due(start.Add(day+2*time.Hour), 2) {
+		t.Error("not Abandoned a day after the last update")
+	}
+	reminded := start.Add(day + 2*time.Hour)
+	cart.RemindersSent, cart.LastRemindedAt = 1, &reminded
+	if due(reminded.Add(time.Hour), 2) {
+		t.Error("Abandoned again right after a reminder")
+	}
+	if !due(reminded.Add(day+time.Hour), 2) {
+		t.Error("not Abandoned a day after the first reminder")
+	}
+	if due(reminded.Add(day+time.Hour), 1) {
+		t.Error("Abandoned past the reminder limit")
+	}
+	cart.Items = nil
+	if due(reminded.Add(day+time.Hour), 2) {
+		t.Error("empty cart Abandoned")
+	}
+}
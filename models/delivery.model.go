@@ -0,0 +1,151 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DateLayout is how calendar dates are written, e.g. "2026-10-16"
+const DateLayout = "2006-01-02"
+
+// Holiday is an admin-managed day on which nothing is dispatched or
+// delivered, in one state or, with no state, everywhere
+type Holiday struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name      string             `bson:"name" json:"name"`                       // e.g. "Thanksgiving"
+	Date      string             `bson:"date" json:"date"`                       // As DateLayout
+	State     string             `bson:"state,omitempty" json:"state,omitempty"` // Matched against Address.State; empty for every state
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Normalize trims the holiday's text fields and upper-cases its state
+func (h *Holiday) Normalize() {
+	h.Name = strings.TrimSpace(h.Name)
+	h.Date = strings.TrimSpace(h.Date)
+	h.State = strings.ToUpper(strings.TrimSpace(h.State))
+}
+
+// Validate checks that the holiday is named and falls on a real date
+func (h Holiday) Validate() error {
+	if h.Name == "" {
+		return errors.New("name is required")
+	}
+	if _, err := time.Parse(DateLayout, h.Date); err != nil {
+		return errors.New("date must be a date such as 2026-12-25")
+	}
+	return nil
+}
+
+// DeliveryWindow is the range of days an order is expected to arrive in.
+// Earliest and Latest are calendar days, held as midnight UTC so they do not
+// shift with the reader's time zone. They are written to JSON as
+// {"earliest": "2026-10-20", "latest": "2026-10-22"}.
+type DeliveryWindow struct {
+	Earliest time.Time `bson:"earliest"`
+	Latest   time.Time `bson:"latest"`
+}
+
+// NewDeliveryWindow returns the window between the calendar days of earliest
+// and latest, wherever those times are
+func NewDeliveryWindow(earliest, latest time.Time) DeliveryWindow {
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return DeliveryWindow{Earliest: day(earliest), Latest: day(latest)}
+}
+
+// String describes the window as "2026-10-20", or "2026-10-20 to 2026-10-22"
+// when it spans several days
+func (w DeliveryWindow) String() string {
+	earliest, latest := formatDate(w.Earliest), formatDate(w.Latest)
+	if earliest == latest {
+		return latest
+	}
+	return earliest + " to " + latest
+}
+
+// formatDate writes t as DateLayout, or "" if it is unset
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DateLayout)
+}
+
+// deliveryWindowJSON is the wire form of DeliveryWindow
+type deliveryWindowJSON struct {
+	Earliest string `json:"earliest"`
+	Latest   string `json:"latest"`
+}
+
+// MarshalJSON writes {"earliest": "2026-10-20", "latest": "2026-10-22"}
+func (w DeliveryWindow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(deliveryWindowJSON{formatDate(w.Earliest), formatDate(w.Latest)})
+}
+
+// UnmarshalJSON reads {"earliest": "2026-10-20", "latest": "2026-10-22"}
+func (w *DeliveryWindow) UnmarshalJSON(data []byte) error {
+	var wire deliveryWindowJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	parse := func(s string) (time.Time, error) {
+		if s == "" {
+			return time.Time{}, nil
+		}
+		return time.Parse(DateLayout, s)
+	}
+	earliest, err := parse(wire.Earliest)
+	if err != nil {
+		return err
+	}
+	latest, err := parse(wire.Latest)
+	if err != nil {
+		return err
+	}
+	*w = DeliveryWindow{Earliest: earliest, Latest: latest}
+	return nil
+}
+
+// UnmarshalBSONValue reads a stored window. Orders placed before delivery
+// windows kept a single "expected by" date string, which is read as a window
+// of that one day.
+func (w *DeliveryWindow) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bson.TypeEmbeddedDocument:
+		type plain DeliveryWindow // Without this method, to avoid recursing
+		var doc plain
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		*w = DeliveryWindow(doc)
+		return nil
+	case bson.TypeString:
+		var s string
+		if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&s); err != nil {
+			return err
+		}
+		*w = DeliveryWindow{}
+		if s == "" {
+			return nil
+		}
+		day, err := time.Parse(DateLayout, s)
+		if err != nil {
+			return fmt.Errorf("delivery date %q: %w", s, err)
+		}
+		*w = DeliveryWindow{Earliest: day, Latest: day}
+		return nil
+	case bson.TypeNull, bson.TypeUndefined:
+		*w = DeliveryWindow{}
+		return nil
+	}
+	return fmt.Errorf("cannot decode %v into a delivery window", t)
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDeliveryWindowJSON(t *testing.T) {
+	window := NewDeliveryWindow(time.Date(2026, 10, 20, 23, 0, 0, 0, time.FixedZone("PDT", -7*60*60)), time.Date(2026, 10, 22, 8, 0, 0, 0, time.UTC))
+	data, err := json.Marshal(window)
+	if err != nil || string(data) != `{"earliest":"2026-10-20","latest":"2026-10-22"}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+	var decoded DeliveryWindow
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != window {
+		t.Errorf("Unmarshal = %+v, %v; want %+v", decoded, err, window)
+	}
+	if got := window.String(); got != "2026-10-20 to 2026-10-22" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestDeliveryWindowBSON(t *testing.T) {
+	type order struct {
+		DeliveryDate DeliveryWindow `bson:"delivery_date"`
+	}
+	window := NewDeliveryWindow(time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 22, 0, 0, 0, 0, time.UTC))
+	data, err := bson.Marshal(order{window})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded order
+	if err := bson.Unmarshal(data, &decoded); err != nil || !decoded.DeliveryDate.Latest.Equal(window.Latest) {
+		t.Errorf("Unmarshal = %+v, %v; want %+v", decoded, err, window)
+	}
+
+	// Older orders stored a single expected-by date
+	data, err = bson.Marshal(bson.M{"delivery_date": "2026-10-22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bson.Unmarshal(data, &decoded); err != nil || decoded.DeliveryDate.String() != "2026-10-22" {
+		t.Errorf("Unmarshal of a legacy date = %+v, %v; want 2026-10-22", decoded, err)
+	}
+}
+
+func TestHolidayValidate(t *testing.T) {
+	tests := []struct {
+		holiday Holiday
+		valid   bool
+	}{
+		{Holiday{Name: "Christmas", Date: "2026-12-25"}, true},
+		{Holiday{Name: "Cesar Chavez Day", Date: "2026-03-31", State: "CA"}, true},
+		{Holiday{Date: "2026-12-25"}, false},
+		{Holiday{Name: "Christmas", Date: "12/25/2026"}, false},
+		{Holiday{Name: "Christmas", Date: "2026-02-30"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.holiday.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.holiday, err, tt.valid)
+		}
+	}
+}
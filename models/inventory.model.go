@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockReason says why a product's on-hand stock changed
+type StockReason string
+
+const (
+	// StockOpening is the stock a product was created with
+	StockOpening StockReason = "opening"
+	// StockSale takes the units of a paid order out of stock
+	StockSale StockReason = "sale"
+	// StockCancellation puts the units of a cancelled paid order back
+	StockCancellation StockReason = "cancellation"
+	// StockReturn puts units a customer sent back into stock
+	StockReturn StockReason = "return"
+	// StockAdjustment corrects stock by hand, e.g. after a count or damage
+	StockAdjustment StockReason = "adjustment"
+	// StockRestock adds units received from a supplier
+	StockRestock StockReason = "restock"
+)
+
+// Manual reports whether admins may record movements of this reason
+// themselves; the others are written by product edits and orders
+func (r StockReason) Manual() bool {
+	switch r {
+	case StockReturn, StockAdjustment, StockRestock:
+		return true
+	}
+	return false
+}
+
+// StockMovement is one entry of the inventory ledger. Entries are never
+// changed or removed, so summing a product's deltas gives its stock.
+type StockMovement struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	SKU       string             `bson:"sku,omitempty" json:"sku,omitempty"` // Set for a variant's stock
+	Delta     int                `bson:"delta" json:"delta"`
+	Balance   int                `bson:"balance" json:"balance"` // Stock after the movement, set by the store
+	Reason    StockReason        `bson:"reason" json:"reason"`
+	Actor     string             `bson:"actor" json:"actor"`                             // Email of whoever made the change, or "system"
+	Reference string             `bson:"reference,omitempty" json:"reference,omitempty"` // e.g. an order ID or supplier invoice number
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Normalize trims the movement's text fields
+func (m *StockMovement) Normalize() {
+	m.SKU = strings.TrimSpace(m.SKU)
+	m.Reason = StockReason(strings.ToLower(strings.TrimSpace(string(m.Reason))))
+	m.Reference = strings.TrimSpace(m.Reference)
+	m.Note = strings.TrimSpace(m.Note)
+}
+
+// Validate checks a movement an admin records by hand: returns and restocks
+// add stock, and an adjustment must change it
+func (m StockMovement) Validate() error {
+	if !m.Reason.Manual() {
+		return errors.New("reason must be return, adjustment or restock")
+	}
+	if m.Delta == 0 {
+		return errors.New("delta must not be zero")
+	}
+	if m.Reason != StockAdjustment && m.Delta < 0 {
+		return errors.New("returns and restocks must add stock")
+	}
+	return nil
+}
+
+// StockSubscription asks for an email when a sold-out product, or one of its
+// variants, can be bought again. It is used once: the store marks it
+// notified when the email goes out.
+type StockSubscription struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ProductID  primitive.ObjectID `bson:"product_id" json:"product_id"`
+	SKU        string             `bson:"sku" json:"sku,omitempty"` // Always stored so pending lookups can match ""
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Email      string             `bson:"email" json:"email"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	NotifiedAt *time.Time         `bson:"notified_at,omitempty" json:"notified_at,omitempty"`
+}
+
+// StockCount compares the stock recorded on a product, or one of its
+// variants, with the sum of its ledger movements
+type StockCount struct {
+	SKU      string `json:"sku,omitempty"`
+	Recorded int    `json:"recorded"`
+	Ledger   int    `json:"ledger"`
+}
+
+// StockChanges returns the movements, without product or reason, that take
+// old's stock figures to p's, with their balances: the base stock and each
+// variant's, matched by SKU.
+// Variants p dropped are brought to zero so their ledger still sums to their
+// stock.
+func (p Product) StockChanges(old Product) []StockMovement {
+	var changes []StockMovement
+	add := func(sku string, delta int) {
+		if delta != 0 {
+			changes = append(changes, StockMovement{SKU: sku, Delta: delta, Balance: p.StockFor(sku)})
+		}
+	}
+	add("", p.Stock-old.Stock)
+	for _, v := range p.Variants {
+		add(v.SKU, v.Stock-old.StockFor(v.SKU))
+	}
+	for _, v := range old.Variants {
+		if _, ok := p.Variant(v.SKU); !ok {
+			add(v.SKU, -v.Stock)
+		}
+	}
+	return changes
+}
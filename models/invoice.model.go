@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invoice is the customer-facing breakdown of what an order cost. It is built
+// from the order's line snapshots, never from the current catalog.
+type Invoice struct {
+	Number        string             `json:"number"`
+	OrderID       primitive.ObjectID `json:"order_id"`
+	IssuedAt      time.Time          `json:"issued_at"`
+	Lines         []OrderItem        `json:"lines"`
+	Subtotal      Money              `json:"subtotal"`
+	Discount      Money              `json:"discount"`
+	Tax           Money              `json:"tax"`
+	TaxIncluded   bool               `json:"tax_included,omitempty"` // Tax is part of the line prices, not added to them
+	Shipping      Money              `json:"shipping"`
+	Total         Money              `json:"total"`
+	PaymentMethod string             `json:"payment_method"`
+	PaymentStatus PaymentStatus      `json:"payment_status"`
+}
+
+// InvoiceFor builds the invoice of an order
+func InvoiceFor(order Order) Invoice {
+	currency := order.TotalAmount.Currency
+	invoice := Invoice{
+		Number:        "INV-" + order.ID.Hex(),
+		OrderID:       order.ID,
+		IssuedAt:      order.CreatedAt,
+		Lines:         order.Items,
+		Subtotal:      NewMoney(0, currency),
+		Discount:      NewMoney(0, currency),
+		Tax:           NewMoney(0, currency),
+		Shipping:      NewMoney(0, currency),
+		Total:         order.TotalAmount,
+		PaymentMethod: order.PaymentMethod,
+		PaymentStatus: order.PaymentStatus,
+	}
+	if order.Shipping != nil {
+		invoice.Shipping = order.Shipping.Total
+	}
+	if invoice.Lines == nil {
+		invoice.Lines = []OrderItem{}
+	}
+	for _, line := range order.Items {
+		invoice.Subtotal = invoice.Subtotal.Add(line.Subtotal())
+		invoice.Discount = invoice.Discount.Add(line.Discount)
+		invoice.Tax = invoice.Tax.Add(line.Tax)
+		invoice.TaxIncluded = invoice.TaxIncluded || line.TaxIncluded
+	}
+	return invoice
+}
@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestNewOrderItem(t *testing.T) {
+	p := shirt()
+	item := NewOrderItem(p, "SHIRT-M", 3)
+	if item.Name != "Shirt" || item.SKU != "SHIRT-M" || item.UnitPrice != NewMoney(2500, DefaultCurrency) {
+		t.Errorf("item = %+v, want the medium at its override price", item)
+	}
+	if item.LineTotal != NewMoney(7500, DefaultCurrency) || !item.Discount.IsZero() || !item.Tax.IsZero() {
+		t.Errorf("item totals = %+v, want 75.00 with no discount or tax", item)
+	}
+
+	// The snapshot does not follow later product edits
+	p.Name = "Renamed"
+	p.Variants[1].Options["size"] = "L"
+	*p.Variants[1].Price = NewMoney(100, DefaultCurrency)
+	if item.Name != "Shirt" || item.Options["size"] != "M" || item.UnitPrice != NewMoney(2500, DefaultCurrency) {
+		t.Errorf("item = %+v, changed with the product", item)
+	}
+}
+
+func TestInvoiceFor(t *testing.T) {
+	shirtLine := NewOrderItem(shirt(), "SHIRT-S", 2)
+	shirtLine.Discount = NewMoney(500, DefaultCurrency)
+	shirtLine.Tax = NewMoney(283, DefaultCurrency)
+	shirtLine.UpdateTotal()
+	plain := NewOrderItem(shirt(), "", 1)
+	order := Order{Items: []OrderItem{shirtLine, plain}, TotalAmount: shirtLine.LineTotal.Add(plain.LineTotal)}
+
+	invoice := InvoiceFor(order)
+	tests := []struct {
+		name      string
+		got, want Money
+	}{
+		{"line total", shirtLine.LineTotal, NewMoney(3783, DefaultCurrency)},
+		{"subtotal", invoice.Subtotal, NewMoney(6000, DefaultCurrency)},
+		{"discount", invoice.Discount, NewMoney(500, DefaultCurrency)},
+		{"tax", invoice.Tax, NewMoney(283, DefaultCurrency)},
+		{"total", invoice.Total, NewMoney(5783, DefaultCurrency)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if empty := InvoiceFor(Order{}); empty.Lines == nil {
+		t.Error("invoice of an order with no lines has nil Lines, want an empty list")
+	}
+}
@@ -0,0 +1,274 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency the shop prices and charges in
+const DefaultCurrency = "USD"
+
+// currencyExponents is the number of minor-unit digits of each supported ISO
+// 4217 currency. Currencies not listed here are rejected.
+var currencyExponents = map[string]int{
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"CAD": 2,
+	"AUD": 2,
+	"CHF": 2,
+	"JPY": 0,
+}
+
+var (
+	// ErrInvalidAmount is returned for an amount that is not a plain decimal number
+	ErrInvalidAmount = errors.New("invalid money amount")
+	// ErrUnknownCurrency is returned for a currency code that is not supported
+	ErrUnknownCurrency = errors.New("unknown currency")
+)
+
+// Money is an exact amount of an ISO 4217 currency, held as integer minor
+// units (cents for USD). It is stored in BSON as {amount, currency} with the
+// amount in minor units, and written to JSON as {"amount": "19.99",
+// "currency": "USD"} with the amount as a decimal string.
+//
+// Rounding: amounts parsed with more decimal places than the currency has
+// are rounded to the nearest minor unit, halves away from zero. Scaling by
+// a rate rounds the same way. Addition and multiplication by a quantity are
+// exact.
+type Money struct {
+	Amount   int64  `bson:"amount"` // Minor units
+	Currency string `bson:"currency"`
+}
+
+// NewMoney returns an amount in minor units of currency
+func NewMoney(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "19.99" or "-5" as an amount of currency
+func ParseMoney(s, currency string) (Money, error) {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digitsOnly(whole) || !digitsOnly(frac) {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, s)
+	}
+
+	// Keep exp fractional digits and round on the first one dropped
+	roundUp := len(frac) > exp && frac[exp] >= '5'
+	if len(frac) > exp {
+		frac = frac[:exp]
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if whole+frac == "" {
+		minor, err = 0, nil
+	}
+	if err != nil {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, s)
+	}
+	if roundUp {
+		if minor == math.MaxInt64 {
+			return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, s)
+		}
+		minor++
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// MoneyFromFloat converts a floating-point amount, such as a price stored
+// before amounts were exact, using its shortest decimal representation
+func MoneyFromFloat(f float64, currency string) (Money, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Money{}, ErrInvalidAmount
+	}
+	return ParseMoney(strconv.FormatFloat(f, 'f', -1, 64), currency)
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + o. Both must be in the same currency; a zero Money with no
+// currency adopts the other's.
+func (m Money) Add(o Money) Money {
+	currency := m.sameCurrency(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}
+}
+
+// Sub returns m - o, with the same currency rules as Add
+func (m Money) Sub(o Money) Money {
+	currency := m.sameCurrency(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: currency}
+}
+
+// Mul returns m multiplied by a whole quantity
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// MulRate returns m scaled by num/den, rounded to the nearest minor unit
+// with halves away from zero, e.g. MulRate(15, 100) for 15%
+func (m Money) MulRate(num, den int64) Money {
+	if den == 0 {
+		panic("models: Money.MulRate with zero denominator")
+	}
+	if den < 0 {
+		num, den = -num, -den
+	}
+	product := m.Amount * num
+	// Go division truncates toward zero, so the remainder carries the sign
+	q, r := product/den, product%den
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= den {
+		if product < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money{Amount: q, Currency: m.Currency}
+}
+
+// Cmp compares m and o, returning -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	m.sameCurrency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// sameCurrency returns the currency shared by m and o. Mixing currencies is
+// a programming error: amounts are checked against the shop currency when
+// they enter the system.
+func (m Money) sameCurrency(o Money) string {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("models: mixing %s and %s amounts", m.Currency, o.Currency))
+}
+
+// Decimal formats the amount in major units, e.g. "19.99"
+func (m Money) Decimal() string {
+	exp := currencyExponents[m.Currency]
+	abs := m.Amount
+	sign := ""
+	if abs < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	digits := strconv.FormatUint(uint64(abs), 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String formats the amount with its currency, e.g. "19.99 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// moneyJSON is the wire form of Money
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON writes {"amount": "19.99", "currency": "USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON reads {"amount": "19.99", "currency": "USD"}. The amount may
+// also be a JSON number, and a bare number or string is accepted as an
+// amount of DefaultCurrency so older clients keep working.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var wire moneyJSON
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &wire); err != nil {
+			return err
+		}
+	} else {
+		wire.Amount = data
+	}
+	if wire.Currency == "" {
+		wire.Currency = DefaultCurrency
+	}
+
+	var text string
+	if err := json.Unmarshal(wire.Amount, &text); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(wire.Amount, &number); err != nil {
+			return fmt.Errorf("%w %s", ErrInvalidAmount, wire.Amount)
+		}
+		text = number.String()
+	}
+	// JSON numbers may use exponents, which ParseMoney does not
+	if strings.ContainsAny(text, "eE") {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("%w %s", ErrInvalidAmount, text)
+		}
+		parsed, err := MoneyFromFloat(f, wire.Currency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	parsed, err := ParseMoney(text, wire.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     int64
+	}{
+		{"19.99", "USD", 1999},
+		{"5", "USD", 500},
+		{".5", "USD", 50},
+		{"-5.1", "USD", -510},
+		{" 0.01 ", "USD", 1},
+		{"0.005", "USD", 1},   // Halves round away from zero
+		{"0.0049", "USD", 0},  // Only the first dropped digit counts
+		{"-0.005", "USD", -1}, // Away from zero for negatives too
+		{"1.995", "USD", 200}, // Rounding carries into the whole part
+		{"1234", "JPY", 1234}, // No minor units
+		{"1234.5", "JPY", 1235},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in, tt.currency)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %s) error: %v", tt.in, tt.currency, err)
+			continue
+		}
+		if got != NewMoney(tt.want, tt.currency) {
+			t.Errorf("ParseMoney(%q, %s) = %v, want %d minor units", tt.in, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoneyRejects(t *testing.T) {
+	for _, in := range []string{"", ".", "-", "1.2.3", "1e3", "$5", "1,000", "99999999999999999999"} {
+		if _, err := ParseMoney(in, "USD"); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("ParseMoney(%q) error = %v, want ErrInvalidAmount", in, err)
+		}
+	}
+	if _, err := ParseMoney("5", "XYZ"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("ParseMoney with unknown currency error = %v, want ErrUnknownCurrency", err)
+	}
+}
+
+func TestMoneyMulRate(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		num, den int64
+		want     int64
+	}{
+		{1000, 825, 10000, 83},   // 82.5 rounds up
+		{-1000, 825, 10000, -83}, // and away from zero when negative
+		{999, 15, 100, 150},      // 149.85
+		{1000, 1, 3, 333},
+		{1000, 2, 3, 667},
+		{1000, 825, -10000, -83},
+	}
+	for _, tt := range tests {
+		got := NewMoney(tt.amount, "USD").MulRate(tt.num, tt.den)
+		if got.Amount != tt.want {
+			t.Errorf("%d.MulRate(%d, %d) = %d, want %d", tt.amount, tt.num, tt.den, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{NewMoney(1999, "USD"), "19.99"},
+		{NewMoney(5, "USD"), "0.05"},
+		{NewMoney(-5, "USD"), "-0.05"},
+		{NewMoney(0, "USD"), "0.00"},
+		{NewMoney(1234, "JPY"), "1234"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Decimal(); got != tt.want {
+			t.Errorf("%#v.Decimal() = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{`{"amount": "19.99", "currency": "EUR"}`, NewMoney(1999, "EUR")},
+		{`{"amount": 19.99}`, NewMoney(1999, DefaultCurrency)},
+		{`19.99`, NewMoney(1999, DefaultCurrency)}, // Older clients send a bare number
+		{`"19.99"`, NewMoney(1999, DefaultCurrency)},
+		{`1.5e1`, NewMoney(1500, DefaultCurrency)},
+		{`0.1`, NewMoney(10, DefaultCurrency)}, // Not 0.1000000000000000055
+	}
+	for _, tt := range tests {
+		var got Money
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("unmarshal %s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("unmarshal %s = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	data, err := json.Marshal(NewMoney(1999, "USD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":"19.99","currency":"USD"}`; string(data) != want {
+		t.Errorf("marshal = %s, want %s", data, want)
+	}
+}
+
+func TestMoneyAddAdoptsCurrency(t *testing.T) {
+	var total Money
+	total = total.Add(NewMoney(250, "EUR"))
+	if total != NewMoney(250, "EUR") {
+		t.Errorf("zero Money + 2.50 EUR = %v", total)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("adding EUR to USD did not panic")
+		}
+	}()
+	NewMoney(1, "USD").Add(NewMoney(1, "EUR"))
+}
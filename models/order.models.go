@@ -6,15 +6,167 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// OrderStatus is a stage in the order lifecycle
+type OrderStatus string
+
+const (
+	OrderPending    OrderStatus = "pending"
+	OrderPaid       OrderStatus = "paid"
+	OrderProcessing OrderStatus = "processing"
+	OrderShipped    OrderStatus = "shipped"
+	OrderDelivered  OrderStatus = "delivered"
+	OrderCancelled  OrderStatus = "cancelled"
+	OrderRefunded   OrderStatus = "refunded"
+)
+
+// orderTransitions lists the statuses each status may move to
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:    {OrderPaid, OrderCancelled},
+	OrderPaid:       {OrderProcessing, OrderCancelled, OrderRefunded},
+	OrderProcessing: {OrderShipped, OrderCancelled, OrderRefunded},
+	OrderShipped:    {OrderDelivered},
+	OrderDelivered:  {OrderRefunded},
+	OrderCancelled:  {OrderRefunded},
+	OrderRefunded:   {},
+}
+
+// Valid reports whether s is a known status
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an order may move from s to next.
+// Orders created before statuses were tracked have an empty status and are treated as pending.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	if s == "" {
+		s = OrderPending
+	}
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Cancellable reports whether an order in status s has not shipped yet and
+// may still be cancelled by the customer
+func (s OrderStatus) Cancellable() bool {
+	switch s {
+	case "", OrderPending, OrderPaid, OrderProcessing:
+		return true
+	}
+	return false
+}
+
+// StatusChange records one transition in an order's history
+type StatusChange struct {
+	From  OrderStatus `bson:"from,omitempty" json:"from,omitempty"`
+	To    OrderStatus `bson:"to" json:"to"`
+	Actor string      `bson:"actor" json:"actor"` // Email of the user or admin, or "system"
+	Note  string      `bson:"note,omitempty" json:"note,omitempty"`
+	At    time.Time   `bson:"at" json:"at"`
+}
+
+// ReservationStatus is where an order's stock reservation stands
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"    // Stock is held until ExpiresAt
+	ReservationCommitted ReservationStatus = "committed" // Paid for; the stock has left on-hand
+	ReservationReleased  ReservationStatus = "released"  // Returned to sale unpaid
+)
+
+// StockReservation holds an order's items out of available stock while the
+// customer pays. Orders that are still unpaid when it expires are cancelled.
+type StockReservation struct {
+	Status     ReservationStatus `bson:"status" json:"status"`
+	ExpiresAt  time.Time         `bson:"expires_at" json:"expires_at"`
+	ResolvedAt *time.Time        `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"` // When it was committed or released
+}
+
+// OrderItem is one order line, described and priced as it was at purchase
+// so later product edits or deletions do not change the order
+type OrderItem struct {
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	SKU       string             `bson:"sku,omitempty" json:"sku,omitempty"`
+	Name      string             `bson:"name" json:"name"`
+	Category  string             `bson:"category,omitempty" json:"category,omitempty"`
+	Options   map[string]string  `bson:"options,omitempty" json:"options,omitempty"`     // Variant option values
+	ImageURL  string             `bson:"image_url,omitempty" json:"image_url,omitempty"` // The variant's image, or else the product's
+	Quantity  int                `bson:"quantity" json:"quantity"`
+	Weight    int                `bson:"weight,omitempty" json:"weight,omitempty"` // Grams per unit
+	UnitPrice Money              `bson:"unit_price" json:"unit_price"`
+	Discount  Money              `bson:"discount" json:"discount"` // For the whole line
+	Tax       Money              `bson:"tax" json:"tax"`           // For the whole line
+	LineTotal Money              `bson:"line_total" json:"line_total"`
+
+	TaxCategory  string    `bson:"tax_category,omitempty" json:"tax_category,omitempty"`
+	TaxIncluded  bool      `bson:"tax_included,omitempty" json:"tax_included,omitempty"` // Tax is part of UnitPrice rather than added to it
+	TaxBreakdown []TaxLine `bson:"tax_breakdown,omitempty" json:"tax_breakdown,omitempty"`
+}
+
+// NewOrderItem snapshots quantity units of a product, or of its variant when
+// sku is set, at the current price with no discount or tax
+func NewOrderItem(product Product, sku string, quantity int) OrderItem {
+	item := OrderItem{
+		ProductID:   product.ID,
+		SKU:         sku,
+		Name:        product.Name,
+		Category:    product.Category,
+		Quantity:    quantity,
+		Weight:      product.Weight,
+		ImageURL:    product.ImageURL,
+		UnitPrice:   product.PriceFor(sku),
+		TaxCategory: product.TaxCategory,
+	}
+	if v, ok := product.Variant(sku); ok {
+		if v.ImageURL != "" {
+			item.ImageURL = v.ImageURL
+		}
+		item.Options = make(map[string]string, len(v.Options))
+		for name, value := range v.Options {
+			item.Options[name] = value
+		}
+	}
+	item.Discount = NewMoney(0, item.UnitPrice.Currency)
+	item.Tax = NewMoney(0, item.UnitPrice.Currency)
+	item.UpdateTotal()
+	return item
+}
+
+// Subtotal is the line's price before discount and tax
+func (i OrderItem) Subtotal() Money {
+	return i.UnitPrice.Mul(i.Quantity)
+}
+
+// UpdateTotal recomputes LineTotal as subtotal - discount + tax, leaving tax
+// out when the price already includes it
+func (i *OrderItem) UpdateTotal() {
+	i.LineTotal = i.Subtotal().Sub(i.Discount)
+	if !i.TaxIncluded {
+		i.LineTotal = i.LineTotal.Add(i.Tax)
+	}
+}
+
 // Order represents a user's order
 type Order struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Items         []CartItem         `bson:"items" json:"items"`
-	TotalAmount   float64            `bson:"total_amount" json:"total_amount"`
-	Address       Address            `bson:"address" json:"address"`
+	Items         []OrderItem        `bson:"items" json:"items"`
+	TotalAmount   Money              `bson:"total_amount" json:"total_amount"`
+	Address       Address            `bson:"address" json:"address"` // Where the order ships, and the basis of its tax
+	Promotion     *AppliedPromotion  `bson:"promotion,omitempty" json:"promotion,omitempty"`
+	Shipping      *ShippingQuote     `bson:"shipping,omitempty" json:"shipping,omitempty"`       // The chosen method as quoted at checkout
+	Reservation   *StockReservation  `bson:"reservation,omitempty" json:"reservation,omitempty"` // Nil for orders from before reservations, whose stock was taken at checkout
 	PaymentMethod string             `bson:"payment_method" json:"payment_method"`
-	Status        string             `bson:"status" json:"status"` // e.g., "Pending", "Shipped"
+	PaymentStatus PaymentStatus      `bson:"payment_status,omitempty" json:"payment_status,omitempty"`
+	CryptoProof   string             `bson:"crypto_proof,omitempty" json:"crypto_proof,omitempty"` // Legacy proof path; proofs now live on the Payment
+	Status        OrderStatus        `bson:"status" json:"status"`
+	History       []StatusChange     `bson:"history,omitempty" json:"history,omitempty"`
+	CancelReason  string             `bson:"cancel_reason,omitempty" json:"cancel_reason,omitempty"`
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
-	DeliveryDate  string             `bson:"delivery_date" json:"delivery_date"` // e.g., "7 working days"
+	DeliveryDate  DeliveryWindow     `bson:"delivery_date" json:"delivery_date"`                       // Expected arrival, estimated at checkout
+	CartReminders int                `bson:"cart_reminders,omitempty" json:"cart_reminders,omitempty"` // Abandoned-cart emails sent before the customer checked out
 }
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		{OrderPending, OrderPaid, true},
+		{OrderPending, OrderCancelled, true},
+		{OrderPending, OrderShipped, false},
+		{"", OrderPaid, true}, // Orders from before statuses were tracked count as pending
+		{"", OrderRefunded, false},
+		{OrderPaid, OrderProcessing, true},
+		{OrderProcessing, OrderShipped, true},
+		{OrderShipped, OrderCancelled, false},
+		{OrderShipped, OrderDelivered, true},
+		{OrderDelivered, OrderRefunded, true},
+		{OrderCancelled, OrderPaid, false},
+		{OrderRefunded, OrderPaid, false},
+		{OrderPaid, OrderPaid, false},
+		{OrderPaid, "lost", false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%q.CanTransitionTo(%q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
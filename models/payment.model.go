@@ -1,15 +1,65 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// PaymentStatus is the state of a payment
+type PaymentStatus string
+
+const (
+	PaymentPending PaymentStatus = "pending"
+	// PaymentRequiresAction waits on the customer, e.g. a 3-D Secure challenge
+	PaymentRequiresAction PaymentStatus = "requires_action"
+	// PaymentAwaitingReview is a crypto payment whose proof an admin has not checked yet
+	PaymentAwaitingReview PaymentStatus = "awaiting_review"
+	// PaymentAuthorized is approved by the card issuer but not yet captured
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentCompleted  PaymentStatus = "completed"
+	PaymentFailed     PaymentStatus = "failed"
+	// PaymentRefundPending marks a completed payment whose order was cancelled
+	PaymentRefundPending PaymentStatus = "refund_pending"
+	PaymentRefunded      PaymentStatus = "refunded"
+)
+
+// paymentStages orders payment statuses by progress. Statuses on the same
+// stage may replace each other, but a payment never returns to an earlier stage.
+var paymentStages = map[PaymentStatus]int{
+	PaymentPending:        0,
+	PaymentRequiresAction: 1,
+	PaymentAwaitingReview: 1,
+	PaymentFailed:         1,
+	PaymentAuthorized:     2,
+	PaymentCompleted:      3,
+	PaymentRefundPending:  4,
+	PaymentRefunded:       5,
+}
+
+// Stage reports how far along a payment in this status is
+func (s PaymentStatus) Stage() int {
+	return paymentStages[s]
+}
+
 // Payment represents a payment for an order
 type Payment struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	OrderID       primitive.ObjectID `bson:"order_id" json:"order_id"`
 	PaymentMethod string             `bson:"payment_method" json:"payment_method"` // "card" or "crypto"
-	Amount        float64            `bson:"amount" json:"amount"`
-	Status        string             `bson:"status" json:"status"`                           // "Pending", "Completed"
+	Amount        Money              `bson:"amount" json:"amount"`
+	Status        PaymentStatus      `bson:"status" json:"status"`
+	Provider      string             `bson:"provider,omitempty" json:"provider,omitempty"`         // Gateway name for card payments
+	ProviderRef   string             `bson:"provider_ref,omitempty" json:"provider_ref,omitempty"` // Gateway payment intent ID
+	NextActionURL string             `bson:"next_action_url,omitempty" json:"next_action_url,omitempty"`
+	FailureReason string             `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	LastEventAt   time.Time          `bson:"last_event_at,omitempty" json:"-"`               // Newest provider event applied
 	ProofURL      string             `bson:"proof_url,omitempty" json:"proof_url,omitempty"` // For crypto payments
+	ProofPath     string             `bson:"proof_path,omitempty" json:"-"`                  // Where the proof file is stored
+	ProofType     string             `bson:"proof_type,omitempty" json:"proof_type,omitempty"`
+	ReviewNote    string             `bson:"review_note,omitempty" json:"review_note,omitempty"`
+	ReviewedBy    string             `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time         `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -1,16 +1,243 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ProductOption is a variant axis, e.g. {"name": "size", "values": ["S", "M", "L"]}
+type ProductOption struct {
+	Name   string   `bson:"name" json:"name"`
+	Values []string `bson:"values" json:"values"`
+}
+
+// Variant is a purchasable combination of option values with its own SKU and stock
+type Variant struct {
+	SKU       string            `bson:"sku" json:"sku"`
+	Options   map[string]string `bson:"options" json:"options"`                       // Option name to value
+	Price     *Money            `bson:"price,omitempty" json:"price,omitempty"`       // Overrides Product.Price when set
+	Stock     int               `bson:"stock" json:"stock"`                           // On hand
+	Reserved  int               `bson:"reserved,omitempty" json:"reserved,omitempty"` // Held for unpaid orders; kept by the store
+	Available int               `bson:"-" json:"available"`                           // Stock less Reserved, filled in for display
+	ImageURL  string            `bson:"image_url,omitempty" json:"image_url,omitempty"`
+}
+
 // Product represents a product in the system
 type Product struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Name        string             `bson:"name" json:"name"`
 	Description string             `bson:"description" json:"description"`
-	Price       float64            `bson:"price" json:"price"`
-	Stock       int                `bson:"stock" json:"stock"`
+	Price       Money              `bson:"price" json:"price"`
+	Stock       int                `bson:"stock" json:"stock"`                           // On hand; unused when the product has variants
+	Reserved    int                `bson:"reserved,omitempty" json:"reserved,omitempty"` // Held for unpaid orders; kept by the store
+	Available   int                `bson:"-" json:"available"`                           // Stock less Reserved, filled in for display
+	Weight      int                `bson:"weight,omitempty" json:"weight,omitempty"`     // Grams per unit, for shipping rates
 	ImageURL    string             `bson:"image_url" json:"image_url"`
 	Category    string             `bson:"category" json:"category"`
+	TaxCategory string             `bson:"tax_category,omitempty" json:"tax_category,omitempty"` // Selects category-specific tax rates
+	Options     []ProductOption    `bson:"options,omitempty" json:"options,omitempty"`
+	Variants    []Variant          `bson:"variants,omitempty" json:"variants,omitempty"`
+
+	// ReorderThreshold is the stock at or below which the product, or any of
+	// its variants, is reported as running low; 0 turns the alert off
+	ReorderThreshold int `bson:"reorder_threshold,omitempty" json:"reorder_threshold,omitempty"`
+}
+
+// HasVariants reports whether the product is sold by variant
+func (p Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// Variant returns the variant with the given SKU
+func (p Product) Variant(sku string) (*Variant, bool) {
+	for i := range p.Variants {
+		if p.Variants[i].SKU == sku {
+			return &p.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// PriceFor returns the unit price of the given variant, or the base price
+// when the SKU is empty or the variant has no override
+func (p Product) PriceFor(sku string) Money {
+	if v, ok := p.Variant(sku); ok && v.Price != nil {
+		return *v.Price
+	}
+	return p.Price
+}
+
+// StockFor returns the on-hand stock of the given variant, or the base stock
+// when the SKU is empty
+func (p Product) StockFor(sku string) int {
+	if sku == "" {
+		return p.Stock
+	}
+	if v, ok := p.Variant(sku); ok {
+		return v.Stock
+	}
+	return 0
+}
+
+// available is on-hand stock less what unpaid orders hold, never below zero
+func available(stock, reserved int) int {
+	return max(stock-reserved, 0)
+}
+
+// AvailableFor returns the stock of the given variant, or the base stock when
+// the SKU is empty, that is not reserved by unpaid orders
+func (p Product) AvailableFor(sku string) int {
+	if sku == "" {
+		return available(p.Stock, p.Reserved)
+	}
+	if v, ok := p.Variant(sku); ok {
+		return available(v.Stock, v.Reserved)
+	}
+	return 0
+}
+
+// InStock reports whether any unit of the product is available
+func (p Product) InStock() bool {
+	if !p.HasVariants() {
+		return p.AvailableFor("") > 0
+	}
+	for _, v := range p.Variants {
+		if available(v.Stock, v.Reserved) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StockLevel is the on-hand stock of a product, or of one of its variants
+type StockLevel struct {
+	SKU   string `json:"sku,omitempty"`
+	Stock int    `json:"stock"`
+}
+
+// LowStock returns the stock figures at or below the reorder threshold: the
+// base stock, or each variant's when the product has variants
+func (p Product) LowStock() []StockLevel {
+	if p.ReorderThreshold <= 0 {
+		return nil
+	}
+	var low []StockLevel
+	if !p.HasVariants() {
+		if p.Stock <= p.ReorderThreshold {
+			low = append(low, StockLevel{Stock: p.Stock})
+		}
+		return low
+	}
+	for _, v := range p.Variants {
+		if v.Stock <= p.ReorderThreshold {
+			low = append(low, StockLevel{SKU: v.SKU, Stock: v.Stock})
+		}
+	}
+	return low
+}
+
+// FillAvailable sets Available on the product and each of its variants
+func (p *Product) FillAvailable() {
+	p.Available = available(p.Stock, p.Reserved)
+	for i := range p.Variants {
+		p.Variants[i].Available = available(p.Variants[i].Stock, p.Variants[i].Reserved)
+	}
+}
+
+// KeepReserved carries the reserved counts of old, the stored version of the
+// product, over to p so client edits cannot change them. Variants are
+// matched by SKU; new variants start with nothing reserved.
+func (p *Product) KeepReserved(old Product) {
+	p.Reserved = old.Reserved
+	for i := range p.Variants {
+		p.Variants[i].Reserved = 0
+		if v, ok := old.Variant(p.Variants[i].SKU); ok {
+			p.Variants[i].Reserved = v.Reserved
+		}
+	}
+}
+
+// Overheld reports whether the product, or any of its variants, has less
+// on-hand stock than unpaid orders hold
+func (p Product) Overheld() bool {
+	if p.Stock < p.Reserved {
+		return true
+	}
+	for _, v := range p.Variants {
+		if v.Stock < v.Reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePrices checks that the base price and every variant price are
+// non-negative amounts of currency
+func (p Product) ValidatePrices(currency string) error {
+	if p.Price.IsNegative() || p.Price.Currency != currency {
+		return fmt.Errorf("price must be a non-negative amount in %s", currency)
+	}
+	for _, v := range p.Variants {
+		if v.Price != nil && (v.Price.IsNegative() || v.Price.Currency != currency) {
+			return fmt.Errorf("variant %q price must be a non-negative amount in %s", v.SKU, currency)
+		}
+	}
+	return nil
+}
+
+// ValidateVariants checks that every variant has a unique SKU and picks exactly
+// one declared value for every option, with no two variants alike
+func (p Product) ValidateVariants() error {
+	if len(p.Variants) == 0 {
+		return nil
+	}
+	if len(p.Options) == 0 {
+		return errors.New("variants require at least one option")
+	}
+
+	allowed := make(map[string]map[string]bool, len(p.Options))
+	for _, opt := range p.Options {
+		if opt.Name == "" || len(opt.Values) == 0 {
+			return errors.New("every option needs a name and at least one value")
+		}
+		allowed[opt.Name] = make(map[string]bool, len(opt.Values))
+		for _, v := range opt.Values {
+			allowed[opt.Name][v] = true
+		}
+	}
+
+	skus := make(map[string]bool, len(p.Variants))
+	combos := make(map[string]bool, len(p.Variants))
+	for _, v := range p.Variants {
+		if v.SKU == "" {
+			return errors.New("every variant needs a SKU")
+		}
+		if skus[v.SKU] {
+			return fmt.Errorf("duplicate SKU %q", v.SKU)
+		}
+		skus[v.SKU] = true
+
+		if len(v.Options) != len(p.Options) {
+			return fmt.Errorf("variant %q must set a value for every option", v.SKU)
+		}
+		combo := ""
+		for _, opt := range p.Options {
+			value, ok := v.Options[opt.Name]
+			if !ok || !allowed[opt.Name][value] {
+				return fmt.Errorf("variant %q has an invalid value for option %q", v.SKU, opt.Name)
+			}
+			combo += opt.Name + "=" + value + ";"
+		}
+		if combos[combo] {
+			return fmt.Errorf("variant %q duplicates another variant's options", v.SKU)
+		}
+		combos[combo] = true
+
+		if v.Stock < 0 || (v.Price != nil && v.Price.IsNegative()) {
+			return fmt.Errorf("variant %q has a negative price or stock", v.SKU)
+		}
+	}
+	return nil
 }
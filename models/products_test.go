@@ -0,0 +1,150 @@
+package models
+
+import "testing"
+
+func shirt() Product {
+	price := NewMoney(2500, DefaultCurrency)
+	return Product{
+		Name:    "Shirt",
+		Price:   NewMoney(2000, DefaultCurrency),
+		Stock:   9,
+		Options: []ProductOption{{Name: "size", Values: []string{"S", "M"}}, {Name: "color", Values: []string{"red"}}},
+		Variants: []Variant{
+			{SKU: "SHIRT-S", Options: map[string]string{"size": "S", "color": "red"}, Stock: 0},
+			{SKU: "SHIRT-M", Options: map[string]string{"size": "M", "color": "red"}, Price: &price, Stock: 2},
+		},
+	}
+}
+
+func TestValidateVariants(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *Product)
+		valid  bool
+	}{
+		{"valid", func(p *Product) {}, true},
+		{"no variants", func(p *Product) { p.Options, p.Variants = nil, nil }, true},
+		{"no options", func(p *Product) { p.Options = nil }, false},
+		{"option without values", func(p *Product) { p.Options[1].Values = nil }, false},
+		{"missing SKU", func(p *Product) { p.Variants[0].SKU = "" }, false},
+		{"duplicate SKU", func(p *Product) { p.Variants[1].SKU = "SHIRT-S" }, false},
+		{"missing option", func(p *Product) { delete(p.Variants[0].Options, "color") }, false},
+		{"undeclared value", func(p *Product) { p.Variants[0].Options["size"] = "XL" }, false},
+		{"duplicate options", func(p *Product) { p.Variants[1].Options["size"] = "S" }, false},
+		{"negative stock", func(p *Product) { p.Variants[0].Stock = -1 }, false},
+		{"negative price", func(p *Product) { *p.Variants[1].Price = NewMoney(-100, DefaultCurrency) }, false},
+	}
+	for _, tt := range tests {
+		p := shirt()
+		tt.modify(&p)
+		if err := p.ValidateVariants(); (err == nil) != tt.valid {
+			t.Errorf("%s: ValidateVariants() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestVariantLookups(t *testing.T) {
+	p := shirt()
+	tests := []struct {
+		sku   string
+		price int64 // Cents
+		stock int
+	}{
+		{"", 2000, 9},
+		{"SHIRT-S", 2000, 0}, // No override, so the base price
+		{"SHIRT-M", 2500, 2},
+		{"SHIRT-XL", 2000, 0},
+	}
+	for _, tt := range tests {
+		if got := p.PriceFor(tt.sku); got != NewMoney(tt.price, DefaultCurrency) {
+			t.Errorf("PriceFor(%q) = %v, want %v", tt.sku, got, tt.price)
+		}
+		if got := p.StockFor(tt.sku); got != tt.stock {
+			t.Errorf("StockFor(%q) = %v, want %v", tt.sku, got, tt.stock)
+		}
+	}
+
+	// The base stock does not count once a product has variants
+	if !p.InStock() {
+		t.Error("InStock() = false with a variant in stock")
+	}
+	p.Variants[1].Stock = 0
+	if p.InStock() {
+		t.Error("InStock() = true with every variant sold out")
+	}
+}
+
+func TestAvailableStock(t *testing.T) {
+	p := shirt()
+	p.Reserved = 4
+	p.Variants[1].Reserved = 3 // More than is on hand after a manual adjustment
+	if got := p.AvailableFor(""); got != 5 {
+		t.Errorf("AvailableFor(\"\") = %d, want 5", got)
+	}
+	if got := p.AvailableFor("SHIRT-M"); got != 0 {
+		t.Errorf("AvailableFor(\"SHIRT-M\") = %d, want 0", got)
+	}
+	if p.InStock() {
+		t.Error("InStock() = true with every variant reserved")
+	}
+
+	// Edits keep the stored reservations, matched by SKU
+	edited := shirt()
+	edited.Reserved = 0
+	edited.Variants = append(edited.Variants, Variant{SKU: "SHIRT-L", Reserved: 7})
+	edited.KeepReserved(p)
+	if edited.Reserved != 4 || edited.Variants[1].Reserved != 3 || edited.Variants[2].Reserved != 0 {
+		t.Errorf("KeepReserved = %+v, want 4 base, 3 medium and none for the new variant", edited)
+	}
+
+	p.FillAvailable()
+	if p.Available != 5 || p.Variants[1].Available != 0 {
+		t.Errorf("FillAvailable = %+v, want 5 base and no medium", p)
+	}
+}
+
+func TestLowStock(t *testing.T) {
+	p := shirt()
+	if low := p.LowStock(); low != nil {
+		t.Errorf("LowStock() with no threshold = %+v, want none", low)
+	}
+	p.ReorderThreshold = 1
+	low := p.LowStock()
+	if len(low) != 1 || low[0] != (StockLevel{SKU: "SHIRT-S", Stock: 0}) {
+		t.Errorf("LowStock() = %+v, want only SHIRT-S", low)
+	}
+
+	// Without variants the base stock counts
+	p.Variants = nil
+	p.ReorderThreshold = 9
+	if low := p.LowStock(); len(low) != 1 || low[0] != (StockLevel{Stock: 9}) {
+		t.Errorf("LowStock() = %+v, want the base stock", low)
+	}
+}
+
+func TestStockChanges(t *testing.T) {
+	old := shirt()
+	p := shirt()
+	p.Stock = 0
+	p.Variants[0].Stock = 4
+	p.Variants = append(p.Variants[:1], Variant{SKU: "SHIRT-L", Stock: 1})
+
+	got := p.StockChanges(old)
+	want := []StockMovement{
+		{Delta: -9, Balance: 0},
+		{SKU: "SHIRT-S", Delta: 4, Balance: 4},
+		{SKU: "SHIRT-L", Delta: 1, Balance: 1},
+		{SKU: "SHIRT-M", Delta: -2, Balance: 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("StockChanges = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("StockChanges[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if changes := old.StockChanges(old); len(changes) != 0 {
+		t.Errorf("StockChanges of an unchanged product = %+v, want none", changes)
+	}
+}
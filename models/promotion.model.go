@@ -0,0 +1,242 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PromotionType is the kind of discount a promotion gives
+type PromotionType string
+
+const (
+	PromotionPercentage   PromotionType = "percentage"    // PercentOff of each eligible line
+	PromotionFixedAmount  PromotionType = "fixed_amount"  // AmountOff spread over the eligible lines
+	PromotionFreeShipping PromotionType = "free_shipping" // No item discount; shipping is waived
+	PromotionBuyXGetY     PromotionType = "buy_x_get_y"   // Of every BuyQuantity+GetQuantity eligible units, the cheapest GetQuantity are free
+)
+
+// Reasons a coupon cannot be redeemed, shown to the customer
+var (
+	ErrPromotionDisabled      = errors.New("coupon is not active")
+	ErrPromotionNotStarted    = errors.New("coupon is not valid yet")
+	ErrPromotionExpired       = errors.New("coupon has expired")
+	ErrPromotionUsedUp        = errors.New("coupon has reached its usage limit")
+	ErrPromotionUserLimit     = errors.New("coupon has already been used the maximum number of times")
+	ErrPromotionMinSpend      = errors.New("cart does not reach the coupon's minimum spend")
+	ErrPromotionNotApplicable = errors.New("coupon does not apply to any item in the cart")
+)
+
+// Promotion is an admin-managed discount that customers redeem with a coupon code
+type Promotion struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	Code         string               `bson:"code" json:"code"` // Stored upper case
+	Description  string               `bson:"description" json:"description"`
+	Type         PromotionType        `bson:"type" json:"type"`
+	PercentOff   int                  `bson:"percent_off,omitempty" json:"percent_off,omitempty"` // 1-100, percentage only
+	AmountOff    *Money               `bson:"amount_off,omitempty" json:"amount_off,omitempty"`   // fixed_amount only
+	BuyQuantity  int                  `bson:"buy_quantity,omitempty" json:"buy_quantity,omitempty"`
+	GetQuantity  int                  `bson:"get_quantity,omitempty" json:"get_quantity,omitempty"`
+	ProductIDs   []primitive.ObjectID `bson:"product_ids,omitempty" json:"product_ids,omitempty"` // With Categories, limits the lines discounted; both empty means the whole cart
+	Categories   []string             `bson:"categories,omitempty" json:"categories,omitempty"`
+	MinSpend     *Money               `bson:"min_spend,omitempty" json:"min_spend,omitempty"` // Cart subtotal before discounts
+	StartsAt     *time.Time           `bson:"starts_at,omitempty" json:"starts_at,omitempty"`
+	EndsAt       *time.Time           `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+	UsageLimit   int                  `bson:"usage_limit" json:"usage_limit"`       // Orders across all customers; 0 is unlimited
+	PerUserLimit int                  `bson:"per_user_limit" json:"per_user_limit"` // Orders per customer; 0 is unlimited
+	UsedCount    int                  `bson:"used_count" json:"used_count"`
+	Disabled     bool                 `bson:"disabled" json:"disabled"`
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// AppliedPromotion is the promotion redeemed by a cart or order and the
+// discount it gave, frozen at checkout. The discount of each line is on the
+// line itself.
+type AppliedPromotion struct {
+	PromotionID  primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	Code         string             `bson:"code" json:"code"`
+	Type         PromotionType      `bson:"type" json:"type"`
+	Description  string             `bson:"description" json:"description"`
+	Discount     Money              `bson:"discount" json:"discount"`
+	FreeShipping bool               `bson:"free_shipping,omitempty" json:"free_shipping,omitempty"`
+}
+
+// NormalizeCouponCode returns code in the form it is stored and looked up in
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks that the promotion is complete for its type and that its
+// amounts are in currency
+func (p Promotion) Validate(currency string) error {
+	if p.Code == "" {
+		return errors.New("code is required")
+	}
+	for _, r := range p.Code {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return fmt.Errorf("code %q may only contain letters, digits, - and _", p.Code)
+		}
+	}
+
+	switch p.Type {
+	case PromotionPercentage:
+		if p.PercentOff < 1 || p.PercentOff > 100 {
+			return errors.New("percent_off must be between 1 and 100")
+		}
+	case PromotionFixedAmount:
+		if p.AmountOff == nil || p.AmountOff.Amount <= 0 {
+			return errors.New("amount_off must be positive")
+		}
+		if p.AmountOff.Currency != currency {
+			return fmt.Errorf("amount_off must be in %s", currency)
+		}
+	case PromotionFreeShipping:
+	case PromotionBuyXGetY:
+		if p.BuyQuantity < 1 || p.GetQuantity < 1 {
+			return errors.New("buy_quantity and get_quantity must be at least 1")
+		}
+	default:
+		return fmt.Errorf("unknown promotion type %q", p.Type)
+	}
+
+	if p.MinSpend != nil && (p.MinSpend.IsNegative() || p.MinSpend.Currency != currency) {
+		return fmt.Errorf("min_spend must be a non-negative amount in %s", currency)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if p.UsageLimit < 0 || p.PerUserLimit < 0 {
+		return errors.New("usage limits cannot be negative")
+	}
+	return nil
+}
+
+// Available reports why the promotion cannot be redeemed at now by a
+// customer who has already used it userUses times, or nil if it can
+func (p Promotion) Available(now time.Time, userUses int) error {
+	switch {
+	case p.Disabled:
+		return ErrPromotionDisabled
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return ErrPromotionNotStarted
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return ErrPromotionExpired
+	case p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit:
+		return ErrPromotionUsedUp
+	case p.PerUserLimit > 0 && userUses >= p.PerUserLimit:
+		return ErrPromotionUserLimit
+	}
+	return nil
+}
+
+// Eligible reports whether an order line falls within the promotion's scope
+func (p Promotion) Eligible(line OrderItem) bool {
+	if len(p.ProductIDs) == 0 && len(p.Categories) == 0 {
+		return true
+	}
+	for _, id := range p.ProductIDs {
+		if id == line.ProductID {
+			return true
+		}
+	}
+	for _, category := range p.Categories {
+		if line.Category != "" && strings.EqualFold(category, line.Category) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the discount of each line in lines, replacing any earlier one,
+// and returns the promotion as applied. Lines are left without a discount
+// when the cart does not qualify.
+func (p Promotion) Apply(lines []OrderItem) (*AppliedPromotion, error) {
+	currency := DefaultCurrency
+	if len(lines) > 0 {
+		currency = lines[0].UnitPrice.Currency
+	}
+	subtotal := NewMoney(0, currency)
+	eligibleSubtotal := NewMoney(0, currency)
+	var eligible []int
+	for i := range lines {
+		lines[i].Discount = NewMoney(0, currency)
+		lines[i].UpdateTotal()
+		subtotal = subtotal.Add(lines[i].Subtotal())
+		if p.Eligible(lines[i]) {
+			eligible = append(eligible, i)
+			eligibleSubtotal = eligibleSubtotal.Add(lines[i].Subtotal())
+		}
+	}
+
+	if p.MinSpend != nil && subtotal.Cmp(*p.MinSpend) < 0 {
+		return nil, fmt.Errorf("%w of %s", ErrPromotionMinSpend, p.MinSpend)
+	}
+	if len(eligible) == 0 || (p.Type != PromotionFreeShipping && eligibleSubtotal.IsZero()) {
+		return nil, ErrPromotionNotApplicable
+	}
+
+	discounts := make([]Money, len(lines))
+	for i := range discounts {
+		discounts[i] = NewMoney(0, currency)
+	}
+	switch p.Type {
+	case PromotionPercentage:
+		for _, i := range eligible {
+			discounts[i] = lines[i].Subtotal().MulRate(int64(p.PercentOff), 100)
+		}
+	case PromotionFixedAmount:
+		// Spread the amount over the lines in proportion to their value; the
+		// last line takes the rounding remainder
+		amount := *p.AmountOff
+		if amount.Cmp(eligibleSubtotal) > 0 {
+			amount = eligibleSubtotal
+		}
+		remaining := amount
+		for n, i := range eligible {
+			share := remaining
+			if n < len(eligible)-1 {
+				share = amount.MulRate(lines[i].Subtotal().Amount, eligibleSubtotal.Amount)
+			}
+			discounts[i] = share
+			remaining = remaining.Sub(share)
+		}
+	case PromotionBuyXGetY:
+		units := 0
+		for _, i := range eligible {
+			units += lines[i].Quantity
+		}
+		free := units / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		if free == 0 {
+			return nil, fmt.Errorf("%w: buy %d to get %d free", ErrPromotionNotApplicable, p.BuyQuantity, p.GetQuantity)
+		}
+		// The customer gets the cheapest units free
+		sort.SliceStable(eligible, func(a, b int) bool {
+			return lines[eligible[a]].UnitPrice.Cmp(lines[eligible[b]].UnitPrice) < 0
+		})
+		for _, i := range eligible {
+			n := min(free, lines[i].Quantity)
+			discounts[i] = lines[i].UnitPrice.Mul(n)
+			free -= n
+		}
+	}
+
+	applied := &AppliedPromotion{
+		PromotionID:  p.ID,
+		Code:         p.Code,
+		Type:         p.Type,
+		Description:  p.Description,
+		Discount:     NewMoney(0, currency),
+		FreeShipping: p.Type == PromotionFreeShipping,
+	}
+	for i := range lines {
+		lines[i].Discount = discounts[i]
+		lines[i].UpdateTotal()
+		applied.Discount = applied.Discount.Add(discounts[i])
+	}
+	return applied, nil
+}
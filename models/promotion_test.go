@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func usd(cents int64) *Money {
+	m := NewMoney(cents, DefaultCurrency)
+	return &m
+}
+
+func TestPromotionValidate(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+	tests := []struct {
+		name      string
+		promotion Promotion
+		valid     bool
+	}{
+		{"percentage", Promotion{Code: "SAVE10", Type: PromotionPercentage, PercentOff: 10}, true},
+		{"percentage over 100", Promotion{Code: "SAVE", Type: PromotionPercentage, PercentOff: 101}, false},
+		{"fixed amount", Promotion{Code: "FIVE-OFF", Type: PromotionFixedAmount, AmountOff: usd(500)}, true},
+		{"fixed amount missing", Promotion{Code: "FIVE", Type: PromotionFixedAmount}, false},
+		{"fixed amount in another currency", Promotion{Code: "FIVE", Type: PromotionFixedAmount, AmountOff: &Money{Amount: 500, Currency: "EUR"}}, false},
+		{"free shipping", Promotion{Code: "SHIP_FREE", Type: PromotionFreeShipping}, true},
+		{"buy x get y", Promotion{Code: "B2G1", Type: PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1}, true},
+		{"buy x get nothing", Promotion{Code: "B2G0", Type: PromotionBuyXGetY, BuyQuantity: 2}, false},
+		{"unknown type", Promotion{Code: "X", Type: "mystery"}, false},
+		{"no code", Promotion{Type: PromotionFreeShipping}, false},
+		{"code with spaces", Promotion{Code: "SAVE 10", Type: PromotionFreeShipping}, false},
+		{"negative min spend", Promotion{Code: "X", Type: PromotionFreeShipping, MinSpend: usd(-1)}, false},
+		{"ends before it starts", Promotion{Code: "X", Type: PromotionFreeShipping, StartsAt: &now, EndsAt: &earlier}, false},
+		{"negative limit", Promotion{Code: "X", Type: PromotionFreeShipping, PerUserLimit: -1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.promotion.Validate(DefaultCurrency); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestPromotionAvailable(t *testing.T) {
+	now := time.Now()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+	tests := []struct {
+		name      string
+		promotion Promotion
+		userUses  int
+		want      error
+	}{
+		{"open", Promotion{}, 5, nil},
+		{"disabled", Promotion{Disabled: true}, 0, ErrPromotionDisabled},
+		{"not started", Promotion{StartsAt: &later}, 0, ErrPromotionNotStarted},
+		{"started", Promotion{StartsAt: &earlier, EndsAt: &later}, 0, nil},
+		{"expired", Promotion{EndsAt: &earlier}, 0, ErrPromotionExpired},
+		{"ends now", Promotion{EndsAt: &now}, 0, ErrPromotionExpired},
+		{"used up", Promotion{UsageLimit: 3, UsedCount: 3}, 0, ErrPromotionUsedUp},
+		{"uses left", Promotion{UsageLimit: 3, UsedCount: 2}, 0, nil},
+		{"user limit reached", Promotion{PerUserLimit: 1}, 1, ErrPromotionUserLimit},
+		{"user limit not reached", Promotion{PerUserLimit: 2}, 1, nil},
+	}
+	for _, tt := range tests {
+		if err := tt.promotion.Available(now, tt.userUses); err != tt.want {
+			t.Errorf("%s: Available() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPromotionApply(t *testing.T) {
+	mugID, lampID := primitive.NewObjectID(), primitive.NewObjectID()
+	lines := func() []OrderItem {
+		mug := NewOrderItem(Product{ID: mugID, Name: "Mug", Price: *usd(1000), Category: "kitchen"}, "", 3)
+		lamp := NewOrderItem(Product{ID: lampID, Name: "Lamp", Price: *usd(2000), Category: "home"}, "", 1)
+		return []OrderItem{mug, lamp}
+	}
+	tests := []struct {
+		name      string
+		promotion Promotion
+		discounts []int64 // Per line, in cents
+		err       error
+	}{
+		{"percentage", Promotion{Type: PromotionPercentage, PercentOff: 15}, []int64{450, 300}, nil},
+		{"percentage of a category", Promotion{Type: PromotionPercentage, PercentOff: 15, Categories: []string{"Kitchen"}}, []int64{450, 0}, nil},
+		{"percentage of a product", Promotion{Type: PromotionPercentage, PercentOff: 15, ProductIDs: []primitive.ObjectID{lampID}}, []int64{0, 300}, nil},
+		// 10.00 split 30:20, with the rounding remainder on the last line
+		{"fixed amount", Promotion{Type: PromotionFixedAmount, AmountOff: usd(1000)}, []int64{600, 400}, nil},
+		{"fixed amount rounding", Promotion{Type: PromotionFixedAmount, AmountOff: usd(1001)}, []int64{601, 400}, nil},
+		{"fixed amount above the cart", Promotion{Type: PromotionFixedAmount, AmountOff: usd(9000)}, []int64{3000, 2000}, nil},
+		// Four units make one set of 3 + 1, and the cheapest unit is free
+		{"buy 3 get 1", Promotion{Type: PromotionBuyXGetY, BuyQuantity: 3, GetQuantity: 1}, []int64{1000, 0}, nil},
+		{"buy 4 get 1", Promotion{Type: PromotionBuyXGetY, BuyQuantity: 4, GetQuantity: 1}, nil, ErrPromotionNotApplicable},
+		{"free shipping", Promotion{Type: PromotionFreeShipping}, []int64{0, 0}, nil},
+		{"min spend met", Promotion{Type: PromotionPercentage, PercentOff: 10, MinSpend: usd(5000)}, []int64{300, 200}, nil},
+		{"min spend missed", Promotion{Type: PromotionPercentage, PercentOff: 10, MinSpend: usd(5001)}, nil, ErrPromotionMinSpend},
+		{"nothing eligible", Promotion{Type: PromotionPercentage, PercentOff: 10, Categories: []string{"garden"}}, nil, ErrPromotionNotApplicable},
+	}
+	for _, tt := range tests {
+		items := lines()
+		applied, err := tt.promotion.Apply(items)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: Apply() error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		total := int64(0)
+		for i, want := range tt.discounts {
+			if items[i].Discount != *usd(want) {
+				t.Errorf("%s: line %d discount = %v, want %d cents", tt.name, i, items[i].Discount, want)
+			}
+			if items[i].LineTotal != items[i].Subtotal().Sub(items[i].Discount) {
+				t.Errorf("%s: line %d total = %v, not updated", tt.name, i, items[i].LineTotal)
+			}
+			total += want
+		}
+		if applied.Discount != *usd(total) || applied.FreeShipping != (tt.promotion.Type == PromotionFreeShipping) {
+			t.Errorf("%s: applied = %+v, want a discount of %d cents", tt.name, applied, total)
+		}
+	}
+
+	// A second promotion replaces the first one's discounts
+	items := lines()
+	(Promotion{Type: PromotionPercentage, PercentOff: 50}).Apply(items)
+	(Promotion{Type: PromotionPercentage, PercentOff: 10, ProductIDs: []primitive.ObjectID{lampID}}).Apply(items)
+	if !items[0].Discount.IsZero() {
+		t.Errorf("mug discount = %v after reapplying, want none", items[0].Discount)
+	}
+}
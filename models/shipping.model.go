@@ -0,0 +1,261 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShippingKind is how a shipping method gets the order to the customer
+type ShippingKind string
+
+const (
+	ShippingStandard ShippingKind = "standard"
+	ShippingExpress  ShippingKind = "express"
+	ShippingPickup   ShippingKind = "pickup"
+)
+
+// ShippingRateType is what a shipping method's fee depends on
+type ShippingRateType string
+
+const (
+	ShippingRateFlat   ShippingRateType = "flat"   // FlatFee for every order
+	ShippingRateWeight ShippingRateType = "weight" // The first of WeightTiers the order's weight fits in
+	ShippingRatePrice  ShippingRateType = "price"  // The first of PriceTiers the order's discounted subtotal fits in
+)
+
+// ShippingRegion is a destination of a zone: a whole state, or the zip codes
+// of a state starting with ZipPrefix
+type ShippingRegion struct {
+	State     string `bson:"state" json:"state"`
+	ZipPrefix string `bson:"zip_prefix,omitempty" json:"zip_prefix,omitempty"`
+}
+
+// WeightTier charges Fee for orders of up to MaxGrams; 0 has no upper bound
+type WeightTier struct {
+	MaxGrams int   `bson:"max_grams" json:"max_grams"`
+	Fee      Money `bson:"fee" json:"fee"`
+}
+
+// PriceTier charges Fee for orders worth up to MaxSubtotal; nil has no upper bound
+type PriceTier struct {
+	MaxSubtotal *Money `bson:"max_subtotal,omitempty" json:"max_subtotal,omitempty"`
+	Fee         Money  `bson:"fee" json:"fee"`
+}
+
+// ShippingMethod is a way of shipping to a zone, with its fee and delivery estimate
+type ShippingMethod struct {
+	Code        string           `bson:"code" json:"code"` // Chosen at checkout, unique within the zone
+	Name        string           `bson:"name" json:"name"`
+	Kind        ShippingKind     `bson:"kind" json:"kind"`
+	Rate        ShippingRateType `bson:"rate" json:"rate"`
+	FlatFee     *Money           `bson:"flat_fee,omitempty" json:"flat_fee,omitempty"`
+	WeightTiers []WeightTier     `bson:"weight_tiers,omitempty" json:"weight_tiers,omitempty"` // Ascending by MaxGrams
+	PriceTiers  []PriceTier      `bson:"price_tiers,omitempty" json:"price_tiers,omitempty"`   // Ascending by MaxSubtotal
+	MinDays     int              `bson:"min_days" json:"min_days"`                             // Delivery estimate, counted from the order date
+	MaxDays     int              `bson:"max_days" json:"max_days"`
+}
+
+// ShippingZone is an admin-configured set of destinations and the methods
+// that ship to them. An address is served by the most specific zone that
+// covers it.
+type ShippingZone struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name      string             `bson:"name" json:"name"`
+	Regions   []ShippingRegion   `bson:"regions" json:"regions"`
+	Methods   []ShippingMethod   `bson:"methods" json:"methods"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ShippingQuote is what one shipping method would cost for a cart
+type ShippingQuote struct {
+	Method   string       `bson:"method" json:"method"` // ShippingMethod.Code
+	Name     string       `bson:"name" json:"name"`
+	Kind     ShippingKind `bson:"kind" json:"kind"`
+	Cost     Money        `bson:"cost" json:"cost"`
+	Discount Money        `bson:"discount" json:"discount"` // Waived by a free shipping promotion
+	Total    Money        `bson:"total" json:"total"`
+	MinDays  int          `bson:"min_days" json:"min_days"`
+	MaxDays  int          `bson:"max_days" json:"max_days"`
+}
+
+// Normalize trims the zone's text fields and upper-cases its states
+func (z *ShippingZone) Normalize() {
+	z.Name = strings.TrimSpace(z.Name)
+	for i := range z.Regions {
+		z.Regions[i].State = strings.ToUpper(strings.TrimSpace(z.Regions[i].State))
+		z.Regions[i].ZipPrefix = strings.TrimSpace(z.Regions[i].ZipPrefix)
+	}
+	for i := range z.Methods {
+		z.Methods[i].Code = strings.ToLower(strings.TrimSpace(z.Methods[i].Code))
+		z.Methods[i].Name = strings.TrimSpace(z.Methods[i].Name)
+	}
+}
+
+// Validate checks that the zone has regions and well-formed methods with fees in currency
+func (z ShippingZone) Validate(currency string) error {
+	if z.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(z.Regions) == 0 {
+		return errors.New("at least one region is required")
+	}
+	for _, region := range z.Regions {
+		if region.State == "" {
+			return errors.New("every region needs a state")
+		}
+		if !digitsOnly(region.ZipPrefix) {
+			return errors.New("zip_prefix may only contain digits")
+		}
+	}
+	if len(z.Methods) == 0 {
+		return errors.New("at least one method is required")
+	}
+	codes := make(map[string]bool, len(z.Methods))
+	for _, method := range z.Methods {
+		if method.Code == "" {
+			return errors.New("every method needs a code")
+		}
+		if codes[method.Code] {
+			return fmt.Errorf("method code %q is used twice", method.Code)
+		}
+		codes[method.Code] = true
+		if err := method.validate(currency); err != nil {
+			return fmt.Errorf("method %s: %w", method.Code, err)
+		}
+	}
+	return nil
+}
+
+func (m ShippingMethod) validate(currency string) error {
+	switch m.Kind {
+	case ShippingStandard, ShippingExpress, ShippingPickup:
+	default:
+		return fmt.Errorf("unknown kind %q", m.Kind)
+	}
+	if m.MinDays < 0 || m.MaxDays < m.MinDays {
+		return errors.New("delivery estimate needs 0 <= min_days <= max_days")
+	}
+
+	fee := func(fee Money) error {
+		if fee.IsNegative() || fee.Currency != currency {
+			return fmt.Errorf("fees must be non-negative amounts in %s", currency)
+		}
+		return nil
+	}
+	switch m.Rate {
+	case ShippingRateFlat:
+		if m.FlatFee == nil {
+			return errors.New("flat_fee is required")
+		}
+		return fee(*m.FlatFee)
+	case ShippingRateWeight:
+		if len(m.WeightTiers) == 0 {
+			return errors.New("weight_tiers are required")
+		}
+		for i, tier := range m.WeightTiers {
+			last := i == len(m.WeightTiers)-1
+			if tier.MaxGrams < 0 || (tier.MaxGrams == 0 && !last) ||
+				(i > 0 && tier.MaxGrams != 0 && tier.MaxGrams <= m.WeightTiers[i-1].MaxGrams) {
+				return errors.New("weight_tiers must ascend by max_grams, with only the last unbounded")
+			}
+			if err := fee(tier.Fee); err != nil {
+				return err
+			}
+		}
+	case ShippingRatePrice:
+		if len(m.PriceTiers) == 0 {
+			return errors.New("price_tiers are required")
+		}
+		for i, tier := range m.PriceTiers {
+			last := i == len(m.PriceTiers)-1
+			if tier.MaxSubtotal == nil && !last {
+				return errors.New("only the last price tier may be unbounded")
+			}
+			if tier.MaxSubtotal != nil {
+				if err := fee(*tier.MaxSubtotal); err != nil {
+					return err
+				}
+				// Earlier tiers are bounded, as checked above
+				if i > 0 && tier.MaxSubtotal.Cmp(*m.PriceTiers[i-1].MaxSubtotal) <= 0 {
+					return errors.New("price_tiers must ascend by max_subtotal")
+				}
+			}
+			if err := fee(tier.Fee); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown rate %q", m.Rate)
+	}
+	return nil
+}
+
+// Covers reports whether the zone ships to address and, if so, how closely:
+// the length of the matching zip prefix, plus one so a whole state counts
+func (z ShippingZone) Covers(address Address) (int, bool) {
+	state := strings.ToUpper(strings.TrimSpace(address.State))
+	zip := strings.TrimSpace(address.ZipCode)
+	best, ok := 0, false
+	for _, region := range z.Regions {
+		if region.State == state && strings.HasPrefix(zip, region.ZipPrefix) {
+			best, ok = max(best, len(region.ZipPrefix)+1), true
+		}
+	}
+	return best, ok
+}
+
+// Fee returns the method's fee for an order of the given weight in grams
+// and discounted subtotal, or false if the order is outside every tier
+func (m ShippingMethod) Fee(grams int, subtotal Money) (Money, bool) {
+	switch m.Rate {
+	case ShippingRateFlat:
+		return *m.FlatFee, true
+	case ShippingRateWeight:
+		for _, tier := range m.WeightTiers {
+			if tier.MaxGrams == 0 || grams <= tier.MaxGrams {
+				return tier.Fee, true
+			}
+		}
+	case ShippingRatePrice:
+		for _, tier := range m.PriceTiers {
+			if tier.MaxSubtotal == nil || subtotal.Cmp(*tier.MaxSubtotal) <= 0 {
+				return tier.Fee, true
+			}
+		}
+	}
+	return Money{}, false
+}
+
+// Quote prices the method for order lines, waiving the fee when free is set
+func (m ShippingMethod) Quote(lines []OrderItem, free bool) (ShippingQuote, bool) {
+	grams := 0
+	subtotal := NewMoney(0, DefaultCurrency)
+	for _, line := range lines {
+		grams += line.Weight * line.Quantity
+		subtotal = subtotal.Add(line.Subtotal().Sub(line.Discount))
+	}
+	cost, ok := m.Fee(grams, subtotal)
+	if !ok {
+		return ShippingQuote{}, false
+	}
+
+	quote := ShippingQuote{
+		Method:   m.Code,
+		Name:     m.Name,
+		Kind:     m.Kind,
+		Cost:     cost,
+		Discount: NewMoney(0, cost.Currency),
+		MinDays:  m.MinDays,
+		MaxDays:  m.MaxDays,
+	}
+	if free {
+		quote.Discount = cost
+	}
+	quote.Total = quote.Cost.Sub(quote.Discount)
+	return quote, true
+}
@@ -0,0 +1,112 @@
+package models
+
+import "testing"
+
+func TestShippingZoneCovers(t *testing.T) {
+	zone := ShippingZone{Regions: []ShippingRegion{{State: "CA"}, {State: "NY", ZipPrefix: "100"}, {State: "NY", ZipPrefix: "1000"}}}
+	tests := []struct {
+		address Address
+		want    int
+		ok      bool
+	}{
+		{Address{State: "CA", ZipCode: "90012"}, 1, true},
+		{Address{State: " ca ", ZipCode: "90012"}, 1, true},
+		{Address{State: "NY", ZipCode: "10004"}, 5, true}, // The longest matching prefix counts
+		{Address{State: "NY", ZipCode: "10012"}, 4, true},
+		{Address{State: "NY", ZipCode: "11201"}, 0, false},
+		{Address{State: "TX", ZipCode: "10004"}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := zone.Covers(tt.address)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Covers(%+v) = %d, %v; want %d, %v", tt.address, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestShippingMethodFee(t *testing.T) {
+	cents := func(n int64) Money { return NewMoney(n, DefaultCurrency) }
+	fifty := cents(5000)
+	byWeight := ShippingMethod{Rate: ShippingRateWeight, WeightTiers: []WeightTier{{MaxGrams: 500, Fee: cents(400)}, {MaxGrams: 2000, Fee: cents(900)}}}
+	byPrice := ShippingMethod{Rate: ShippingRatePrice, PriceTiers: []PriceTier{{MaxSubtotal: &fifty, Fee: cents(700)}, {Fee: cents(0)}}}
+	tests := []struct {
+		name     string
+		method   ShippingMethod
+		grams    int
+		subtotal int64
+		want     int64
+		ok       bool
+	}{
+		{"light", byWeight, 500, 0, 400, true},
+		{"heavier", byWeight, 501, 0, 900, true},
+		{"too heavy", byWeight, 2001, 0, 0, false},
+		{"small order", byPrice, 0, 5000, 700, true},
+		{"large order ships free", byPrice, 0, 5001, 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := tt.method.Fee(tt.grams, cents(tt.subtotal))
+		if ok != tt.ok || (ok && got != cents(tt.want)) {
+			t.Errorf("%s: Fee() = %v, %v; want %d cents, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestShippingMethodQuote(t *testing.T) {
+	fee := NewMoney(500, DefaultCurrency)
+	heavy := NewMoney(1200, DefaultCurrency)
+	method := ShippingMethod{Code: "standard", Kind: ShippingStandard, Rate: ShippingRateWeight, WeightTiers: []WeightTier{{MaxGrams: 1000, Fee: fee}, {Fee: heavy}}, MinDays: 2, MaxDays: 4}
+	line := NewOrderItem(Product{Name: "Mug", Price: NewMoney(1000, DefaultCurrency), Weight: 400}, "", 3)
+
+	quote, ok := method.Quote([]OrderItem{line}, false)
+	if !ok || quote.Cost != heavy || quote.Total != heavy || quote.MaxDays != 4 {
+		t.Errorf("Quote() = %+v, %v; want 12.00 for 1.2kg", quote, ok)
+	}
+	quote, ok = method.Quote([]OrderItem{line}, true)
+	if !ok || quote.Cost != heavy || quote.Discount != heavy || !quote.Total.IsZero() {
+		t.Errorf("free Quote() = %+v, %v; want the fee waived", quote, ok)
+	}
+}
+
+func TestShippingZoneValidate(t *testing.T) {
+	fee := NewMoney(500, DefaultCurrency)
+	valid := func() ShippingZone {
+		return ShippingZone{
+			Name:    "West",
+			Regions: []ShippingRegion{{State: "CA"}, {State: "OR", ZipPrefix: "97"}},
+			Methods: []ShippingMethod{{Code: "standard", Kind: ShippingStandard, Rate: ShippingRateFlat, FlatFee: &fee, MinDays: 3, MaxDays: 5}},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(z *ShippingZone)
+		valid  bool
+	}{
+		{"valid", func(z *ShippingZone) {}, true},
+		{"no regions", func(z *ShippingZone) { z.Regions = nil }, false},
+		{"letters in zip prefix", func(z *ShippingZone) { z.Regions[1].ZipPrefix = "9A" }, false},
+		{"no methods", func(z *ShippingZone) { z.Methods = nil }, false},
+		{"duplicate code", func(z *ShippingZone) { z.Methods = append(z.Methods, z.Methods[0]) }, false},
+		{"unknown kind", func(z *ShippingZone) { z.Methods[0].Kind = "drone" }, false},
+		{"max before min days", func(z *ShippingZone) { z.Methods[0].MaxDays = 2 }, false},
+		{"flat without fee", func(z *ShippingZone) { z.Methods[0].FlatFee = nil }, false},
+		{"weight tiers out of order", func(z *ShippingZone) {
+			z.Methods[0].Rate = ShippingRateWeight
+			z.Methods[0].WeightTiers = []WeightTier{{MaxGrams: 1000, Fee: fee}, {MaxGrams: 500, Fee: fee}}
+		}, false},
+		{"unbounded weight tier first", func(z *ShippingZone) {
+			z.Methods[0].Rate = ShippingRateWeight
+			z.Methods[0].WeightTiers = []WeightTier{{Fee: fee}, {MaxGrams: 500, Fee: fee}}
+		}, false},
+		{"unbounded price tier first", func(z *ShippingZone) {
+			z.Methods[0].Rate = ShippingRatePrice
+			z.Methods[0].PriceTiers = []PriceTier{{Fee: fee}, {MaxSubtotal: &fee, Fee: fee}}
+		}, false},
+	}
+	for _, tt := range tests {
+		zone := valid()
+		tt.modify(&zone)
+		if err := zone.Validate(DefaultCurrency); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
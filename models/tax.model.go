@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxRate is one admin-managed rate of a tax. Rates sharing a Name are
+// alternatives for the same tax: for a given line the most specific match
+// applies, a rate for the product's tax category over one for every
+// category, then a longer zip code prefix over a shorter one. Rates with
+// different names stack.
+type TaxRate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name      string             `bson:"name" json:"name"`                                 // e.g. "CA State Tax", shown in the breakdown
+	State     string             `bson:"state" json:"state"`                               // Matched against Address.State, e.g. "CA"
+	ZipPrefix string             `bson:"zip_prefix,omitempty" json:"zip_prefix,omitempty"` // Narrows the rate to zip codes starting with it
+	Category  string             `bson:"category,omitempty" json:"category,omitempty"`     // Product tax category; empty for every category
+	Rate      int                `bson:"rate" json:"rate"`                                 // Basis points: 825 is 8.25%
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TaxLine is the part of a line's tax owed to one tax
+type TaxLine struct {
+	Name   string `bson:"name" json:"name"`
+	Rate   int    `bson:"rate" json:"rate"` // Basis points
+	Amount Money  `bson:"amount" json:"amount"`
+}
+
+// Normalize trims the rate's text fields and upper-cases its state
+func (r *TaxRate) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.State = strings.ToUpper(strings.TrimSpace(r.State))
+	r.ZipPrefix = strings.TrimSpace(r.ZipPrefix)
+	r.Category = strings.TrimSpace(r.Category)
+}
+
+// Validate checks that the rate names a tax and a state and is between 0 and 100%
+func (r TaxRate) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.State == "" {
+		return errors.New("state is required")
+	}
+	if !digitsOnly(r.ZipPrefix) {
+		return errors.New("zip_prefix may only contain digits")
+	}
+	if r.Rate < 0 || r.Rate > 10000 {
+		return errors.New("rate must be between 0 and 10000 basis points")
+	}
+	return nil
+}
+
+// Matches reports whether the rate applies to a line of the given tax
+// category shipped to address
+func (r TaxRate) Matches(address Address, category string) bool {
+	return strings.EqualFold(strings.TrimSpace(address.State), r.State) &&
+		strings.HasPrefix(strings.TrimSpace(address.ZipCode), r.ZipPrefix) &&
+		(r.Category == "" || strings.EqualFold(r.Category, category))
+}
+
+// MoreSpecific reports whether r should apply instead of o when both match
+func (r TaxRate) MoreSpecific(o TaxRate) bool {
+	if (r.Category != "") != (o.Category != "") {
+		return r.Category != ""
+	}
+	return len(r.ZipPrefix) > len(o.ZipPrefix)
+}
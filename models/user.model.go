@@ -22,4 +22,5 @@ type User struct {
 	Role              string             `bson:"role" json:"role"` // "user" or "admin"
 	IsVerified        bool               `bson:"is_verified" json:"is_verified"`
 	VerificationToken string             `bson:"verification_token" json:"-"`
+	CartRemindersOff  bool               `bson:"cart_reminders_off,omitempty" json:"cart_reminders_off,omitempty"` // Unsubscribed from abandoned-cart emails
 }
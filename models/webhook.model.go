@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// WebhookEventStatus is how far processing of a received webhook got
+type WebhookEventStatus string
+
+const (
+	WebhookReceived  WebhookEventStatus = "received"
+	WebhookProcessed WebhookEventStatus = "processed"
+	// WebhookIgnored marks an event that was stale or of no interest
+	WebhookIgnored WebhookEventStatus = "ignored"
+	// WebhookFailed marks an event kept for replay
+	WebhookFailed WebhookEventStatus = "failed"
+)
+
+// WebhookEvent is a payment provider event as received by the webhook endpoint
+type WebhookEvent struct {
+	ID            string             `bson:"_id" json:"id"` // "<provider>:<event id>", unique per event
+	Provider      string             `bson:"provider" json:"provider"`
+	EventID       string             `bson:"event_id" json:"event_id"`
+	Type          string             `bson:"type" json:"type"`
+	IntentID      string             `bson:"intent_id" json:"intent_id"`
+	Amount        Money              `bson:"amount" json:"amount"`
+	FailureReason string             `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	OccurredAt    time.Time          `bson:"occurred_at" json:"occurred_at"` // When the provider created the event
+	Status        WebhookEventStatus `bson:"status" json:"status"`
+	Error         string             `bson:"error,omitempty" json:"error,omitempty"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	ReceivedAt    time.Time          `bson:"received_at" json:"received_at"`
+	ProcessedAt   *time.Time         `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+}
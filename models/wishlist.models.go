@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxWishlistNameLength caps the length of a wishlist's name, in characters
+const MaxWishlistNameLength = 100
+
+// WishlistItem is a product, or one variant of it, kept on a wishlist
+type WishlistItem struct {
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	SKU       string             `bson:"sku,omitempty" json:"sku,omitempty"` // Required when the product has variants
+	// SavedPrice is the price when the item was added, so a later drop can
+	// be pointed out
+	SavedPrice *Money    `bson:"saved_price,omitempty" json:"saved_price,omitempty"`
+	AddedAt    time.Time `bson:"added_at" json:"added_at"`
+}
+
+// Wishlist is a named list of products a user wants to buy some day. A
+// public wishlist can be viewed by anyone with its ShareToken.
+type Wishlist struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	Public     bool               `bson:"public" json:"public"`
+	ShareToken string             `bson:"share_token,omitempty" json:"share_token,omitempty"` // Set while Public
+	Items      []WishlistItem     `bson:"items" json:"items"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Normalize trims the wishlist's name
+func (w *Wishlist) Normalize() {
+	w.Name = strings.TrimSpace(w.Name)
+}
+
+// Validate checks that the wishlist is named
+func (w Wishlist) Validate() error {
+	if w.Name == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(w.Name) > MaxWishlistNameLength {
+		return errors.New("name is too long")
+	}
+	return nil
+}
+
+// Item returns the wishlist's entry for a product, or one variant of it
+func (w *Wishlist) Item(productID primitive.ObjectID, sku string) (*WishlistItem, bool) {
+	for i := range w.Items {
+		if w.Items[i].ProductID == productID && w.Items[i].SKU == sku {
+			return &w.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// RemoveItem takes a product, or one variant of it, off the wishlist and
+// returns it
+func (w *Wishlist) RemoveItem(productID primitive.ObjectID, sku string) (WishlistItem, bool) {
+	for i, item := range w.Items {
+		if item.ProductID == productID && item.SKU == sku {
+			w.Items = append(w.Items[:i:i], w.Items[i+1:]...)
+			return item, true
+		}
+	}
+	return WishlistItem{}, false
+}
+
+// SavedLine shows a wishlist or saved-for-later item as it stands now: the
+// product's current price and stock, and whether the price has dropped
+// since the item was saved
+type SavedLine struct {
+	ProductID    primitive.ObjectID `json:"product_id"`
+	SKU          string             `json:"sku,omitempty"`
+	Name         string             `json:"name,omitempty"`
+	Options      map[string]string  `json:"options,omitempty"`
+	ImageURL     string             `json:"image_url,omitempty"`
+	Quantity     int                `json:"quantity,omitempty"` // Saved-for-later items only
+	Price        *Money             `json:"price,omitempty"`    // Current price; unset when Unavailable
+	SavedPrice   *Money             `json:"saved_price,omitempty"`
+	PriceDropped bool               `json:"price_dropped"`
+	Available    int                `json:"available"`
+	InStock      bool               `json:"in_stock"`
+	Unavailable  bool               `json:"unavailable,omitempty"` // The product or variant was removed
+}
+
+// NewSavedLine shows quantity units of a product, or of its variant with the
+// given SKU, saved at savedPrice. A nil product, or a variant it no longer
+// has, makes the line Unavailable.
+func NewSavedLine(product *Product, productID primitive.ObjectID, sku string, quantity int, savedPrice *Money) SavedLine {
+	line := SavedLine{ProductID: productID, SKU: sku, Quantity: quantity, SavedPrice: savedPrice}
+	if product == nil {
+		line.Unavailable = true
+		return line
+	}
+	line.Name = product.Name
+	if _, ok := product.Variant(sku); product.HasVariants() && !ok {
+		line.Unavailable = true
+		return line
+	}
+
+	item := NewOrderItem(*product, sku, 1)
+	line.Options = item.Options
+	line.ImageURL = item.ImageURL
+	line.Price = &item.UnitPrice
+	line.Available = product.AvailableFor(sku)
+	line.InStock = line.Available > 0
+	line.PriceDropped = savedPrice != nil && savedPrice.Currency == item.UnitPrice.Currency && item.UnitPrice.Amount < savedPrice.Amount
+	return line
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateWishlist(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"Birthday", true},
+		{"  ", false},
+		{strings.Repeat("é", MaxWishlistNameLength), true},
+		{strings.Repeat("é", MaxWishlistNameLength+1), false},
+	}
+	for _, tt := range tests {
+		w := Wishlist{Name: tt.name}
+		w.Normalize()
+		if err := w.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestNewSavedLine(t *testing.T) {
+	p := shirt()
+	p.ID = primitive.NewObjectID()
+	dearer := NewMoney(3000, DefaultCurrency)
+	cheaper := NewMoney(2000, DefaultCurrency)
+
+	line := NewSavedLine(&p, p.ID, "SHIRT-M", 1, &dearer)
+	if line.Unavailable || *line.Price != NewMoney(2500, DefaultCurrency) || line.Available != 2 || !line.InStock || !line.PriceDropped {
+		t.Errorf("line = %+v, want SHIRT-M at 25.00, 2 in stock, cheaper than saved", line)
+	}
+	if line.Options["size"] != "M" {
+		t.Errorf("options = %v, want size M", line.Options)
+	}
+	if line := NewSavedLine(&p, p.ID, "SHIRT-M", 1, &cheaper); line.PriceDropped {
+		t.Error("a price rise is flagged as a drop")
+	}
+	if line := NewSavedLine(&p, p.ID, "SHIRT-M", 1, nil); line.PriceDropped {
+		t.Error("an item saved without a price is flagged as a drop")
+	}
+	if line := NewSavedLine(&p, p.ID, "SHIRT-S", 1, nil); line.InStock || line.Unavailable {
+		t.Errorf("line = %+v, want SHIRT-S out of stock but still sold", line)
+	}
+	if line := NewSavedLine(&p, p.ID, "SHIRT-XL", 1, nil); !line.Unavailable || line.Price != nil {
+		t.Errorf("line = %+v, want a removed variant unavailable", line)
+	}
+	if line := NewSavedLine(nil, p.ID, "", 1, &dearer); !line.Unavailable || line.PriceDropped {
+		t.Errorf("line = %+v, want a deleted product unavailable", line)
+	}
+}
@@ -0,0 +1,197 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Test tokens understood by the mock gateway
+const (
+	TokenSuccess = "tok_success" // Authorizes immediately
+	TokenDecline = "tok_decline" // Declined by the issuer
+	TokenThreeDS = "tok_3ds"     // Requires a 3-D Secure challenge
+)
+
+// challenge states of a mock 3-D Secure check
+const (
+	challengePending = "pending"
+	challengePassed  = "passed"
+	challengeFailed  = "failed"
+)
+
+// Mock is an in-memory gateway that simulates approvals, declines and 3-D
+// Secure challenges. Challenges are completed through ChallengeHandler.
+type Mock struct {
+	mu            sync.Mutex
+	baseURL       string
+	webhookSecret []byte
+	intents       map[string]*Intent
+	challenges    map[string]string
+}
+
+var _ Provider = (*Mock)(nil)
+
+// NewMock creates a mock gateway; baseURL is where this API is reachable and
+// is used to build 3-D Secure challenge links. Webhooks must be signed with
+// webhookSecret.
+func NewMock(baseURL string, webhookSecret []byte) *Mock {
+	return &Mock{
+		baseURL:       baseURL,
+		webhookSecret: webhookSecret,
+		intents:       make(map[string]*Intent),
+		challenges:    make(map[string]string),
+	}
+}
+
+// Name identifies the provider on stored payment records
+func (m *Mock) Name() string {
+	return "mock"
+}
+
+// CreateIntent starts a payment for amount
+func (m *Mock) CreateIntent(ctx context.Context, amount int64, currency, reference string) (*Intent, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	intent := &Intent{
+		ID:       "pi_mock_" + hex.EncodeToString(b),
+		Status:   IntentRequiresConfirmation,
+		Amount:   amount,
+		Currency: currency,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.intents[intent.ID] = intent
+	copied := *intent
+	return &copied, nil
+}
+
+// ConfirmIntent applies the outcome selected by the test token, or finishes a
+// confirmation once its 3-D Secure challenge has been completed
+func (m *Mock) ConfirmIntent(ctx context.Context, intentID, paymentToken string) (*Intent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	intent, ok := m.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+
+	switch intent.Status {
+	case IntentRequiresConfirmation, IntentFailed:
+		// A failed intent may be retried with another card
+		intent.FailureReason = ""
+		intent.NextActionURL = ""
+		switch paymentToken {
+		case TokenSuccess:
+			intent.Status = IntentRequiresCapture
+		case TokenThreeDS:
+			intent.Status = IntentRequiresAction
+			intent.NextActionURL = m.baseURL + "/payments/mock/3ds/" + intent.ID
+			m.challenges[intent.ID] = challengePending
+		case TokenDecline:
+			intent.Status = IntentFailed
+			intent.FailureReason = "card_declined"
+		default:
+			intent.Status = IntentFailed
+			intent.FailureReason = "invalid_payment_token"
+		}
+	case IntentRequiresAction:
+		switch m.challenges[intent.ID] {
+		case challengePassed:
+			intent.Status = IntentRequiresCapture
+			intent.NextActionURL = ""
+		case challengeFailed:
+			intent.Status = IntentFailed
+			intent.FailureReason = "authentication_failed"
+			intent.NextActionURL = ""
+		}
+	default:
+		return nil, ErrInvalidState
+	}
+
+	copied := *intent
+	return &copied, nil
+}
+
+// Capture collects an authorized payment
+func (m *Mock) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	return m.move(intentID, IntentRequiresCapture, IntentSucceeded)
+}
+
+// Refund returns a captured payment in full
+func (m *Mock) Refund(ctx context.Context, intentID string) (*Intent, error) {
+	return m.move(intentID, IntentSucceeded, IntentRefunded)
+}
+
+// ParseWebhook verifies the signature of a JSON event body and decodes it
+func (m *Mock) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	err := VerifySignature(m.webhookSecret, payload, header.Get(SignatureHeader), DefaultTolerance, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil || event.ID == "" || event.IntentID == "" {
+		return nil, ErrInvalidWebhook
+	}
+	return &event, nil
+}
+
+// SignWebhook signs payload as the mock gateway would, for sending test
+// events to the webhook endpoint
+func (m *Mock) SignWebhook(payload []byte) string {
+	return Sign(m.webhookSecret, payload, time.Now())
+}
+
+// ChallengeHandler completes a 3-D Secure challenge. It serves
+// /payments/mock/3ds/{id}?outcome=pass|fail and defaults to pass.
+func (m *Mock) ChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	outcome := challengePassed
+	if r.URL.Query().Get("outcome") == "fail" {
+		outcome = challengeFailed
+	}
+
+	m.mu.Lock()
+	state, ok := m.challenges[id]
+	if ok && state == challengePending {
+		m.challenges[id] = outcome
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "Challenge not found", http.StatusNotFound)
+		return
+	}
+	if state != challengePending {
+		http.Error(w, "Challenge already completed", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"intent_id": id, "challenge": outcome})
+}
+
+func (m *Mock) move(intentID string, from, to IntentStatus) (*Intent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	intent, ok := m.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != from {
+		return nil, ErrInvalidState
+	}
+	intent.Status = to
+	copied := *intent
+	return &copied, nil
+}
@@ -0,0 +1,117 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMockConfirmIntent(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		token   string
+		status  IntentStatus
+		failure string
+	}{
+		{TokenSuccess, IntentRequiresCapture, ""},
+		{TokenDecline, IntentFailed, "card_declined"},
+		{TokenThreeDS, IntentRequiresAction, ""},
+		{"tok_unknown", IntentFailed, "invalid_payment_token"},
+	}
+	for _, tt := range tests {
+		m := NewMock("http://shop.test", nil)
+		intent, err := m.CreateIntent(ctx, 25, "USD", "order-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		intent, err = m.ConfirmIntent(ctx, intent.ID, tt.token)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.token, err)
+		}
+		if intent.Status != tt.status || intent.FailureReason != tt.failure {
+			t.Errorf("%s: intent = %+v, want %s %q", tt.token, intent, tt.status, tt.failure)
+		}
+		if (tt.status == IntentRequiresAction) != (intent.NextActionURL != "") {
+			t.Errorf("%s: next action URL = %q", tt.token, intent.NextActionURL)
+		}
+	}
+}
+
+func TestMockLifecycle(t *testing.T) {
+	ctx := context.Background()
+	m := NewMock("http://shop.test", nil)
+	intent, _ := m.CreateIntent(ctx, 25, "USD", "order-1")
+
+	if _, err := m.Capture(ctx, intent.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Capture before confirmation error = %v, want ErrInvalidState", err)
+	}
+	// A declined intent may be retried with another card
+	if intent, _ = m.ConfirmIntent(ctx, intent.ID, TokenDecline); intent.Status != IntentFailed {
+		t.Fatalf("intent = %+v, want it declined", intent)
+	}
+	if intent, _ = m.ConfirmIntent(ctx, intent.ID, TokenSuccess); intent.Status != IntentRequiresCapture || intent.FailureReason != "" {
+		t.Fatalf("intent = %+v, want it authorized", intent)
+	}
+	if _, err := m.Refund(ctx, intent.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Refund before capture error = %v, want ErrInvalidState", err)
+	}
+	if intent, _ = m.Capture(ctx, intent.ID); intent.Status != IntentSucceeded {
+		t.Fatalf("intent = %+v, want it captured", intent)
+	}
+	if _, err := m.ConfirmIntent(ctx, intent.ID, TokenSuccess); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("ConfirmIntent after capture error = %v, want ErrInvalidState", err)
+	}
+	if intent, _ = m.Refund(ctx, intent.ID); intent.Status != IntentRefunded {
+		t.Fatalf("intent = %+v, want it refunded", intent)
+	}
+	if _, err := m.Refund(ctx, intent.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("second Refund error = %v, want ErrInvalidState", err)
+	}
+	if _, err := m.ConfirmIntent(ctx, "pi_missing", TokenSuccess); !errors.Is(err, ErrIntentNotFound) {
+		t.Errorf("ConfirmIntent of unknown intent error = %v, want ErrIntentNotFound", err)
+	}
+}
+
+func TestMockChallenge(t *testing.T) {
+	ctx := context.Background()
+	m := NewMock("http://shop.test", nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/payments/mock/3ds/{id}", m.ChallengeHandler)
+	challenge := func(id, outcome string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/payments/mock/3ds/"+id+"?outcome="+outcome, nil))
+		return rec.Code
+	}
+
+	for _, tt := range []struct {
+		outcome string
+		want    IntentStatus
+	}{{"pass", IntentRequiresCapture}, {"fail", IntentFailed}} {
+		intent, _ := m.CreateIntent(ctx, 25, "USD", "order-1")
+		intent, _ = m.ConfirmIntent(ctx, intent.ID, TokenThreeDS)
+		if intent.NextActionURL != "http://shop.test/payments/mock/3ds/"+intent.ID {
+			t.Errorf("next action URL = %q", intent.NextActionURL)
+		}
+
+		// Confirming before the challenge is done changes nothing
+		if intent, _ = m.ConfirmIntent(ctx, intent.ID, ""); intent.Status != IntentRequiresAction {
+			t.Errorf("intent = %+v, want it still waiting", intent)
+		}
+		if code := challenge(intent.ID, tt.outcome); code != http.StatusOK {
+			t.Fatalf("challenge = %d", code)
+		}
+		if code := challenge(intent.ID, tt.outcome); code != http.StatusConflict {
+			t.Errorf("second challenge = %d, want 409", code)
+		}
+		if intent, _ = m.ConfirmIntent(ctx, intent.ID, ""); intent.Status != tt.want || intent.NextActionURL != "" {
+			t.Errorf("%s: intent = %+v, want %s", tt.outcome, intent, tt.want)
+		}
+	}
+	if code := challenge("pi_missing", "pass"); code != http.StatusNotFound {
+		t.Errorf("unknown challenge = %d, want 404", code)
+	}
+}
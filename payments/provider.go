@@ -0,0 +1,79 @@
+// Package payments abstracts card payment gateways behind the Provider
+// interface. Mock is a local gateway for offline development and tests.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// IntentStatus is the gateway-side state of a payment intent
+type IntentStatus string
+
+const (
+	IntentRequiresConfirmation IntentStatus = "requires_confirmation"
+	IntentRequiresAction       IntentStatus = "requires_action"  // e.g. a 3-D Secure challenge
+	IntentRequiresCapture      IntentStatus = "requires_capture" // Authorized, not yet captured
+	IntentSucceeded            IntentStatus = "succeeded"
+	IntentFailed               IntentStatus = "failed"
+	IntentRefunded             IntentStatus = "refunded"
+)
+
+// Intent is a gateway payment intent
+type Intent struct {
+	ID            string       `json:"id"`
+	Status        IntentStatus `json:"status"`
+	Amount        int64        `json:"amount"` // Minor units, e.g. cents
+	Currency      string       `json:"currency"`
+	NextActionURL string       `json:"next_action_url,omitempty"` // Where the customer completes a challenge
+	FailureReason string       `json:"failure_reason,omitempty"`
+}
+
+// EventType identifies what a webhook event reports
+type EventType string
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+	EventPaymentRefunded  EventType = "payment.refunded"
+)
+
+// Event is a webhook notification from the gateway
+type Event struct {
+	ID            string    `json:"id"`
+	Type          EventType `json:"type"`
+	IntentID      string    `json:"intent_id"`
+	Amount        int64     `json:"amount"` // Minor units, e.g. cents
+	Currency      string    `json:"currency"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	Created       int64     `json:"created"` // Unix seconds
+}
+
+var (
+	// ErrIntentNotFound is returned for an unknown intent ID
+	ErrIntentNotFound = errors.New("payments: intent not found")
+	// ErrInvalidState is returned when an operation does not fit the intent's status
+	ErrInvalidState = errors.New("payments: intent is not in a valid state for this operation")
+	// ErrInvalidWebhook is returned for a webhook payload that cannot be parsed
+	ErrInvalidWebhook = errors.New("payments: invalid webhook payload")
+)
+
+// Provider is a card payment gateway. Declines are not errors: they come back
+// as an Intent with status IntentFailed and a FailureReason.
+type Provider interface {
+	// Name identifies the provider on stored payment records
+	Name() string
+	// CreateIntent starts a payment for amount, in minor units of currency;
+	// reference is our order ID
+	CreateIntent(ctx context.Context, amount int64, currency, reference string) (*Intent, error)
+	// ConfirmIntent attempts the payment with a tokenised card. Calling it again
+	// after a challenge finishes the confirmation.
+	ConfirmIntent(ctx context.Context, intentID, paymentToken string) (*Intent, error)
+	// Capture collects an authorized payment
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Refund returns a captured payment in full
+	Refund(ctx context.Context, intentID string) (*Intent, error)
+	// ParseWebhook decodes a webhook request body into an event
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
@@ -0,0 +1,86 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries a webhook's signature as "t=<unix seconds>,v1=<hex>",
+// where the hex value is the HMAC-SHA256 of "<t>.<body>" under the shared secret
+const SignatureHeader = "Payment-Signature"
+
+// DefaultTolerance is how far a webhook's signed timestamp may be from our
+// clock before it is rejected as a possible replay
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned when a webhook signature is missing or wrong
+	ErrInvalidSignature = errors.New("payments: invalid webhook signature")
+	// ErrStaleWebhook is returned when a webhook's timestamp is outside the tolerance
+	ErrStaleWebhook = errors.New("payments: webhook timestamp outside tolerance")
+)
+
+// Sign returns the SignatureHeader value for payload sent at the given time
+func Sign(secret, payload []byte, at time.Time) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac(secret, t, payload))
+}
+
+// VerifySignature checks a SignatureHeader value against payload. Any of
+// several v1 signatures may match, which allows the secret to be rotated.
+// Without a secret nothing verifies.
+func VerifySignature(secret, payload []byte, header string, tolerance time.Duration, now time.Time) error {
+	if len(secret) == 0 {
+		return ErrInvalidSignature
+	}
+
+	var t string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	expected := mac(secret, t, payload)
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrStaleWebhook
+	}
+	return nil
+}
+
+func mac(secret []byte, t string, payload []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,45 @@
+package payments
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("whsec_test")
+	payload := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1_700_000_000, 0)
+	signed := Sign(secret, payload, now)
+	v1 := signed[strings.Index(signed, "v1="):]
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		payload string
+		header  string
+		want    error
+	}{
+		{"valid", secret, `{"id":"evt_1"}`, signed, nil},
+		// During rotation the provider signs with the old and the new secret
+		{"rotated secret", secret, `{"id":"evt_1"}`, Sign([]byte("whsec_old"), payload, now) + "," + v1, nil},
+		{"within tolerance", secret, `{"id":"evt_1"}`, Sign(secret, payload, now.Add(-4*time.Minute)), nil},
+		{"wrong secret", []byte("whsec_other"), `{"id":"evt_1"}`, signed, ErrInvalidSignature},
+		{"no secret", nil, `{"id":"evt_1"}`, signed, ErrInvalidSignature},
+		{"tampered body", secret, `{"id":"evt_2"}`, signed, ErrInvalidSignature},
+		{"missing header", secret, `{"id":"evt_1"}`, "", ErrInvalidSignature},
+		{"missing timestamp", secret, `{"id":"evt_1"}`, v1, ErrInvalidSignature},
+		{"not hex", secret, `{"id":"evt_1"}`, "t=1700000000,v1=zz", ErrInvalidSignature},
+		// The timestamp is signed too, so it cannot be moved forward
+		{"resigned timestamp", secret, `{"id":"evt_1"}`, "t=1700000001," + v1, ErrInvalidSignature},
+		{"stale", secret, `{"id":"evt_1"}`, Sign(secret, payload, now.Add(-6*time.Minute)), ErrStaleWebhook},
+		{"from the future", secret, `{"id":"evt_1"}`, Sign(secret, payload, now.Add(6*time.Minute)), ErrStaleWebhook},
+	}
+	for _, tt := range tests {
+		err := VerifySignature(tt.secret, []byte(tt.payload), tt.header, DefaultTolerance, now)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: VerifySignature error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -9,19 +9,30 @@ import (
 )
 
 // RegisterRoutes sets up all the routes for the application
-func RegisterRoutes(router *mux.Router, userController *controllers.UserController, productController *controllers.ProductController, cartController *controllers.CartController, orderController *controllers.OrderController) {
+func RegisterRoutes(router *mux.Router, userController *controllers.UserController, productController *controllers.ProductController, cartController *controllers.CartController, orderController *controllers.OrderController, promotionController *controllers.PromotionController, taxController *controllers.TaxController, shippingController *controllers.ShippingController, holidayController *controllers.HolidayController, wishlistController *controllers.WishlistController) {
 	// Public routes
 	router.HandleFunc("/register", userController.Register).Methods("POST")
 	router.HandleFunc("/login", userController.Login).Methods("POST")
 	router.HandleFunc("/verify", userController.VerifyEmail).Methods("GET")
+	router.HandleFunc("/token/refresh", userController.RefreshToken).Methods("POST")
+	router.HandleFunc("/password/forgot", userController.ForgotPassword).Methods("POST")
+	router.HandleFunc("/password/reset", userController.ResetPassword).Methods("POST")
+	router.HandleFunc("/webhooks/payments", orderController.HandlePaymentWebhook).Methods("POST")
+	router.HandleFunc("/cart/reminders/unsubscribe", cartController.UnsubscribeFromCartReminders).Methods("GET")
+	router.HandleFunc("/wishlists/shared/{token}", wishlistController.GetSharedWishlist).Methods("GET")
 
 	// Protected routes
 	protected := router.PathPrefix("/").Subrouter()
 	protected.Use(middleware.AuthMiddleware)
 	protected.HandleFunc("/profile", userController.GetProfile).Methods("GET")
+	protected.HandleFunc("/logout", userController.Logout).Methods("POST")
+	protected.HandleFunc("/logout/all", userController.LogoutAll).Methods("POST")
+	protected.HandleFunc("/products/{id}/stock-subscription", productController.SubscribeToStock).Methods("POST")
+	protected.HandleFunc("/products/{id}/stock-subscription", productController.UnsubscribeFromStock).Methods("DELETE")
 
 	// Product routes
 	router.HandleFunc("/products", productController.GetProducts).Methods("GET")
+	router.HandleFunc("/products/search", productController.SearchProducts).Methods("GET")
 	router.HandleFunc("/products/{id}", productController.GetProductByID).Methods("GET")
 
 	// Admin routes
@@ -31,14 +42,109 @@ func RegisterRoutes(router *mux.Router, userController *controllers.UserControll
 	admin.HandleFunc("", productController.CreateProduct).Methods("POST")
 	admin.HandleFunc("/{id}", productController.UpdateProduct).Methods("PUT")
 	admin.HandleFunc("/{id}", productController.DeleteProduct).Methods("DELETE")
+	admin.HandleFunc("/{id}/stock-movements", productController.ListStockMovements).Methods("GET")
+	admin.HandleFunc("/{id}/stock-movements", productController.RecordStockMovement).Methods("POST")
+	admin.HandleFunc("/{id}/stock/reconcile", productController.ReconcileStock).Methods("POST")
 
-	// Cart Routes
-	router.HandleFunc("/cart", cartController.AddToCart).Methods("POST")
-	router.HandleFunc("/cart", cartController.GetCart).Methods("GET")
-	router.HandleFunc("/cart", cartController.RemoveFromCart).Methods("DELETE")
+	// Cart Routes; anonymous visitors use a guest cart through its X-Cart-Token
+	carts := router.PathPrefix("/cart").Subrouter()
+	carts.Use(middleware.OptionalAuthMiddleware)
+	carts.HandleFunc("", cartController.AddToCart).Methods("POST")
+	carts.HandleFunc("", cartController.GetCart).Methods("GET")
+	carts.HandleFunc("/items/{product_id}", cartController.UpdateCartItem).Methods("PATCH")
+	carts.HandleFunc("/items/{product_id}", cartController.RemoveFromCart).Methods("DELETE")
+	carts.HandleFunc("/items/{product_id}/save-for-later", cartController.SaveForLater).Methods("POST")
+	carts.HandleFunc("/saved/{product_id}/move-to-cart", cartController.MoveToCart).Methods("POST")
+	carts.HandleFunc("/saved/{product_id}", cartController.RemoveSavedItem).Methods("DELETE")
+	protected.HandleFunc("/cart/coupon", cartController.ApplyCoupon).Methods("POST")
+	protected.HandleFunc("/cart/coupon", cartController.RemoveCoupon).Methods("DELETE")
+	protected.HandleFunc("/cart/shipping-options", cartController.GetShippingOptions).Methods("GET")
+	protected.HandleFunc("/cart/items/{product_id}/move-to-wishlist", wishlistController.MoveCartItemToWishlist).Methods("POST")
+
+	// Wishlist Routes
+	protected.HandleFunc("/wishlists", wishlistController.ListWishlists).Methods("GET")
+	protected.HandleFunc("/wishlists", wishlistController.CreateWishlist).Methods("POST")
+	protected.HandleFunc("/wishlists/{id}", wishlistController.GetWishlist).Methods("GET")
+	protected.HandleFunc("/wishlists/{id}", wishlistController.UpdateWishlist).Methods("PUT")
+	protected.HandleFunc("/wishlists/{id}", wishlistController.DeleteWishlist).Methods("DELETE")
+	protected.HandleFunc("/wishlists/{id}/items", wishlistController.AddWishlistItem).Methods("POST")
+	protected.HandleFunc("/wishlists/{id}/items/{product_id}", wishlistController.RemoveWishlistItem).Methods("DELETE")
+	protected.HandleFunc("/wishlists/{id}/items/{product_id}/move-to-cart", wishlistController.MoveWishlistItemToCart).Methods("POST")
 
 	//Order Routes
-	router.HandleFunc("/orders", orderController.GetOrders).Methods("GET")
-	router.HandleFunc("/order", orderController.CreateOrder).Methods("POST")
-	router.HandleFunc("/order/{id}", orderController.UpdateOrderPaymentStatus).Methods("UPADTE")
+	protected.HandleFunc("/orders", orderController.GetOrders).Methods("GET")
+	protected.HandleFunc("/order", orderController.CreateOrder).Methods("POST")
+	protected.HandleFunc("/orders/{id}/invoice", orderController.GetInvoice).Methods("GET")
+	protected.HandleFunc("/orders/{id}/cancel", orderController.CancelOrder).Methods("POST")
+	protected.HandleFunc("/orders/{id}/payment/confirm", orderController.ConfirmPayment).Methods("POST")
+	protected.HandleFunc("/orders/{id}/payment-proof", orderController.UploadPaymentProof).Methods("POST")
+
+	// Admin order management
+	adminOrders := router.PathPrefix("/admin/orders").Subrouter()
+	adminOrders.Use(middleware.AuthMiddleware)
+	adminOrders.Use(middleware.AdminMiddleware)
+	adminOrders.HandleFunc("", orderController.ListAllOrders).Methods("GET")
+	adminOrders.HandleFunc("/{id}", orderController.GetOrderByID).Methods("GET")
+	adminOrders.HandleFunc("/{id}/history", orderController.GetOrderHistory).Methods("GET")
+	adminOrders.HandleFunc("/{id}/invoice", orderController.GetOrderInvoice).Methods("GET")
+	adminOrders.HandleFunc("/{id}/status", orderController.UpdateOrderStatus).Methods("POST")
+	adminOrders.HandleFunc("/{id}/payment-status", orderController.UpdateOrderPaymentStatus).Methods("PUT")
+
+	// Admin crypto payment proof review
+	adminPayments := router.PathPrefix("/admin/payments").Subrouter()
+	adminPayments.Use(middleware.AuthMiddleware)
+	adminPayments.Use(middleware.AdminMiddleware)
+	adminPayments.HandleFunc("/proofs", orderController.ListPendingProofs).Methods("GET")
+	adminPayments.HandleFunc("/{id}/proof", orderController.GetPaymentProof).Methods("GET")
+	adminPayments.HandleFunc("/{id}/review", orderController.ReviewPaymentProof).Methods("POST")
+
+	// Admin payment webhook events
+	adminWebhooks := router.PathPrefix("/admin/webhooks").Subrouter()
+	adminWebhooks.Use(middleware.AuthMiddleware)
+	adminWebhooks.Use(middleware.AdminMiddleware)
+	adminWebhooks.HandleFunc("", orderController.ListWebhookEvents).Methods("GET")
+	adminWebhooks.HandleFunc("/{id}/replay", orderController.ReplayWebhookEvent).Methods("POST")
+
+	// Admin abandoned-cart recovery report
+	adminCarts := router.PathPrefix("/admin/carts").Subrouter()
+	adminCarts.Use(middleware.AuthMiddleware)
+	adminCarts.Use(middleware.AdminMiddleware)
+	adminCarts.HandleFunc("/recovery", cartController.GetCartRecoveryStats).Methods("GET")
+
+	// Admin promotions and coupons
+	adminPromotions := router.PathPrefix("/admin/promotions").Subrouter()
+	adminPromotions.Use(middleware.AuthMiddleware)
+	adminPromotions.Use(middleware.AdminMiddleware)
+	adminPromotions.HandleFunc("", promotionController.ListPromotions).Methods("GET")
+	adminPromotions.HandleFunc("", promotionController.CreatePromotion).Methods("POST")
+	adminPromotions.HandleFunc("/{id}", promotionController.GetPromotion).Methods("GET")
+	adminPromotions.HandleFunc("/{id}", promotionController.UpdatePromotion).Methods("PUT")
+	adminPromotions.HandleFunc("/{id}", promotionController.DeletePromotion).Methods("DELETE")
+
+	// Admin tax rate tables
+	adminTax := router.PathPrefix("/admin/tax-rates").Subrouter()
+	adminTax.Use(middleware.AuthMiddleware)
+	adminTax.Use(middleware.AdminMiddleware)
+	adminTax.HandleFunc("", taxController.ListTaxRates).Methods("GET")
+	adminTax.HandleFunc("", taxController.CreateTaxRate).Methods("POST")
+	adminTax.HandleFunc("/{id}", taxController.UpdateTaxRate).Methods("PUT")
+	adminTax.HandleFunc("/{id}", taxController.DeleteTaxRate).Methods("DELETE")
+
+	// Admin shipping zones and methods
+	adminShipping := router.PathPrefix("/admin/shipping-zones").Subrouter()
+	adminShipping.Use(middleware.AuthMiddleware)
+	adminShipping.Use(middleware.AdminMiddleware)
+	adminShipping.HandleFunc("", shippingController.ListShippingZones).Methods("GET")
+	adminShipping.HandleFunc("", shippingController.CreateShippingZone).Methods("POST")
+	adminShipping.HandleFunc("/{id}", shippingController.UpdateShippingZone).Methods("PUT")
+	adminShipping.HandleFunc("/{id}", shippingController.DeleteShippingZone).Methods("DELETE")
+
+	// Admin holiday calendar for delivery estimates
+	adminHolidays := router.PathPrefix("/admin/holidays").Subrouter()
+	adminHolidays.Use(middleware.AuthMiddleware)
+	adminHolidays.Use(middleware.AdminMiddleware)
+	adminHolidays.HandleFunc("", holidayController.ListHolidays).Methods("GET")
+	adminHolidays.HandleFunc("", holidayController.CreateHoliday).Methods("POST")
+	adminHolidays.HandleFunc("/{id}", holidayController.UpdateHoliday).Methods("PUT")
+	adminHolidays.HandleFunc("/{id}", holidayController.DeleteHoliday).Methods("DELETE")
 }
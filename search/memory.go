@@ -0,0 +1,96 @@
+package search
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Memory is an in-process inverted index over product fields
+type Memory struct {
+	mu       sync.RWMutex
+	products map[primitive.ObjectID]models.Product
+	postings map[string]map[primitive.ObjectID]struct{} // token to products containing it
+}
+
+var _ Index = (*Memory)(nil)
+
+// NewMemory creates an empty in-process index
+func NewMemory() *Memory {
+	return &Memory{
+		products: make(map[primitive.ObjectID]models.Product),
+		postings: make(map[string]map[primitive.ObjectID]struct{}),
+	}
+}
+
+// Search returns up to limit products matching the query, best match first
+func (m *Memory) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	terms := tokenize(query)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Every token that matches some term exactly, by prefix or within the typo
+	// allowance contributes its products as candidates
+	candidates := make(map[primitive.ObjectID]struct{})
+	for token, ids := range m.postings {
+		for _, term := range terms {
+			if matchTerm(term, token) > 0 {
+				for id := range ids {
+					candidates[id] = struct{}{}
+				}
+				break
+			}
+		}
+	}
+
+	hits := []Hit{}
+	for id := range candidates {
+		if hit, ok := newHit(m.products[id], terms, 0); ok {
+			hits = append(hits, hit)
+		}
+	}
+	return rank(hits, limit), nil
+}
+
+// Upsert adds or refreshes a product in the index
+func (m *Memory) Upsert(ctx context.Context, product models.Product) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(product.ID)
+	m.products[product.ID] = product
+	for _, f := range fields {
+		for _, token := range tokenize(f.text(product)) {
+			if m.postings[token] == nil {
+				m.postings[token] = make(map[primitive.ObjectID]struct{})
+			}
+			m.postings[token][product.ID] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// Remove drops a product from the index
+func (m *Memory) Remove(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+	return nil
+}
+
+func (m *Memory) remove(id primitive.ObjectID) {
+	old, ok := m.products[id]
+	if !ok {
+		return
+	}
+	for _, f := range fields {
+		for _, token := range tokenize(f.text(old)) {
+			delete(m.postings[token], id)
+			if len(m.postings[token]) == 0 {
+				delete(m.postings, token)
+			}
+		}
+	}
+	delete(m.products, id)
+}
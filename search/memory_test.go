@@ -0,0 +1,112 @@
+package search
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMatchTerm(t *testing.T) {
+	tests := []struct {
+		term, token string
+		want        float64
+	}{
+		{"mug", "mug", exactMatch},
+		{"ce", "ceramic", prefixMatch},
+		{"c", "ceramic", 0}, // Too short for a prefix
+		{"lamb", "lamp", fuzzyMatch},
+		{"ceramci", "ceramic", fuzzyMatch}, // Transposition
+		{"keyboard", "kyebaord", fuzzyMatch},
+		{"mug", "mud", 0}, // Short terms must match exactly
+		{"lamp", "lump", fuzzyMatch},
+		{"lamp", "limb", 0},
+	}
+	for _, tt := range tests {
+		if got := matchTerm(tt.term, tt.token); got != tt.want {
+			t.Errorf("matchTerm(%q, %q) = %v, want %v", tt.term, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+		ok    bool
+	}{
+		{"Blue ceramic mug", []string{"mug"}, "Blue ceramic <em>mug</em>", true},
+		{"Mugs & <cups>", []string{"mug", "cups"}, "<em>Mugs</em> &amp; &lt;<em>cups</em>&gt;", true},
+		{"Desk lamp", []string{"mug"}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := highlight(tt.text, tt.terms)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("highlight(%q, %v) = %q, %v; want %q, %v", tt.text, tt.terms, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMemorySearch(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemory()
+	mug := models.Product{ID: primitive.NewObjectID(), Name: "Ceramic mug", Category: "kitchen", Description: "Holds tea"}
+	teapot := models.Product{ID: primitive.NewObjectID(), Name: "Teapot", Category: "kitchen", Description: "Pairs with any mug"}
+	lamp := models.Product{ID: primitive.NewObjectID(), Name: "Desk lamp", Category: "home"}
+	for _, product := range []models.Product{mug, teapot, lamp} {
+		if err := index.Upsert(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(hits []Hit) []primitive.ObjectID {
+		var ids []primitive.ObjectID
+		for _, hit := range hits {
+			ids = append(ids, hit.Product.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		query string
+		limit int
+		want  []primitive.ObjectID
+	}{
+		// A name match outranks a description match
+		{"mug", 10, []primitive.ObjectID{mug.ID, teapot.ID}},
+		{"mug", 1, []primitive.ObjectID{mug.ID}},
+		{"kitchen lamp", 10, []primitive.ObjectID{lamp.ID, mug.ID, teapot.ID}},
+		{"lmap", 10, []primitive.ObjectID{lamp.ID}},
+		{"sofa", 10, nil},
+	}
+	for _, tt := range tests {
+		hits, err := index.Search(ctx, tt.query, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(hits); len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	hits, _ := index.Search(ctx, "mug", 1)
+	if hits[0].Highlights["name"] != "Ceramic <em>mug</em>" {
+		t.Errorf("highlights = %v", hits[0].Highlights)
+	}
+
+	// Renaming a product drops its old tokens
+	mug.Name = "Ceramic cup"
+	if err := index.Upsert(ctx, mug); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := index.Search(ctx, "mug", 10); len(hits) != 1 || hits[0].Product.ID != teapot.ID {
+		t.Errorf("after rename, Search(mug) = %v, want only the teapot", ids(hits))
+	}
+	if err := index.Remove(ctx, teapot.ID); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := index.Search(ctx, "mug", 10); len(hits) != 0 {
+		t.Errorf("after removal, Search(mug) = %v, want nothing", ids(hits))
+	}
+}
@@ -0,0 +1,146 @@
+package search
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// candidateLimit caps how many documents each query stage may return for re-ranking
+const candidateLimit = 200
+
+// Mongo searches the products collection through a weighted text index.
+// The text index provides stemmed matches; a regex stage adds the prefix and
+// single-typo matches it cannot find. Candidates are re-ranked in Go.
+type Mongo struct {
+	products *mongo.Collection
+}
+
+var _ Index = (*Mongo)(nil)
+
+// NewMongo creates the text index on the products collection if needed
+func NewMongo(ctx context.Context, client *mongo.Client) (*Mongo, error) {
+	products := client.Database("ecommerce").Collection("products")
+	_, err := products.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "name", Value: "text"},
+			{Key: "category", Value: "text"},
+			{Key: "description", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("product_text").
+			SetWeights(bson.M{"name": 10, "category": 5, "description": 2}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Mongo{products: products}, nil
+}
+
+// Search returns up to limit products matching the query, best match first
+func (m *Mongo) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return []Hit{}, nil
+	}
+
+	type scored struct {
+		models.Product `bson:",inline"`
+		TextScore      float64 `bson:"text_score"`
+	}
+	candidates := make(map[primitive.ObjectID]scored)
+
+	// Stemmed full-text matches, best text score first
+	cursor, err := m.products.Find(ctx, bson.M{"$text": bson.M{"$search": query}}, options.Find().
+		SetProjection(bson.M{"text_score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"text_score": bson.M{"$meta": "textScore"}}).
+		SetLimit(candidateLimit))
+	if err != nil {
+		return nil, err
+	}
+	var textHits []scored
+	if err := cursor.All(ctx, &textHits); err != nil {
+		return nil, err
+	}
+	for _, c := range textHits {
+		candidates[c.ID] = c
+	}
+
+	// Prefix and typo matches the text index misses
+	pattern := primitive.Regex{Pattern: fuzzyPattern(terms), Options: "i"}
+	cursor, err = m.products.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"name": pattern},
+		bson.M{"category": pattern},
+		bson.M{"description": pattern},
+	}}, options.Find().SetLimit(candidateLimit))
+	if err != nil {
+		return nil, err
+	}
+	var fuzzyHits []scored
+	if err := cursor.All(ctx, &fuzzyHits); err != nil {
+		return nil, err
+	}
+	for _, c := range fuzzyHits {
+		if _, ok := candidates[c.ID]; !ok {
+			candidates[c.ID] = c
+		}
+	}
+
+	hits := []Hit{}
+	for _, c := range candidates {
+		if hit, ok := newHit(c.Product, terms, c.TextScore); ok {
+			hits = append(hits, hit)
+		}
+	}
+	return rank(hits, limit), nil
+}
+
+// Upsert is a no-op; MongoDB maintains the text index itself
+func (m *Mongo) Upsert(ctx context.Context, product models.Product) error {
+	return nil
+}
+
+// Remove is a no-op; MongoDB maintains the text index itself
+func (m *Mongo) Remove(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
+
+// fuzzyPattern builds a regex matching any word that starts with a query term
+// or with a one-edit variant of it (deletion, substitution, insertion or
+// transposition of neighbouring letters)
+func fuzzyPattern(terms []string) string {
+	var alts []string
+	for _, term := range terms {
+		if len(term) < minPrefixLen {
+			continue
+		}
+		alts = append(alts, regexp.QuoteMeta(term))
+		if maxEdits(term) == 0 {
+			continue
+		}
+		r := []rune(term)
+		for i := range r {
+			head, tail := regexp.QuoteMeta(string(r[:i])), regexp.QuoteMeta(string(r[i+1:]))
+			alts = append(alts,
+				head+tail,
+				head+"."+tail,
+				head+"."+regexp.QuoteMeta(string(r[i:])),
+			)
+			if i+1 < len(r) {
+				swapped := string(r[i+1]) + string(r[i])
+				alts = append(alts, head+regexp.QuoteMeta(swapped+string(r[i+2:])))
+			}
+		}
+	}
+	if len(alts) == 0 {
+		// Nothing long enough to match on; return a pattern that never matches
+		return `a\bb`
+	}
+	return `\b(?:` + strings.Join(alts, "|") + `)`
+}
@@ -0,0 +1,238 @@
+package search
+
+import (
+	"go-ecommerce/models"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// field is a searchable product field and its relevance weight
+type field struct {
+	name   string
+	weight float64
+	text   func(models.Product) string
+}
+
+var fields = []field{
+	{"name", 3, func(p models.Product) string { return p.Name }},
+	{"category", 2, func(p models.Product) string { return p.Category }},
+	{"description", 1, func(p models.Product) string { return p.Description }},
+}
+
+// How much a token counts towards the score depending on how it matched
+const (
+	exactMatch  = 1.0
+	prefixMatch = 0.6
+	fuzzyMatch  = 0.4
+)
+
+// minPrefixLen is the shortest query term that may match as a prefix
+const minPrefixLen = 2
+
+// snippetLen is the approximate length of a highlighted snippet in bytes
+const snippetLen = 160
+
+// tokenize splits text into lowercase letter and digit runs
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isSeparator)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// maxEdits is the typo allowance for a query term of the given length
+func maxEdits(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// matchTerm rates how well a document token matches a query term; zero means no match
+func matchTerm(term, token string) float64 {
+	switch {
+	case token == term:
+		return exactMatch
+	case len(term) >= minPrefixLen && strings.HasPrefix(token, term):
+		return prefixMatch
+	case withinEdits(term, token, maxEdits(term)):
+		return fuzzyMatch
+	}
+	return 0
+}
+
+// withinEdits reports whether a and b are at most max edits apart, counting
+// insertions, deletions, substitutions and adjacent transpositions
+func withinEdits(a, b string, max int) bool {
+	if max == 0 {
+		return false
+	}
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return false
+	}
+
+	// Optimal string alignment distance, keeping the last three rows
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return false
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)] <= max
+}
+
+// score rates a product against the query terms. Each term contributes its
+// best match across all fields, so products matching more terms rank higher.
+func score(p models.Product, terms []string) float64 {
+	total := 0.0
+	for _, term := range terms {
+		best := 0.0
+		for _, f := range fields {
+			for _, token := range tokenize(f.text(p)) {
+				best = max(best, f.weight*matchTerm(term, token))
+			}
+		}
+		total += best
+	}
+	return total
+}
+
+// span is the byte range of a token within its source text
+type span struct {
+	start, end int
+}
+
+// tokenSpans finds the tokens of text without losing their positions
+func tokenSpans(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		if isSeparator(r) {
+			if start >= 0 {
+				spans = append(spans, span{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// highlight returns an HTML-escaped snippet of text with matching tokens wrapped
+// in <em>, trimmed to roughly snippetLen around the first match. The second
+// result is false when nothing in text matches.
+func highlight(text string, terms []string) (string, bool) {
+	spans := tokenSpans(text)
+	var hits []span
+	for _, s := range spans {
+		token := strings.ToLower(text[s.start:s.end])
+		for _, term := range terms {
+			if matchTerm(term, token) > 0 {
+				hits = append(hits, s)
+				break
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return "", false
+	}
+
+	// Open the window a few words before the first match
+	start, end := 0, len(text)
+	if len(text) > snippetLen {
+		for i, s := range spans {
+			if s == hits[0] {
+				start = spans[max(0, i-5)].start
+				break
+			}
+		}
+		end = min(len(text), start+snippetLen)
+		for _, s := range spans {
+			if s.start < end && s.end > end {
+				end = s.end
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, h := range hits {
+		if h.start < start || h.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:h.start]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[h.start:h.end]))
+		b.WriteString("</em>")
+		pos = h.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// newHit scores and highlights a product, returning false if it does not match
+func newHit(p models.Product, terms []string, bonus float64) (Hit, bool) {
+	s := score(p, terms) + bonus
+	if s == 0 {
+		return Hit{}, false
+	}
+	hit := Hit{Product: p, Score: s, Highlights: map[string]string{}}
+	for _, f := range fields {
+		if snippet, ok := highlight(f.text(p), terms); ok {
+			hit.Highlights[f.name] = snippet
+		}
+	}
+	return hit, true
+}
+
+// rank orders hits best first and keeps at most limit of them
+func rank(hits []Hit, limit int) []Hit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Product.ID.Hex() < hits[j].Product.ID.Hex()
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
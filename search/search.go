@@ -0,0 +1,28 @@
+// Package search provides full-text product search. The Mongo implementation
+// is backed by a weighted text index; Memory is a pure-Go in-process index
+// for tests and local demos. Both rank and highlight results the same way.
+package search
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hit is a single search result
+type Hit struct {
+	Product    models.Product    `json:"product"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"` // Field name to HTML snippet with <em> marks
+}
+
+// Index searches the product catalog
+type Index interface {
+	// Search returns up to limit products matching the query, best match first
+	Search(ctx context.Context, query string, limit int) ([]Hit, error)
+	// Upsert adds or refreshes a product in the index
+	Upsert(ctx context.Context, product models.Product) error
+	// Remove drops a product from the index
+	Remove(ctx context.Context, id primitive.ObjectID) error
+}
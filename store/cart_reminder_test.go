@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAbandonedCarts(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		now := time.Now().Truncate(time.Millisecond)
+		item := []models.CartItem{{ProductID: primitive.NewObjectID(), Quantity: 1}}
+		save := func(updated time.Time, items []models.CartItem) models.Cart {
+			t.Helper()
+			cart := models.Cart{UserID: primitive.NewObjectID(), Items: items, CreatedAt: updated, UpdatedAt: updated}
+			if err := s.SaveCart(ctx, &cart); err != nil {
+				t.Fatal(err)
+			}
+			return cart
+		}
+		older := save(now.Add(-72*time.Hour), item)
+		old := save(now.Add(-48*time.Hour), item)
+		save(now.Add(-time.Hour), item) // Still being shopped
+		save(now.Add(-72*time.Hour), nil)
+
+		idleSince := now.Add(-24 * time.Hour)
+		carts, err := s.ListAbandonedCarts(ctx, idleSince, 2)
+		if err != nil || len(carts) != 2 || carts[0].ID != older.ID || carts[1].ID != old.ID {
+			t.Fatalf("ListAbandonedCarts = %+v, %v; want the two idle carts, oldest first", carts, err)
+		}
+
+		// A reminder counts once and holds the cart back for another idle period
+		if err := s.MarkCartReminded(ctx, older.ID, 0, now); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.MarkCartReminded(ctx, older.ID, 0, now); !errors.Is(err, ErrConflict) {
+			t.Errorf("second MarkCartReminded error = %v, want ErrConflict", err)
+		}
+		if err := s.MarkCartReminded(ctx, primitive.NewObjectID(), 0, now); !errors.Is(err, ErrNotFound) {
+			t.Errorf("MarkCartReminded of a stranger error = %v, want ErrNotFound", err)
+		}
+		carts, err = s.ListAbandonedCarts(ctx, idleSince, 2)
+		if err != nil || len(carts) != 1 || carts[0].ID != old.ID {
+			t.Errorf("ListAbandonedCarts after a reminder = %+v, %v; want only the other", carts, err)
+		}
+		carts, err = s.ListAbandonedCarts(ctx, now.Add(time.Hour), 1)
+		if err != nil || len(carts) != 2 || carts[0].ID != old.ID {
+			t.Errorf("ListAbandonedCarts with a limit of 1 = %+v, %v; want the unreminded carts", carts, err)
+		}
+	})
+}
+
+func TestCartRecoveryStats(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		now := time.Now().Truncate(time.Millisecond)
+
+		// One reminded cart is still open
+		open := models.Cart{UserID: primitive.NewObjectID(), Items: []models.CartItem{{ProductID: primitive.NewObjectID(), Quantity: 1}}, UpdatedAt: now}
+		if err := s.SaveCart(ctx, &open); err != nil {
+			t.Fatal(err)
+		}
+		for sent := 0; sent < 2; sent++ {
+			if err := s.MarkCartReminded(ctx, open.ID, sent, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Two were ordered, one of them then cancelled; one order needed no reminder
+		for _, order := range []models.Order{
+			{CartReminders: 1, Status: models.OrderPaid, TotalAmount: usd(3000)},
+			{CartReminders: 2, Status: models.OrderCancelled, TotalAmount: usd(5000)},
+			{Status: models.OrderPaid, TotalAmount: usd(7000)},
+		} {
+			order.UserID = primitive.NewObjectID()
+			order.CreatedAt = now
+			if err := s.CreateOrder(ctx, &order); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stats, err := s.CartRecoveryStats(ctx, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := models.CartRecoveryStats{RemindedCarts: 3, RemindersSent: 5, RecoveredCarts: 1, RecoveryRate: 1.0 / 3, RecoveredRevenue: usd(3000)}
+		if *stats != want {
+			t.Errorf("stats = %+v, want %+v", *stats, want)
+		}
+		if stats, err := s.CartRecoveryStats(ctx, now.Add(time.Hour)); err != nil || stats.RemindedCarts != 0 || stats.RecoveryRate != 0 {
+			t.Errorf("stats from later = %+v, %v; want none", stats, err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHolidayStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		holidays := []models.Holiday{
+			{Name: "Christmas", Date: "2026-12-25"},
+			{Name: "Day after Thanksgiving", Date: "2026-11-27", State: "CA"},
+			{Name: "Thanksgiving", Date: "2026-11-26"},
+			{Name: "Lincoln's Birthday", Date: "2026-02-12", State: "NY"},
+		}
+		for i := range holidays {
+			if err := s.CreateHoliday(ctx, &holidays[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		california, err := s.ListHolidays(ctx, "CA")
+		if err != nil || len(california) != 3 || california[0].Name != "Thanksgiving" || california[2].Name != "Christmas" {
+			t.Errorf("ListHolidays(CA) = %+v, %v; want CA's and every state's by date", california, err)
+		}
+		all, err := s.ListHolidays(ctx, "")
+		if err != nil || len(all) != 4 || all[0].State != "NY" {
+			t.Errorf("ListHolidays() = %+v, %v; want every holiday by date", all, err)
+		}
+
+		holidays[0].Date = "2026-12-24"
+		if err := s.UpdateHoliday(ctx, &holidays[0]); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindHolidayByID(ctx, holidays[0].ID); err != nil || got.Date != "2026-12-24" {
+			t.Errorf("FindHolidayByID = %+v, %v; want the new date", got, err)
+		}
+		if err := s.UpdateHoliday(ctx, &models.Holiday{ID: primitive.NewObjectID()}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateHoliday of a missing holiday error = %v, want ErrNotFound", err)
+		}
+
+		if err := s.DeleteHoliday(ctx, holidays[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.FindHolidayByID(ctx, holidays[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindHolidayByID after delete error = %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStockLedger(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		shirt := variantProduct()
+		if err := s.CreateProduct(ctx, &shirt, "admin@example.com"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Edits record the difference, including dropped and new variants
+		edited := variantProduct()
+		edited.Options[0].Values = []string{"S", "L"}
+		edited.Variants[0].Stock = 5
+		edited.Variants[1] = models.Variant{SKU: "SHIRT-L", Options: map[string]string{"size": "L"}, Stock: 2}
+		if err := s.UpdateProduct(ctx, shirt.ID, edited, "admin@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		restock := models.StockMovement{ProductID: shirt.ID, SKU: "SHIRT-L", Delta: 4, Reason: models.StockRestock, Actor: "admin@example.com", Reference: "INV-7"}
+		if err := s.MoveStock(ctx, &restock); err != nil {
+			t.Fatal(err)
+		}
+		if restock.Balance != 6 || restock.ID.IsZero() {
+			t.Errorf("restock = %+v, want it recorded with a balance of 6", restock)
+		}
+
+		// Paying takes stock out as a sale; cancelling puts it back
+		order := models.Order{
+			UserID:      primitive.NewObjectID(),
+			Items:       []models.OrderItem{{ProductID: shirt.ID, SKU: "SHIRT-L", Quantity: 3}},
+			Status:      models.OrderPending,
+			Reservation: &models.StockReservation{Status: models.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)},
+		}
+		if err := s.PlaceOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+		change := models.StatusChange{From: models.OrderPending, Actor: "system", At: time.Now()}
+		if err := s.PayOrder(ctx, order.ID, models.OrderPending, change); err != nil {
+			t.Fatal(err)
+		}
+		change = models.StatusChange{From: models.OrderPaid, Actor: "customer@example.com", At: time.Now()}
+		if err := s.CancelOrder(ctx, order.ID, models.OrderPaid, change, "Wrong size"); err != nil {
+			t.Fatal(err)
+		}
+
+		movements, err := s.ListStockMovements(ctx, shirt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		type entry struct {
+			sku     string
+			delta   int
+			balance int
+			reason  models.StockReason
+		}
+		want := []entry{
+			{"SHIRT-S", 3, 3, models.StockOpening},
+			{"SHIRT-M", 5, 5, models.StockOpening},
+			{"SHIRT-S", 2, 5, models.StockAdjustment},
+			{"SHIRT-L", 2, 2, models.StockAdjustment},
+			{"SHIRT-M", -5, 0, models.StockAdjustment},
+			{"SHIRT-L", 4, 6, models.StockRestock},
+			{"SHIRT-L", -3, 3, models.StockSale},
+			{"SHIRT-L", 3, 6, models.StockCancellation},
+		}
+		if len(movements) != len(want) {
+			t.Fatalf("movements = %+v, want %d", movements, len(want))
+		}
+		for i, m := range movements {
+			got := entry{m.SKU, m.Delta, m.Balance, m.Reason}
+			if got != want[i] {
+				t.Errorf("movement %d = %+v, want %+v", i, got, want[i])
+			}
+		}
+		if sale := movements[6]; sale.Actor != "system" || sale.Reference != order.ID.Hex() {
+			t.Errorf("sale = %+v, want it attributed to the order", sale)
+		}
+		if cancel := movements[7]; cancel.Actor != "customer@example.com" || cancel.Note != "Wrong size" {
+			t.Errorf("cancellation = %+v, want the customer's reason", cancel)
+		}
+
+		counts, err := s.ReconcileStock(ctx, shirt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range counts {
+			if c.Recorded != c.Ledger {
+				t.Errorf("count %+v, want the ledger to match the stock", c)
+			}
+		}
+		if _, err := s.ReconcileStock(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ReconcileStock of a missing product error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestMoveStockKeepsHolds(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		mug := createProduct(t, s, "Mug", 5)
+		order := models.Order{
+			UserID:      primitive.NewObjectID(),
+			Items:       []models.OrderItem{{ProductID: mug.ID, Quantity: 3}},
+			Status:      models.OrderPending,
+			Reservation: &models.StockReservation{Status: models.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)},
+		}
+		if err := s.PlaceOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the two unreserved units can be written off
+		loss := models.StockMovement{ProductID: mug.ID, Delta: -3, Reason: models.StockAdjustment, Actor: "admin@example.com"}
+		if err := s.MoveStock(ctx, &loss); !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("MoveStock into the hold error = %v, want ErrInsufficientStock", err)
+		}
+		if got, err := s.FindProductByID(ctx, mug.ID); err != nil || got.Stock != 5 {
+			t.Errorf("mug = %+v, %v; want its stock untouched", got, err)
+		}
+		if movements, err := s.ListStockMovements(ctx, mug.ID); err != nil || len(movements) != 1 {
+			t.Errorf("movements = %+v, %v; want only the opening stock", movements, err)
+		}
+		loss.Delta = -2
+		if err := s.MoveStock(ctx, &loss); err != nil || loss.Balance != 3 {
+			t.Errorf("MoveStock of the unreserved units = %+v, %v; want a balance of 3", loss, err)
+		}
+		missing := models.StockMovement{ProductID: mug.ID, SKU: "MUG-XL", Delta: -1, Reason: models.StockAdjustment}
+		if err := s.MoveStock(ctx, &missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("MoveStock of a missing variant error = %v, want ErrNotFound", err)
+		}
+
+		// The held units can still be sold
+		change := models.StatusChange{From: models.OrderPending, Actor: "system", At: time.Now()}
+		if err := s.PayOrder(ctx, order.ID, models.OrderPending, change); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindProductByID(ctx, mug.ID); err != nil || got.Stock != 0 || got.Reserved != 0 {
+			t.Errorf("mug = %+v, %v; want it sold out", got, err)
+		}
+	})
+}
+
+func TestReconcileStockRepairsDrift(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	mug := models.Product{Name: "Mug", Price: usd(1000), Stock: 5}
+	if err := m.CreateProduct(ctx, &mug, "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	// A write that bypassed the ledger
+	drifted := m.products[mug.ID]
+	drifted.Stock = 9
+	m.products[mug.ID] = drifted
+
+	counts, err := m.ReconcileStock(ctx, mug.ID)
+	if err != nil || len(counts) != 1 || counts[0] != (models.StockCount{Recorded: 9, Ledger: 5}) {
+		t.Errorf("ReconcileStock = %+v, %v; want 9 recorded against 5 in the ledger", counts, err)
+	}
+	if got, err := m.FindProductByID(ctx, mug.ID); err != nil || got.Stock != 5 {
+		t.Errorf("mug = %+v, %v; want its stock rebuilt as 5", got, err)
+	}
+}
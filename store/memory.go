@@ -0,0 +1,139 @@
+package store
+
+import (
+	"go-ecommerce/models"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Memory is an in-memory Store for tests and local demos. It is safe for
+// concurrent use and hands out copies so callers never share its state.
+type Memory struct {
+	mu         sync.RWMutex
+	users      map[primitive.ObjectID]models.User
+	products   map[primitive.ObjectID]models.Product
+	movements  []models.StockMovement // The inventory ledger, in insertion order
+	stockSubs  map[primitive.ObjectID]models.StockSubscription
+	carts      map[primitive.ObjectID]models.Cart // keyed by user ID
+	guestCarts map[primitive.ObjectID]models.Cart
+	wishlists  map[primitive.ObjectID]models.Wishlist
+	orders     map[primitive.ObjectID]models.Order
+	payments   map[primitive.ObjectID]models.Payment
+	webhooks   map[string]models.WebhookEvent
+	promotions map[primitive.ObjectID]models.Promotion
+	taxRates   map[primitive.ObjectID]models.TaxRate
+	shipping   map[primitive.ObjectID]models.ShippingZone
+	holidays   map[primitive.ObjectID]models.Holiday
+	sessions   map[primitive.ObjectID]models.Session
+	refresh    map[primitive.ObjectID]models.RefreshToken
+	resets     map[primitive.ObjectID]models.PasswordReset
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory creates an empty in-memory Store
+func NewMemory() *Memory {
+	return &Memory{
+		users:      make(map[primitive.ObjectID]models.User),
+		products:   make(map[primitive.ObjectID]models.Product),
+		stockSubs:  make(map[primitive.ObjectID]models.StockSubscription),
+		carts:      make(map[primitive.ObjectID]models.Cart),
+		guestCarts: make(map[primitive.ObjectID]models.Cart),
+		wishlists:  make(map[primitive.ObjectID]models.Wishlist),
+		orders:     make(map[primitive.ObjectID]models.Order),
+		payments:   make(map[primitive.ObjectID]models.Payment),
+		webhooks:   make(map[string]models.WebhookEvent),
+		promotions: make(map[primitive.ObjectID]models.Promotion),
+		taxRates:   make(map[primitive.ObjectID]models.TaxRate),
+		shipping:   make(map[primitive.ObjectID]models.ShippingZone),
+		holidays:   make(map[primitive.ObjectID]models.Holiday),
+		sessions:   make(map[primitive.ObjectID]models.Session),
+		refresh:    make(map[primitive.ObjectID]models.RefreshToken),
+		resets:     make(map[primitive.ObjectID]models.PasswordReset),
+	}
+}
+
+// sortByID orders documents by ObjectID, which matches insertion order
+func sortByID[T any](docs []T, id func(T) primitive.ObjectID) {
+	sort.Slice(docs, func(i, j int) bool {
+		a, b := id(docs[i]), id(docs[j])
+		return a.Hex() < b.Hex()
+	})
+}
+
+// cloneProduct copies a product deeply enough that variant edits do not leak
+func cloneProduct(p models.Product) models.Product {
+	p.Options = append([]models.ProductOption(nil), p.Options...)
+	if p.Variants != nil {
+		variants := make([]models.Variant, len(p.Variants))
+		for i, v := range p.Variants {
+			options := make(map[string]string, len(v.Options))
+			for name, value := range v.Options {
+				options[name] = value
+			}
+			v.Options = options
+			variants[i] = v
+		}
+		p.Variants = variants
+	}
+	return p
+}
+
+// cloneOrder copies an order so its slices and pointers are not shared
+func cloneOrder(o models.Order) models.Order {
+	if o.Items != nil {
+		items := make([]models.OrderItem, len(o.Items))
+		for i, item := range o.Items {
+			if item.Options != nil {
+				options := make(map[string]string, len(item.Options))
+				for k, v := range item.Options {
+					options[k] = v
+				}
+				item.Options = options
+			}
+			item.TaxBreakdown = append([]models.TaxLine(nil), item.TaxBreakdown...)
+			items[i] = item
+		}
+		o.Items = items
+	}
+	o.History = append([]models.StatusChange(nil), o.History...)
+	if o.Promotion != nil {
+		promotion := *o.Promotion
+		o.Promotion = &promotion
+	}
+	if o.Shipping != nil {
+		shipping := *o.Shipping
+		o.Shipping = &shipping
+	}
+	if o.Reservation != nil {
+		reservation := *o.Reservation
+		o.Reservation = &reservation
+	}
+	return o
+}
+
+func cloneCart(c models.Cart) models.Cart {
+	c.Items = cloneItems(c.Items)
+	c.SavedForLater = cloneItems(c.SavedForLater)
+	if c.LastRemindedAt != nil {
+		at := *c.LastRemindedAt
+		c.LastRemindedAt = &at
+	}
+	return c
+}
+
+func cloneItems(items []models.CartItem) []models.CartItem {
+	if items == nil {
+		return nil
+	}
+	items = append([]models.CartItem(nil), items...)
+	for i := range items {
+		if items[i].UnitPrice != nil {
+			price := *items[i].UnitPrice
+			items[i].UnitPrice = &price
+		}
+	}
+	return items
+}
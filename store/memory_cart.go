@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FindCartByUserID returns the user's cart
+func (m *Memory) FindCartByUserID(ctx context.Context, userID primitive.ObjectID) (*models.Cart, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cart, ok := m.carts[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cart = cloneCart(cart)
+	return &cart, nil
+}
+
+// SaveCart creates or replaces the user's cart
+func (m *Memory) SaveCart(ctx context.Context, cart *models.Cart) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cart.ID.IsZero() {
+		cart.ID = primitive.NewObjectID()
+	}
+	saved := cloneCart(*cart)
+	m.carts[cart.UserID] = saved
+	return nil
+}
+
+// DeleteCartByUserID removes the user's cart
+func (m *Memory) DeleteCartByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.carts[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.carts, userID)
+	return nil
+}
+
+// FindGuestCart returns the guest cart with the given ID
+func (m *Memory) FindGuestCart(ctx context.Context, id primitive.ObjectID) (*models.Cart, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cart, ok := m.guestCarts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cart = cloneCart(cart)
+	return &cart, nil
+}
+
+// SaveGuestCart creates or replaces a guest cart, setting its ID if it is new
+func (m *Memory) SaveGuestCart(ctx context.Context, cart *models.Cart) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cart.ID.IsZero() {
+		cart.ID = primitive.NewObjectID()
+	}
+	saved := cloneCart(*cart)
+	m.guestCarts[cart.ID] = saved
+	return nil
+}
+
+// DeleteGuestCart removes a guest cart
+func (m *Memory) DeleteGuestCart(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.guestCarts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.guestCarts, id)
+	return nil
+}
+
+// ListAbandonedCarts returns the users' carts due a reminder, least recently updated first
+func (m *Memory) ListAbandonedCarts(ctx context.Context, idleSince time.Time, limit int) ([]models.Cart, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	carts := []models.Cart{}
+	for _, cart := range m.carts {
+		if cart.Abandoned(idleSince, limit) {
+			carts = append(carts, cloneCart(cart))
+		}
+	}
+	sort.Slice(carts, func(i, j int) bool { return carts[i].UpdatedAt.Before(carts[j].UpdatedAt) })
+	return carts, nil
+}
+
+// MarkCartReminded counts a reminder unless another was counted since the caller looked
+func (m *Memory) MarkCartReminded(ctx context.Context, id primitive.ObjectID, sent int, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for userID, cart := range m.carts {
+		if cart.ID != id {
+			continue
+		}
+		if cart.RemindersSent != sent {
+			return ErrConflict
+		}
+		cart.RemindersSent++
+		cart.LastRemindedAt = &at
+		m.carts[userID] = cart
+		return nil
+	}
+	return ErrNotFound
+}
+
+// CartRecoveryStats counts reminded carts still open and orders placed from reminded carts
+func (m *Memory) CartRecoveryStats(ctx context.Context, since time.Time) (*models.CartRecoveryStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := &models.CartRecoveryStats{RecoveredRevenue: models.NewMoney(0, models.DefaultCurrency)}
+	for _, cart := range m.carts {
+		if cart.RemindersSent > 0 && !cart.LastRemindedAt.Before(since) {
+			stats.RemindedCarts++
+			stats.RemindersSent += cart.RemindersSent
+		}
+	}
+	for _, order := range m.orders {
+		if order.CartReminders == 0 || order.CreatedAt.Before(since) {
+			continue
+		}
+		stats.RemindedCarts++
+		stats.RemindersSent += order.CartReminders
+		if order.Status != models.OrderCancelled {
+			stats.RecoveredCarts++
+			stats.RecoveredRevenue = stats.RecoveredRevenue.Add(order.TotalAmount)
+		}
+	}
+	stats.Rate()
+	return stats, nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateHoliday stores a new holiday and sets its ID
+func (m *Memory) CreateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if holiday.ID.IsZero() {
+		holiday.ID = primitive.NewObjectID()
+	}
+	m.holidays[holiday.ID] = *holiday
+	return nil
+}
+
+// FindHolidayByID looks up a holiday by ID
+func (m *Memory) FindHolidayByID(ctx context.Context, id primitive.ObjectID) (*models.Holiday, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	holiday, ok := m.holidays[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &holiday, nil
+}
+
+// ListHolidays returns the holidays observed in state, or every holiday when state is empty
+func (m *Memory) ListHolidays(ctx context.Context, state string) ([]models.Holiday, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	holidays := []models.Holiday{}
+	for _, holiday := range m.holidays {
+		if state == "" || holiday.State == "" || holiday.State == state {
+			holidays = append(holidays, holiday)
+		}
+	}
+	sort.Slice(holidays, func(i, j int) bool {
+		a, b := holidays[i], holidays[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.State < b.State
+	})
+	return holidays, nil
+}
+
+// UpdateHoliday replaces a stored holiday
+func (m *Memory) UpdateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.holidays[holiday.ID]; !ok {
+		return ErrNotFound
+	}
+	m.holidays[holiday.ID] = *holiday
+	return nil
+}
+
+// DeleteHoliday removes a holiday
+func (m *Memory) DeleteHoliday(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.holidays[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.holidays, id)
+	return nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MoveStock changes stock and records the movement under a single lock
+func (m *Memory) MoveStock(ctx context.Context, movement *models.StockMovement) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.addStock(movement)
+}
+
+// recordMovement appends a movement to the ledger, setting its ID and time
+// if unset; the caller must hold the write lock
+func (m *Memory) recordMovement(movement *models.StockMovement) {
+	if movement.ID.IsZero() {
+		movement.ID = primitive.NewObjectID()
+	}
+	if movement.CreatedAt.IsZero() {
+		movement.CreatedAt = time.Now()
+	}
+	m.movements = append(m.movements, *movement)
+}
+
+// recordStockChanges records the movements of a product edit; the caller
+// must hold the write lock
+func (m *Memory) recordStockChanges(productID primitive.ObjectID, changes []models.StockMovement, reason models.StockReason, actor string) {
+	for i := range changes {
+		changes[i].ProductID = productID
+		changes[i].Reason = reason
+		changes[i].Actor = actor
+		m.recordMovement(&changes[i])
+	}
+}
+
+// ListStockMovements returns a product's movements, oldest first
+func (m *Memory) ListStockMovements(ctx context.Context, productID primitive.ObjectID) ([]models.StockMovement, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	movements := []models.StockMovement{}
+	for _, movement := range m.movements {
+		if movement.ProductID == productID {
+			movements = append(movements, movement)
+		}
+	}
+	return movements, nil
+}
+
+// ReconcileStock resets a product's stock figures to its ledger sums under a single lock
+func (m *Memory) ReconcileStock(ctx context.Context, productID primitive.ObjectID) ([]models.StockCount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[productID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	sums := make(map[string]int)
+	for _, movement := range m.movements {
+		if movement.ProductID == productID {
+			sums[movement.SKU] += movement.Delta
+		}
+	}
+
+	product = cloneProduct(product)
+	counts := []models.StockCount{{Recorded: product.Stock, Ledger: sums[""]}}
+	product.Stock = sums[""]
+	for i := range product.Variants {
+		v := &product.Variants[i]
+		counts = append(counts, models.StockCount{SKU: v.SKU, Recorded: v.Stock, Ledger: sums[v.SKU]})
+		v.Stock = sums[v.SKU]
+	}
+	m.products[productID] = product
+	return counts, nil
+}
@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"go-ecommerce/models"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateOrder stores a new order and sets its ID
+func (m *Memory) CreateOrder(ctx context.Context, order *models.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	m.orders[order.ID] = cloneOrder(*order)
+	return nil
+}
+
+// stockKey names a product, or one of its variants when sku is set
+type stockKey struct {
+	id  primitive.ObjectID
+	sku string
+}
+
+// orderStock sums an order's quantities by product and variant, leaving out
+// the products and variants deleted since it was placed, so that every line
+// can be checked before any is applied; the caller must hold the lock
+func (m *Memory) orderStock(items []models.OrderItem) map[stockKey]int {
+	lines := make(map[stockKey]int)
+	for _, item := range items {
+		if _, _, err := m.stockFigures(item.ProductID, item.SKU); err == nil {
+			lines[stockKey{item.ProductID, item.SKU}] += item.Quantity
+		}
+	}
+	return lines
+}
+
+// PlaceOrder reserves product or variant stock, redeems the promotion, stores the order and clears
+// the user's cart under a single lock. Stock and promotion limits are validated before anything
+// is written.
+func (m *Memory) PlaceOrder(ctx context.Context, order *models.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Sum quantities first so repeated lines for one product are checked together
+	wanted := make(map[stockKey]int)
+	for _, item := range order.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: product %s", ErrInvalidQuantity, item.ProductID.Hex())
+		}
+		wanted[stockKey{item.ProductID, item.SKU}] += item.Quantity
+	}
+	for key, qty := range wanted {
+		product, ok := m.products[key.id]
+		if !ok || product.AvailableFor(key.sku) < qty {
+			return fmt.Errorf("%w: product %s %s", ErrInsufficientStock, key.id.Hex(), key.sku)
+		}
+	}
+
+	if order.Promotion != nil {
+		if err := m.checkPromotionLimits(order); err != nil {
+			return err
+		}
+	}
+
+	for key, qty := range wanted {
+		if err := m.addReserved(key.id, key.sku, qty); err != nil {
+			return err
+		}
+	}
+	if order.Promotion != nil {
+		promotion := m.promotions[order.Promotion.PromotionID]
+		promotion.UsedCount++
+		m.promotions[promotion.ID] = promotion
+	}
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	m.orders[order.ID] = cloneOrder(*order)
+	if cart, ok := m.carts[order.UserID]; ok && len(cart.SavedForLater) > 0 {
+		// Only what was ordered goes; the items saved for later stay
+		m.carts[order.UserID] = models.Cart{
+			ID:            cart.ID,
+			UserID:        cart.UserID,
+			Items:         []models.CartItem{},
+			SavedForLater: cart.SavedForLater,
+			CreatedAt:     cart.CreatedAt,
+			UpdatedAt:     cart.UpdatedAt,
+		}
+	} else {
+		delete(m.carts, order.UserID)
+	}
+	return nil
+}
+
+// FindOrderByID looks up an order by ID
+func (m *Memory) FindOrderByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, ok := m.orders[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+// ListOrdersByUser returns every order placed by the user
+func (m *Memory) ListOrdersByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	orders := []models.Order{}
+	for _, order := range m.orders {
+		if order.UserID == userID {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+	sortByID(orders, func(o models.Order) primitive.ObjectID { return o.ID })
+	return orders, nil
+}
+
+// ListOrders returns every order, newest first, optionally limited to one status
+func (m *Memory) ListOrders(ctx context.Context, status models.OrderStatus) ([]models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	orders := []models.Order{}
+	for _, order := range m.orders {
+		if status == "" || order.Status == status {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+	sortByID(orders, func(o models.Order) primitive.ObjectID { return o.ID })
+	slices.Reverse(orders)
+	return orders, nil
+}
+
+// TransitionOrderStatus moves an order out of status from and records the change
+func (m *Memory) TransitionOrderStatus(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if order.Status != from {
+		return ErrConflict
+	}
+	order = cloneOrder(order)
+	order.Status = change.To
+	order.History = append(order.History, change)
+	m.orders[id] = order
+	return nil
+}
+
+// PayOrder marks an order paid and commits its stock reservation under a
+// single lock. Every line is checked before any stock is changed.
+func (m *Memory) PayOrder(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if order.Status != from {
+		return ErrConflict
+	}
+
+	order = cloneOrder(order)
+	change.To = models.OrderPaid
+	order.Status = change.To
+	order.History = append(order.History, change)
+	if order.Reservation != nil && order.Reservation.Status == models.ReservationActive {
+		// Each line's hold is released as it is sold, so the sale cannot eat
+		// into other orders' holds. Products and variants deleted since the
+		// order was placed are skipped.
+		lines := m.orderStock(order.Items)
+		for key, qty := range lines {
+			stock, reserved, _ := m.stockFigures(key.id, key.sku)
+			if reserved < qty || stock-(reserved-qty) < qty {
+				return fmt.Errorf("%w: product %s %s", ErrInsufficientStock, key.id.Hex(), key.sku)
+			}
+		}
+		for _, item := range order.Items {
+			if _, ok := lines[stockKey{item.ProductID, item.SKU}]; !ok {
+				continue
+			}
+			sale := models.StockMovement{
+				ProductID: item.ProductID,
+				SKU:       item.SKU,
+				Delta:     -item.Quantity,
+				Reason:    models.StockSale,
+				Actor:     change.Actor,
+				Reference: id.Hex(),
+				CreatedAt: change.At,
+			}
+			if err := m.addReserved(item.ProductID, item.SKU, -item.Quantity); err != nil {
+				return err
+			}
+			if err := m.addStock(&sale); err != nil {
+				return err
+			}
+		}
+		order.Reservation.Status = models.ReservationCommitted
+		order.Reservation.ResolvedAt = &change.At
+	}
+	m.orders[id] = order
+	return nil
+}
+
+// CancelOrder cancels an order and releases or restores its stock under a
+// single lock. Every line is checked before any stock is changed.
+func (m *Memory) CancelOrder(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if order.Status != from {
+		return ErrConflict
+	}
+
+	order = cloneOrder(order)
+	change.To = models.OrderCancelled
+	order.Status = change.To
+	order.CancelReason = reason
+	order.History = append(order.History, change)
+
+	// Unpaid orders only give up their hold; paid ones return their stock.
+	// Products deleted since the order was placed are skipped.
+	release := order.Reservation != nil && order.Reservation.Status == models.ReservationActive
+	lines := m.orderStock(order.Items)
+	for _, item := range order.Items {
+		if _, ok := lines[stockKey{item.ProductID, item.SKU}]; !ok {
+			continue
+		}
+		var err error
+		if release {
+			err = m.addReserved(item.ProductID, item.SKU, -item.Quantity)
+		} else {
+			err = m.addStock(&models.StockMovement{
+				ProductID: item.ProductID,
+				SKU:       item.SKU,
+				Delta:     item.Quantity,
+				Reason:    models.StockCancellation,
+				Actor:     change.Actor,
+				Reference: id.Hex(),
+				Note:      reason,
+				CreatedAt: change.At,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if release {
+		order.Reservation.Status = models.ReservationReleased
+		order.Reservation.ResolvedAt = &change.At
+	}
+	// The promotion's use is given back; a deleted promotion is skipped
+	if order.Promotion != nil {
+		if promotion, ok := m.promotions[order.Promotion.PromotionID]; ok && promotion.UsedCount > 0 {
+			promotion.UsedCount--
+			m.promotions[promotion.ID] = promotion
+		}
+	}
+
+	if order.PaymentStatus == models.PaymentCompleted {
+		order.PaymentStatus = models.PaymentRefundPending
+		for pid, payment := range m.payments {
+			if payment.OrderID == id && payment.Status == models.PaymentCompleted {
+				payment.Status = models.PaymentRefundPending
+				m.payments[pid] = payment
+			}
+		}
+	}
+	m.orders[id] = order
+	return nil
+}
+
+// ListExpiredReservations returns the orders whose active reservation expired before the given time
+func (m *Memory) ListExpiredReservations(ctx context.Context, before time.Time) ([]models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	orders := []models.Order{}
+	for _, order := range m.orders {
+		r := order.Reservation
+		if r != nil && r.Status == models.ReservationActive && r.ExpiresAt.Before(before) {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+	sortByID(orders, func(o models.Order) primitive.ObjectID { return o.ID })
+	return orders, nil
+}
+
+// UpdateOrderPaymentStatus sets the order's payment status
+func (m *Memory) UpdateOrderPaymentStatus(ctx context.Context, id primitive.ObjectID, status models.PaymentStatus) error {
+	return m.updateOrder(id, func(o *models.Order) { o.PaymentStatus = status })
+}
+
+func (m *Memory) updateOrder(id primitive.ObjectID, update func(*models.Order)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	order = cloneOrder(order)
+	update(&order)
+	m.orders[id] = order
+	return nil
+}
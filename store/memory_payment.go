@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreatePayment stores a new payment record and sets its ID
+func (m *Memory) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+	m.payments[payment.ID] = *payment
+	return nil
+}
+
+// FindPaymentByID looks up a payment by ID
+func (m *Memory) FindPaymentByID(ctx context.Context, id primitive.ObjectID) (*models.Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	payment, ok := m.payments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &payment, nil
+}
+
+// FindPaymentByOrderID looks up the payment belonging to an order
+func (m *Memory) FindPaymentByOrderID(ctx context.Context, orderID primitive.ObjectID) (*models.Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, payment := range m.payments {
+		if payment.OrderID == orderID {
+			return &payment, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindPaymentByProviderRef looks up a card payment by its gateway intent ID
+func (m *Memory) FindPaymentByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, payment := range m.payments {
+		if payment.Provider == provider && payment.ProviderRef == ref {
+			return &payment, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListPaymentsByStatus returns payments in one status, oldest first
+func (m *Memory) ListPaymentsByStatus(ctx context.Context, status models.PaymentStatus) ([]models.Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	payments := []models.Payment{}
+	for _, payment := range m.payments {
+		if payment.Status == status {
+			payments = append(payments, payment)
+		}
+	}
+	sortByID(payments, func(p models.Payment) primitive.ObjectID { return p.ID })
+	return payments, nil
+}
+
+// UpdatePaymentStatus sets the payment's status
+func (m *Memory) UpdatePaymentStatus(ctx context.Context, id primitive.ObjectID, status models.PaymentStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	payment, ok := m.payments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	payment.Status = status
+	m.payments[id] = payment
+	return nil
+}
+
+// UpdatePayment replaces a stored payment record
+func (m *Memory) UpdatePayment(ctx context.Context, payment *models.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.payments[payment.ID]; !ok {
+		return ErrNotFound
+	}
+	m.payments[payment.ID] = *payment
+	return nil
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"go-ecommerce/models"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateProduct stores a new product, sets its ID and records its opening stock
+func (m *Memory) CreateProduct(ctx context.Context, product *models.Product, actor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	m.products[product.ID] = cloneProduct(*product)
+	m.recordStockChanges(product.ID, product.StockChanges(models.Product{}), models.StockOpening, actor)
+	return nil
+}
+
+// FindProductByID looks up a product by ID
+func (m *Memory) FindProductByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	product, ok := m.products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	product = cloneProduct(product)
+	return &product, nil
+}
+
+// ListProducts returns one page of products matching the query
+func (m *Memory) ListProducts(ctx context.Context, q ProductQuery) (*ProductPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := []models.Product{}
+	for _, product := range m.products {
+		if matchesFilter(product, q.ProductFilter) {
+			matches = append(matches, product)
+		}
+	}
+	total := int64(len(matches))
+	sort.Slice(matches, func(i, j int) bool {
+		return compareProducts(q.Sort, matches[i], matches[j]) < 0
+	})
+
+	products := []models.Product{}
+	for _, product := range matches {
+		if q.After != nil && compareProducts(q.Sort, product, cursorProduct(q.After)) <= 0 {
+			continue
+		}
+		products = append(products, cloneProduct(product))
+		if len(products) > q.Limit {
+			break
+		}
+	}
+	return pageOf(products, q, total), nil
+}
+
+func matchesFilter(p models.Product, f ProductFilter) bool {
+	if f.Category != "" && p.Category != f.Category {
+		return false
+	}
+	if f.MinPrice != nil && p.Price.Amount < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && p.Price.Amount > *f.MaxPrice {
+		return false
+	}
+	if f.InStockOnly && !p.InStock() {
+		return false
+	}
+	return true
+}
+
+// compareProducts orders two products under a sort, breaking ties by ID
+func compareProducts(s ProductSort, a, b models.Product) int {
+	c := 0
+	switch s {
+	case SortPriceAsc, SortPriceDesc:
+		c = cmp.Compare(a.Price.Amount, b.Price.Amount)
+	case SortNameAsc, SortNameDesc:
+		c = strings.Compare(a.Name, b.Name)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID.Hex(), b.ID.Hex())
+	}
+	if s == SortNewest || s == SortPriceDesc || s == SortNameDesc {
+		c = -c
+	}
+	return c
+}
+
+// cursorProduct turns a cursor back into the sort key of the product it marks
+func cursorProduct(c *ProductCursor) models.Product {
+	return models.Product{ID: c.ID, Price: models.Money{Amount: c.Price}, Name: c.Name}
+}
+
+// UpdateProduct overwrites a product's fields, keeping its reserved counts
+// and recording its stock changes. Stock may not be set below what is held.
+func (m *Memory) UpdateProduct(ctx context.Context, id primitive.ObjectID, product models.Product, actor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	product = cloneProduct(product)
+	product.ID = id
+	product.KeepReserved(old)
+	if product.Overheld() {
+		return fmt.Errorf("%w: product %s", ErrInsufficientStock, id.Hex())
+	}
+	m.products[id] = product
+	m.recordStockChanges(product.ID, product.StockChanges(old), models.StockAdjustment, actor)
+	return nil
+}
+
+// DeleteProduct removes a product
+func (m *Memory) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.products[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.products, id)
+	return nil
+}
+
+// addStock changes on-hand stock in place and records the movement. A
+// reduction may take stock neither below zero nor below what unpaid orders
+// hold. The caller must hold the write lock.
+func (m *Memory) addStock(movement *models.StockMovement) error {
+	if movement.Delta < 0 {
+		stock, reserved, err := m.stockFigures(movement.ProductID, movement.SKU)
+		if err != nil {
+			return err
+		}
+		if stock-reserved < -movement.Delta {
+			return fmt.Errorf("%w: product %s %s", ErrInsufficientStock, movement.ProductID.Hex(), movement.SKU)
+		}
+	}
+	err := m.updateStock(movement.ProductID, movement.SKU, func(stock, reserved *int) {
+		*stock += movement.Delta
+		movement.Balance = *stock
+	})
+	if err != nil {
+		return err
+	}
+	m.recordMovement(movement)
+	return nil
+}
+
+// addReserved changes the reserved count in place; the caller must hold the write lock
+func (m *Memory) addReserved(id primitive.ObjectID, sku string, delta int) error {
+	return m.updateStock(id, sku, func(stock, reserved *int) { *reserved += delta })
+}
+
+// stockFigures returns the on-hand and reserved stock of a product, or of one
+// of its variants when sku is set; the caller must hold the lock
+func (m *Memory) stockFigures(id primitive.ObjectID, sku string) (stock, reserved int, err error) {
+	product, ok := m.products[id]
+	if !ok {
+		return 0, 0, ErrNotFound
+	}
+	if sku == "" {
+		return product.Stock, product.Reserved, nil
+	}
+	v, ok := product.Variant(sku)
+	if !ok {
+		return 0, 0, ErrNotFound
+	}
+	return v.Stock, v.Reserved, nil
+}
+
+// updateStock applies update to the stock figures of a product, or of one
+// of its variants when sku is set; the caller must hold the write lock
+func (m *Memory) updateStock(id primitive.ObjectID, sku string, update func(stock, reserved *int)) error {
+	product, ok := m.products[id]
+	if !ok {
+		return ErrNotFound
+	}
+	product = cloneProduct(product)
+	if sku == "" {
+		update(&product.Stock, &product.Reserved)
+	} else {
+		v, ok := product.Variant(sku)
+		if !ok {
+			return ErrNotFound
+		}
+		update(&v.Stock, &v.Reserved)
+	}
+	m.products[id] = product
+	return nil
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// clonePromotion copies a promotion so its slices and pointers are not shared
+func clonePromotion(p models.Promotion) models.Promotion {
+	p.ProductIDs = append([]primitive.ObjectID(nil), p.ProductIDs...)
+	p.Categories = append([]string(nil), p.Categories...)
+	if p.AmountOff != nil {
+		amount := *p.AmountOff
+		p.AmountOff = &amount
+	}
+	if p.MinSpend != nil {
+		amount := *p.MinSpend
+		p.MinSpend = &amount
+	}
+	if p.StartsAt != nil {
+		at := *p.StartsAt
+		p.StartsAt = &at
+	}
+	if p.EndsAt != nil {
+		at := *p.EndsAt
+		p.EndsAt = &at
+	}
+	return p
+}
+
+// codeTaken reports whether a promotion other than id already uses code
+func (m *Memory) codeTaken(code string, id primitive.ObjectID) bool {
+	for _, p := range m.promotions {
+		if p.Code == code && p.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatePromotion stores a new promotion and sets its ID
+func (m *Memory) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if promotion.ID.IsZero() {
+		promotion.ID = primitive.NewObjectID()
+	}
+	if m.codeTaken(promotion.Code, promotion.ID) {
+		return ErrDuplicate
+	}
+	m.promotions[promotion.ID] = clonePromotion(*promotion)
+	return nil
+}
+
+// FindPromotionByID looks up a promotion by ID
+func (m *Memory) FindPromotionByID(ctx context.Context, id primitive.ObjectID) (*models.Promotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	promotion, ok := m.promotions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	promotion = clonePromotion(promotion)
+	return &promotion, nil
+}
+
+// FindPromotionByCode looks up a promotion by its coupon code
+func (m *Memory) FindPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, promotion := range m.promotions {
+		if promotion.Code == code {
+			promotion = clonePromotion(promotion)
+			return &promotion, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListPromotions returns every promotion, newest first
+func (m *Memory) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	promotions := []models.Promotion{}
+	for _, promotion := range m.promotions {
+		promotions = append(promotions, clonePromotion(promotion))
+	}
+	sortByID(promotions, func(p models.Promotion) primitive.ObjectID { return p.ID })
+	slices.Reverse(promotions)
+	return promotions, nil
+}
+
+// UpdatePromotion saves every field of a promotion except its usage count
+func (m *Memory) UpdatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.promotions[promotion.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if m.codeTaken(promotion.Code, promotion.ID) {
+		return ErrDuplicate
+	}
+	updated := clonePromotion(*promotion)
+	updated.UsedCount = stored.UsedCount
+	updated.CreatedAt = stored.CreatedAt
+	m.promotions[promotion.ID] = updated
+	return nil
+}
+
+// DeletePromotion removes a promotion. Orders keep their copy of it.
+func (m *Memory) DeletePromotion(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.promotions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.promotions, id)
+	return nil
+}
+
+// CountPromotionRedemptions counts the user's orders placed with the
+// promotion, leaving out cancelled ones
+func (m *Memory) CountPromotionRedemptions(ctx context.Context, promotionID, userID primitive.ObjectID) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.countRedemptions(promotionID, userID), nil
+}
+
+func (m *Memory) countRedemptions(promotionID, userID primitive.ObjectID) int {
+	n := 0
+	for _, order := range m.orders {
+		if order.UserID == userID && order.Status != models.OrderCancelled &&
+			order.Promotion != nil && order.Promotion.PromotionID == promotionID {
+			n++
+		}
+	}
+	return n
+}
+
+// checkPromotionLimits reports whether the order's promotion may be redeemed
+// once more. The caller holds the lock.
+func (m *Memory) checkPromotionLimits(order *models.Order) error {
+	promotion, ok := m.promotions[order.Promotion.PromotionID]
+	if !ok {
+		return ErrNotFound
+	}
+	if promotion.UsageLimit > 0 && promotion.UsedCount >= promotion.UsageLimit {
+		return ErrLimitReached
+	}
+	if promotion.PerUserLimit > 0 && m.countRedemptions(promotion.ID, order.UserID) >= promotion.PerUserLimit {
+		return ErrLimitReached
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSession stores a new session and sets its ID
+func (m *Memory) CreateSession(ctx context.Context, session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	m.sessions[session.ID] = *session
+	return nil
+}
+
+// FindSessionByID looks up a session by ID
+func (m *Memory) FindSessionByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// RevokeSession revokes a session; revoking an already revoked session is a no-op
+func (m *Memory) RevokeSession(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+		m.sessions[id] = session
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session belonging to the user
+func (m *Memory) RevokeUserSessions(ctx context.Context, userID primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for id, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			m.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+// CreateRefreshToken stores a new refresh token record and sets its ID
+func (m *Memory) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	m.refresh[token.ID] = *token
+	return nil
+}
+
+// FindRefreshTokenByHash looks up a refresh token by the hash of its value
+func (m *Memory) FindRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, token := range m.refresh {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ConsumeRefreshToken marks an unused token as used
+func (m *Memory) ConsumeRefreshToken(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.refresh[id]
+	if !ok || token.UsedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	m.refresh[id] = token
+	return nil
+}
+
+// CreatePasswordReset stores a reset token, discarding the user's unused tokens
+func (m *Memory) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, existing := range m.resets {
+		if existing.UserID == reset.UserID && existing.UsedAt == nil {
+			delete(m.resets, id)
+		}
+	}
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	m.resets[reset.ID] = *reset
+	return nil
+}
+
+// FindPasswordResetByHash looks up a reset token by the hash of its value
+func (m *Memory) FindPasswordResetByHash(ctx context.Context, hash string) (*models.PasswordReset, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, reset := range m.resets {
+		if reset.TokenHash == hash {
+			return &reset, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ConsumePasswordReset marks an unused reset token as used
+func (m *Memory) ConsumePasswordReset(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reset, ok := m.resets[id]
+	if !ok || reset.UsedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	reset.UsedAt = &now
+	m.resets[id] = reset
+	return nil
+}
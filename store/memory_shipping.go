@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// cloneZone copies a shipping zone so its regions, methods and tiers are not shared
+func cloneZone(z models.ShippingZone) models.ShippingZone {
+	z.Regions = append([]models.ShippingRegion(nil), z.Regions...)
+	methods := make([]models.ShippingMethod, len(z.Methods))
+	for i, method := range z.Methods {
+		method.WeightTiers = append([]models.WeightTier(nil), method.WeightTiers...)
+		method.PriceTiers = append([]models.PriceTier(nil), method.PriceTiers...)
+		methods[i] = method
+	}
+	z.Methods = methods
+	return z
+}
+
+// CreateShippingZone stores a new shipping zone and sets its ID
+func (m *Memory) CreateShippingZone(ctx context.Context, zone *models.ShippingZone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if zone.ID.IsZero() {
+		zone.ID = primitive.NewObjectID()
+	}
+	m.shipping[zone.ID] = cloneZone(*zone)
+	return nil
+}
+
+// FindShippingZoneByID looks up a shipping zone by ID
+func (m *Memory) FindShippingZoneByID(ctx context.Context, id primitive.ObjectID) (*models.ShippingZone, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	zone, ok := m.shipping[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	zone = cloneZone(zone)
+	return &zone, nil
+}
+
+// ListShippingZones returns every zone, oldest first
+func (m *Memory) ListShippingZones(ctx context.Context) ([]models.ShippingZone, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	zones := []models.ShippingZone{}
+	for _, zone := range m.shipping {
+		zones = append(zones, cloneZone(zone))
+	}
+	sortByID(zones, func(z models.ShippingZone) primitive.ObjectID { return z.ID })
+	return zones, nil
+}
+
+// UpdateShippingZone replaces a stored shipping zone
+func (m *Memory) UpdateShippingZone(ctx context.Context, zone *models.ShippingZone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.shipping[zone.ID]; !ok {
+		return ErrNotFound
+	}
+	m.shipping[zone.ID] = cloneZone(*zone)
+	return nil
+}
+
+// DeleteShippingZone removes a shipping zone
+func (m *Memory) DeleteShippingZone(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.shipping[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.shipping, id)
+	return nil
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ListLowStockProducts returns the products at or below their reorder threshold, by name
+func (m *Memory) ListLowStockProducts(ctx context.Context) ([]models.Product, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	products := []models.Product{}
+	for _, product := range m.products {
+		if len(product.LowStock()) > 0 {
+			products = append(products, cloneProduct(product))
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return compareProducts(SortNameAsc, products[i], products[j]) < 0
+	})
+	return products, nil
+}
+
+// CreateStockSubscription stores a subscription and sets its ID
+func (m *Memory) CreateStockSubscription(ctx context.Context, subscription *models.StockSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.pendingStockSubscription(subscription.UserID, subscription.ProductID, subscription.SKU); ok {
+		return ErrDuplicate
+	}
+	if subscription.ID.IsZero() {
+		subscription.ID = primitive.NewObjectID()
+	}
+	m.stockSubs[subscription.ID] = *subscription
+	return nil
+}
+
+// DeleteStockSubscription removes the user's pending subscription
+func (m *Memory) DeleteStockSubscription(ctx context.Context, userID, productID primitive.ObjectID, sku string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.pendingStockSubscription(userID, productID, sku)
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.stockSubs, id)
+	return nil
+}
+
+// pendingStockSubscription finds the user's pending subscription; the caller must hold the lock
+func (m *Memory) pendingStockSubscription(userID, productID primitive.ObjectID, sku string) (primitive.ObjectID, bool) {
+	for id, sub := range m.stockSubs {
+		if sub.UserID == userID && sub.ProductID == productID && sub.SKU == sku && sub.NotifiedAt == nil {
+			return id, true
+		}
+	}
+	return primitive.NilObjectID, false
+}
+
+// ClaimStockSubscriptions marks pending subscriptions notified and returns them
+func (m *Memory) ClaimStockSubscriptions(ctx context.Context, productID primitive.ObjectID, sku string, at time.Time) ([]models.StockSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	claimed := []models.StockSubscription{}
+	for id, sub := range m.stockSubs {
+		if sub.ProductID == productID && sub.SKU == sku && sub.NotifiedAt == nil {
+			sub.NotifiedAt = &at
+			m.stockSubs[id] = sub
+			claimed = append(claimed, sub)
+		}
+	}
+	sortByID(claimed, func(s models.StockSubscription) primitive.ObjectID { return s.ID })
+	return claimed, nil
+}
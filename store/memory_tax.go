@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateTaxRate stores a new tax rate and sets its ID
+func (m *Memory) CreateTaxRate(ctx context.Context, rate *models.TaxRate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rate.ID.IsZero() {
+		rate.ID = primitive.NewObjectID()
+	}
+	m.taxRates[rate.ID] = *rate
+	return nil
+}
+
+// FindTaxRateByID looks up a tax rate by ID
+func (m *Memory) FindTaxRateByID(ctx context.Context, id primitive.ObjectID) (*models.TaxRate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rate, ok := m.taxRates[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &rate, nil
+}
+
+// ListTaxRates returns the rates of one state, or of every state when state is empty
+func (m *Memory) ListTaxRates(ctx context.Context, state string) ([]models.TaxRate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rates := []models.TaxRate{}
+	for _, rate := range m.taxRates {
+		if state == "" || rate.State == state {
+			rates = append(rates, rate)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		a, b := rates[i], rates[j]
+		if a.State != b.State {
+			return a.State < b.State
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ZipPrefix < b.ZipPrefix
+	})
+	return rates, nil
+}
+
+// UpdateTaxRate replaces a stored tax rate
+func (m *Memory) UpdateTaxRate(ctx context.Context, rate *models.TaxRate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.taxRates[rate.ID]; !ok {
+		return ErrNotFound
+	}
+	m.taxRates[rate.ID] = *rate
+	return nil
+}
+
+// DeleteTaxRate removes a tax rate
+func (m *Memory) DeleteTaxRate(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.taxRates[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.taxRates, id)
+	return nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateUser stores a new user and sets its ID
+func (m *Memory) CreateUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	m.users[user.ID] = *user
+	return nil
+}
+
+// FindUserByID looks up a user by ID
+func (m *Memory) FindUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// FindUserByEmail looks up a user by email address
+func (m *Memory) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return m.findUser(func(u models.User) bool { return u.Email == email })
+}
+
+// FindUserByVerificationToken looks up the user holding a verification token
+func (m *Memory) FindUserByVerificationToken(ctx context.Context, token string) (*models.User, error) {
+	return m.findUser(func(u models.User) bool { return u.VerificationToken == token })
+}
+
+// MarkUserVerified flags the user's email as verified and clears the token
+func (m *Memory) MarkUserVerified(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.IsVerified = true
+	user.VerificationToken = ""
+	m.users[id] = user
+	return nil
+}
+
+// UpdateUserPassword replaces the user's password hash
+func (m *Memory) UpdateUserPassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Password = passwordHash
+	m.users[id] = user
+	return nil
+}
+
+// ListUsersByRole returns the users with a role, oldest first
+func (m *Memory) ListUsersByRole(ctx context.Context, role string) ([]models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	users := []models.User{}
+	for _, user := range m.users {
+		if user.Role == role {
+			users = append(users, user)
+		}
+	}
+	sortByID(users, func(u models.User) primitive.ObjectID { return u.ID })
+	return users, nil
+}
+
+func (m *Memory) findUser(match func(models.User) bool) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if match(user) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// SetCartRemindersOff turns the user's abandoned-cart emails off or back on
+func (m *Memory) SetCartRemindersOff(ctx context.Context, id primitive.ObjectID, off bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.CartRemindersOff = off
+	m.users[id] = user
+	return nil
+}
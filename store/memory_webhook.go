@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"sort"
+)
+
+// CreateWebhookEvent stores a newly received event
+func (m *Memory) CreateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[event.ID]; ok {
+		return ErrDuplicate
+	}
+	m.webhooks[event.ID] = *event
+	return nil
+}
+
+// FindWebhookEventByID looks up a webhook event by ID
+func (m *Memory) FindWebhookEventByID(ctx context.Context, id string) (*models.WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	event, ok := m.webhooks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &event, nil
+}
+
+// ListWebhookEvents returns events newest first, optionally limited to one status
+func (m *Memory) ListWebhookEvents(ctx context.Context, status models.WebhookEventStatus) ([]models.WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	events := []models.WebhookEvent{}
+	for _, event := range m.webhooks {
+		if status == "" || event.Status == status {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ReceivedAt.After(events[j].ReceivedAt)
+	})
+	return events, nil
+}
+
+// UpdateWebhookEvent replaces a stored webhook event
+func (m *Memory) UpdateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[event.ID]; !ok {
+		return ErrNotFound
+	}
+	m.webhooks[event.ID] = *event
+	return nil
+}
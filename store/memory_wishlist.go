@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// cloneWishlist copies a wishlist so its items are not shared
+func cloneWishlist(w models.Wishlist) models.Wishlist {
+	if w.Items != nil {
+		items := make([]models.WishlistItem, len(w.Items))
+		for i, item := range w.Items {
+			if item.SavedPrice != nil {
+				price := *item.SavedPrice
+				item.SavedPrice = &price
+			}
+			items[i] = item
+		}
+		w.Items = items
+	}
+	return w
+}
+
+// wishlistNameTaken reports whether another of the user's wishlists is
+// called name; the caller must hold the lock
+func (m *Memory) wishlistNameTaken(wishlist models.Wishlist) bool {
+	for _, w := range m.wishlists {
+		if w.UserID == wishlist.UserID && w.Name == wishlist.Name && w.ID != wishlist.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWishlist stores a new wishlist and sets its ID
+func (m *Memory) CreateWishlist(ctx context.Context, wishlist *models.Wishlist) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if wishlist.ID.IsZero() {
+		wishlist.ID = primitive.NewObjectID()
+	}
+	if m.wishlistNameTaken(*wishlist) {
+		return ErrDuplicate
+	}
+	m.wishlists[wishlist.ID] = cloneWishlist(*wishlist)
+	return nil
+}
+
+// FindWishlistByID looks up a wishlist by ID
+func (m *Memory) FindWishlistByID(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wishlist, ok := m.wishlists[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	wishlist = cloneWishlist(wishlist)
+	return &wishlist, nil
+}
+
+// FindWishlistByShareToken returns the public wishlist with the token
+func (m *Memory) FindWishlistByShareToken(ctx context.Context, token string) (*models.Wishlist, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, wishlist := range m.wishlists {
+		if token != "" && wishlist.Public && wishlist.ShareToken == token {
+			wishlist = cloneWishlist(wishlist)
+			return &wishlist, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListWishlistsByUser returns the user's wishlists, oldest first
+func (m *Memory) ListWishlistsByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Wishlist, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wishlists := []models.Wishlist{}
+	for _, wishlist := range m.wishlists {
+		if wishlist.UserID == userID {
+			wishlists = append(wishlists, cloneWishlist(wishlist))
+		}
+	}
+	sortByID(wishlists, func(w models.Wishlist) primitive.ObjectID { return w.ID })
+	return wishlists, nil
+}
+
+// UpdateWishlist overwrites a wishlist
+func (m *Memory) UpdateWishlist(ctx context.Context, wishlist *models.Wishlist) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.wishlists[wishlist.ID]; !ok {
+		return ErrNotFound
+	}
+	if m.wishlistNameTaken(*wishlist) {
+		return ErrDuplicate
+	}
+	m.wishlists[wishlist.ID] = cloneWishlist(*wishlist)
+	return nil
+}
+
+// DeleteWishlist removes a wishlist
+func (m *Memory) DeleteWishlist(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.wishlists[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.wishlists, id)
+	return nil
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMoneyFromNumber(t *testing.T) {
+	decimal, err := primitive.ParseDecimal128("12.345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in   interface{}
+		want int64
+	}{
+		{19.99, 1999},
+		{0.1, 10},
+		{int32(5), 500},
+		{int64(12), 1200},
+		{decimal, 1235},
+	}
+	for _, tt := range tests {
+		got, err := moneyFromNumber(tt.in, "USD")
+		if err != nil || got != usd(tt.want) {
+			t.Errorf("moneyFromNumber(%v) = %v, %v; want %d cents", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := moneyFromNumber("19.99", "USD"); !errors.Is(err, models.ErrInvalidAmount) {
+		t.Errorf("moneyFromNumber of a string error = %v, want ErrInvalidAmount", err)
+	}
+}
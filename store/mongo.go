@@ -0,0 +1,76 @@
+package store
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Mongo is the MongoDB-backed Store
+type Mongo struct {
+	client     *mongo.Client
+	users      *mongo.Collection
+	products   *mongo.Collection
+	movements  *mongo.Collection
+	stockSubs  *mongo.Collection
+	carts      *mongo.Collection
+	guestCarts *mongo.Collection
+	wishlists  *mongo.Collection
+	orders     *mongo.Collection
+	payments   *mongo.Collection
+	webhooks   *mongo.Collection
+	promotions *mongo.Collection
+	taxRates   *mongo.Collection
+	shipping   *mongo.Collection
+	holidays   *mongo.Collection
+	sessions   *mongo.Collection
+	refresh    *mongo.Collection
+	resets     *mongo.Collection
+}
+
+var _ Store = (*Mongo)(nil)
+
+// NewMongo creates a Store backed by the "ecommerce" database
+func NewMongo(client *mongo.Client) *Mongo {
+	return newMongo(client.Database("ecommerce"))
+}
+
+// newMongo creates a Store backed by db
+func newMongo(db *mongo.Database) *Mongo {
+	return &Mongo{
+		client:     db.Client(),
+		users:      db.Collection("users"),
+		products:   db.Collection("products"),
+		movements:  db.Collection("stock_movements"),
+		stockSubs:  db.Collection("stock_subscriptions"),
+		carts:      db.Collection("carts"),
+		guestCarts: db.Collection("guest_carts"),
+		wishlists:  db.Collection("wishlists"),
+		orders:     db.Collection("orders"),
+		payments:   db.Collection("payments"),
+		webhooks:   db.Collection("webhook_events"),
+		promotions: db.Collection("promotions"),
+		taxRates:   db.Collection("tax_rates"),
+		shipping:   db.Collection("shipping_zones"),
+		holidays:   db.Collection("holidays"),
+		sessions:   db.Collection("sessions"),
+		refresh:    db.Collection("refresh_tokens"),
+		resets:     db.Collection("password_resets"),
+	}
+}
+
+// notFound maps the driver's "no documents" error onto ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// matched returns ErrNotFound when an update or delete touched nothing
+func matched(n int64) error {
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindCartByUserID returns the user's cart
+func (m *Mongo) FindCartByUserID(ctx context.Context, userID primitive.ObjectID) (*models.Cart, error) {
+	var cart models.Cart
+	if err := m.carts.FindOne(ctx, bson.M{"user_id": userID}).Decode(&cart); err != nil {
+		return nil, notFound(err)
+	}
+	return &cart, nil
+}
+
+// SaveCart creates or replaces the user's cart
+func (m *Mongo) SaveCart(ctx context.Context, cart *models.Cart) error {
+	if cart.ID.IsZero() {
+		cart.ID = primitive.NewObjectID()
+	}
+	_, err := m.carts.ReplaceOne(ctx, bson.M{"user_id": cart.UserID}, cart, options.Replace().SetUpsert(true))
+	return err
+}
+
+// DeleteCartByUserID removes the user's cart
+func (m *Mongo) DeleteCartByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := m.carts.DeleteOne(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
+
+// FindGuestCart returns the guest cart with the given ID
+func (m *Mongo) FindGuestCart(ctx context.Context, id primitive.ObjectID) (*models.Cart, error) {
+	var cart models.Cart
+	if err := m.guestCarts.FindOne(ctx, bson.M{"_id": id}).Decode(&cart); err != nil {
+		return nil, notFound(err)
+	}
+	return &cart, nil
+}
+
+// SaveGuestCart creates or replaces a guest cart, setting its ID if it is new
+func (m *Mongo) SaveGuestCart(ctx context.Context, cart *models.Cart) error {
+	if cart.ID.IsZero() {
+		cart.ID = primitive.NewObjectID()
+	}
+	_, err := m.guestCarts.ReplaceOne(ctx, bson.M{"_id": cart.ID}, cart, options.Replace().SetUpsert(true))
+	return err
+}
+
+// DeleteGuestCart removes a guest cart
+func (m *Mongo) DeleteGuestCart(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.guestCarts.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
+
+// ListAbandonedCarts returns the users' carts due a reminder, least recently updated first
+func (m *Mongo) ListAbandonedCarts(ctx context.Context, idleSince time.Time, limit int) ([]models.Cart, error) {
+	filter := bson.M{
+		"items.0":        bson.M{"$exists": true},
+		"updated_at":     bson.M{"$lt": idleSince},
+		"reminders_sent": bson.M{"$lt": limit},
+		"$or": bson.A{
+			bson.M{"last_reminded_at": nil},
+			bson.M{"last_reminded_at": bson.M{"$lt": idleSince}},
+		},
+	}
+	cursor, err := m.carts.Find(ctx, filter, options.Find().SetSort(bson.M{"updated_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	carts := []models.Cart{}
+	if err := cursor.All(ctx, &carts); err != nil {
+		return nil, err
+	}
+	return carts, nil
+}
+
+// MarkCartReminded counts a reminder unless another was counted since the caller looked
+func (m *Mongo) MarkCartReminded(ctx context.Context, id primitive.ObjectID, sent int, at time.Time) error {
+	result, err := m.carts.UpdateOne(ctx,
+		bson.M{"_id": id, "reminders_sent": sent},
+		bson.M{"$inc": bson.M{"reminders_sent": 1}, "$set": bson.M{"last_reminded_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if err := m.carts.FindOne(ctx, bson.M{"_id": id}).Err(); err != nil {
+			return notFound(err)
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+// CartRecoveryStats counts reminded carts still open and orders placed from reminded carts
+func (m *Mongo) CartRecoveryStats(ctx context.Context, since time.Time) (*models.CartRecoveryStats, error) {
+	stats := &models.CartRecoveryStats{RecoveredRevenue: models.NewMoney(0, models.DefaultCurrency)}
+
+	cursor, err := m.carts.Find(ctx,
+		bson.M{"reminders_sent": bson.M{"$gt": 0}, "last_reminded_at": bson.M{"$gte": since}},
+		options.Find().SetProjection(bson.M{"reminders_sent": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var carts []models.Cart
+	if err := cursor.All(ctx, &carts); err != nil {
+		return nil, err
+	}
+	for _, cart := range carts {
+		stats.RemindedCarts++
+		stats.RemindersSent += cart.RemindersSent
+	}
+
+	cursor, err = m.orders.Find(ctx,
+		bson.M{"cart_reminders": bson.M{"$gt": 0}, "created_at": bson.M{"$gte": since}},
+		options.Find().SetProjection(bson.M{"cart_reminders": 1, "status": 1, "total_amount": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		stats.RemindedCarts++
+		stats.RemindersSent += order.CartReminders
+		if order.Status != models.OrderCancelled {
+			stats.RecoveredCarts++
+			stats.RecoveredRevenue = stats.RecoveredRevenue.Add(order.TotalAmount)
+		}
+	}
+	stats.Rate()
+	return stats, nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateHoliday inserts a new holiday and sets its ID
+func (m *Mongo) CreateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	if holiday.ID.IsZero() {
+		holiday.ID = primitive.NewObjectID()
+	}
+	_, err := m.holidays.InsertOne(ctx, holiday)
+	return err
+}
+
+// FindHolidayByID looks up a holiday by ID
+func (m *Mongo) FindHolidayByID(ctx context.Context, id primitive.ObjectID) (*models.Holiday, error) {
+	var holiday models.Holiday
+	if err := m.holidays.FindOne(ctx, bson.M{"_id": id}).Decode(&holiday); err != nil {
+		return nil, notFound(err)
+	}
+	return &holiday, nil
+}
+
+// ListHolidays returns the holidays observed in state, or every holiday when state is empty
+func (m *Mongo) ListHolidays(ctx context.Context, state string) ([]models.Holiday, error) {
+	filter := bson.M{}
+	if state != "" {
+		// Holidays of every state are stored without one
+		filter["state"] = bson.M{"$in": bson.A{state, nil}}
+	}
+	sort := bson.D{{Key: "date", Value: 1}, {Key: "state", Value: 1}}
+	cursor, err := m.holidays.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return nil, err
+	}
+	holidays := []models.Holiday{}
+	if err := cursor.All(ctx, &holidays); err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+// UpdateHoliday replaces a stored holiday
+func (m *Mongo) UpdateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	result, err := m.holidays.ReplaceOne(ctx, bson.M{"_id": holiday.ID}, holiday)
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// DeleteHoliday removes a holiday
+func (m *Mongo) DeleteHoliday(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.holidays.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
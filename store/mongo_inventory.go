@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MoveStock changes stock and records the movement in one transaction
+func (m *Mongo) MoveStock(ctx context.Context, movement *models.StockMovement) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, m.moveStock(sc, movement)
+	})
+	return err
+}
+
+// moveStock changes on-hand stock and records the movement. Run it inside a
+// transaction so the two cannot drift apart. A reduction only matches a
+// product or variant with that much unreserved stock, so it can take stock
+// neither below zero nor below what unpaid orders hold.
+func (m *Mongo) moveStock(ctx context.Context, movement *models.StockMovement) error {
+	filter, prefix := stockTarget(movement.ProductID, movement.SKU)
+	if movement.Delta < 0 {
+		filter, prefix = availableTarget(movement.ProductID, movement.SKU, -movement.Delta)
+	}
+	var product models.Product
+	err := m.products.FindOneAndUpdate(ctx, filter, bson.M{
+		"$inc": bson.M{prefix + "stock": movement.Delta},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) && movement.Delta < 0 {
+		return m.stockConflict(ctx, movement.ProductID, movement.SKU)
+	}
+	if err != nil {
+		return notFound(err)
+	}
+	movement.Balance = product.StockFor(movement.SKU)
+	return m.recordMovements(ctx, movement)
+}
+
+// stockConflict explains why a stock reduction matched nothing
+func (m *Mongo) stockConflict(ctx context.Context, id primitive.ObjectID, sku string) error {
+	filter, _ := stockTarget(id, sku)
+	n, err := m.products.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return fmt.Errorf("%w: product %s %s", ErrInsufficientStock, id.Hex(), sku)
+}
+
+// recordMovements inserts movements into the ledger, setting their IDs and
+// times if unset
+func (m *Mongo) recordMovements(ctx context.Context, movements ...*models.StockMovement) error {
+	if len(movements) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(movements))
+	for i, movement := range movements {
+		if movement.ID.IsZero() {
+			movement.ID = primitive.NewObjectID()
+		}
+		if movement.CreatedAt.IsZero() {
+			movement.CreatedAt = time.Now()
+		}
+		docs[i] = movement
+	}
+	_, err := m.movements.InsertMany(ctx, docs)
+	return err
+}
+
+// recordStockChanges records the movements of a product edit
+func (m *Mongo) recordStockChanges(ctx context.Context, productID primitive.ObjectID, changes []models.StockMovement, reason models.StockReason, actor string) error {
+	movements := make([]*models.StockMovement, len(changes))
+	for i := range changes {
+		changes[i].ProductID = productID
+		changes[i].Reason = reason
+		changes[i].Actor = actor
+		movements[i] = &changes[i]
+	}
+	return m.recordMovements(ctx, movements...)
+}
+
+// ListStockMovements returns a product's movements, oldest first
+func (m *Mongo) ListStockMovements(ctx context.Context, productID primitive.ObjectID) ([]models.StockMovement, error) {
+	cursor, err := m.movements.Find(ctx, bson.M{"product_id": productID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	movements := []models.StockMovement{}
+	if err := cursor.All(ctx, &movements); err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// ReconcileStock resets a product's stock figures to its ledger sums in one transaction
+func (m *Mongo) ReconcileStock(ctx context.Context, productID primitive.ObjectID) ([]models.StockCount, error) {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	var counts []models.StockCount
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var product models.Product
+		if err := m.products.FindOne(sc, bson.M{"_id": productID}).Decode(&product); err != nil {
+			return nil, notFound(err)
+		}
+		movements, err := m.ListStockMovements(sc, productID)
+		if err != nil {
+			return nil, err
+		}
+		sums := make(map[string]int)
+		for _, movement := range movements {
+			sums[movement.SKU] += movement.Delta
+		}
+
+		counts = []models.StockCount{{Recorded: product.Stock, Ledger: sums[""]}}
+		set := bson.M{"stock": sums[""]}
+		for i, v := range product.Variants {
+			counts = append(counts, models.StockCount{SKU: v.SKU, Recorded: v.Stock, Ledger: sums[v.SKU]})
+			set[fmt.Sprintf("variants.%d.stock", i)] = sums[v.SKU]
+		}
+		_, err = m.products.UpdateOne(sc, bson.M{"_id": productID}, bson.M{"$set": set})
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
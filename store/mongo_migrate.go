@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"go-ecommerce/models"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MigrateMoney converts amounts stored as plain numbers, from before they
+// were models.Money, into Money documents of currency: product and variant
+// prices, order totals, payment amounts and webhook event amounts. Numbers
+// are rounded by ParseMoney's rules. Only numeric fields are touched, so
+// running it again is harmless. It returns the number of documents changed.
+func (m *Mongo) MigrateMoney(ctx context.Context, currency string) (int64, error) {
+	var total int64
+	for _, target := range []struct {
+		coll  *mongo.Collection
+		field string
+	}{
+		{m.products, "price"},
+		{m.orders, "total_amount"},
+		{m.payments, "amount"},
+		{m.webhooks, "amount"},
+	} {
+		n, err := migrateMoneyField(ctx, target.coll, target.field, currency)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	n, err := m.migrateVariantPrices(ctx, currency)
+	return total + n, err
+}
+
+// migrateMoneyField converts one top-level numeric field of every document in coll
+func migrateMoneyField(ctx context.Context, coll *mongo.Collection, field, currency string) (int64, error) {
+	cursor, err := coll.Find(ctx, bson.M{field: bson.M{"$type": "number"}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var n int64
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return n, err
+		}
+		// Payments briefly kept their currency in a field of its own
+		docCurrency := currency
+		if c, ok := doc["currency"].(string); ok && c != "" {
+			docCurrency = c
+		}
+		money, err := moneyFromNumber(doc[field], docCurrency)
+		if err != nil {
+			return n, fmt.Errorf("%s %v: %w", coll.Name(), doc["_id"], err)
+		}
+
+		update := bson.M{"$set": bson.M{field: money}}
+		if _, ok := doc["currency"]; ok {
+			update["$unset"] = bson.M{"currency": ""}
+		}
+		// Matching the old value skips documents changed since they were read
+		result, err := coll.UpdateOne(ctx, bson.M{"_id": doc["_id"], field: doc[field]}, update)
+		if err != nil {
+			return n, err
+		}
+		n += result.ModifiedCount
+	}
+	return n, cursor.Err()
+}
+
+// migrateVariantPrices converts numeric variant price overrides
+func (m *Mongo) migrateVariantPrices(ctx context.Context, currency string) (int64, error) {
+	cursor, err := m.products.Find(ctx, bson.M{"variants.price": bson.M{"$type": "number"}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var n int64
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Variants []bson.M           `bson:"variants"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return n, err
+		}
+		for _, variant := range doc.Variants {
+			// Leave missing and already converted prices alone
+			price := variant["price"]
+			switch price.(type) {
+			case float64, int32, int64, primitive.Decimal128:
+			default:
+				continue
+			}
+			money, err := moneyFromNumber(price, currency)
+			if err != nil {
+				return n, fmt.Errorf("products %s variant %v: %w", doc.ID.Hex(), variant["sku"], err)
+			}
+			variant["price"] = money
+		}
+
+		result, err := m.products.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"variants": doc.Variants}})
+		if err != nil {
+			return n, err
+		}
+		n += result.ModifiedCount
+	}
+	return n, cursor.Err()
+}
+
+// moneyFromNumber converts a numeric BSON value into Money
+func moneyFromNumber(v interface{}, currency string) (models.Money, error) {
+	switch n := v.(type) {
+	case float64:
+		return models.MoneyFromFloat(n, currency)
+	case int32:
+		return models.ParseMoney(strconv.FormatInt(int64(n), 10), currency)
+	case int64:
+		return models.ParseMoney(strconv.FormatInt(n, 10), currency)
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		if err != nil {
+			return models.Money{}, models.ErrInvalidAmount
+		}
+		return models.MoneyFromFloat(f, currency)
+	}
+	return models.Money{}, fmt.Errorf("%w: %T", models.ErrInvalidAmount, v)
+}
+
+// OpenStockLedger records the current stock of every product that has no
+// ledger movements yet, from before the ledger existed, as opening
+// movements, so that reconciling it leaves its stock unchanged. Products
+// already in the ledger are skipped, so running it again is harmless. It
+// returns the number of products opened.
+func (m *Mongo) OpenStockLedger(ctx context.Context) (int64, error) {
+	cursor, err := m.products.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var n int64
+	for cursor.Next(ctx) {
+		var product models.Product
+		if err := cursor.Decode(&product); err != nil {
+			return n, err
+		}
+		count, err := m.movements.CountDocuments(ctx, bson.M{"product_id": product.ID})
+		if err != nil {
+			return n, err
+		}
+		if count > 0 {
+			continue
+		}
+		changes := product.StockChanges(models.Product{})
+		for i := range changes {
+			changes[i].Note = "Stock held before the ledger was opened"
+		}
+		if err := m.recordStockChanges(ctx, product.ID, changes, models.StockOpening, "system"); err != nil {
+			return n, fmt.Errorf("products %s: %w", product.ID.Hex(), err)
+		}
+		n++
+	}
+	return n, cursor.Err()
+}
@@ -0,0 +1,315 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateOrder inserts a new order and sets its ID
+func (m *Mongo) CreateOrder(ctx context.Context, order *models.Order) error {
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	_, err := m.orders.InsertOne(ctx, order)
+	return err
+}
+
+// PlaceOrder runs checkout in a single transaction (this requires MongoDB to
+// run as a replica set). Stock, or variant stock for items with a SKU, is
+// reserved only where stock - reserved >= quantity, so concurrent checkouts
+// cannot oversell, and a promotion is only redeemed within its usage limits;
+// any failure aborts every write.
+func (m *Mongo) PlaceOrder(ctx context.Context, order *models.Order) error {
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		for _, item := range order.Items {
+			if item.Quantity <= 0 {
+				return nil, fmt.Errorf("%w: product %s", ErrInvalidQuantity, item.ProductID.Hex())
+			}
+			filter, prefix := availableTarget(item.ProductID, item.SKU, item.Quantity)
+			result, err := m.products.UpdateOne(sc, filter, bson.M{
+				"$inc": bson.M{prefix + "reserved": item.Quantity},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if result.MatchedCount == 0 {
+				return nil, fmt.Errorf("%w: product %s %s", ErrInsufficientStock, item.ProductID.Hex(), item.SKU)
+			}
+		}
+
+		if order.Promotion != nil {
+			if err := m.redeemPromotion(sc, order); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := m.orders.InsertOne(sc, order); err != nil {
+			return nil, err
+		}
+		// Only what was ordered goes; a cart with items saved for later is
+		// kept for them
+		if _, err := m.carts.DeleteOne(sc, bson.M{"user_id": order.UserID, "saved_for_later.0": bson.M{"$exists": false}}); err != nil {
+			return nil, err
+		}
+		if _, err := m.carts.UpdateOne(sc, bson.M{"user_id": order.UserID}, bson.M{
+			"$set":   bson.M{"items": bson.A{}, "reminders_sent": 0},
+			"$unset": bson.M{"coupon_code": "", "last_reminded_at": ""},
+		}); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// FindOrderByID looks up an order by ID
+func (m *Mongo) FindOrderByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error) {
+	var order models.Order
+	if err := m.orders.FindOne(ctx, bson.M{"_id": id}).Decode(&order); err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+// ListOrdersByUser returns every order placed by the user
+func (m *Mongo) ListOrdersByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Order, error) {
+	cursor, err := m.orders.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	orders := []models.Order{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListOrders returns every order, newest first, optionally limited to one status
+func (m *Mongo) ListOrders(ctx context.Context, status models.OrderStatus) ([]models.Order, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	cursor, err := m.orders.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		return nil, err
+	}
+	orders := []models.Order{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// TransitionOrderStatus moves an order out of status from and records the change
+func (m *Mongo) TransitionOrderStatus(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange) error {
+	result, err := m.orders.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{
+		"$set":  bson.M{"status": change.To},
+		"$push": bson.M{"history": change},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return m.orderConflict(ctx, id)
+	}
+	return nil
+}
+
+// PayOrder marks an order paid and commits its stock reservation in one transaction
+func (m *Mongo) PayOrder(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange) error {
+	change.To = models.OrderPaid
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// Returns the order as it was before the update
+		var order models.Order
+		err := m.orders.FindOneAndUpdate(sc, bson.M{"_id": id, "status": from}, bson.M{
+			"$set":  bson.M{"status": change.To},
+			"$push": bson.M{"history": change},
+		}).Decode(&order)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, m.orderConflict(sc, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if order.Reservation == nil || order.Reservation.Status != models.ReservationActive {
+			return nil, nil
+		}
+
+		// Each line's hold is released as it is sold, so the sale cannot eat
+		// into other orders' holds. Products deleted since the order was
+		// placed are skipped.
+		for _, item := range order.Items {
+			filter, prefix := stockTarget(item.ProductID, item.SKU)
+			_, err := m.products.UpdateOne(sc, filter, bson.M{"$inc": bson.M{prefix + "reserved": -item.Quantity}})
+			if err != nil {
+				return nil, err
+			}
+			sale := models.StockMovement{
+				ProductID: item.ProductID,
+				SKU:       item.SKU,
+				Delta:     -item.Quantity,
+				Reason:    models.StockSale,
+				Actor:     change.Actor,
+				Reference: id.Hex(),
+				CreatedAt: change.At,
+			}
+			if err := m.moveStock(sc, &sale); err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+		}
+		return nil, m.resolveReservation(sc, id, models.ReservationCommitted, change.At)
+	})
+	return err
+}
+
+// resolveReservation records that an order's reservation was committed or released
+func (m *Mongo) resolveReservation(ctx context.Context, id primitive.ObjectID, status models.ReservationStatus, at time.Time) error {
+	_, err := m.orders.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"reservation.status": status, "reservation.resolved_at": at},
+	})
+	return err
+}
+
+// CancelOrder cancels an order and releases or restores its stock in one transaction
+func (m *Mongo) CancelOrder(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange, reason string) error {
+	change.To = models.OrderCancelled
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// Returns the order as it was before the update
+		var order models.Order
+		err := m.orders.FindOneAndUpdate(sc, bson.M{"_id": id, "status": from}, bson.M{
+			"$set":  bson.M{"status": change.To, "cancel_reason": reason},
+			"$push": bson.M{"history": change},
+		}).Decode(&order)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, m.orderConflict(sc, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Unpaid orders only give up their hold; paid ones return their
+		// stock. Products deleted since the order was placed are skipped.
+		release := order.Reservation != nil && order.Reservation.Status == models.ReservationActive
+		for _, item := range order.Items {
+			var err error
+			if release {
+				filter, prefix := stockTarget(item.ProductID, item.SKU)
+				_, err = m.products.UpdateOne(sc, filter, bson.M{"$inc": bson.M{prefix + "reserved": -item.Quantity}})
+			} else {
+				err = m.moveStock(sc, &models.StockMovement{
+					ProductID: item.ProductID,
+					SKU:       item.SKU,
+					Delta:     item.Quantity,
+					Reason:    models.StockCancellation,
+					Actor:     change.Actor,
+					Reference: id.Hex(),
+					Note:      reason,
+					CreatedAt: change.At,
+				})
+			}
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+		}
+		if release {
+			if err := m.resolveReservation(sc, id, models.ReservationReleased, change.At); err != nil {
+				return nil, err
+			}
+		}
+		if order.Promotion != nil {
+			if err := m.unredeemPromotion(sc, &order); err != nil {
+				return nil, err
+			}
+		}
+
+		if order.PaymentStatus == models.PaymentCompleted {
+			_, err := m.orders.UpdateOne(sc, bson.M{"_id": id}, bson.M{
+				"$set": bson.M{"payment_status": models.PaymentRefundPending},
+			})
+			if err != nil {
+				return nil, err
+			}
+			_, err = m.payments.UpdateMany(sc, bson.M{"order_id": id, "status": models.PaymentCompleted}, bson.M{
+				"$set": bson.M{"status": models.PaymentRefundPending},
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// orderConflict explains why a conditional order update matched nothing
+func (m *Mongo) orderConflict(ctx context.Context, id primitive.ObjectID) error {
+	n, err := m.orders.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+// ListExpiredReservations returns the orders whose active reservation expired before the given time
+func (m *Mongo) ListExpiredReservations(ctx context.Context, before time.Time) ([]models.Order, error) {
+	filter := bson.M{"reservation.status": models.ReservationActive, "reservation.expires_at": bson.M{"$lt": before}}
+	cursor, err := m.orders.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	orders := []models.Order{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// UpdateOrderPaymentStatus sets the order's payment status
+func (m *Mongo) UpdateOrderPaymentStatus(ctx context.Context, id primitive.ObjectID, status models.PaymentStatus) error {
+	return m.setOrderField(ctx, id, "payment_status", status)
+}
+
+func (m *Mongo) setOrderField(ctx context.Context, id primitive.ObjectID, field string, value interface{}) error {
+	result, err := m.orders.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{field: value},
+	})
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreatePayment inserts a new payment record and sets its ID
+func (m *Mongo) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+	_, err := m.payments.InsertOne(ctx, payment)
+	return err
+}
+
+// FindPaymentByID looks up a payment by ID
+func (m *Mongo) FindPaymentByID(ctx context.Context, id primitive.ObjectID) (*models.Payment, error) {
+	return m.findPayment(ctx, bson.M{"_id": id})
+}
+
+// FindPaymentByOrderID looks up the payment belonging to an order
+func (m *Mongo) FindPaymentByOrderID(ctx context.Context, orderID primitive.ObjectID) (*models.Payment, error) {
+	return m.findPayment(ctx, bson.M{"order_id": orderID})
+}
+
+// FindPaymentByProviderRef looks up a card payment by its gateway intent ID
+func (m *Mongo) FindPaymentByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error) {
+	return m.findPayment(ctx, bson.M{"provider": provider, "provider_ref": ref})
+}
+
+// ListPaymentsByStatus returns payments in one status, oldest first
+func (m *Mongo) ListPaymentsByStatus(ctx context.Context, status models.PaymentStatus) ([]models.Payment, error) {
+	cursor, err := m.payments.Find(ctx, bson.M{"status": status}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	payments := []models.Payment{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// UpdatePaymentStatus sets the payment's status
+func (m *Mongo) UpdatePaymentStatus(ctx context.Context, id primitive.ObjectID, status models.PaymentStatus) error {
+	result, err := m.payments.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"status": status},
+	})
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// UpdatePayment replaces a stored payment record
+func (m *Mongo) UpdatePayment(ctx context.Context, payment *models.Payment) error {
+	result, err := m.payments.ReplaceOne(ctx, bson.M{"_id": payment.ID}, payment)
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+func (m *Mongo) findPayment(ctx context.Context, filter bson.M) (*models.Payment, error) {
+	var payment models.Payment
+	if err := m.payments.FindOne(ctx, filter).Decode(&payment); err != nil {
+		return nil, notFound(err)
+	}
+	return &payment, nil
+}
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateProduct inserts a new product, sets its ID and records its opening
+// stock in one transaction
+func (m *Mongo) CreateProduct(ctx context.Context, product *models.Product, actor string) error {
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if _, err := m.products.InsertOne(sc, product); err != nil {
+			return nil, err
+		}
+		return nil, m.recordStockChanges(sc, product.ID, product.StockChanges(models.Product{}), models.StockOpening, actor)
+	})
+	return err
+}
+
+// FindProductByID looks up a product by ID
+func (m *Mongo) FindProductByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	var product models.Product
+	if err := m.products.FindOne(ctx, bson.M{"_id": id}).Decode(&product); err != nil {
+		return nil, notFound(err)
+	}
+	return &product, nil
+}
+
+// ListProducts returns one page of products matching the query
+func (m *Mongo) ListProducts(ctx context.Context, q ProductQuery) (*ProductPage, error) {
+	filter := productFilter(q.ProductFilter)
+	total, err := m.products.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	field, dir := sortField(q.Sort)
+	if q.After != nil {
+		filter = bson.M{"$and": bson.A{filter, afterCursor(field, dir, q.After)}}
+	}
+	sort := bson.D{{Key: field, Value: dir}}
+	if field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: dir})
+	}
+
+	opts := options.Find().SetSort(sort).SetLimit(int64(q.Limit + 1))
+	cursor, err := m.products.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	products := []models.Product{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return pageOf(products, q, total), nil
+}
+
+// stockTarget returns the filter matching a product, or one of its variants
+// when sku is set, and the path prefix of the matched "stock" and "reserved"
+// fields
+func stockTarget(id primitive.ObjectID, sku string) (bson.M, string) {
+	if sku == "" {
+		return bson.M{"_id": id}, ""
+	}
+	return bson.M{"_id": id, "variants.sku": sku}, "variants.$."
+}
+
+// unreserved is the expression for the stock not held by unpaid orders, of
+// the product or of the variant bound to $$v when variant is set
+func unreserved(variant bool) bson.M {
+	stock, reserved := "$stock", "$reserved"
+	if variant {
+		stock, reserved = "$$v.stock", "$$v.reserved"
+	}
+	return bson.M{"$subtract": bson.A{stock, bson.M{"$ifNull": bson.A{reserved, 0}}}}
+}
+
+// availableTarget is stockTarget narrowed to a product or variant with at
+// least qty units of unreserved stock
+func availableTarget(id primitive.ObjectID, sku string, qty int) (bson.M, string) {
+	filter, prefix := stockTarget(id, sku)
+	if sku == "" {
+		filter["$expr"] = bson.M{"$gte": bson.A{unreserved(false), qty}}
+		return filter, prefix
+	}
+	filter["$expr"] = bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+		"input": "$variants",
+		"as":    "v",
+		"in": bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$$v.sku", sku}},
+			bson.M{"$gte": bson.A{unreserved(true), qty}},
+		}},
+	}}}}
+	return filter, prefix
+}
+
+// productFilter translates a ProductFilter into a MongoDB query
+func productFilter(f ProductFilter) bson.M {
+	filter := bson.M{}
+	if f.Category != "" {
+		filter["category"] = f.Category
+	}
+	price := bson.M{}
+	if f.MinPrice != nil {
+		price["$gte"] = *f.MinPrice
+	}
+	if f.MaxPrice != nil {
+		price["$lte"] = *f.MaxPrice
+	}
+	if len(price) > 0 {
+		filter["price.amount"] = price
+	}
+	if f.InStockOnly {
+		filter["$expr"] = bson.M{"$or": bson.A{
+			bson.M{"$gt": bson.A{unreserved(false), 0}},
+			bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$variants", bson.A{}}},
+				"as":    "v",
+				"in":    bson.M{"$gt": bson.A{unreserved(true), 0}},
+			}}}},
+		}}
+	}
+	return filter
+}
+
+// sortField returns the document field and direction for a sort order
+func sortField(s ProductSort) (string, int) {
+	switch s {
+	case SortPriceAsc:
+		return "price.amount", 1
+	case SortPriceDesc:
+		return "price.amount", -1
+	case SortNameAsc:
+		return "name", 1
+	case SortNameDesc:
+		return "name", -1
+	}
+	return "_id", -1
+}
+
+// afterCursor matches documents that sort strictly after the cursor, using
+// _id to break ties between equal sort keys
+func afterCursor(field string, dir int, c *ProductCursor) bson.M {
+	op := "$gt"
+	if dir < 0 {
+		op = "$lt"
+	}
+	if field == "_id" {
+		return bson.M{"_id": bson.M{op: c.ID}}
+	}
+
+	var value interface{} = c.Name
+	if field == "price.amount" {
+		value = c.Price
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: c.ID}},
+	}}
+}
+
+// UpdateProduct replaces a product, keeping its reserved counts and
+// recording its stock changes. It reads and writes in one transaction so a
+// concurrent checkout's reservation or sale is not lost. The document is
+// replaced rather than $set so that fields the update leaves empty, which
+// the model omits, are cleared too. Stock may not be set below what is held.
+func (m *Mongo) UpdateProduct(ctx context.Context, id primitive.ObjectID, product models.Product, actor string) error {
+	product.ID = primitive.NilObjectID
+	product.Variants = append([]models.Variant(nil), product.Variants...)
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var old models.Product
+		if err := m.products.FindOne(sc, bson.M{"_id": id}).Decode(&old); err != nil {
+			return nil, notFound(err)
+		}
+		product.KeepReserved(old)
+		if product.Overheld() {
+			return nil, fmt.Errorf("%w: product %s", ErrInsufficientStock, id.Hex())
+		}
+		if _, err := m.products.ReplaceOne(sc, bson.M{"_id": id}, product); err != nil {
+			return nil, err
+		}
+		return nil, m.recordStockChanges(sc, id, product.StockChanges(old), models.StockAdjustment, actor)
+	})
+	return err
+}
+
+// DeleteProduct removes a product
+func (m *Mongo) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.products.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// codeTaken reports whether a promotion other than id already uses code.
+// Promotions are only written by admins, so checking before writing is
+// enough to keep codes unique.
+func (m *Mongo) codeTaken(ctx context.Context, code string, id primitive.ObjectID) (bool, error) {
+	err := m.promotions.FindOne(ctx, bson.M{"code": code, "_id": bson.M{"$ne": id}}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreatePromotion inserts a new promotion and sets its ID
+func (m *Mongo) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	if promotion.ID.IsZero() {
+		promotion.ID = primitive.NewObjectID()
+	}
+	taken, err := m.codeTaken(ctx, promotion.Code, promotion.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicate
+	}
+	_, err = m.promotions.InsertOne(ctx, promotion)
+	return err
+}
+
+// FindPromotionByID looks up a promotion by ID
+func (m *Mongo) FindPromotionByID(ctx context.Context, id primitive.ObjectID) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := m.promotions.FindOne(ctx, bson.M{"_id": id}).Decode(&promotion); err != nil {
+		return nil, notFound(err)
+	}
+	return &promotion, nil
+}
+
+// FindPromotionByCode looks up a promotion by its coupon code
+func (m *Mongo) FindPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := m.promotions.FindOne(ctx, bson.M{"code": code}).Decode(&promotion); err != nil {
+		return nil, notFound(err)
+	}
+	return &promotion, nil
+}
+
+// ListPromotions returns every promotion, newest first
+func (m *Mongo) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	cursor, err := m.promotions.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		return nil, err
+	}
+	promotions := []models.Promotion{}
+	if err := cursor.All(ctx, &promotions); err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+// UpdatePromotion saves every field of a promotion except its usage count,
+// which checkouts may be incrementing concurrently
+func (m *Mongo) UpdatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	taken, err := m.codeTaken(ctx, promotion.Code, promotion.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicate
+	}
+	result, err := m.promotions.UpdateOne(ctx, bson.M{"_id": promotion.ID}, bson.M{"$set": bson.M{
+		"code":           promotion.Code,
+		"description":    promotion.Description,
+		"type":           promotion.Type,
+		"percent_off":    promotion.PercentOff,
+		"amount_off":     promotion.AmountOff,
+		"buy_quantity":   promotion.BuyQuantity,
+		"get_quantity":   promotion.GetQuantity,
+		"product_ids":    promotion.ProductIDs,
+		"categories":     promotion.Categories,
+		"min_spend":      promotion.MinSpend,
+		"starts_at":      promotion.StartsAt,
+		"ends_at":        promotion.EndsAt,
+		"usage_limit":    promotion.UsageLimit,
+		"per_user_limit": promotion.PerUserLimit,
+		"disabled":       promotion.Disabled,
+		"updated_at":     promotion.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// DeletePromotion removes a promotion. Orders keep their copy of it.
+func (m *Mongo) DeletePromotion(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.promotions.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
+
+// CountPromotionRedemptions counts the user's orders placed with the
+// promotion, leaving out cancelled ones
+func (m *Mongo) CountPromotionRedemptions(ctx context.Context, promotionID, userID primitive.ObjectID) (int, error) {
+	n, err := m.orders.CountDocuments(ctx, bson.M{
+		"user_id":                userID,
+		"promotion.promotion_id": promotionID,
+		"status":                 bson.M{"$ne": models.OrderCancelled},
+	})
+	return int(n), err
+}
+
+// redeemPromotion counts one use of the order's promotion inside a checkout
+// transaction. Two checkouts redeeming the same promotion both write its
+// document, so one of them is retried and sees the other's order.
+func (m *Mongo) redeemPromotion(sc mongo.SessionContext, order *models.Order) error {
+	var promotion models.Promotion
+	if err := m.promotions.FindOne(sc, bson.M{"_id": order.Promotion.PromotionID}).Decode(&promotion); err != nil {
+		return notFound(err)
+	}
+	if promotion.PerUserLimit > 0 {
+		used, err := m.CountPromotionRedemptions(sc, promotion.ID, order.UserID)
+		if err != nil {
+			return err
+		}
+		if used >= promotion.PerUserLimit {
+			return ErrLimitReached
+		}
+	}
+
+	filter := bson.M{"_id": promotion.ID}
+	if promotion.UsageLimit > 0 {
+		filter["used_count"] = bson.M{"$lt": promotion.UsageLimit}
+	}
+	result, err := m.promotions.UpdateOne(sc, filter, bson.M{"$inc": bson.M{"used_count": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLimitReached
+	}
+	return nil
+}
+
+// unredeemPromotion gives back the use of the promotion a cancelled order
+// redeemed, inside the cancelling transaction. A deleted promotion is skipped.
+func (m *Mongo) unredeemPromotion(sc mongo.SessionContext, order *models.Order) error {
+	_, err := m.promotions.UpdateOne(sc, bson.M{"_id": order.Promotion.PromotionID, "used_count": bson.M{"$gt": 0}}, bson.M{
+		"$inc": bson.M{"used_count": -1},
+	})
+	return err
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSession inserts a new session and sets its ID
+func (m *Mongo) CreateSession(ctx context.Context, session *models.Session) error {
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	_, err := m.sessions.InsertOne(ctx, session)
+	return err
+}
+
+// FindSessionByID looks up a session by ID
+func (m *Mongo) FindSessionByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	if err := m.sessions.FindOne(ctx, bson.M{"_id": id}).Decode(&session); err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+// RevokeSession revokes a session; revoking an already revoked session is a no-op
+func (m *Mongo) RevokeSession(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.sessions.UpdateOne(ctx, bson.M{"_id": id}, bson.A{
+		bson.M{"$set": bson.M{"revoked_at": bson.M{"$ifNull": bson.A{"$revoked_at", time.Now()}}}},
+	})
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// RevokeUserSessions revokes every active session belonging to the user
+func (m *Mongo) RevokeUserSessions(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := m.sessions.UpdateMany(ctx, bson.M{"user_id": userID, "revoked_at": nil}, bson.M{
+		"$set": bson.M{"revoked_at": time.Now()},
+	})
+	return err
+}
+
+// CreateRefreshToken inserts a new refresh token record and sets its ID
+func (m *Mongo) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	_, err := m.refresh.InsertOne(ctx, token)
+	return err
+}
+
+// FindRefreshTokenByHash looks up a refresh token by the hash of its value
+func (m *Mongo) FindRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := m.refresh.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&token); err != nil {
+		return nil, notFound(err)
+	}
+	return &token, nil
+}
+
+// ConsumeRefreshToken marks an unused token as used
+func (m *Mongo) ConsumeRefreshToken(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.refresh.UpdateOne(ctx, bson.M{"_id": id, "used_at": nil}, bson.M{
+		"$set": bson.M{"used_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// CreatePasswordReset stores a reset token, discarding the user's unused tokens
+func (m *Mongo) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	_, err := m.resets.DeleteMany(ctx, bson.M{"user_id": reset.UserID, "used_at": nil})
+	if err != nil {
+		return err
+	}
+	_, err = m.resets.InsertOne(ctx, reset)
+	return err
+}
+
+// FindPasswordResetByHash looks up a reset token by the hash of its value
+func (m *Mongo) FindPasswordResetByHash(ctx context.Context, hash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	if err := m.resets.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&reset); err != nil {
+		return nil, notFound(err)
+	}
+	return &reset, nil
+}
+
+// ConsumePasswordReset marks an unused reset token as used
+func (m *Mongo) ConsumePasswordReset(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.resets.UpdateOne(ctx, bson.M{"_id": id, "used_at": nil}, bson.M{
+		"$set": bson.M{"used_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrConflict
+	}
+	return nil
+}
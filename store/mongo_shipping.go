@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateShippingZone inserts a new shipping zone and sets its ID
+func (m *Mongo) CreateShippingZone(ctx context.Context, zone *models.ShippingZone) error {
+	if zone.ID.IsZero() {
+		zone.ID = primitive.NewObjectID()
+	}
+	_, err := m.shipping.InsertOne(ctx, zone)
+	return err
+}
+
+// FindShippingZoneByID looks up a shipping zone by ID
+func (m *Mongo) FindShippingZoneByID(ctx context.Context, id primitive.ObjectID) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	if err := m.shipping.FindOne(ctx, bson.M{"_id": id}).Decode(&zone); err != nil {
+		return nil, notFound(err)
+	}
+	return &zone, nil
+}
+
+// ListShippingZones returns every zone, oldest first
+func (m *Mongo) ListShippingZones(ctx context.Context) ([]models.ShippingZone, error) {
+	cursor, err := m.shipping.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	zones := []models.ShippingZone{}
+	if err := cursor.All(ctx, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// UpdateShippingZone replaces a stored shipping zone
+func (m *Mongo) UpdateShippingZone(ctx context.Context, zone *models.ShippingZone) error {
+	result, err := m.shipping.ReplaceOne(ctx, bson.M{"_id": zone.ID}, zone)
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// DeleteShippingZone removes a shipping zone
+func (m *Mongo) DeleteShippingZone(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.shipping.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListLowStockProducts returns the products at or below their reorder threshold, by name
+func (m *Mongo) ListLowStockProducts(ctx context.Context) ([]models.Product, error) {
+	variants := bson.M{"$ifNull": bson.A{"$variants", bson.A{}}}
+	filter := bson.M{
+		"reorder_threshold": bson.M{"$gt": 0},
+		"$expr": bson.M{"$or": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$size": variants}, 0}},
+				bson.M{"$lte": bson.A{"$stock", "$reorder_threshold"}},
+			}},
+			bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+				"input": variants,
+				"as":    "v",
+				"in":    bson.M{"$lte": bson.A{"$$v.stock", "$reorder_threshold"}},
+			}}}},
+		}},
+	}
+	sort := bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}
+	cursor, err := m.products.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return nil, err
+	}
+	products := []models.Product{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// pendingStockSubscription matches the user's subscription that has not been notified yet
+func pendingStockSubscription(userID, productID primitive.ObjectID, sku string) bson.M {
+	return bson.M{"user_id": userID, "product_id": productID, "sku": sku, "notified_at": nil}
+}
+
+// CreateStockSubscription inserts a subscription unless the user already has
+// a pending one for the product and SKU
+func (m *Mongo) CreateStockSubscription(ctx context.Context, subscription *models.StockSubscription) error {
+	if subscription.ID.IsZero() {
+		subscription.ID = primitive.NewObjectID()
+	}
+	filter := pendingStockSubscription(subscription.UserID, subscription.ProductID, subscription.SKU)
+	result, err := m.stockSubs.UpdateOne(ctx, filter, bson.M{"$setOnInsert": subscription}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	if result.UpsertedCount == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+// DeleteStockSubscription removes the user's pending subscription
+func (m *Mongo) DeleteStockSubscription(ctx context.Context, userID, productID primitive.ObjectID, sku string) error {
+	result, err := m.stockSubs.DeleteOne(ctx, pendingStockSubscription(userID, productID, sku))
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
+
+// ClaimStockSubscriptions marks pending subscriptions notified and returns
+// them. Each is marked with a conditional update, so a subscription claimed
+// concurrently by another call is left out.
+func (m *Mongo) ClaimStockSubscriptions(ctx context.Context, productID primitive.ObjectID, sku string, at time.Time) ([]models.StockSubscription, error) {
+	filter := bson.M{"product_id": productID, "sku": sku, "notified_at": nil}
+	cursor, err := m.stockSubs.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	pending := []models.StockSubscription{}
+	if err := cursor.All(ctx, &pending); err != nil {
+		return nil, err
+	}
+
+	claimed := []models.StockSubscription{}
+	for _, sub := range pending {
+		result, err := m.stockSubs.UpdateOne(ctx, bson.M{"_id": sub.ID, "notified_at": nil}, bson.M{
+			"$set": bson.M{"notified_at": at},
+		})
+		if err != nil {
+			return claimed, err
+		}
+		if result.ModifiedCount == 1 {
+			sub.NotifiedAt = &at
+			claimed = append(claimed, sub)
+		}
+	}
+	return claimed, nil
+}
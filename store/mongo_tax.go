@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateTaxRate inserts a new tax rate and sets its ID
+func (m *Mongo) CreateTaxRate(ctx context.Context, rate *models.TaxRate) error {
+	if rate.ID.IsZero() {
+		rate.ID = primitive.NewObjectID()
+	}
+	_, err := m.taxRates.InsertOne(ctx, rate)
+	return err
+}
+
+// FindTaxRateByID looks up a tax rate by ID
+func (m *Mongo) FindTaxRateByID(ctx context.Context, id primitive.ObjectID) (*models.TaxRate, error) {
+	var rate models.TaxRate
+	if err := m.taxRates.FindOne(ctx, bson.M{"_id": id}).Decode(&rate); err != nil {
+		return nil, notFound(err)
+	}
+	return &rate, nil
+}
+
+// ListTaxRates returns the rates of one state, or of every state when state is empty
+func (m *Mongo) ListTaxRates(ctx context.Context, state string) ([]models.TaxRate, error) {
+	filter := bson.M{}
+	if state != "" {
+		filter["state"] = state
+	}
+	sort := bson.D{{Key: "state", Value: 1}, {Key: "name", Value: 1}, {Key: "zip_prefix", Value: 1}}
+	cursor, err := m.taxRates.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return nil, err
+	}
+	rates := []models.TaxRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// UpdateTaxRate replaces a stored tax rate
+func (m *Mongo) UpdateTaxRate(ctx context.Context, rate *models.TaxRate) error {
+	result, err := m.taxRates.ReplaceOne(ctx, bson.M{"_id": rate.ID}, rate)
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// DeleteTaxRate removes a tax rate
+func (m *Mongo) DeleteTaxRate(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.taxRates.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
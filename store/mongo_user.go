@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateUser inserts a new user and sets its ID
+func (m *Mongo) CreateUser(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	_, err := m.users.InsertOne(ctx, user)
+	return err
+}
+
+// FindUserByID looks up a user by ID
+func (m *Mongo) FindUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return m.findUser(ctx, bson.M{"_id": id})
+}
+
+// FindUserByEmail looks up a user by email address
+func (m *Mongo) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return m.findUser(ctx, bson.M{"email": email})
+}
+
+// FindUserByVerificationToken looks up the user holding a verification token
+func (m *Mongo) FindUserByVerificationToken(ctx context.Context, token string) (*models.User, error) {
+	return m.findUser(ctx, bson.M{"verification_token": token})
+}
+
+// MarkUserVerified flags the user's email as verified and clears the token
+func (m *Mongo) MarkUserVerified(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.users.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"is_verified":        true,
+			"verification_token": "",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// UpdateUserPassword replaces the user's password hash
+func (m *Mongo) UpdateUserPassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	result, err := m.users.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"password": passwordHash},
+	})
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// ListUsersByRole returns the users with a role, oldest first
+func (m *Mongo) ListUsersByRole(ctx context.Context, role string) ([]models.User, error) {
+	cursor, err := m.users.Find(ctx, bson.M{"role": role}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (m *Mongo) findUser(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	if err := m.users.FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+// SetCartRemindersOff turns the user's abandoned-cart emails off or back on
+func (m *Mongo) SetCartRemindersOff(ctx context.Context, id primitive.ObjectID, off bool) error {
+	result, err := m.users.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"cart_reminders_off": off}})
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
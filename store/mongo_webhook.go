@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateWebhookEvent inserts a newly received event. The event ID is the
+// document _id, so a second delivery fails with a duplicate key error.
+func (m *Mongo) CreateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error {
+	_, err := m.webhooks.InsertOne(ctx, event)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// FindWebhookEventByID looks up a webhook event by ID
+func (m *Mongo) FindWebhookEventByID(ctx context.Context, id string) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := m.webhooks.FindOne(ctx, bson.M{"_id": id}).Decode(&event); err != nil {
+		return nil, notFound(err)
+	}
+	return &event, nil
+}
+
+// ListWebhookEvents returns events newest first, optionally limited to one status
+func (m *Mongo) ListWebhookEvents(ctx context.Context, status models.WebhookEventStatus) ([]models.WebhookEvent, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	cursor, err := m.webhooks.Find(ctx, filter, options.Find().SetSort(bson.M{"received_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	events := []models.WebhookEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// UpdateWebhookEvent replaces a stored webhook event
+func (m *Mongo) UpdateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error {
+	result, err := m.webhooks.ReplaceOne(ctx, bson.M{"_id": event.ID}, event)
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// wishlistNameTaken reports whether another of the user's wishlists is
+// called name. Each user only writes their own wishlists, so checking
+// before writing is enough to keep the names unique.
+func (m *Mongo) wishlistNameTaken(ctx context.Context, wishlist *models.Wishlist) (bool, error) {
+	err := m.wishlists.FindOne(ctx, bson.M{
+		"user_id": wishlist.UserID,
+		"name":    wishlist.Name,
+		"_id":     bson.M{"$ne": wishlist.ID},
+	}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateWishlist inserts a new wishlist and sets its ID
+func (m *Mongo) CreateWishlist(ctx context.Context, wishlist *models.Wishlist) error {
+	if wishlist.ID.IsZero() {
+		wishlist.ID = primitive.NewObjectID()
+	}
+	taken, err := m.wishlistNameTaken(ctx, wishlist)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicate
+	}
+	_, err = m.wishlists.InsertOne(ctx, wishlist)
+	return err
+}
+
+// FindWishlistByID looks up a wishlist by ID
+func (m *Mongo) FindWishlistByID(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	if err := m.wishlists.FindOne(ctx, bson.M{"_id": id}).Decode(&wishlist); err != nil {
+		return nil, notFound(err)
+	}
+	return &wishlist, nil
+}
+
+// FindWishlistByShareToken returns the public wishlist with the token
+func (m *Mongo) FindWishlistByShareToken(ctx context.Context, token string) (*models.Wishlist, error) {
+	if token == "" {
+		return nil, ErrNotFound
+	}
+	var wishlist models.Wishlist
+	if err := m.wishlists.FindOne(ctx, bson.M{"share_token": token, "public": true}).Decode(&wishlist); err != nil {
+		return nil, notFound(err)
+	}
+	return &wishlist, nil
+}
+
+// ListWishlistsByUser returns the user's wishlists, oldest first
+func (m *Mongo) ListWishlistsByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Wishlist, error) {
+	cursor, err := m.wishlists.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	wishlists := []models.Wishlist{}
+	if err := cursor.All(ctx, &wishlists); err != nil {
+		return nil, err
+	}
+	return wishlists, nil
+}
+
+// UpdateWishlist overwrites a wishlist
+func (m *Mongo) UpdateWishlist(ctx context.Context, wishlist *models.Wishlist) error {
+	taken, err := m.wishlistNameTaken(ctx, wishlist)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicate
+	}
+	result, err := m.wishlists.ReplaceOne(ctx, bson.M{"_id": wishlist.ID}, wishlist)
+	if err != nil {
+		return err
+	}
+	return matched(result.MatchedCount)
+}
+
+// DeleteWishlist removes a wishlist
+func (m *Mongo) DeleteWishlist(ctx context.Context, id primitive.ObjectID) error {
+	result, err := m.wishlists.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return matched(result.DeletedCount)
+}
@@ -0,0 +1,408 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOrderStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		var orders []models.Order
+		for i := 0; i < 2; i++ {
+			order := models.Order{UserID: userID, Items: []models.OrderItem{{ProductID: primitive.NewObjectID(), Quantity: i + 1}}, PaymentMethod: "card"}
+			if err := s.CreateOrder(ctx, &order); err != nil {
+				t.Fatal(err)
+			}
+			orders = append(orders, order)
+		}
+		other := models.Order{UserID: primitive.NewObjectID(), PaymentMethod: "crypto"}
+		if err := s.CreateOrder(ctx, &other); err != nil {
+			t.Fatal(err)
+		}
+
+		listed, err := s.ListOrdersByUser(ctx, userID)
+		if err != nil || len(listed) != 2 || listed[0].ID != orders[0].ID || listed[1].Items[0].Quantity != 2 {
+			t.Errorf("ListOrdersByUser = %+v, %v; want the user's two orders, oldest first", listed, err)
+		}
+
+		if err := s.UpdateOrderPaymentStatus(ctx, orders[0].ID, "completed"); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindOrderByID(ctx, orders[0].ID); err != nil || got.PaymentStatus != "completed" {
+			t.Errorf("order = %+v, %v; want its payment completed", got, err)
+		}
+		if err := s.UpdateOrderPaymentStatus(ctx, primitive.NewObjectID(), "completed"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateOrderPaymentStatus of a missing order error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestPaymentStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		payment := models.Payment{OrderID: primitive.NewObjectID(), PaymentMethod: "crypto", Amount: usd(2500), Status: "pending"}
+		if err := s.CreatePayment(ctx, &payment); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdatePaymentStatus(ctx, payment.ID, "completed"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.FindPaymentByOrderID(ctx, payment.OrderID)
+		if err != nil || got.ID != payment.ID || got.Status != "completed" {
+			t.Errorf("FindPaymentByOrderID = %+v, %v; want the completed payment", got, err)
+		}
+		if _, err := s.FindPaymentByID(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindPaymentByID of a missing payment error = %v, want ErrNotFound", err)
+		}
+
+		got.Provider, got.ProviderRef, got.Status = "mock", "pi_1", models.PaymentRequiresAction
+		if err := s.UpdatePayment(ctx, got); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindPaymentByID(ctx, payment.ID); err != nil || got.ProviderRef != "pi_1" || got.Status != models.PaymentRequiresAction {
+			t.Errorf("FindPaymentByID = %+v, %v; want the updated payment", got, err)
+		}
+		if err := s.UpdatePayment(ctx, &models.Payment{ID: primitive.NewObjectID()}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdatePayment of a missing payment error = %v, want ErrNotFound", err)
+		}
+
+		review := models.Payment{OrderID: primitive.NewObjectID(), PaymentMethod: "crypto", Status: models.PaymentAwaitingReview}
+		if err := s.CreatePayment(ctx, &review); err != nil {
+			t.Fatal(err)
+		}
+		queue, err := s.ListPaymentsByStatus(ctx, models.PaymentAwaitingReview)
+		if err != nil || len(queue) != 1 || queue[0].ID != review.ID {
+			t.Errorf("ListPaymentsByStatus = %+v, %v; want the payment awaiting review", queue, err)
+		}
+	})
+}
+
+func createProduct(t *testing.T, s Store, name string, stock int) models.Product {
+	t.Helper()
+	product := models.Product{Name: name, Price: usd(1000), Stock: stock}
+	if err := s.CreateProduct(context.Background(), &product, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	return product
+}
+
+func TestPlaceOrder(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		mug := createProduct(t, s, "Mug", 5)
+		cart := models.Cart{UserID: userID, Items: []models.CartItem{{ProductID: mug.ID, Quantity: 2}}}
+		if err := s.SaveCart(ctx, &cart); err != nil {
+			t.Fatal(err)
+		}
+
+		// Repeated lines for one product are taken together
+		order := models.Order{UserID: userID, Items: []models.OrderItem{{ProductID: mug.ID, Quantity: 2}, {ProductID: mug.ID, Quantity: 1}}}
+		if err := s.PlaceOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+		if product, err := s.FindProductByID(ctx, mug.ID); err != nil || product.AvailableFor("") != 2 {
+			t.Errorf("stock = %+v, %v; want 2 available", product, err)
+		}
+		if _, err := s.FindCartByUserID(ctx, userID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindCartByUserID error = %v, want the cart cleared", err)
+		}
+		if stored, err := s.FindOrderByID(ctx, order.ID); err != nil || len(stored.Items) != 2 {
+			t.Errorf("stored order = %+v, %v", stored, err)
+		}
+	})
+}
+
+func TestPlaceOrderIsAtomic(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		mug := createProduct(t, s, "Mug", 5)
+		lamp := createProduct(t, s, "Lamp", 1)
+		cart := models.Cart{UserID: userID, Items: []models.CartItem{{ProductID: mug.ID, Quantity: 2}}}
+		if err := s.SaveCart(ctx, &cart); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name  string
+			items []models.OrderItem
+			want  error
+		}{
+			// The lamp runs short, so the mugs are not taken either
+			{"short", []models.OrderItem{{ProductID: mug.ID, Quantity: 2}, {ProductID: lamp.ID, Quantity: 2}}, ErrInsufficientStock},
+			{"missing product", []models.OrderItem{{ProductID: mug.ID, Quantity: 2}, {ProductID: primitive.NewObjectID(), Quantity: 1}}, ErrInsufficientStock},
+			{"zero quantity", []models.OrderItem{{ProductID: mug.ID, Quantity: 2}, {ProductID: lamp.ID, Quantity: 0}}, ErrInvalidQuantity},
+		}
+		for _, tt := range tests {
+			order := models.Order{UserID: userID, Items: tt.items}
+			if err := s.PlaceOrder(ctx, &order); !errors.Is(err, tt.want) {
+				t.Errorf("%s: PlaceOrder error = %v, want %v", tt.name, err, tt.want)
+			}
+		}
+
+		if product, err := s.FindProductByID(ctx, mug.ID); err != nil || product.Stock != 5 {
+			t.Errorf("mug stock = %+v, %v; want 5", product, err)
+		}
+		if orders, err := s.ListOrdersByUser(ctx, userID); err != nil || len(orders) != 0 {
+			t.Errorf("orders = %+v, %v; want none", orders, err)
+		}
+		if kept, err := s.FindCartByUserID(ctx, userID); err != nil || len(kept.Items) != 1 {
+			t.Errorf("cart = %+v, %v; want it kept", kept, err)
+		}
+	})
+}
+
+func TestTransitionOrderStatus(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		order := models.Order{UserID: primitive.NewObjectID(), Status: models.OrderPending}
+		if err := s.CreateOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+
+		paid := models.StatusChange{From: models.OrderPending, To: models.OrderPaid, Actor: "system", At: time.Now()}
+		if err := s.TransitionOrderStatus(ctx, order.ID, models.OrderPending, paid); err != nil {
+			t.Fatal(err)
+		}
+		// A second writer still expecting a pending order loses
+		cancelled := models.StatusChange{From: models.OrderPending, To: models.OrderCancelled, Actor: "user", At: time.Now()}
+		if err := s.TransitionOrderStatus(ctx, order.ID, models.OrderPending, cancelled); !errors.Is(err, ErrConflict) {
+			t.Errorf("stale TransitionOrderStatus error = %v, want ErrConflict", err)
+		}
+		if err := s.TransitionOrderStatus(ctx, primitive.NewObjectID(), models.OrderPending, cancelled); !errors.Is(err, ErrNotFound) {
+			t.Errorf("TransitionOrderStatus of a missing order error = %v, want ErrNotFound", err)
+		}
+
+		stored, err := s.FindOrderByID(ctx, order.ID)
+		if err != nil || stored.Status != models.OrderPaid || len(stored.History) != 1 || stored.History[0].To != models.OrderPaid {
+			t.Errorf("order = %+v, %v; want it paid with one history entry", stored, err)
+		}
+
+		other := models.Order{UserID: order.UserID, Status: models.OrderPending}
+		if err := s.CreateOrder(ctx, &other); err != nil {
+			t.Fatal(err)
+		}
+		if orders, err := s.ListOrders(ctx, ""); err != nil || len(orders) != 2 || orders[0].ID != other.ID {
+			t.Errorf("ListOrders = %+v, %v; want both, newest first", orders, err)
+		}
+		if orders, err := s.ListOrders(ctx, models.OrderPaid); err != nil || len(orders) != 1 || orders[0].ID != order.ID {
+			t.Errorf("ListOrders(paid) = %+v, %v; want the paid order", orders, err)
+		}
+	})
+}
+
+func TestCancelOrder(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		mug := createProduct(t, s, "Mug", 5)
+		shirt := variantProduct()
+		if err := s.CreateProduct(ctx, &shirt, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		order := models.Order{
+			UserID:        userID,
+			Items:         []models.OrderItem{{ProductID: mug.ID, Quantity: 2}, {ProductID: shirt.ID, SKU: "SHIRT-M", Quantity: 1}},
+			Status:        models.OrderPending,
+			PaymentStatus: models.PaymentCompleted,
+			Reservation:   &models.StockReservation{Status: models.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)},
+		}
+		if err := s.PlaceOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+		paid := models.StatusChange{From: models.OrderPending, Actor: "system", At: time.Now()}
+		if err := s.PayOrder(ctx, order.ID, models.OrderPending, paid); err != nil {
+			t.Fatal(err)
+		}
+		payment := models.Payment{OrderID: order.ID, Status: models.PaymentCompleted}
+		if err := s.CreatePayment(ctx, &payment); err != nil {
+			t.Fatal(err)
+		}
+
+		change := models.StatusChange{From: models.OrderPaid, To: models.OrderCancelled, Actor: "user", At: time.Now()}
+		if err := s.CancelOrder(ctx, order.ID, models.OrderPaid, change, "Changed my mind"); err != nil {
+			t.Fatal(err)
+		}
+		// Cancelling twice does not restore stock twice
+		if err := s.CancelOrder(ctx, order.ID, models.OrderPaid, change, "Again"); !errors.Is(err, ErrConflict) {
+			t.Errorf("second CancelOrder error = %v, want ErrConflict", err)
+		}
+
+		stored, err := s.FindOrderByID(ctx, order.ID)
+		if err != nil || stored.Status != models.OrderCancelled || stored.CancelReason != "Changed my mind" || stored.PaymentStatus != models.PaymentRefundPending {
+			t.Errorf("order = %+v, %v; want it cancelled with a refund pending", stored, err)
+		}
+		if got, err := s.FindPaymentByID(ctx, payment.ID); err != nil || got.Status != models.PaymentRefundPending {
+			t.Errorf("payment = %+v, %v; want refund pending", got, err)
+		}
+		if got, err := s.FindProductByID(ctx, mug.ID); err != nil || got.Stock != 5 || got.AvailableFor("") != 5 {
+			t.Errorf("mug = %+v, %v; want its stock restored", got, err)
+		}
+		if got, err := s.FindProductByID(ctx, shirt.ID); err != nil || got.StockFor("SHIRT-M") != 5 || got.AvailableFor("SHIRT-M") != 5 {
+			t.Errorf("shirt = %+v, %v; want its variant stock restored", got, err)
+		}
+	})
+}
+
+func TestPayOrderIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	mug := createProduct(t, m, "Mug", 5)
+	pen := createProduct(t, m, "Pen", 5)
+	order := models.Order{
+		UserID:      primitive.NewObjectID(),
+		Items:       []models.OrderItem{{ProductID: mug.ID, Quantity: 1}, {ProductID: pen.ID, Quantity: 2}},
+		Status:      models.OrderPending,
+		Reservation: &models.StockReservation{Status: models.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	if err := m.PlaceOrder(ctx, &order); err != nil {
+		t.Fatal(err)
+	}
+	// A write that bypassed the checks left the pens short of their hold
+	drifted := m.products[pen.ID]
+	drifted.Stock = 1
+	m.products[pen.ID] = drifted
+
+	change := models.StatusChange{From: models.OrderPending, Actor: "system", At: time.Now()}
+	if err := m.PayOrder(ctx, order.ID, models.OrderPending, change); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("PayOrder error = %v, want ErrInsufficientStock", err)
+	}
+	if got, err := m.FindProductByID(ctx, mug.ID); err != nil || got.Stock != 5 || got.AvailableFor("") != 4 {
+		t.Errorf("mug = %+v, %v; want it still held, not sold", got, err)
+	}
+	if movements, err := m.ListStockMovements(ctx, mug.ID); err != nil || len(movements) != 1 {
+		t.Errorf("mug movements = %+v, %v; want only the opening stock", movements, err)
+	}
+	if got, err := m.FindOrderByID(ctx, order.ID); err != nil || got.Status != models.OrderPending || got.Reservation.Status != models.ReservationActive {
+		t.Errorf("order = %+v, %v; want it still pending", got, err)
+	}
+}
+
+func TestUpdateProductKeepsHolds(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		mug := createProduct(t, s, "Mug", 5)
+		shirt := variantProduct()
+		if err := s.CreateProduct(ctx, &shirt, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		order := models.Order{
+			UserID:      primitive.NewObjectID(),
+			Items:       []models.OrderItem{{ProductID: mug.ID, Quantity: 3}, {ProductID: shirt.ID, SKU: "SHIRT-M", Quantity: 2}},
+			Status:      models.OrderPending,
+			Reservation: &models.StockReservation{Status: models.ReservationActive, ExpiresAt: time.Now().Add(time.Hour)},
+		}
+		if err := s.PlaceOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+
+		edited := mug
+		edited.Stock = 2
+		if err := s.UpdateProduct(ctx, mug.ID, edited, "admin"); !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("UpdateProduct below the hold error = %v, want ErrInsufficientStock", err)
+		}
+		if got, err := s.FindProductByID(ctx, mug.ID); err != nil || got.Stock != 5 || got.Reserved != 3 {
+			t.Errorf("mug = %+v, %v; want it unchanged", got, err)
+		}
+		if movements, err := s.ListStockMovements(ctx, mug.ID); err != nil || len(movements) != 1 {
+			t.Errorf("mug movements = %+v, %v; want only the opening stock", movements, err)
+		}
+		editedShirt := variantProduct()
+		editedShirt.Variants[1].Stock = 1
+		if err := s.UpdateProduct(ctx, shirt.ID, editedShirt, "admin"); !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("UpdateProduct of a variant below its hold error = %v, want ErrInsufficientStock", err)
+		}
+
+		// Stock down to the hold itself is fine, and the order can still be paid
+		edited.Stock = 3
+		if err := s.UpdateProduct(ctx, mug.ID, edited, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		change := models.StatusChange{From: models.OrderPending, Actor: "system", At: time.Now()}
+		if err := s.PayOrder(ctx, order.ID, models.OrderPending, change); err != nil {
+			t.Errorf("PayOrder error = %v, want the held stock sold", err)
+		}
+	})
+}
+
+func TestStockReservation(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		now := time.Now()
+		mug := createProduct(t, s, "Mug", 5)
+		place := func(qty int, expires time.Time) models.Order {
+			t.Helper()
+			order := models.Order{
+				UserID:      primitive.NewObjectID(),
+				Items:       []models.OrderItem{{ProductID: mug.ID, Quantity: qty}},
+				Status:      models.OrderPending,
+				Reservation: &models.StockReservation{Status: models.ReservationActive, ExpiresAt: expires},
+			}
+			if err := s.PlaceOrder(ctx, &order); err != nil {
+				t.Fatal(err)
+			}
+			return order
+		}
+		stock := func(wantStock, wantAvailable int) {
+			t.Helper()
+			got, err := s.FindProductByID(ctx, mug.ID)
+			if err != nil || got.Stock != wantStock || got.AvailableFor("") != wantAvailable {
+				t.Errorf("mug = %+v, %v; want stock %d with %d available", got, err, wantStock, wantAvailable)
+			}
+		}
+
+		paid := place(2, now.Add(time.Hour))
+		expired := place(2, now.Add(-time.Minute))
+		stock(5, 1)
+		// Only unreserved stock can be sold
+		order := models.Order{UserID: primitive.NewObjectID(), Items: []models.OrderItem{{ProductID: mug.ID, Quantity: 2}}}
+		if err := s.PlaceOrder(ctx, &order); !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("PlaceOrder over the reservations error = %v, want ErrInsufficientStock", err)
+		}
+
+		// Editing the product does not drop what is held
+		edited := mug
+		edited.Stock = 6
+		if err := s.UpdateProduct(ctx, mug.ID, edited, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		stock(6, 2)
+
+		if orders, err := s.ListExpiredReservations(ctx, now); err != nil || len(orders) != 1 || orders[0].ID != expired.ID {
+			t.Errorf("ListExpiredReservations = %+v, %v; want the expired order", orders, err)
+		}
+
+		change := models.StatusChange{From: models.OrderPending, Actor: "system", At: now}
+		if err := s.PayOrder(ctx, paid.ID, models.OrderPending, change); err != nil {
+			t.Fatal(err)
+		}
+		stock(4, 2)
+		if err := s.CancelOrder(ctx, expired.ID, models.OrderPending, change, "Reservation expired"); err != nil {
+			t.Fatal(err)
+		}
+		stock(4, 4)
+
+		if got, err := s.FindOrderByID(ctx, paid.ID); err != nil || got.Status != models.OrderPaid || got.Reservation.Status != models.ReservationCommitted || got.Reservation.ResolvedAt == nil {
+			t.Errorf("paid order = %+v, %v; want its reservation committed", got, err)
+		}
+		if got, err := s.FindOrderByID(ctx, expired.ID); err != nil || got.Reservation.Status != models.ReservationReleased {
+			t.Errorf("cancelled order = %+v, %v; want its reservation released", got, err)
+		}
+		if orders, err := s.ListExpiredReservations(ctx, now.Add(2*time.Hour)); err != nil || len(orders) != 0 {
+			t.Errorf("ListExpiredReservations = %+v, %v; want none active", orders, err)
+		}
+		// Paying again neither commits stock twice nor succeeds
+		if err := s.PayOrder(ctx, paid.ID, models.OrderPending, change); !errors.Is(err, ErrConflict) {
+			t.Errorf("second PayOrder error = %v, want ErrConflict", err)
+		}
+	})
+}
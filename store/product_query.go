@@ -0,0 +1,106 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"go-ecommerce/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProductSort is the order in which products are listed
+type ProductSort string
+
+const (
+	SortNewest    ProductSort = "newest"
+	SortPriceAsc  ProductSort = "price_asc"
+	SortPriceDesc ProductSort = "price_desc"
+	SortNameAsc   ProductSort = "name_asc"
+	SortNameDesc  ProductSort = "name_desc"
+)
+
+// Valid reports whether s is a known sort order
+func (s ProductSort) Valid() bool {
+	switch s {
+	case SortNewest, SortPriceAsc, SortPriceDesc, SortNameAsc, SortNameDesc:
+		return true
+	}
+	return false
+}
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was issued for a different sort
+var ErrInvalidCursor = errors.New("store: invalid cursor")
+
+// ProductFilter narrows a product listing
+type ProductFilter struct {
+	Category    string
+	MinPrice    *int64 // Minor units of the shop currency
+	MaxPrice    *int64
+	InStockOnly bool
+}
+
+// ProductCursor marks the last product of a page. Listing resumes strictly after it.
+type ProductCursor struct {
+	Sort  ProductSort        `json:"s"`
+	ID    primitive.ObjectID `json:"id"`
+	Price int64              `json:"p,omitempty"`
+	Name  string             `json:"n,omitempty"`
+}
+
+// ProductQuery describes one page of a product listing
+type ProductQuery struct {
+	ProductFilter
+	Sort  ProductSort
+	Limit int
+	After *ProductCursor
+}
+
+// ProductPage is one page of a product listing
+type ProductPage struct {
+	Products []models.Product
+	Total    int64          // Number of products matching the filter across all pages
+	Next     *ProductCursor // Nil on the last page
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c *ProductCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeProductCursor parses a cursor produced by Encode and checks that it
+// belongs to the requested sort order
+func DecodeProductCursor(s string, sort ProductSort) (*ProductCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c ProductCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort || c.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// cursorFor returns the cursor positioned at product p
+func cursorFor(sort ProductSort, p models.Product) *ProductCursor {
+	c := &ProductCursor{Sort: sort, ID: p.ID}
+	switch sort {
+	case SortPriceAsc, SortPriceDesc:
+		c.Price = p.Price.Amount
+	case SortNameAsc, SortNameDesc:
+		c.Name = p.Name
+	}
+	return c
+}
+
+// pageOf trims a result fetched with one extra row into a page
+func pageOf(products []models.Product, q ProductQuery, total int64) *ProductPage {
+	page := &ProductPage{Products: products, Total: total}
+	if len(products) > q.Limit {
+		page.Products = products[:q.Limit]
+		page.Next = cursorFor(q.Sort, page.Products[q.Limit-1])
+	}
+	return page
+}
@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProductStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		mug := models.Product{Name: "Mug", Price: usd(850), Stock: 3, Category: "kitchen"}
+		lamp := models.Product{Name: "Lamp", Price: usd(3000), Stock: 1, Category: "home"}
+		for _, product := range []*models.Product{&mug, &lamp} {
+			if err := s.CreateProduct(ctx, product, "admin"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		page, err := s.ListProducts(ctx, ProductQuery{Sort: SortNewest, Limit: 10})
+		if err != nil || len(page.Products) != 2 || page.Products[0].ID != lamp.ID || page.Products[1].ID != mug.ID {
+			t.Errorf("ListProducts = %+v, %v; want the lamp then the mug", page, err)
+		}
+
+		mug.Price = usd(900)
+		if err := s.UpdateProduct(ctx, mug.ID, mug, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.MoveStock(ctx, &models.StockMovement{ProductID: mug.ID, Delta: -2, Reason: models.StockAdjustment}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.FindProductByID(ctx, mug.ID)
+		if err != nil || got.Price != usd(900) || got.Stock != 1 {
+			t.Errorf("mug = %+v, %v; want 9.00 with 1 in stock", got, err)
+		}
+
+		if err := s.DeleteProduct(ctx, lamp.ID); err != nil {
+			t.Fatal(err)
+		}
+		missing := primitive.NewObjectID()
+		if _, err := s.FindProductByID(ctx, lamp.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindProductByID after delete error = %v, want ErrNotFound", err)
+		}
+		if err := s.UpdateProduct(ctx, missing, mug, "admin"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateProduct of a missing product error = %v, want ErrNotFound", err)
+		}
+		if err := s.MoveStock(ctx, &models.StockMovement{ProductID: missing, Delta: 1, Reason: models.StockAdjustment}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("MoveStock of a missing product error = %v, want ErrNotFound", err)
+		}
+		if err := s.DeleteProduct(ctx, missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DeleteProduct of a missing product error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestUpdateProductClearsFields(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		shirt := variantProduct()
+		shirt.Weight = 200
+		shirt.TaxCategory = "clothing"
+		shirt.ReorderThreshold = 2
+		if err := s.CreateProduct(ctx, &shirt, "admin"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Sell it as one plain product with nothing optional set
+		plain := models.Product{Name: "Shirt", Price: usd(2000), Stock: 4}
+		if err := s.UpdateProduct(ctx, shirt.ID, plain, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.FindProductByID(ctx, shirt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.HasVariants() || len(got.Options) != 0 || got.Weight != 0 || got.TaxCategory != "" || got.ReorderThreshold != 0 || got.Stock != 4 {
+			t.Errorf("product = %+v, want its variants, options, weight, tax category and threshold cleared", got)
+		}
+
+		// The ledger agrees with the stock the product is left with
+		counts, err := s.ReconcileStock(ctx, shirt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, count := range counts {
+			if count.Recorded != count.Ledger {
+				t.Errorf("count %+v: recorded stock and ledger differ", count)
+			}
+		}
+	})
+}
+
+func variantProduct() models.Product {
+	return models.Product{
+		Name:    "Shirt",
+		Price:   usd(2000),
+		Options: []models.ProductOption{{Name: "size", Values: []string{"S", "M"}}},
+		Variants: []models.Variant{
+			{SKU: "SHIRT-S", Options: map[string]string{"size": "S"}, Stock: 3},
+			{SKU: "SHIRT-M", Options: map[string]string{"size": "M"}, Stock: 5},
+		},
+	}
+}
+
+func TestVariantStock(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		product := variantProduct()
+		if err := s.CreateProduct(ctx, &product, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		// The store keeps its own copy
+		product.Variants[0].Stock = 100
+
+		if err := s.MoveStock(ctx, &models.StockMovement{ProductID: product.ID, SKU: "SHIRT-M", Delta: -2, Reason: models.StockAdjustment}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.MoveStock(ctx, &models.StockMovement{ProductID: product.ID, SKU: "SHIRT-XL", Delta: 1, Reason: models.StockAdjustment}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("MoveStock of unknown SKU error = %v, want ErrNotFound", err)
+		}
+
+		order := models.Order{UserID: primitive.NewObjectID(), Items: []models.OrderItem{{ProductID: product.ID, SKU: "SHIRT-S", Quantity: 3}}}
+		if err := s.PlaceOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+		// Each variant's stock is checked on its own
+		order = models.Order{UserID: order.UserID, Items: []models.OrderItem{{ProductID: product.ID, SKU: "SHIRT-S", Quantity: 1}}}
+		if err := s.PlaceOrder(ctx, &order); !errors.Is(err, ErrInsufficientStock) {
+			t.Errorf("PlaceOrder of sold-out variant error = %v, want ErrInsufficientStock", err)
+		}
+
+		got, err := s.FindProductByID(ctx, product.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.AvailableFor("SHIRT-S") != 0 || got.AvailableFor("SHIRT-M") != 3 {
+			t.Errorf("variants = %+v, want 0 small and 3 medium", got.Variants)
+		}
+
+		page, err := s.ListProducts(ctx, ProductQuery{Sort: SortNewest, Limit: 10, ProductFilter: ProductFilter{InStockOnly: true}})
+		if err != nil || len(page.Products) != 1 {
+			t.Errorf("in-stock listing = %+v, %v; want the shirt", page, err)
+		}
+	})
+}
+
+func TestListProducts(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		catalog := []models.Product{
+			{Name: "Mug", Price: usd(850), Stock: 3, Category: "kitchen"},
+			{Name: "Bowl", Price: usd(1200), Stock: 0, Category: "kitchen"},
+			{Name: "Lamp", Price: usd(3000), Stock: 1, Category: "home"},
+			{Name: "Rug", Price: usd(1200), Stock: 2, Category: "home"},
+		}
+		for i := range catalog {
+			if err := s.CreateProduct(ctx, &catalog[i], "admin"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		price := func(cents int64) *int64 { return &cents }
+
+		tests := []struct {
+			name  string
+			query ProductQuery
+			want  []string
+		}{
+			{"newest", ProductQuery{Sort: SortNewest}, []string{"Rug", "Lamp", "Bowl", "Mug"}},
+			// Equal prices fall back to ID order
+			{"price ascending", ProductQuery{Sort: SortPriceAsc}, []string{"Mug", "Bowl", "Rug", "Lamp"}},
+			{"price descending", ProductQuery{Sort: SortPriceDesc}, []string{"Lamp", "Rug", "Bowl", "Mug"}},
+			{"name ascending", ProductQuery{Sort: SortNameAsc}, []string{"Bowl", "Lamp", "Mug", "Rug"}},
+			{"category", ProductQuery{Sort: SortNameAsc, ProductFilter: ProductFilter{Category: "kitchen"}}, []string{"Bowl", "Mug"}},
+			{"in stock", ProductQuery{Sort: SortNameAsc, ProductFilter: ProductFilter{InStockOnly: true}}, []string{"Lamp", "Mug", "Rug"}},
+			{"price range", ProductQuery{Sort: SortNameAsc, ProductFilter: ProductFilter{MinPrice: price(1000), MaxPrice: price(1200)}}, []string{"Bowl", "Rug"}},
+		}
+		for _, tt := range tests {
+			tt.query.Limit = 10
+			page, err := s.ListProducts(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if got := productNames(page.Products); !slices.Equal(got, tt.want) || page.Total != int64(len(tt.want)) || page.Next != nil {
+				t.Errorf("%s: got %v (total %d, next %v), want %v", tt.name, got, page.Total, page.Next, tt.want)
+			}
+		}
+	})
+}
+
+func TestListProductsPaginates(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		for _, name := range []string{"A", "B", "C", "D", "E"} {
+			product := models.Product{Name: name, Price: usd(500), Stock: 1}
+			if err := s.CreateProduct(ctx, &product, "admin"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var got []string
+		query := ProductQuery{Sort: SortPriceAsc, Limit: 2}
+		for pages := 0; ; pages++ {
+			if pages == 3 {
+				t.Fatal("more than three pages of two for five products")
+			}
+			page, err := s.ListProducts(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 5 {
+				t.Errorf("total = %d, want 5", page.Total)
+			}
+			got = append(got, productNames(page.Products)...)
+			if page.Next == nil {
+				break
+			}
+			// Cursors survive the round trip through the query string
+			if query.After, err = DecodeProductCursor(page.Next.Encode(), SortPriceAsc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if want := []string{"A", "B", "C", "D", "E"}; !slices.Equal(got, want) {
+			t.Errorf("paged through %v, want %v", got, want)
+		}
+	})
+}
+
+func TestDecodeProductCursor(t *testing.T) {
+	cursor := (&ProductCursor{Sort: SortNameAsc, ID: primitive.NewObjectID(), Name: "Mug"}).Encode()
+	if _, err := DecodeProductCursor(cursor, SortNameAsc); err != nil {
+		t.Errorf("DecodeProductCursor error = %v", err)
+	}
+	for _, tt := range []struct{ cursor string }{{"!!"}, {"e30"}, {cursor}} {
+		// The last case is valid but was issued for another sort
+		if _, err := DecodeProductCursor(tt.cursor, SortPriceAsc); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeProductCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+		}
+	}
+}
+
+func productNames(products []models.Product) []string {
+	names := []string{}
+	for _, product := range products {
+		names = append(names, product.Name)
+	}
+	return names
+}
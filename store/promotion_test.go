@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPromotionStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		first := models.Promotion{Code: "SAVE10", Type: models.PromotionPercentage, PercentOff: 10}
+		second := models.Promotion{Code: "SHIPFREE", Type: models.PromotionFreeShipping}
+		for _, p := range []*models.Promotion{&first, &second} {
+			if err := s.CreatePromotion(ctx, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.CreatePromotion(ctx, &models.Promotion{Code: "SAVE10", Type: models.PromotionFreeShipping}); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("CreatePromotion with a taken code error = %v, want ErrDuplicate", err)
+		}
+
+		if got, err := s.FindPromotionByCode(ctx, "SAVE10"); err != nil || got.ID != first.ID {
+			t.Errorf("FindPromotionByCode = %+v, %v; want the first promotion", got, err)
+		}
+		if _, err := s.FindPromotionByCode(ctx, "NOPE"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindPromotionByCode of an unknown code error = %v, want ErrNotFound", err)
+		}
+		listed, err := s.ListPromotions(ctx)
+		if err != nil || len(listed) != 2 || listed[0].ID != second.ID {
+			t.Errorf("ListPromotions = %+v, %v; want both, newest first", listed, err)
+		}
+
+		// The usage count is the store's to keep
+		update := first
+		update.PercentOff, update.UsedCount = 20, 99
+		if err := s.UpdatePromotion(ctx, &update); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindPromotionByID(ctx, first.ID); err != nil || got.PercentOff != 20 || got.UsedCount != 0 {
+			t.Errorf("FindPromotionByID = %+v, %v; want 20%% off and no uses", got, err)
+		}
+		update.Code = "SHIPFREE"
+		if err := s.UpdatePromotion(ctx, &update); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("UpdatePromotion to a taken code error = %v, want ErrDuplicate", err)
+		}
+
+		if err := s.DeletePromotion(ctx, second.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeletePromotion(ctx, second.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second DeletePromotion error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestPlaceOrderRedeemsPromotion(t *testing.T) {
+	tests := []struct {
+		name         string
+		usageLimit   int
+		perUserLimit int
+		placed       int // Orders that go through before ErrLimitReached
+	}{
+		{"unlimited", 0, 0, 3},
+		{"usage limit", 2, 0, 2},
+		{"per-user limit", 0, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eachBackend(t, func(t *testing.T, s Store) {
+				ctx := context.Background()
+				mug := createProduct(t, s, "Mug", 10)
+				promotion := models.Promotion{Code: "SAVE10", Type: models.PromotionPercentage, PercentOff: 10, UsageLimit: tt.usageLimit, PerUserLimit: tt.perUserLimit}
+				if err := s.CreatePromotion(ctx, &promotion); err != nil {
+					t.Fatal(err)
+				}
+
+				userID := primitive.NewObjectID()
+				placed := 0
+				for i := 0; i < 3; i++ {
+					order := models.Order{
+						UserID:    userID,
+						Items:     []models.OrderItem{{ProductID: mug.ID, Quantity: 1}},
+						Promotion: &models.AppliedPromotion{PromotionID: promotion.ID, Code: promotion.Code},
+					}
+					err := s.PlaceOrder(ctx, &order)
+					if errors.Is(err, ErrLimitReached) {
+						break
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					placed++
+				}
+				if placed != tt.placed {
+					t.Errorf("placed %d orders, want %d", placed, tt.placed)
+				}
+
+				got, err := s.FindPromotionByID(ctx, promotion.ID)
+				if err != nil || got.UsedCount != tt.placed {
+					t.Errorf("promotion = %+v, %v; want %d uses", got, err, tt.placed)
+				}
+				if n, err := s.CountPromotionRedemptions(ctx, promotion.ID, userID); err != nil || n != tt.placed {
+					t.Errorf("CountPromotionRedemptions = %d, %v; want %d", n, err, tt.placed)
+				}
+				// A refused redemption takes no stock
+				if product, err := s.FindProductByID(ctx, mug.ID); err != nil || product.AvailableFor("") != 10-tt.placed {
+					t.Errorf("stock = %+v, %v; want %d", product, err, 10-tt.placed)
+				}
+			})
+		})
+	}
+}
+
+func TestCancelOrderGivesBackPromotion(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		mug := createProduct(t, s, "Mug", 10)
+		promotion := models.Promotion{Code: "ONCE", Type: models.PromotionPercentage, PercentOff: 10, UsageLimit: 1, PerUserLimit: 1}
+		if err := s.CreatePromotion(ctx, &promotion); err != nil {
+			t.Fatal(err)
+		}
+		userID := primitive.NewObjectID()
+		place := func() (*models.Order, error) {
+			order := models.Order{
+				UserID:    userID,
+				Status:    models.OrderPending,
+				Items:     []models.OrderItem{{ProductID: mug.ID, Quantity: 1}},
+				Promotion: &models.AppliedPromotion{PromotionID: promotion.ID, Code: promotion.Code},
+			}
+			return &order, s.PlaceOrder(ctx, &order)
+		}
+
+		order, err := place()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := place(); !errors.Is(err, ErrLimitReached) {
+			t.Fatalf("second PlaceOrder error = %v, want ErrLimitReached", err)
+		}
+		if err := s.CancelOrder(ctx, order.ID, models.OrderPending, models.StatusChange{From: models.OrderPending, At: time.Now()}, "changed my mind"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.FindPromotionByID(ctx, promotion.ID)
+		if err != nil || got.UsedCount != 0 {
+			t.Errorf("promotion after cancelling = %+v, %v; want no uses", got, err)
+		}
+		if n, err := s.CountPromotionRedemptions(ctx, promotion.ID, userID); err != nil || n != 0 {
+			t.Errorf("CountPromotionRedemptions after cancelling = %d, %v; want 0", n, err)
+		}
+		// The cancelled use can be redeemed again
+		if _, err := place(); err != nil {
+			t.Errorf("PlaceOrder after cancelling error = %v, want it redeemed", err)
+		}
+		if got, err := s.FindPromotionByID(ctx, promotion.ID); err != nil || got.UsedCount != 1 {
+			t.Errorf("promotion = %+v, %v; want one use", got, err)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSessionStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		first := models.Session{UserID: userID, CreatedAt: time.Now()}
+		second := models.Session{UserID: userID, CreatedAt: time.Now()}
+		other := models.Session{UserID: primitive.NewObjectID(), CreatedAt: time.Now()}
+		for _, session := range []*models.Session{&first, &second, &other} {
+			if err := s.CreateSession(ctx, session); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := s.RevokeSession(ctx, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		// Revoking twice is a no-op
+		if err := s.RevokeSession(ctx, first.ID); err != nil {
+			t.Errorf("second RevokeSession error = %v", err)
+		}
+		if err := s.RevokeSession(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("RevokeSession of unknown session error = %v, want ErrNotFound", err)
+		}
+		if session, err := s.FindSessionByID(ctx, second.ID); err != nil || session.RevokedAt != nil {
+			t.Errorf("second session = %+v, %v; want it live", session, err)
+		}
+
+		if err := s.RevokeUserSessions(ctx, userID); err != nil {
+			t.Fatal(err)
+		}
+		if session, err := s.FindSessionByID(ctx, second.ID); err != nil || session.RevokedAt == nil {
+			t.Errorf("second session = %+v, %v; want it revoked", session, err)
+		}
+		if session, err := s.FindSessionByID(ctx, other.ID); err != nil || session.RevokedAt != nil {
+			t.Errorf("other user's session = %+v, %v; want it live", session, err)
+		}
+	})
+}
+
+func TestConsumeRefreshToken(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		token := models.RefreshToken{
+			SessionID: primitive.NewObjectID(),
+			UserID:    primitive.NewObjectID(),
+			TokenHash: "abc123",
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		if err := s.CreateRefreshToken(ctx, &token); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := s.FindRefreshTokenByHash(ctx, "abc123"); err != nil || found.ID != token.ID {
+			t.Fatalf("FindRefreshTokenByHash = %+v, %v", found, err)
+		}
+		if _, err := s.FindRefreshTokenByHash(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindRefreshTokenByHash error = %v, want ErrNotFound", err)
+		}
+
+		if err := s.ConsumeRefreshToken(ctx, token.ID); err != nil {
+			t.Fatal(err)
+		}
+		// Each token may be used once
+		if err := s.ConsumeRefreshToken(ctx, token.ID); !errors.Is(err, ErrConflict) {
+			t.Errorf("second ConsumeRefreshToken error = %v, want ErrConflict", err)
+		}
+	})
+}
+
+func TestPasswordResetStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		reset := func(hash string) models.PasswordReset {
+			t.Helper()
+			r := models.PasswordReset{UserID: userID, TokenHash: hash, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+			if err := s.CreatePasswordReset(ctx, &r); err != nil {
+				t.Fatal(err)
+			}
+			return r
+		}
+		reset("first")
+		second := reset("second")
+
+		// Only the latest emailed link works
+		if _, err := s.FindPasswordResetByHash(ctx, "first"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindPasswordResetByHash(first) error = %v, want ErrNotFound", err)
+		}
+		if found, err := s.FindPasswordResetByHash(ctx, "second"); err != nil || found.ID != second.ID {
+			t.Fatalf("FindPasswordResetByHash(second) = %+v, %v", found, err)
+		}
+
+		if err := s.ConsumePasswordReset(ctx, second.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ConsumePasswordReset(ctx, second.ID); !errors.Is(err, ErrConflict) {
+			t.Errorf("second ConsumePasswordReset error = %v, want ErrConflict", err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestShippingStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		fee := usd(500)
+		zones := []models.ShippingZone{
+			{Name: "West", Regions: []models.ShippingRegion{{State: "CA"}}, Methods: []models.ShippingMethod{{Code: "standard", FlatFee: &fee}}},
+			{Name: "Manhattan", Regions: []models.ShippingRegion{{State: "NY", ZipPrefix: "100"}}, Methods: []models.ShippingMethod{{Code: "courier", FlatFee: &fee}}},
+		}
+		for i := range zones {
+			if err := s.CreateShippingZone(ctx, &zones[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		listed, err := s.ListShippingZones(ctx)
+		if err != nil || len(listed) != 2 || listed[0].Name != "West" || listed[1].Regions[0].ZipPrefix != "100" {
+			t.Errorf("ListShippingZones = %+v, %v; want both, oldest first", listed, err)
+		}
+
+		// Stored zones are not changed through the caller's copy
+		zones[0].Methods[0].Code = "changed"
+		zones[0].Regions = append(zones[0].Regions, models.ShippingRegion{State: "OR"})
+		got, err := s.FindShippingZoneByID(ctx, zones[0].ID)
+		if err != nil || got.Methods[0].Code != "standard" || len(got.Regions) != 1 {
+			t.Errorf("FindShippingZoneByID = %+v, %v; want the zone as created", got, err)
+		}
+		if err := s.UpdateShippingZone(ctx, &zones[0]); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindShippingZoneByID(ctx, zones[0].ID); err != nil || len(got.Regions) != 2 {
+			t.Errorf("FindShippingZoneByID = %+v, %v; want the update", got, err)
+		}
+		if err := s.UpdateShippingZone(ctx, &models.ShippingZone{ID: primitive.NewObjectID()}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateShippingZone of a missing zone error = %v, want ErrNotFound", err)
+		}
+
+		if err := s.DeleteShippingZone(ctx, zones[1].ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteShippingZone(ctx, zones[1].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second DeleteShippingZone error = %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListLowStockProducts(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		mug := models.Product{Name: "Mug", Price: usd(1000), Stock: 2, ReorderThreshold: 2}
+		plate := models.Product{Name: "Plate", Price: usd(1000), Stock: 3, ReorderThreshold: 2}
+		shirt := variantProduct()
+		shirt.ReorderThreshold = 3
+		untracked := models.Product{Name: "Bowl", Price: usd(1000)}
+		for _, product := range []*models.Product{&mug, &plate, &shirt, &untracked} {
+			if err := s.CreateProduct(ctx, product, "admin"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		products, err := s.ListLowStockProducts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, p := range products {
+			names = append(names, p.Name)
+		}
+		if len(names) != 2 || names[0] != "Mug" || names[1] != "Shirt" {
+			t.Errorf("low stock products = %v, want [Mug Shirt]", names)
+		}
+	})
+}
+
+func TestStockSubscriptions(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		productID := primitive.NewObjectID()
+		ada, bob := primitive.NewObjectID(), primitive.NewObjectID()
+		subscribe := func(userID primitive.ObjectID, sku string) error {
+			return s.CreateStockSubscription(ctx, &models.StockSubscription{
+				ProductID: productID, SKU: sku, UserID: userID, Email: userID.Hex() + "@example.com", CreatedAt: time.Now(),
+			})
+		}
+		for _, sub := range []struct {
+			user primitive.ObjectID
+			sku  string
+		}{{ada, "SHIRT-S"}, {bob, "SHIRT-S"}, {ada, "SHIRT-M"}} {
+			if err := subscribe(sub.user, sub.sku); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := subscribe(ada, "SHIRT-S"); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("second subscription error = %v, want ErrDuplicate", err)
+		}
+
+		if err := s.DeleteStockSubscription(ctx, bob, productID, "SHIRT-S"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteStockSubscription(ctx, bob, productID, "SHIRT-S"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("deleting twice error = %v, want ErrNotFound", err)
+		}
+
+		// Each subscription is claimed once
+		claimed, err := s.ClaimStockSubscriptions(ctx, productID, "SHIRT-S", time.Now())
+		if err != nil || len(claimed) != 1 || claimed[0].UserID != ada || claimed[0].NotifiedAt == nil {
+			t.Errorf("ClaimStockSubscriptions = %+v, %v; want Ada's", claimed, err)
+		}
+		claimed, err = s.ClaimStockSubscriptions(ctx, productID, "SHIRT-S", time.Now())
+		if err != nil || len(claimed) != 0 {
+			t.Errorf("second ClaimStockSubscriptions = %+v, %v; want none", claimed, err)
+		}
+
+		// Once notified, the customer may ask again
+		if err := subscribe(ada, "SHIRT-S"); err != nil {
+			t.Errorf("subscribing after notification error = %v", err)
+		}
+	})
+}
+
+func TestListUsersByRole(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		for _, user := range []models.User{
+			{Name: "Ada", Email: "ada@example.com", Role: "admin"},
+			{Name: "Bob", Email: "bob@example.com", Role: "user"},
+			{Name: "Cy", Email: "cy@example.com", Role: "admin"},
+		} {
+			if err := s.CreateUser(ctx, &user); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		admins, err := s.ListUsersByRole(ctx, "admin")
+		if err != nil || len(admins) != 2 || admins[0].Email != "ada@example.com" || admins[1].Email != "cy@example.com" {
+			t.Errorf("ListUsersByRole = %+v, %v; want Ada and Cy", admins, err)
+		}
+	})
+}
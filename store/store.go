@@ -0,0 +1,286 @@
+// Package store defines the persistence interfaces the controllers depend on,
+// together with a MongoDB implementation and an in-memory implementation.
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound is returned when a lookup matches no document
+	ErrNotFound = errors.New("store: not found")
+	// ErrInsufficientStock is returned when a checkout, payment or stock movement would take a
+	// product's stock below zero or below what unpaid orders hold
+	ErrInsufficientStock = errors.New("store: insufficient stock")
+	// ErrInvalidQuantity is returned when a line item has a non-positive quantity
+	ErrInvalidQuantity = errors.New("store: invalid quantity")
+	// ErrConflict is returned when a conditional update finds the document already changed
+	ErrConflict = errors.New("store: conflicting update")
+	// ErrDuplicate is returned when inserting a document whose key was already recorded
+	ErrDuplicate = errors.New("store: duplicate")
+	// ErrLimitReached is returned when placing an order would redeem a promotion past its usage limits
+	ErrLimitReached = errors.New("store: usage limit reached")
+)
+
+// UserStore persists users
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	FindUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
+	FindUserByVerificationToken(ctx context.Context, token string) (*models.User, error)
+	MarkUserVerified(ctx context.Context, id primitive.ObjectID) error
+	UpdateUserPassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error
+	// ListUsersByRole returns the users with a role, oldest first
+	ListUsersByRole(ctx context.Context, role string) ([]models.User, error)
+	// SetCartRemindersOff turns the user's abandoned-cart emails off or back on
+	SetCartRemindersOff(ctx context.Context, id primitive.ObjectID, off bool) error
+}
+
+// ProductStore persists the product catalog
+type ProductStore interface {
+	// CreateProduct inserts a product and records its stock in the ledger as
+	// opening movements by actor
+	CreateProduct(ctx context.Context, product *models.Product, actor string) error
+	FindProductByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error)
+	ListProducts(ctx context.Context, q ProductQuery) (*ProductPage, error)
+	// UpdateProduct overwrites a product, keeping its reserved counts, and
+	// records any change to its stock figures as adjustments by actor. It
+	// returns ErrInsufficientStock, changing nothing, if the product or a
+	// variant would have less stock than unpaid orders hold.
+	UpdateProduct(ctx context.Context, id primitive.ObjectID, product models.Product, actor string) error
+	DeleteProduct(ctx context.Context, id primitive.ObjectID) error
+}
+
+// InventoryStore keeps the ledger of stock movements. Every change to a
+// product's on-hand stock, including those made by ProductStore and
+// OrderStore, is written to it in the same step as the change itself.
+type InventoryStore interface {
+	// MoveStock adds movement.Delta to the stock of its product, or of one of
+	// its variants when SKU is set, and appends the movement with the
+	// resulting balance to the ledger. It returns ErrNotFound if the product
+	// or variant does not exist, and ErrInsufficientStock if a negative delta
+	// would take stock below zero or below what unpaid orders hold.
+	MoveStock(ctx context.Context, movement *models.StockMovement) error
+	// ListStockMovements returns a product's movements, oldest first
+	ListStockMovements(ctx context.Context, productID primitive.ObjectID) ([]models.StockMovement, error)
+	// ReconcileStock sets the stock of a product, and of each of its
+	// variants, to the sum of its ledger movements. It returns the figures as
+	// it found them; those whose Recorded and Ledger differ were corrected.
+	ReconcileStock(ctx context.Context, productID primitive.ObjectID) ([]models.StockCount, error)
+}
+
+// StockAlertStore finds products running low and persists back-in-stock
+// subscriptions
+type StockAlertStore interface {
+	// ListLowStockProducts returns the products whose stock, or a variant's,
+	// is at or below their reorder threshold, by name
+	ListLowStockProducts(ctx context.Context) ([]models.Product, error)
+	// CreateStockSubscription stores a subscription. It returns ErrDuplicate
+	// if the user is already waiting for the same product and SKU.
+	CreateStockSubscription(ctx context.Context, subscription *models.StockSubscription) error
+	// DeleteStockSubscription removes the user's pending subscription to a
+	// product and SKU. It returns ErrNotFound if there is none.
+	DeleteStockSubscription(ctx context.Context, userID, productID primitive.ObjectID, sku string) error
+	// ClaimStockSubscriptions marks the pending subscriptions to a product and
+	// SKU notified at the given time and returns them, oldest first. Each
+	// subscription is returned by one call only.
+	ClaimStockSubscriptions(ctx context.Context, productID primitive.ObjectID, sku string, at time.Time) ([]models.StockSubscription, error)
+}
+
+// CartStore persists shopping carts, one per user, and the guest carts of
+// visitors who have not signed in
+type CartStore interface {
+	FindCartByUserID(ctx context.Context, userID primitive.ObjectID) (*models.Cart, error)
+	SaveCart(ctx context.Context, cart *models.Cart) error
+	DeleteCartByUserID(ctx context.Context, userID primitive.ObjectID) error
+	// Guest carts belong to anonymous visitors and are looked up by their own ID
+	FindGuestCart(ctx context.Context, id primitive.ObjectID) (*models.Cart, error)
+	SaveGuestCart(ctx context.Context, cart *models.Cart) error
+	DeleteGuestCart(ctx context.Context, id primitive.ObjectID) error
+	// ListAbandonedCarts returns the users' carts due a reminder as of
+	// idleSince (see Cart.Abandoned), least recently updated first
+	ListAbandonedCarts(ctx context.Context, idleSince time.Time, limit int) ([]models.Cart, error)
+	// MarkCartReminded counts one more reminder sent about a cart at the given
+	// time. It returns ErrConflict if the cart no longer has sent reminders,
+	// so that two senders cannot both send the same one.
+	MarkCartReminded(ctx context.Context, id primitive.ObjectID, sent int, at time.Time) error
+	// CartRecoveryStats counts the carts reminded since the given time,
+	// whether still open or ordered since
+	CartRecoveryStats(ctx context.Context, since time.Time) (*models.CartRecoveryStats, error)
+}
+
+// WishlistStore persists users' named wishlists
+type WishlistStore interface {
+	// CreateWishlist stores a new wishlist and sets its ID. It returns
+	// ErrDuplicate if the user already has a wishlist by that name.
+	CreateWishlist(ctx context.Context, wishlist *models.Wishlist) error
+	FindWishlistByID(ctx context.Context, id primitive.ObjectID) (*models.Wishlist, error)
+	// FindWishlistByShareToken returns the public wishlist with the token
+	FindWishlistByShareToken(ctx context.Context, token string) (*models.Wishlist, error)
+	// ListWishlistsByUser returns the user's wishlists, oldest first
+	ListWishlistsByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Wishlist, error)
+	// UpdateWishlist overwrites a wishlist. It returns ErrDuplicate if it was
+	// renamed to the name of another of the user's wishlists.
+	UpdateWishlist(ctx context.Context, wishlist *models.Wishlist) error
+	DeleteWishlist(ctx context.Context, id primitive.ObjectID) error
+}
+
+// OrderStore persists orders
+type OrderStore interface {
+	CreateOrder(ctx context.Context, order *models.Order) error
+	// PlaceOrder atomically reserves available stock for every item under the
+	// order's Reservation, which the caller sets, redeems the order's
+	// promotion, inserts the order and clears the user's cart of all but
+	// the items saved for later. Either all of it happens or none of it
+	// does. It returns ErrInsufficientStock if an item's available stock is
+	// short, and ErrLimitReached if the promotion has been used up, globally
+	// or by the user.
+	PlaceOrder(ctx context.Context, order *models.Order) error
+	FindOrderByID(ctx context.Context, id primitive.ObjectID) (*models.Order, error)
+	ListOrdersByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Order, error)
+	// ListOrders returns every order, newest first, optionally limited to one status
+	ListOrders(ctx context.Context, status models.OrderStatus) ([]models.Order, error)
+	// TransitionOrderStatus moves an order from one status to another and
+	// appends change to its history. It returns ErrConflict if the order is no
+	// longer in status from.
+	TransitionOrderStatus(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange) error
+	// PayOrder atomically moves an order from status from to paid, appends
+	// change to its history and commits its active stock reservation, taking
+	// the reserved quantities out of on-hand stock as sales. It returns
+	// ErrConflict if the order is no longer in status from, and
+	// ErrInsufficientStock, changing nothing, if an item's stock or
+	// reservation no longer covers it.
+	PayOrder(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange) error
+	// CancelOrder atomically moves an order from status from to cancelled,
+	// records the reason, releases an active stock reservation or else
+	// returns every item's quantity to stock as a cancellation movement,
+	// gives back the use of its promotion and flags a completed payment for
+	// refund. It returns ErrConflict if the order is no longer in status from.
+	CancelOrder(ctx context.Context, id primitive.ObjectID, from models.OrderStatus, change models.StatusChange, reason string) error
+	// ListExpiredReservations returns the orders whose stock reservation is
+	// still active but expired before the given time, oldest first
+	ListExpiredReservations(ctx context.Context, before time.Time) ([]models.Order, error)
+	UpdateOrderPaymentStatus(ctx context.Context, id primitive.ObjectID, status models.PaymentStatus) error
+}
+
+// PaymentStore persists payment records
+type PaymentStore interface {
+	CreatePayment(ctx context.Context, payment *models.Payment) error
+	FindPaymentByID(ctx context.Context, id primitive.ObjectID) (*models.Payment, error)
+	FindPaymentByOrderID(ctx context.Context, orderID primitive.ObjectID) (*models.Payment, error)
+	// FindPaymentByProviderRef looks up a card payment by its gateway intent ID
+	FindPaymentByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error)
+	// ListPaymentsByStatus returns payments in one status, oldest first
+	ListPaymentsByStatus(ctx context.Context, status models.PaymentStatus) ([]models.Payment, error)
+	UpdatePaymentStatus(ctx context.Context, id primitive.ObjectID, status models.PaymentStatus) error
+	UpdatePayment(ctx context.Context, payment *models.Payment) error
+}
+
+// WebhookStore persists payment webhook events
+type WebhookStore interface {
+	// CreateWebhookEvent records a newly received event. It returns
+	// ErrDuplicate if an event with the same ID was recorded before.
+	CreateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error
+	FindWebhookEventByID(ctx context.Context, id string) (*models.WebhookEvent, error)
+	// ListWebhookEvents returns events newest first, optionally limited to one status
+	ListWebhookEvents(ctx context.Context, status models.WebhookEventStatus) ([]models.WebhookEvent, error)
+	UpdateWebhookEvent(ctx context.Context, event *models.WebhookEvent) error
+}
+
+// PromotionStore persists promotions and counts their redemptions
+type PromotionStore interface {
+	// CreatePromotion inserts a promotion. It returns ErrDuplicate if its code is taken.
+	CreatePromotion(ctx context.Context, promotion *models.Promotion) error
+	FindPromotionByID(ctx context.Context, id primitive.ObjectID) (*models.Promotion, error)
+	FindPromotionByCode(ctx context.Context, code string) (*models.Promotion, error)
+	// ListPromotions returns every promotion, newest first
+	ListPromotions(ctx context.Context) ([]models.Promotion, error)
+	// UpdatePromotion saves an edited promotion, leaving its usage count as
+	// stored. It returns ErrDuplicate if the new code is taken.
+	UpdatePromotion(ctx context.Context, promotion *models.Promotion) error
+	DeletePromotion(ctx context.Context, id primitive.ObjectID) error
+	// CountPromotionRedemptions returns how many orders the user placed with
+	// the promotion and has not cancelled
+	CountPromotionRedemptions(ctx context.Context, promotionID, userID primitive.ObjectID) (int, error)
+}
+
+// TaxStore persists tax rate tables
+type TaxStore interface {
+	CreateTaxRate(ctx context.Context, rate *models.TaxRate) error
+	FindTaxRateByID(ctx context.Context, id primitive.ObjectID) (*models.TaxRate, error)
+	// ListTaxRates returns the rates of one state, or of every state when
+	// state is empty, ordered by state, name and zip prefix
+	ListTaxRates(ctx context.Context, state string) ([]models.TaxRate, error)
+	UpdateTaxRate(ctx context.Context, rate *models.TaxRate) error
+	DeleteTaxRate(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ShippingStore persists shipping zones and their methods
+type ShippingStore interface {
+	CreateShippingZone(ctx context.Context, zone *models.ShippingZone) error
+	FindShippingZoneByID(ctx context.Context, id primitive.ObjectID) (*models.ShippingZone, error)
+	// ListShippingZones returns every zone, oldest first
+	ListShippingZones(ctx context.Context) ([]models.ShippingZone, error)
+	UpdateShippingZone(ctx context.Context, zone *models.ShippingZone) error
+	DeleteShippingZone(ctx context.Context, id primitive.ObjectID) error
+}
+
+// HolidayStore persists the holiday calendar
+type HolidayStore interface {
+	CreateHoliday(ctx context.Context, holiday *models.Holiday) error
+	FindHolidayByID(ctx context.Context, id primitive.ObjectID) (*models.Holiday, error)
+	// ListHolidays returns the holidays observed in state, its own and those
+	// of every state, or every holiday when state is empty, ordered by date
+	// and state
+	ListHolidays(ctx context.Context, state string) ([]models.Holiday, error)
+	UpdateHoliday(ctx context.Context, holiday *models.Holiday) error
+	DeleteHoliday(ctx context.Context, id primitive.ObjectID) error
+}
+
+// SessionStore persists login sessions and their refresh tokens
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	FindSessionByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error)
+	RevokeSession(ctx context.Context, id primitive.ObjectID) error
+	RevokeUserSessions(ctx context.Context, userID primitive.ObjectID) error
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// ConsumeRefreshToken marks an unused token as used. It returns ErrConflict
+	// if the token had already been used.
+	ConsumeRefreshToken(ctx context.Context, id primitive.ObjectID) error
+}
+
+// PasswordResetStore persists password reset tokens
+type PasswordResetStore interface {
+	// CreatePasswordReset stores a reset token, discarding any unused token the
+	// user already had so that only the latest emailed link works.
+	CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error
+	FindPasswordResetByHash(ctx context.Context, hash string) (*models.PasswordReset, error)
+	// ConsumePasswordReset marks an unused token as used. It returns ErrConflict
+	// if the token had already been used.
+	ConsumePasswordReset(ctx context.Context, id primitive.ObjectID) error
+}
+
+// Store groups every store interface; both implementations satisfy it
+type Store interface {
+	UserStore
+	ProductStore
+	InventoryStore
+	StockAlertStore
+	CartStore
+	WishlistStore
+	OrderStore
+	PaymentStore
+	WebhookStore
+	PromotionStore
+	TaxStore
+	ShippingStore
+	HolidayStore
+	SessionStore
+	PasswordResetStore
+}
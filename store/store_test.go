@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// backends returns the stores a test runs against: always the in-memory
+// store, and MongoDB too when MONGO_TEST_URI names a replica set. Each
+// MongoDB test gets a scratch database that is dropped afterwards.
+func backends(t *testing.T) map[string]Store {
+	t.Helper()
+	stores := map[string]Store{"memory": NewMemory()}
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		return stores
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect to MongoDB: %v", err)
+	}
+	db := client.Database("ecommerce_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+	stores["mongo"] = newMongo(db)
+	return stores
+}
+
+// eachBackend runs test against every backend as a subtest
+func eachBackend(t *testing.T, test func(t *testing.T, s Store)) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) { test(t, s) })
+	}
+}
+
+// usd returns an amount of the shop currency in cents
+func usd(cents int64) models.Money {
+	return models.NewMoney(cents, models.DefaultCurrency)
+}
+
+func TestUserStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		user := models.User{Name: "Ada", Email: "ada@example.com", VerificationToken: "verify-me"}
+		if err := s.CreateUser(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+		if user.ID.IsZero() {
+			t.Fatal("CreateUser did not set the ID")
+		}
+
+		byEmail, err := s.FindUserByEmail(ctx, "ada@example.com")
+		if err != nil || byEmail.ID != user.ID {
+			t.Errorf("FindUserByEmail = %+v, %v", byEmail, err)
+		}
+		byToken, err := s.FindUserByVerificationToken(ctx, "verify-me")
+		if err != nil || byToken.ID != user.ID {
+			t.Errorf("FindUserByVerificationToken = %+v, %v", byToken, err)
+		}
+
+		if err := s.MarkUserVerified(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		verified, err := s.FindUserByID(ctx, user.ID)
+		if err != nil || !verified.IsVerified {
+			t.Errorf("user after MarkUserVerified = %+v, %v", verified, err)
+		}
+
+		if _, err := s.FindUserByEmail(ctx, "nobody@example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindUserByEmail of a stranger error = %v, want ErrNotFound", err)
+		}
+		if err := s.MarkUserVerified(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("MarkUserVerified of a stranger error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestCartStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		if _, err := s.FindCartByUserID(ctx, userID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindCartByUserID before any save error = %v, want ErrNotFound", err)
+		}
+
+		cart := models.Cart{UserID: userID, Items: []models.CartItem{{ProductID: primitive.NewObjectID(), Quantity: 2}}}
+		if err := s.SaveCart(ctx, &cart); err != nil {
+			t.Fatal(err)
+		}
+		// Saving again replaces the cart rather than adding a second one
+		cart.Items[0].Quantity = 3
+		if err := s.SaveCart(ctx, &cart); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.FindCartByUserID(ctx, userID)
+		if err != nil || len(got.Items) != 1 || got.Items[0].Quantity != 3 {
+			t.Errorf("cart = %+v, %v; want one line of 3", got, err)
+		}
+
+		if err := s.DeleteCartByUserID(ctx, userID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.FindCartByUserID(ctx, userID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindCartByUserID after delete error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestGuestCartStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		guest := models.Cart{Items: []models.CartItem{{ProductID: primitive.NewObjectID(), Quantity: 1}}}
+		if err := s.SaveGuestCart(ctx, &guest); err != nil {
+			t.Fatal(err)
+		}
+		if guest.ID.IsZero() {
+			t.Fatal("SaveGuestCart did not set the ID")
+		}
+		// Guest carts have no user, so a second one must not replace the first
+		other := models.Cart{Items: []models.CartItem{{ProductID: primitive.NewObjectID(), Quantity: 4}}}
+		if err := s.SaveGuestCart(ctx, &other); err != nil {
+			t.Fatal(err)
+		}
+		guest.Items[0].Quantity = 2
+		if err := s.SaveGuestCart(ctx, &guest); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.FindGuestCart(ctx, guest.ID)
+		if err != nil || len(got.Items) != 1 || got.Items[0].Quantity != 2 {
+			t.Errorf("guest cart = %+v, %v; want one line of 2", got, err)
+		}
+		got, err = s.FindGuestCart(ctx, other.ID)
+		if err != nil || len(got.Items) != 1 || got.Items[0].Quantity != 4 {
+			t.Errorf("other guest cart = %+v, %v; want one line of 4", got, err)
+		}
+
+		if err := s.DeleteGuestCart(ctx, guest.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.FindGuestCart(ctx, guest.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindGuestCart after delete error = %v, want ErrNotFound", err)
+		}
+		if err := s.DeleteGuestCart(ctx, guest.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DeleteGuestCart twice error = %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTaxStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		rates := []models.TaxRate{
+			{Name: "NY State Tax", State: "NY", Rate: 400},
+			{Name: "LA County Tax", State: "CA", ZipPrefix: "900", Rate: 100},
+			{Name: "CA State Tax", State: "CA", Rate: 725},
+		}
+		for i := range rates {
+			if err := s.CreateTaxRate(ctx, &rates[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		california, err := s.ListTaxRates(ctx, "CA")
+		if err != nil || len(california) != 2 || california[0].Name != "CA State Tax" {
+			t.Errorf("ListTaxRates(CA) = %+v, %v; want the two CA rates by name", california, err)
+		}
+		all, err := s.ListTaxRates(ctx, "")
+		if err != nil || len(all) != 3 || all[2].State != "NY" {
+			t.Errorf("ListTaxRates() = %+v, %v; want every rate by state", all, err)
+		}
+
+		rates[0].Rate = 450
+		if err := s.UpdateTaxRate(ctx, &rates[0]); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindTaxRateByID(ctx, rates[0].ID); err != nil || got.Rate != 450 {
+			t.Errorf("FindTaxRateByID = %+v, %v; want the new rate", got, err)
+		}
+		if err := s.UpdateTaxRate(ctx, &models.TaxRate{ID: primitive.NewObjectID()}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateTaxRate of a missing rate error = %v, want ErrNotFound", err)
+		}
+
+		if err := s.DeleteTaxRate(ctx, rates[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.FindTaxRateByID(ctx, rates[0].ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindTaxRateByID after delete error = %v, want ErrNotFound", err)
+		}
+	})
+}
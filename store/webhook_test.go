@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+)
+
+func TestWebhookStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		older := models.WebhookEvent{ID: "mock:evt_1", EventID: "evt_1", Status: models.WebhookReceived, ReceivedAt: time.Now().Add(-time.Minute)}
+		newer := models.WebhookEvent{ID: "mock:evt_2", EventID: "evt_2", Status: models.WebhookReceived, ReceivedAt: time.Now()}
+		for _, event := range []*models.WebhookEvent{&older, &newer} {
+			if err := s.CreateWebhookEvent(ctx, event); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Each event is recorded once
+		if err := s.CreateWebhookEvent(ctx, &older); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("second CreateWebhookEvent error = %v, want ErrDuplicate", err)
+		}
+
+		older.Status, older.Error, older.Attempts = models.WebhookFailed, "no payment", 1
+		if err := s.UpdateWebhookEvent(ctx, &older); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.FindWebhookEventByID(ctx, older.ID); err != nil || got.Status != models.WebhookFailed || got.Attempts != 1 {
+			t.Errorf("FindWebhookEventByID = %+v, %v; want the failed event", got, err)
+		}
+		if _, err := s.FindWebhookEventByID(ctx, "mock:evt_3"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindWebhookEventByID of a missing event error = %v, want ErrNotFound", err)
+		}
+
+		if events, err := s.ListWebhookEvents(ctx, ""); err != nil || len(events) != 2 || events[0].ID != newer.ID {
+			t.Errorf("ListWebhookEvents = %+v, %v; want both, newest first", events, err)
+		}
+		if events, err := s.ListWebhookEvents(ctx, models.WebhookFailed); err != nil || len(events) != 1 || events[0].ID != older.ID {
+			t.Errorf("ListWebhookEvents(failed) = %+v, %v; want the failed event", events, err)
+		}
+	})
+}
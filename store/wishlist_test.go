@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"go-ecommerce/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWishlistStore(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		price := usd(1500)
+		birthday := models.Wishlist{
+			UserID: userID,
+			Name:   "Birthday",
+			Items:  []models.WishlistItem{{ProductID: primitive.NewObjectID(), SavedPrice: &price, AddedAt: time.Now().Truncate(time.Millisecond)}},
+		}
+		if err := s.CreateWishlist(ctx, &birthday); err != nil {
+			t.Fatal(err)
+		}
+		if birthday.ID.IsZero() {
+			t.Fatal("CreateWishlist did not set the ID")
+		}
+		duplicate := models.Wishlist{UserID: userID, Name: "Birthday"}
+		if err := s.CreateWishlist(ctx, &duplicate); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("CreateWishlist of a second Birthday error = %v, want ErrDuplicate", err)
+		}
+		// Names only need to be unique per user
+		other := models.Wishlist{UserID: primitive.NewObjectID(), Name: "Birthday"}
+		if err := s.CreateWishlist(ctx, &other); err != nil {
+			t.Fatal(err)
+		}
+		home := models.Wishlist{UserID: userID, Name: "Home", Public: true, ShareToken: "share-me"}
+		if err := s.CreateWishlist(ctx, &home); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := s.FindWishlistByID(ctx, birthday.ID)
+		if err != nil || len(found.Items) != 1 || *found.Items[0].SavedPrice != price {
+			t.Errorf("FindWishlistByID = %+v, %v", found, err)
+		}
+		lists, err := s.ListWishlistsByUser(ctx, userID)
+		if err != nil || len(lists) != 2 || lists[0].ID != birthday.ID || lists[1].ID != home.ID {
+			t.Errorf("ListWishlistsByUser = %+v, %v; want Birthday then Home", lists, err)
+		}
+		if shared, err := s.FindWishlistByShareToken(ctx, "share-me"); err != nil || shared.ID != home.ID {
+			t.Errorf("FindWishlistByShareToken = %+v, %v; want Home", shared, err)
+		}
+		if _, err := s.FindWishlistByShareToken(ctx, ""); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindWishlistByShareToken of no token error = %v, want ErrNotFound", err)
+		}
+
+		home.Name = "Birthday"
+		if err := s.UpdateWishlist(ctx, &home); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("UpdateWishlist to a taken name error = %v, want ErrDuplicate", err)
+		}
+		home.Name, home.Public = "Kitchen", false
+		if err := s.UpdateWishlist(ctx, &home); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.FindWishlistByShareToken(ctx, "share-me"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindWishlistByShareToken of a private wishlist error = %v, want ErrNotFound", err)
+		}
+		missing := models.Wishlist{ID: primitive.NewObjectID(), UserID: userID, Name: "Gone"}
+		if err := s.UpdateWishlist(ctx, &missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateWishlist of a stranger error = %v, want ErrNotFound", err)
+		}
+
+		if err := s.DeleteWishlist(ctx, birthday.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteWishlist(ctx, birthday.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second DeleteWishlist error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestPlaceOrderKeepsSavedForLater(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		userID := primitive.NewObjectID()
+		mug := createProduct(t, s, "Mug", 5)
+		plate := createProduct(t, s, "Plate", 5)
+		cart := models.Cart{
+			UserID:        userID,
+			Items:         []models.CartItem{{ProductID: mug.ID, Quantity: 1}},
+			SavedForLater: []models.CartItem{{ProductID: plate.ID, Quantity: 2}},
+			CouponCode:    "SAVE10",
+			RemindersSent: 1,
+		}
+		if err := s.SaveCart(ctx, &cart); err != nil {
+			t.Fatal(err)
+		}
+
+		order := models.Order{UserID: userID, Items: []models.OrderItem{{ProductID: mug.ID, Quantity: 1}}}
+		if err := s.PlaceOrder(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+		kept, err := s.FindCartByUserID(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kept.Items) != 0 || kept.CouponCode != "" || kept.RemindersSent != 0 {
+			t.Errorf("cart = %+v, want its items, coupon and reminders cleared", kept)
+		}
+		if len(kept.SavedForLater) != 1 || kept.SavedForLater[0].ProductID != plate.ID {
+			t.Errorf("saved = %+v, want the plates kept", kept.SavedForLater)
+		}
+	})
+}
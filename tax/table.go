@@ -0,0 +1,89 @@
+package tax
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"sort"
+	"strings"
+)
+
+// RateSource lists the tax rates of a state; store.TaxStore satisfies it
+type RateSource interface {
+	ListTaxRates(ctx context.Context, state string) ([]models.TaxRate, error)
+}
+
+// Table calculates tax from stored rate tables. With Inclusive set, prices
+// are taken to include tax and the tax is carved out of them; otherwise it is
+// added on top.
+type Table struct {
+	Rates     RateSource
+	Inclusive bool
+}
+
+var _ Calculator = (*Table)(nil)
+
+// NewTable creates a Table calculator reading rates from source
+func NewTable(source RateSource, inclusive bool) *Table {
+	return &Table{Rates: source, Inclusive: inclusive}
+}
+
+// Calculate sets the tax of every line from the rates of the address's state
+func (t *Table) Calculate(ctx context.Context, address models.Address, lines []models.OrderItem) error {
+	var rates []models.TaxRate
+	if state := strings.ToUpper(strings.TrimSpace(address.State)); state != "" {
+		var err error
+		rates, err = t.Rates.ListTaxRates(ctx, state)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range lines {
+		t.taxLine(&lines[i], applicable(rates, address, lines[i].TaxCategory))
+	}
+	return nil
+}
+
+// applicable picks the most specific matching rate of each tax, sorted by name
+func applicable(rates []models.TaxRate, address models.Address, category string) []models.TaxRate {
+	byName := make(map[string]models.TaxRate)
+	for _, rate := range rates {
+		if !rate.Matches(address, category) {
+			continue
+		}
+		if current, ok := byName[rate.Name]; !ok || rate.MoreSpecific(current) {
+			byName[rate.Name] = rate
+		}
+	}
+	chosen := make([]models.TaxRate, 0, len(byName))
+	for _, rate := range byName {
+		chosen = append(chosen, rate)
+	}
+	sort.Slice(chosen, func(i, j int) bool { return chosen[i].Name < chosen[j].Name })
+	return chosen
+}
+
+// taxLine applies rates to the line's discounted price. An inclusive price
+// P at total rate R holds P*R/(1+R) of tax, split between the taxes by rate.
+func (t *Table) taxLine(line *models.OrderItem, rates []models.TaxRate) {
+	taxable := line.Subtotal().Sub(line.Discount)
+	den := int64(10000)
+	if t.Inclusive {
+		for _, rate := range rates {
+			den += int64(rate.Rate)
+		}
+	}
+
+	line.Tax = models.NewMoney(0, taxable.Currency)
+	line.TaxIncluded = t.Inclusive
+	line.TaxBreakdown = nil
+	for _, rate := range rates {
+		amount := taxable.MulRate(int64(rate.Rate), den)
+		line.Tax = line.Tax.Add(amount)
+		line.TaxBreakdown = append(line.TaxBreakdown, models.TaxLine{
+			Name:   rate.Name,
+			Rate:   rate.Rate,
+			Amount: amount,
+		})
+	}
+	line.UpdateTotal()
+}
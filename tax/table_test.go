@@ -0,0 +1,95 @@
+package tax
+
+import (
+	"context"
+	"go-ecommerce/models"
+	"testing"
+)
+
+// rates is a RateSource over a fixed list
+type rates []models.TaxRate
+
+func (rs rates) ListTaxRates(ctx context.Context, state string) ([]models.TaxRate, error) {
+	var matched []models.TaxRate
+	for _, rate := range rs {
+		if rate.State == state {
+			matched = append(matched, rate)
+		}
+	}
+	return matched, nil
+}
+
+var californiaRates = rates{
+	{Name: "CA State Tax", State: "CA", Rate: 725},
+	{Name: "LA County Tax", State: "CA", ZipPrefix: "900", Rate: 100},
+	{Name: "LA County Tax", State: "CA", ZipPrefix: "9001", Rate: 150},
+	{Name: "CA State Tax", State: "CA", Category: "food", Rate: 0},
+}
+
+func line(unit int64, quantity int, category string) models.OrderItem {
+	return models.OrderItem{
+		Quantity:    quantity,
+		UnitPrice:   models.NewMoney(unit, "USD"),
+		Discount:    models.NewMoney(0, "USD"),
+		TaxCategory: category,
+	}
+}
+
+func TestTableExclusive(t *testing.T) {
+	lines := []models.OrderItem{line(1000, 2, ""), line(1000, 1, "food")}
+	lines[0].Discount = models.NewMoney(500, "USD")
+
+	address := models.Address{State: "ca", ZipCode: "90012"}
+	if err := NewTable(californiaRates, false).Calculate(context.Background(), address, lines); err != nil {
+		t.Fatal(err)
+	}
+
+	// 15.00 after the discount: 7.25% state tax and the 1.5% of the longer zip
+	// prefix, each rounded on its own (1.0875 and 0.225)
+	if got := lines[0].Tax; got != models.NewMoney(132, "USD") {
+		t.Errorf("tax = %v, want 1.32 USD", got)
+	}
+	if got := lines[0].LineTotal; got != models.NewMoney(1632, "USD") {
+		t.Errorf("line total = %v, want 16.32 USD", got)
+	}
+	breakdown := lines[0].TaxBreakdown
+	if len(breakdown) != 2 || breakdown[0].Name != "CA State Tax" || breakdown[0].Amount.Amount != 109 ||
+		breakdown[1].Name != "LA County Tax" || breakdown[1].Rate != 150 || breakdown[1].Amount.Amount != 23 {
+		t.Errorf("breakdown = %+v", breakdown)
+	}
+
+	// The food rate replaces the general state rate; the county tax still applies
+	if got := lines[1].Tax; got != models.NewMoney(15, "USD") {
+		t.Errorf("food tax = %v, want 0.15 USD", got)
+	}
+}
+
+func TestTableInclusive(t *testing.T) {
+	lines := []models.OrderItem{line(1088, 1, "")}
+	address := models.Address{State: "CA", ZipCode: "90012"}
+	if err := NewTable(californiaRates, true).Calculate(context.Background(), address, lines); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10.88 at 8.75% holds 0.88 of tax, already part of the price
+	if got := lines[0].Tax; got != models.NewMoney(88, "USD") {
+		t.Errorf("tax = %v, want 0.88 USD", got)
+	}
+	if !lines[0].TaxIncluded {
+		t.Error("line not marked tax-included")
+	}
+	if got := lines[0].LineTotal; got != models.NewMoney(1088, "USD") {
+		t.Errorf("line total = %v, want the price, 10.88 USD", got)
+	}
+}
+
+func TestTableNoRates(t *testing.T) {
+	lines := []models.OrderItem{line(1000, 1, "")}
+	lines[0].Tax = models.NewMoney(99, "USD")
+	if err := NewTable(californiaRates, false).Calculate(context.Background(), models.Address{State: "NY"}, lines); err != nil {
+		t.Fatal(err)
+	}
+	if !lines[0].Tax.IsZero() || lines[0].TaxBreakdown != nil || lines[0].LineTotal != models.NewMoney(1000, "USD") {
+		t.Errorf("untaxed line = %+v", lines[0])
+	}
+}
@@ -0,0 +1,17 @@
+// Package tax works out the sales tax on order lines. Calculator is the
+// extension point for an external tax service; Table is the built-in
+// calculator driven by admin-managed rate tables.
+package tax
+
+import (
+	"context"
+	"go-ecommerce/models"
+)
+
+// Calculator sets the tax on order lines
+type Calculator interface {
+	// Calculate sets Tax, TaxIncluded and TaxBreakdown on every line shipped
+	// to address, taxing each line's price after its discount, and updates
+	// the line totals
+	Calculate(ctx context.Context, address models.Address, lines []models.OrderItem) error
+}
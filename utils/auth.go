@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -9,10 +14,32 @@ import (
 // JWT Secret Key
 var JwtKey = []byte("your_secret_key") // This will be loaded from .env
 
+const (
+	// AccessTokenTTL is how long an access token stays valid
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a refresh token stays valid if unused
+	RefreshTokenTTL = 7 * 24 * time.Hour
+	// CartTokenTTL is how long a guest can keep coming back to their cart
+	CartTokenTTL = 30 * 24 * time.Hour
+)
+
 // Claims represents the JWT claims
 type Claims struct {
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"` // Set on access tokens only
+	jwt.StandardClaims
+}
+
+// CartClaims represents the claims of a guest cart token
+type CartClaims struct {
+	CartID string `json:"cart"`
+	jwt.StandardClaims
+}
+
+// UnsubscribeClaims represents the claims of an abandoned-cart unsubscribe link
+type UnsubscribeClaims struct {
+	Email string `json:"unsubscribe"`
 	jwt.StandardClaims
 }
 
@@ -33,3 +60,88 @@ func GenerateJWT(email, role string) (string, error) {
 	}
 	return tokenString, nil
 }
+
+// GenerateAccessToken generates a short-lived access token bound to a session
+func GenerateAccessToken(email, role, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenTTL)
+	claims := &Claims{
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expirationTime.Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JwtKey)
+}
+
+// GenerateCartToken signs a token that lets an anonymous visitor use the
+// guest cart with the given ID
+func GenerateCartToken(cartID string) (string, error) {
+	claims := &CartClaims{
+		CartID: cartID,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(CartTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JwtKey)
+}
+
+// ParseCartToken returns the ID of the guest cart a cart token was issued
+// for. Tokens of any other kind are rejected.
+func ParseCartToken(tokenStr string) (string, error) {
+	claims := &CartClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return JwtKey, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !token.Valid || claims.CartID == "" {
+		return "", errors.New("not a cart token")
+	}
+	return claims.CartID, nil
+}
+
+// GenerateUnsubscribeToken signs a token for the link in abandoned-cart
+// emails that turns them off. It does not expire, so old emails keep working.
+func GenerateUnsubscribeToken(email string) (string, error) {
+	claims := &UnsubscribeClaims{Email: email}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JwtKey)
+}
+
+// ParseUnsubscribeToken returns the email an unsubscribe token was issued
+// for. Tokens of any other kind are rejected.
+func ParseUnsubscribeToken(tokenStr string) (string, error) {
+	claims := &UnsubscribeClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return JwtKey, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !token.Valid || claims.Email == "" {
+		return "", errors.New("not an unsubscribe token")
+	}
+	return claims.Email, nil
+}
+
+// GenerateOpaqueToken returns a random URL-safe token for refresh and reset links
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest under which an opaque token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestOpaqueTokens(t *testing.T) {
+	first, err := GenerateOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("two tokens are equal")
+	}
+	// 32 random bytes, unpadded base64
+	if len(first) != 43 {
+		t.Errorf("token length = %d, want 43", len(first))
+	}
+
+	// Tokens are stored by hash, so hashing must be stable and not the identity
+	if HashToken(first) != HashToken(first) {
+		t.Error("HashToken is not deterministic")
+	}
+	if HashToken(first) == HashToken(second) || HashToken(first) == first {
+		t.Error("HashToken does not tell tokens apart")
+	}
+}
+
+func TestGenerateAccessToken(t *testing.T) {
+	signed, err := GenerateAccessToken("user@example.com", "admin", "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(signed, claims, func(*jwt.Token) (interface{}, error) { return JwtKey, nil })
+	if err != nil || !token.Valid {
+		t.Fatalf("parse = %v, %v", token, err)
+	}
+	if claims.Email != "user@example.com" || claims.Role != "admin" || claims.SessionID != "session-1" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if lifetime := claims.ExpiresAt - claims.IssuedAt; lifetime != int64(AccessTokenTTL.Seconds()) {
+		t.Errorf("lifetime = %ds, want %v", lifetime, AccessTokenTTL)
+	}
+}
+
+func TestCartTokens(t *testing.T) {
+	signed, err := GenerateCartToken("cart-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cartID, err := ParseCartToken(signed); err != nil || cartID != "cart-1" {
+		t.Errorf("ParseCartToken = %q, %v; want cart-1", cartID, err)
+	}
+
+	// Access tokens do not name a cart
+	access, err := GenerateAccessToken("user@example.com", "user", "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseCartToken(access); err == nil {
+		t.Error("ParseCartToken accepted an access token")
+	}
+	if _, err := ParseCartToken(signed + "x"); err == nil {
+		t.Error("ParseCartToken accepted a tampered token")
+	}
+}
+
+func TestUnsubscribeTokens(t *testing.T) {
+	signed, err := GenerateUnsubscribeToken("user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email, err := ParseUnsubscribeToken(signed); err != nil || email != "user@example.com" {
+		t.Errorf("ParseUnsubscribeToken = %q, %v; want user@example.com", email, err)
+	}
+
+	// Other tokens carrying an email must not unsubscribe anyone
+	verification, err := GenerateJWT("user@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseUnsubscribeToken(verification); err == nil {
+		t.Error("ParseUnsubscribeToken accepted a verification token")
+	}
+}
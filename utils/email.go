@@ -4,7 +4,10 @@ package utils
 import (
 	"fmt"
 	"go-ecommerce/models"
+	"html"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/keighl/postmark"
 )
@@ -21,6 +24,10 @@ func NewEmailService() *EmailService {
 		panic("POSTMARK_API_TOKEN is not set in environment variables")
 	}
 	client := postmark.NewClient(apiToken, "") // Include a valid sender if needed
+	// POSTMARK_API_URL points the service at a sandbox or test server instead
+	if apiURL := os.Getenv("POSTMARK_API_URL"); apiURL != "" {
+		client.BaseURL = strings.TrimSuffix(apiURL, "/")
+	}
 	return &EmailService{
 		client: client,
 	}
@@ -56,16 +63,167 @@ func (es *EmailService) SendVerificationEmail(toEmail, token string) error {
 	return es.SendEmail(toEmail, subject, htmlContent) // Ensure htmlContent is used
 }
 
+// SendPasswordResetEmail sends a single-use password reset link to the user
+func (es *EmailService) SendPasswordResetEmail(toEmail, token string, validFor time.Duration) error {
+	subject := "Reset Your Password"
+	resetLink := fmt.Sprintf("http://localhost:%s/reset-password?token=%s", os.Getenv("PORT"), token)
+	htmlContent := fmt.Sprintf(
+		"<strong>We received a request to reset your password.</strong> <a href=\"%s\">Reset Password</a><br><br>This link expires in %d minutes and can only be used once. If you did not ask for a reset, you can ignore this email.",
+		resetLink,
+		int(validFor.Minutes()),
+	)
+
+	return es.SendEmail(toEmail, subject, htmlContent)
+}
+
 // SendOrderConfirmationEmail sends an order confirmation email to the user
 func (es *EmailService) SendOrderConfirmationEmail(toEmail string, order models.Order) error {
 	subject := "Order Confirmation"
 	htmlContent := fmt.Sprintf(
-		"<strong>Dear Customer,</strong><br><br>Thank you for your purchase! Your order (ID: %s) has been placed successfully and will be delivered by <strong>%s</strong>.<br><br>Total Amount: <strong>$%.2f</strong><br>Payment Method: <strong>%s</strong><br><br>Thank you for shopping with us!",
+		"<strong>Dear Customer,</strong><br><br>Thank you for your purchase! Your order (ID: %s) has been placed successfully. Expected delivery: <strong>%s</strong>.<br><br>%s<br>Total Amount: <strong>%s</strong><br>Payment Method: <strong>%s</strong><br><br>Thank you for shopping with us!",
 		order.ID.Hex(),
 		order.DeliveryDate,
+		invoiceTable(models.InvoiceFor(order)),
 		order.TotalAmount,
 		order.PaymentMethod,
 	)
 
 	return es.SendEmail(toEmail, subject, htmlContent) // Ensure htmlContent is used
 }
+
+// invoiceTable renders an invoice's lines and totals as an HTML table
+func invoiceTable(invoice models.Invoice) string {
+	var b strings.Builder
+	b.WriteString("<table><tr><th align=\"left\">Item</th><th>Qty</th><th align=\"right\">Unit price</th><th align=\"right\">Total</th></tr>")
+	for _, line := range invoice.Lines {
+		name := html.EscapeString(line.Name)
+		if line.SKU != "" {
+			name += " (" + html.EscapeString(line.SKU) + ")"
+		}
+		fmt.Fprintf(&b, "<tr><td>%s</td><td align=\"center\">%d</td><td align=\"right\">%s</td><td align=\"right\">%s</td></tr>",
+			name, line.Quantity, line.UnitPrice, line.LineTotal)
+	}
+	fmt.Fprintf(&b, "<tr><td colspan=\"3\" align=\"right\">Subtotal</td><td align=\"right\">%s</td></tr>", invoice.Subtotal)
+	if !invoice.Discount.IsZero() {
+		fmt.Fprintf(&b, "<tr><td colspan=\"3\" align=\"right\">Discount</td><td align=\"right\">-%s</td></tr>", invoice.Discount)
+	}
+	if !invoice.Tax.IsZero() {
+		label := "Tax"
+		if invoice.TaxIncluded {
+			label = "Includes tax"
+		}
+		fmt.Fprintf(&b, "<tr><td colspan=\"3\" align=\"right\">%s</td><td align=\"right\">%s</td></tr>", label, invoice.Tax)
+	}
+	if !invoice.Shipping.IsZero() {
+		fmt.Fprintf(&b, "<tr><td colspan=\"3\" align=\"right\">Shipping</td><td align=\"right\">%s</td></tr>", invoice.Shipping)
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// SendOrderStatusEmail tells the user that their order moved to a new status
+func (es *EmailService) SendOrderStatusEmail(toEmail string, order models.Order, change models.StatusChange) error {
+	subject := fmt.Sprintf("Your order is now %s", change.To)
+	htmlContent := fmt.Sprintf(
+		"<strong>Dear Customer,</strong><br><br>Your order (ID: %s) status has changed from <strong>%s</strong> to <strong>%s</strong>.",
+		order.ID.Hex(),
+		change.From,
+		change.To,
+	)
+	if change.Note != "" {
+		htmlContent += fmt.Sprintf("<br><br>Note: %s", html.EscapeString(change.Note))
+	}
+	htmlContent += "<br><br>Thank you for shopping with us!"
+
+	return es.SendEmail(toEmail, subject, htmlContent)
+}
+
+// SendOrderCancellationEmail confirms to the user that their order was cancelled
+func (es *EmailService) SendOrderCancellationEmail(toEmail string, order models.Order, reason string, refundPending bool) error {
+	subject := "Order Cancelled"
+	htmlContent := fmt.Sprintf(
+		"<strong>Dear Customer,</strong><br><br>Your order (ID: %s) has been cancelled.<br><br>Reason: %s",
+		order.ID.Hex(),
+		html.EscapeString(reason),
+	)
+	if refundPending {
+		htmlContent += fmt.Sprintf("<br><br>A refund of <strong>%s</strong> has been requested and will be returned to your original payment method.", order.TotalAmount)
+	}
+	htmlContent += "<br><br>Thank you for shopping with us!"
+
+	return es.SendEmail(toEmail, subject, htmlContent)
+}
+
+// SendPaymentReviewEmail tells the user whether their crypto payment proof was accepted
+func (es *EmailService) SendPaymentReviewEmail(toEmail string, order models.Order, approved bool, note string) error {
+	subject := "Payment Verified"
+	htmlContent := fmt.Sprintf(
+		"<strong>Dear Customer,</strong><br><br>Your crypto payment for order (ID: %s) has been verified and your order is now being prepared.",
+		order.ID.Hex(),
+	)
+	if !approved {
+		subject = "Payment Proof Rejected"
+		htmlContent = fmt.Sprintf(
+			"<strong>Dear Customer,</strong><br><br>We could not verify the crypto payment proof for order (ID: %s). Please upload a new proof of payment.",
+			order.ID.Hex(),
+		)
+	}
+	if note != "" {
+		htmlContent += fmt.Sprintf("<br><br>Note: %s", html.EscapeString(note))
+	}
+	htmlContent += "<br><br>Thank you for shopping with us!"
+
+	return es.SendEmail(toEmail, subject, htmlContent)
+}
+
+// SendLowStockDigest lists the products at or below their reorder threshold for an admin
+func (es *EmailService) SendLowStockDigest(toEmail string, products []models.Product) error {
+	subject := fmt.Sprintf("Low stock: %d to reorder", len(products))
+	var b strings.Builder
+	b.WriteString("<strong>These products are at or below their reorder threshold:</strong><br><br>")
+	b.WriteString("<table><tr><th align=\"left\">Product</th><th align=\"left\">SKU</th><th>In stock</th><th>Reorder at</th></tr>")
+	for _, product := range products {
+		for _, level := range product.LowStock() {
+			fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td align=\"center\">%d</td><td align=\"center\">%d</td></tr>",
+				html.EscapeString(product.Name), html.EscapeString(level.SKU), level.Stock, product.ReorderThreshold)
+		}
+	}
+	b.WriteString("</table>")
+
+	return es.SendEmail(toEmail, subject, b.String())
+}
+
+// SendBackInStockEmail tells a subscriber that a product, or the variant
+// with the given SKU, can be bought again
+func (es *EmailService) SendBackInStockEmail(toEmail string, product models.Product, sku string) error {
+	name := html.EscapeString(product.Name)
+	if sku != "" {
+		name += " (" + html.EscapeString(sku) + ")"
+	}
+	subject := fmt.Sprintf("%s is back in stock", product.Name)
+	productLink := fmt.Sprintf("http://localhost:%s/products/%s", os.Getenv("PORT"), product.ID.Hex())
+	htmlContent := fmt.Sprintf(
+		"<strong>Dear Customer,</strong><br><br>Good news: <strong>%s</strong> is back in stock. <a href=\"%s\">View the product</a> before it sells out again.<br><br>Thank you for shopping with us!",
+		name,
+		productLink,
+	)
+
+	return es.SendEmail(toEmail, subject, htmlContent)
+}
+
+// SendCartReminderEmail reminds a customer of the items left in their cart,
+// with a link back to it and one to stop these reminders
+func (es *EmailService) SendCartReminderEmail(toEmail string, lines []models.OrderItem, unsubscribeToken string) error {
+	subject := "You left something in your cart"
+	cartLink := fmt.Sprintf("http://localhost:%s/cart", os.Getenv("PORT"))
+	unsubscribeLink := fmt.Sprintf("http://localhost:%s/cart/reminders/unsubscribe?token=%s", os.Getenv("PORT"), unsubscribeToken)
+	var b strings.Builder
+	b.WriteString("<strong>Dear Customer,</strong><br><br>These items are still waiting in your cart:<br><ul>")
+	for _, line := range lines {
+		fmt.Fprintf(&b, "<li>%d x %s (%s)</li>", line.Quantity, html.EscapeString(line.Name), line.UnitPrice)
+	}
+	fmt.Fprintf(&b, "</ul><a href=\"%s\">Return to your cart</a> to check out before they sell out.<br><br>", cartLink)
+	fmt.Fprintf(&b, "<small>Don't want these reminders? <a href=\"%s\">Unsubscribe</a>.</small>", unsubscribeLink)
+
+	return es.SendEmail(toEmail, subject, b.String())
+}